@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/export"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	redisAddr    = flag.String("redis-addr", "127.0.0.1:6379", "Addr: redis address")
+	ossAddr      = flag.String("addr-oss", "127.0.0.1:9000", "Addr: oss server")
+	ossKey       = flag.String("oss-key", "HELLO", "oss client access key")
+	ossSecretKey = flag.String("oss-secret-key", "WORLD", "oss client access secret key")
+	ossUseSSL    = flag.Bool("oss-ssl", false, "oss client use ssl")
+	ossBucket    = flag.String("oss-bucket", "images", "oss bucket name")
+	ossWorkers   = flag.Int("oss-workers", 8, "Max images downloaded in parallel")
+	dateStart    = flag.String("date-start", "", "Datatime: date start in RFC3339 format. For example: 2019-03-01T00:00:00+08:00")
+	dateEnd      = flag.String("date-end", "", "Datatime: date end in RFC3339 format")
+	uids         = flag.String("uids", "", "interested user ids. Empyt means all. For example: 1226,3495")
+	shops        = flag.String("shops", "", "interested shops. Empyt means all")
+	positions    = flag.String("positions", "", "interested positions. Empyt means all")
+	gender       = flag.Int("gender", export.AnyGender, "interested gender, -1 means any")
+	ageMin       = flag.Uint("age-min", 0, "min age, inclusive")
+	ageMax       = flag.Uint("age-max", 0, "max age, inclusive. 0 means no limit")
+	format       = flag.String("format", export.FormatCSV, "Output format: csv, jsonl or zip (images with manifest.csv)")
+	output       = flag.String("output", "visits.csv", "output file")
+	progress     = flag.String("progress", "", "File: the progress to resume from, the default is <output>.progress")
+	batchSize    = flag.Int64("batch-size", 1000, "Max visits per round trip, the progress is saved after each batch")
+)
+
+func s3Get(srv *s3.S3, key string) (value []byte, err error) {
+	var out *s3.GetObjectOutput
+	out, err = srv.GetObject(&s3.GetObjectInput{
+		Bucket: ossBucket,
+		Key:    &key,
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			err = export.ErrImageNotFound
+			return
+		}
+		err = errors.Wrap(err, "")
+		return
+	}
+	defer out.Body.Close()
+	if value, err = ioutil.ReadAll(out.Body); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	return
+}
+
+func parseFilter() (filter *export.Filter, err error) {
+	filter = &export.Filter{Gender: *gender, AgeMin: uint32(*ageMin), AgeMax: uint32(*ageMax)}
+	if filter.Uids, err = export.ParseUints(*uids); err != nil {
+		return
+	}
+	if filter.Shops, err = export.ParseUints(*shops); err != nil {
+		return
+	}
+	filter.Positions, err = export.ParseUints(*positions)
+	return
+}
+
+// exportVisits exports the visits in visit_index. It resumes from the progress
+// if it's interrupted, run it again with the same arguments.
+func main() {
+	var err error
+	flag.Parse()
+
+	var filter *export.Filter
+	if filter, err = parseFilter(); err != nil {
+		log.Fatalf("got error %+v", err)
+	}
+	var tsStart, tsEnd int64
+	if tsStart, tsEnd, err = server.ParseTimeRange(*dateStart, *dateEnd); err != nil {
+		log.Fatalf("got error %+v", err)
+	}
+	if *progress == "" {
+		*progress = *output + ".progress"
+	}
+	// an empty date end is now, which changes every run
+	query := fmt.Sprintf("format=%s start=%d end=%s uids=%s shops=%s positions=%s gender=%d age=%d-%d",
+		*format, tsStart, *dateEnd, *uids, *shops, *positions, *gender, *ageMin, *ageMax)
+	var p *export.Progress
+	if p, err = export.LoadProgress(*progress, query, tsStart); err != nil {
+		log.Fatalf("got error %+v", err)
+	}
+
+	var w export.Writer
+	var iw *export.ImageWriter
+	staging := *output + ".d"
+	switch *format {
+	case export.FormatCSV:
+		w, err = export.NewCSVWriter(*output, p.Size)
+	case export.FormatJSONL:
+		w, err = export.NewJSONLWriter(*output, p.Size)
+	case export.FormatZip:
+		sess := session.Must(session.NewSession(&aws.Config{
+			Credentials:      credentials.NewStaticCredentials(*ossKey, *ossSecretKey, ""),
+			Endpoint:         aws.String(*ossAddr),
+			DisableSSL:       aws.Bool(!*ossUseSSL),
+			S3ForcePathStyle: aws.Bool(true),
+			Region:           aws.String("default"),
+		}))
+		srv := s3.New(sess)
+		if iw, err = export.NewImageWriter(staging, p.Size, func(objID string) ([]byte, error) {
+			return s3Get(srv, objID)
+		}, *ossWorkers); err == nil {
+			w = iw
+		}
+	default:
+		err = errors.Errorf("unsupported format %q", *format)
+	}
+	if err != nil {
+		log.Fatalf("got error %+v", err)
+	}
+
+	rcli := redis.NewClient(&redis.Options{
+		Addr:     *redisAddr,
+		Password: "", // no password set
+		DB:       0,  // use default DB
+	})
+
+	if err = export.Export(rcli, server.VisitIndexKey, filter, tsEnd, *batchSize, w, p, *progress); err != nil {
+		w.Close()
+		log.Fatalf("got error %+v, exported %v visits, run it again to resume", err, p.Exported)
+	}
+	if err = w.Close(); err != nil {
+		log.Fatalf("got error %+v", err)
+	}
+	if *format == export.FormatZip {
+		if err = export.Pack(staging, *output); err != nil {
+			log.Fatalf("got error %+v", err)
+		}
+		log.Infof("Packed %s to %s, remove %s when it's not needed", staging, *output, staging)
+		if iw.Missing != 0 {
+			log.Warnf("%d images are not found in oss, their visits are marked missing in manifest.csv", iw.Missing)
+		}
+	}
+
+	log.Infof("Exported %v visits to %s", p.Exported, *output)
+	return
+}
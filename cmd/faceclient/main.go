@@ -77,7 +77,7 @@ func main() {
 			buf := bytes.NewBuffer([]byte{})
 			_ = runPprof.Lookup("goroutine").WriteTo(buf, 1)
 			log.Infof("got signal=<%d>.", sig)
-			log.Infof(buf.String())
+			log.Info(buf.String())
 			continue
 		case syscall.SIGUSR2:
 			log.Infof("got signal=<%d>.", sig)
@@ -135,6 +135,10 @@ func parseCfg() *monitor.Cfg {
 	cfg.RetriesInterval = time.Second * time.Duration(*retriesInterval)
 	cfg.RetriesPerServer = *retriesPerServer
 	cfg.UsageInterval = time.Second * time.Duration(*usageInterval)
+	// the log file flag is registered by the log package
+	if f := flag.Lookup("log-file"); f != nil {
+		cfg.LogFile = f.Value.String()
+	}
 
 	return cfg
 }
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fagongzi/log"
+	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+)
+
+const (
+	// adminTokenHeader is the header of the token required by the correction handlers
+	adminTokenHeader = server.AdminTokenHeader
+)
+
+var (
+	// errInvalidCorrection the correction is rejected before anything is changed
+	errInvalidCorrection = errors.New("invalid correction")
+)
+
+// Corrector merges and splits uids. The vectors are kept in the vector index
+// since they're keyed by xid, only the xid to uid mappings change. A correction
+// event is published to identity_correction_queue and applied to PostgreSQL.
+// The history of a merged uid is merged too, the visits of the split xids are
+// moved to the history of the new uid.
+type Corrector struct {
+	ids      server.IdentityStore
+	history  server.HistoryStore
+	rcli     *redis.Client
+	recorder *Recorder
+	// token is required by the handlers, they are disabled if it's empty
+	token string
+
+	// splitMu serializes the visit_queue scans of splits
+	splitMu sync.Mutex
+	// wg waits for the splits moving visits in background
+	wg sync.WaitGroup
+}
+
+func newCorrector(iden3 *Identifier3, recorder *Recorder, token string) *Corrector {
+	return &Corrector{
+		ids:      iden3.ids,
+		history:  iden3.history,
+		rcli:     iden3.rcli,
+		recorder: recorder,
+		token:    token,
+	}
+}
+
+// Merge moves the xids and visits of src to dst
+func (c *Corrector) Merge(dst, src int64) (ev *server.CorrectionEvent, err error) {
+	if dst == src {
+		err = errors.Wrapf(errInvalidCorrection, "merge uid %d into itself", dst)
+		return
+	}
+	var moved []int64
+	if moved, err = c.ids.Merge(dst, src); err != nil {
+		return
+	}
+	log.Infof("merged uid %d into %d, xids %v", src, dst, moved)
+	ev = server.NewCorrectionEvent(server.CorrectionMerge, dst, src, moved)
+	err = c.emit(ev)
+	if c.history != nil {
+		if e := c.history.Merge(dst, src); e != nil && err == nil {
+			err = errors.Wrapf(e, "uid %d is merged into %d, but its history is not", src, dst)
+		}
+	}
+	return
+}
+
+// Split moves the xids of uid and the visits identified by them to a new uid.
+// The visits are found in visit_queue, the ones recorded before the xid is
+// kept in Visit are not moved.
+func (c *Corrector) Split(uid int64, xids []int64) (ev *server.CorrectionEvent, err error) {
+	if ev, err = c.splitXids(uid, xids); err != nil {
+		return
+	}
+	err = c.moveVisits(ev)
+	return
+}
+
+// splitXids moves the xids of uid to a new uid, and returns the event without the visits
+func (c *Corrector) splitXids(uid int64, xids []int64) (ev *server.CorrectionEvent, err error) {
+	if len(xids) == 0 {
+		err = errors.Wrapf(errInvalidCorrection, "split uid %d without xids", uid)
+		return
+	}
+	unique := make([]int64, 0, len(xids))
+	seen := make(map[int64]bool, len(xids))
+	for _, xid := range xids {
+		if seen[xid] {
+			continue
+		}
+		seen[xid] = true
+		var owner int64
+		if owner, err = c.ids.GetUid(xid); errors.Cause(err) == server.ErrIdentityNotFound || (err == nil && owner != uid) {
+			err = errors.Wrapf(errInvalidCorrection, "xid %016x is not of uid %d", uint64(xid), uid)
+			return
+		} else if err != nil {
+			return
+		}
+		unique = append(unique, xid)
+	}
+
+	var newUid int64
+	if newUid, err = c.ids.Split(uid, unique); err != nil {
+		return
+	}
+	log.Infof("split xids %v of uid %d to uid %d", unique, uid, newUid)
+	ev = server.NewCorrectionEvent(server.CorrectionSplit, newUid, uid, unique)
+	return
+}
+
+// moveVisits finds the visits of the split xids in visit_queue, applies the event
+// and moves the visits in the history
+func (c *Corrector) moveVisits(ev *server.CorrectionEvent) (err error) {
+	c.splitMu.Lock()
+	defer c.splitMu.Unlock()
+
+	moved := make(map[int64]bool, len(ev.Xids))
+	for _, xid := range ev.Xids {
+		moved[xid] = true
+	}
+	if err = server.ScanVisits(c.rcli, server.VisitQueueKey, 1000, func(visit *server.Visit, raw string) {
+		if moved[visit.Xid] {
+			ev.PictureIds = append(ev.PictureIds, visit.PictureId)
+			ev.VisitTimes = append(ev.VisitTimes, visit.VisitTime)
+		}
+	}); err != nil {
+		err = errors.Wrapf(err, "uid %d is split to %d, but its visits are not", ev.From, ev.Uid)
+		return
+	}
+	err = c.emit(ev)
+	if c.history != nil {
+		if e := c.history.Split(ev.From, ev.Uid, ev.Xids); e != nil && err == nil {
+			err = errors.Wrapf(e, "uid %d is split to %d, but its history is not", ev.From, ev.Uid)
+		}
+	}
+	return
+}
+
+// Wait waits for the splits moving visits in background
+func (c *Corrector) Wait() {
+	c.wg.Wait()
+}
+
+func (c *Corrector) emit(ev *server.CorrectionEvent) (err error) {
+	if err = server.PublishCorrection(c.rcli, server.CorrectionKey, ev); err != nil {
+		log.Errorf("lost correction event %+v, errors:%+v", ev, err)
+	}
+	// the first error is returned, the correction is applied anyway
+	if e := c.recorder.Correct(ev); e != nil && err == nil {
+		err = errors.Wrapf(e, "apply correction %+v", ev)
+	}
+	return
+}
+
+func parseUid(value string) (uid int64, err error) {
+	if uid, err = strconv.ParseInt(value, 10, 64); err != nil || uid <= 0 {
+		err = errors.Errorf("invalid uid %q", value)
+	}
+	return
+}
+
+func parseXids(value string) (xids []int64, err error) {
+	for _, field := range strings.Split(value, ",") {
+		var xid int64
+		if xid, err = server.ParseXid(strings.TrimSpace(field)); err != nil {
+			return
+		}
+		xids = append(xids, xid)
+	}
+	return
+}
+
+func writeCorrection(w http.ResponseWriter, ev *server.CorrectionEvent, status int, err error) {
+	if ev == nil {
+		if errors.Cause(err) == errInvalidCorrection {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			log.Errorf("correction failed, errors:%+v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		// the identities are corrected, but the visits may be not
+		w.Header().Set("X-Correction-Error", strconv.Quote(err.Error()))
+		status = http.StatusInternalServerError
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ev)
+}
+
+// authorize checks the method and the token of a correction request
+func (c *Corrector) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return checkAdminToken(w, r, c.token, "identity correction")
+}
+
+// checkAdminToken checks the token of an admin request, what is disabled if token is empty
+func checkAdminToken(w http.ResponseWriter, r *http.Request, token, what string) bool {
+	if token == "" {
+		http.Error(w, what+" is disabled without --admin-token", http.StatusForbidden)
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(adminTokenHeader)), []byte(token)) != 1 {
+		http.Error(w, "invalid "+adminTokenHeader, http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// MergeHandler returns a http handler that merges uids.
+// Usage: POST /identity/merge?dst=12&src=34 with the X-Admin-Token header
+func (c *Corrector) MergeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.authorize(w, r) {
+			return
+		}
+		q := r.URL.Query()
+		dst, err := parseUid(q.Get("dst"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		src, err := parseUid(q.Get("src"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ev, err := c.Merge(dst, src)
+		writeCorrection(w, ev, http.StatusOK, err)
+	})
+}
+
+// SplitHandler returns a http handler that splits xids of a uid to a new uid.
+// The visits are moved in background since visit_queue is scanned, so it
+// responds 202 with the event without the visits.
+// Usage: POST /identity/split?uid=12&xids=8f3b2a1c0d9e7f65,0123456789abcdef with the X-Admin-Token header
+func (c *Corrector) SplitHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.authorize(w, r) {
+			return
+		}
+		q := r.URL.Query()
+		uid, err := parseUid(q.Get("uid"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		xids, err := parseXids(q.Get("xids"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ev, err := c.splitXids(uid, xids)
+		if err != nil {
+			writeCorrection(w, nil, 0, err)
+			return
+		}
+		writeCorrection(w, ev, http.StatusAccepted, nil)
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			if err := c.moveVisits(ev); err != nil {
+				log.Errorf("split xids %v of uid %d to uid %d, moving the visits failed, errors:%+v", ev.Xids, ev.From, ev.Uid, err)
+			}
+		}()
+	})
+}
+
+// runCorrection merges or splits uids as the flags say, then quit
+func runCorrection(c *Corrector) (err error) {
+	var ev *server.CorrectionEvent
+	if *mergeUids != "" {
+		uids := strings.Split(*mergeUids, ",")
+		if len(uids) != 2 {
+			return errors.Errorf("--merge-uids requires dst,src but got %q", *mergeUids)
+		}
+		var dst, src int64
+		if dst, err = parseUid(uids[0]); err != nil {
+			return
+		}
+		if src, err = parseUid(uids[1]); err != nil {
+			return
+		}
+		ev, err = c.Merge(dst, src)
+	} else {
+		var uid int64
+		var xids []int64
+		if uid, err = parseUid(*splitUid); err != nil {
+			return
+		}
+		if xids, err = parseXids(*splitXids); err != nil {
+			return
+		}
+		ev, err = c.Split(uid, xids)
+	}
+	if ev != nil {
+		log.Infof("correction event: %+v", ev)
+	}
+	return
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret"
+
+func newTestCorrector(rcli *redis.Client) (*Corrector, *server.MemoryIdentityStore) {
+	ids := server.NewMemoryIdentityStore()
+	return &Corrector{
+		ids:      ids,
+		history:  server.NewMemoryHistoryStore(0, 100),
+		rcli:     rcli,
+		recorder: &Recorder{},
+		token:    testToken,
+	}, ids
+}
+
+func correct(h http.Handler, method, url, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	if token != "" {
+		req.Header.Set(adminTokenHeader, token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestCorrectorAuthorize(t *testing.T) {
+	c, _ := newTestCorrector(nil)
+	for _, h := range []http.Handler{c.MergeHandler(), c.SplitHandler()} {
+		require.Equal(t, http.StatusMethodNotAllowed, correct(h, "GET", "/identity?uid=1", testToken).Code)
+		require.Equal(t, http.StatusUnauthorized, correct(h, "POST", "/identity?uid=1", "").Code)
+		require.Equal(t, http.StatusUnauthorized, correct(h, "POST", "/identity?uid=1", "wrong").Code)
+	}
+
+	// disabled without a token
+	c.token = ""
+	require.Equal(t, http.StatusForbidden, correct(c.MergeHandler(), "POST", "/identity/merge?dst=1&src=2", "").Code)
+}
+
+func TestCorrectorInvalid(t *testing.T) {
+	c, ids := newTestCorrector(nil)
+	uid, err := ids.AllocateUid()
+	require.NoError(t, err)
+	require.NoError(t, ids.Associate(uid, 0x10))
+
+	merge, split := c.MergeHandler(), c.SplitHandler()
+	for _, url := range []string{
+		"/identity/merge?dst=1&src=x",
+		"/identity/merge?dst=1&src=1",
+	} {
+		require.Equal(t, http.StatusBadRequest, correct(merge, "POST", url, testToken).Code, url)
+	}
+	for _, url := range []string{
+		"/identity/split?uid=1&xids=zz",
+		"/identity/split?uid=1&xids=0000000000000020",
+		"/identity/split?uid=2&xids=0000000000000010",
+	} {
+		require.Equal(t, http.StatusBadRequest, correct(split, "POST", url, testToken).Code, url)
+	}
+}
+
+func TestCorrectorSplitDuplicatedXids(t *testing.T) {
+	c, ids := newTestCorrector(nil)
+	uid, err := ids.AllocateUid()
+	require.NoError(t, err)
+	for _, xid := range []int64{0x10, 0x20, 0x30} {
+		require.NoError(t, ids.Associate(uid, xid))
+	}
+
+	ev, err := c.splitXids(uid, []int64{0x10, 0x20, 0x10})
+	require.NoError(t, err)
+	require.Equal(t, []int64{0x10, 0x20}, ev.Xids)
+	xids, err := ids.ListXids(ev.Uid)
+	require.NoError(t, err)
+	require.Len(t, xids, 2)
+	xids, err = ids.ListXids(uid)
+	require.NoError(t, err)
+	require.Equal(t, []int64{0x30}, xids)
+}
+
+func TestCorrectorEmitKeepsPublishError(t *testing.T) {
+	// the client is closed, publishing fails, so does applying without PostgreSQL
+	rcli := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	c, _ := newTestCorrector(rcli)
+	require.NoError(t, rcli.Close())
+	err := c.emit(server.NewCorrectionEvent(server.CorrectionMerge, 1, 2, nil))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "publish correction")
+}
+
+func TestCorrectorRedis(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	rcli := redis.NewClient(&redis.Options{Addr: addr, DB: 15})
+	defer rcli.Close()
+	require.NoError(t, rcli.FlushDB().Err())
+
+	c, ids := newTestCorrector(rcli)
+	uid, err := ids.AllocateUid()
+	require.NoError(t, err)
+	require.NoError(t, ids.Associate(uid, 0x10))
+	require.NoError(t, ids.Associate(uid, 0x20))
+	for i, xid := range []int64{0x10, 0x20, 0x10} {
+		visit := &server.Visit{PictureId: string(rune('a' + i)), Uid: uint64(uid), Xid: xid, VisitTime: uint64(1000 + i)}
+		data, err := visit.Marshal()
+		require.NoError(t, err)
+		require.NoError(t, server.AppendVisit(rcli, server.VisitQueueKey, server.VisitIndexKey, visit, data))
+		require.NoError(t, c.history.Record(visit))
+	}
+
+	// the database is not configured, the event is returned with the error
+	w := correct(c.SplitHandler(), "POST", "/identity/split?uid=1&xids=0000000000000010", testToken)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var ev server.CorrectionEvent
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ev))
+	require.Equal(t, []int64{0x10}, ev.Xids)
+	c.Wait()
+	require.Equal(t, int64(1), rcli.LLen(server.CorrectionKey).Val())
+	require.NoError(t, json.Unmarshal([]byte(rcli.LIndex(server.CorrectionKey, 0).Val()), &ev))
+	require.Equal(t, []string{"a", "c"}, ev.PictureIds)
+	// the history of the visits moves with the xid
+	visits, err := c.history.ListVisits(ev.Uid, 0, 2000, 10)
+	require.NoError(t, err)
+	require.Len(t, visits, 2)
+	visits, err = c.history.ListVisits(uid, 0, 2000, 10)
+	require.NoError(t, err)
+	require.Len(t, visits, 1)
+	require.Equal(t, "b", visits[0].PictureId)
+
+	w = correct(c.MergeHandler(), "POST", "/identity/merge?dst=1&src=2", testToken)
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.NotEmpty(t, w.Header().Get("X-Correction-Error"))
+	xids, err := ids.ListXids(1)
+	require.NoError(t, err)
+	require.Len(t, xids, 2)
+}
@@ -317,6 +317,67 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
+CREATE OR REPLACE FUNCTION rebuild_visit_stats_user(param_uid int8) RETURNS int AS $$
+BEGIN
+	DELETE FROM visit_stats_user WHERE uid=param_uid;
+	INSERT INTO visit_stats_user(shop_id, uid, hours, last_visit_time) SELECT shop_id, uid, rb_build_agg((EXTRACT(EPOCH FROM visit_time)/(60*60))::int), max(visit_time) FROM visit_events WHERE uid=param_uid AND position=0 GROUP BY shop_id, uid;
+	RETURN 0;
+END;
+$$ LANGUAGE plpgsql;
+
+-- re-label the visits of param_src as param_dst, a visit of param_src at the same time as one of param_dst is dropped.
+-- param_src is replaced by param_dst in the visit_stats_uv bitmaps.
+CREATE OR REPLACE FUNCTION merge_uid(param_dst int8, param_src int8) RETURNS int AS $$
+DECLARE
+	val_columns text[] := array['frequent_users', 'total', 'gender_0', 'gender_1', 'age_0', 'age_1', 'age_2', 'age_3', 'age_4', 'age_5', 'age_6', 'age_7', 'age_8', 'age_9', 'age_10', 'age_11', 'age_12', 'age_13', 'age_14', 'age_15', 'age_16', 'age_17', 'age_18', 'age_19'];
+	val_col text;
+BEGIN
+	UPDATE users SET uid=param_dst WHERE uid=param_src;
+	DELETE FROM visit_events s WHERE s.uid=param_src AND EXISTS (SELECT 1 FROM visit_events d WHERE d.uid=param_dst AND d.visit_time=s.visit_time);
+	UPDATE visit_events SET uid=param_dst WHERE uid=param_src;
+	PERFORM rebuild_visit_stats_user(param_src);
+	PERFORM rebuild_visit_stats_user(param_dst);
+	FOREACH val_col IN ARRAY val_columns LOOP
+		EXECUTE format('UPDATE visit_stats_uv SET %1$s=rb_add(rb_remove(%1$s, $1), $2) WHERE rb_contains(%1$s, $1)', val_col) USING param_src::int, param_dst::int;
+	END LOOP;
+	RETURN 1;
+END;
+$$ LANGUAGE plpgsql;
+
+-- move the given visits of param_uid to param_new_uid
+CREATE OR REPLACE FUNCTION split_uid(param_uid int8, param_new_uid int8, param_picture_ids text[], param_visit_times timestamptz[]) RETURNS int AS $$
+BEGIN
+	UPDATE users SET uid=param_new_uid WHERE uid=param_uid AND picture_id=ANY(param_picture_ids);
+	UPDATE visit_events SET uid=param_new_uid WHERE uid=param_uid AND visit_time=ANY(param_visit_times);
+	PERFORM rebuild_visit_stats_user(param_uid);
+	PERFORM rebuild_visit_stats_user(param_new_uid);
+	RETURN 1;
+END;
+$$ LANGUAGE plpgsql;
+
+-- erase param_uid, the anonymous counters in visit_stats_pv are kept. Returns the deleted rows.
+CREATE OR REPLACE FUNCTION forget_uid(param_uid int8) RETURNS int8 AS $$
+DECLARE
+	val_columns text[] := array['frequent_users', 'total', 'gender_0', 'gender_1', 'age_0', 'age_1', 'age_2', 'age_3', 'age_4', 'age_5', 'age_6', 'age_7', 'age_8', 'age_9', 'age_10', 'age_11', 'age_12', 'age_13', 'age_14', 'age_15', 'age_16', 'age_17', 'age_18', 'age_19'];
+	val_rows int8;
+	val_cnt int8;
+	val_col text;
+BEGIN
+	DELETE FROM users WHERE uid=param_uid;
+	GET DIAGNOSTICS val_rows = ROW_COUNT;
+	DELETE FROM visit_events WHERE uid=param_uid;
+	GET DIAGNOSTICS val_cnt = ROW_COUNT;
+	val_rows = val_rows + val_cnt;
+	DELETE FROM visit_stats_user WHERE uid=param_uid;
+	GET DIAGNOSTICS val_cnt = ROW_COUNT;
+	val_rows = val_rows + val_cnt;
+	FOREACH val_col IN ARRAY val_columns LOOP
+		EXECUTE format('UPDATE visit_stats_uv SET %1$s=rb_remove(%1$s, $1) WHERE rb_contains(%1$s, $1)', val_col) USING param_uid::int;
+	END LOOP;
+	RETURN val_rows;
+END;
+$$ LANGUAGE plpgsql;
+
 CREATE OR REPLACE FUNCTION get_shuke_stats(param_shop_id int8, param_day_start timestamptz, param_day_end timestamptz) RETURNS table(vt timestamptz, first_users int, multi_users int) AS $$
 BEGIN
     param_day_start = date_trunc('day', param_day_start);
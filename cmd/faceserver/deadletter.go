@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/fagongzi/log"
+	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/infinivision/filesyncer/pkg/sessionizer"
+	"github.com/infinivision/filesyncer/pkg/sink"
+	"github.com/pkg/errors"
+)
+
+func newDeadLetterQueue() server.DeadLetterQueue {
+	if *deadLetterFile != "" {
+		log.Infof("dead letters are kept in %s", *deadLetterFile)
+		return server.NewFileDeadLetterQueue(*deadLetterFile)
+	}
+	rcli := redis.NewClient(&redis.Options{
+		Addr:     *redisAddr,
+		Password: "", // no password set
+		DB:       0,  // use default DB
+	})
+	log.Infof("dead letters are kept in %s of %s", server.DeadLetterKey, *redisAddr)
+	return server.NewRedisDeadLetterQueue(rcli, server.DeadLetterKey)
+}
+
+// pushDeadLetters pushes the letters, they're logged as lost if it failed
+func pushDeadLetters(dlq server.DeadLetterQueue, letters []*server.DeadLetter) (err error) {
+	if len(letters) == 0 {
+		return
+	}
+	server.ObserveDeadLetters(letters...)
+	if err = dlq.Push(letters...); err != nil {
+		// the last resort
+		for _, dl := range letters {
+			log.Errorf("lost dead letter %+v, errors:%+v", dl, err)
+		}
+	}
+	return
+}
+
+// redriveDeadLetters re-drives the dead letters queued before it starts. The
+// letters failed again are queued back with the attempts increased. Popped letters
+// are acked only after they are re-driven or queued back, the ones left by a crashed
+// re-drive are recovered first.
+func redriveDeadLetters(iden3 *Identifier3, recorder *Recorder, dlq server.DeadLetterQueue) (err error) {
+	var recovered, total int64
+	if recovered, err = dlq.Recover(); err != nil {
+		return
+	}
+	if recovered != 0 {
+		log.Infof("recovered %d dead letters left by the last re-drive", recovered)
+	}
+	if total, err = dlq.Len(); err != nil {
+		return
+	}
+	log.Infof("re-driving %d dead letters...", total)
+	srv := newS3()
+
+	var succ, failed int64
+	for done := int64(0); done < total; {
+		var letters []*server.DeadLetter
+		if letters, err = dlq.Pop(*identifyBatchSize); err != nil {
+			return
+		}
+		if len(letters) == 0 {
+			break
+		}
+		done += int64(len(letters))
+
+		// letters being re-driven by ObjID, an image may have several letters
+		pending := make(map[string][]*server.DeadLetter, len(letters))
+		var imgMsgs []server.ImgMsg
+		// the identified visits by the failed sinks
+		visits := make(map[string][]*server.Visit)
+		var refailed []*server.DeadLetter
+		for _, dl := range letters {
+			if dl.Stage == server.StageRecord && len(dl.Visit) != 0 {
+				// identified already, record it only to the failed sinks
+				var visit *server.Visit
+				if visit, err = server.DecodeVisit(dl.Visit); err == nil {
+					sinks := strings.Join(dl.Sinks, ",")
+					visits[sinks] = append(visits[sinks], visit)
+					pending[dl.ObjID] = append(pending[dl.ObjID], dl)
+					continue
+				}
+				log.Errorf("decode visit of %s failed, identify it again, errors:%+v", dl.ObjID, err)
+			}
+
+			var img []byte
+			if img, err = s3Get(srv, dl.ObjID); err != nil {
+				log.Errorf("get %s from oss failed, errors:%+v", dl.ObjID, err)
+				server.ObserveRedrive(dl.Stage, false)
+				dl.Fail(dl.Stage, err)
+				refailed = append(refailed, dl)
+				continue
+			}
+			imgMsgs = append(imgMsgs, dl.ImgMsg(img))
+			pending[dl.ObjID] = append(pending[dl.ObjID], dl)
+		}
+		err = nil
+
+		var failures []*server.DeadLetter
+		for sinks, vs := range visits {
+			failures = append(failures, recordVisitsTo(sink.Select(recorder.sink, sink.ParseKinds(sinks)), vs, nil)...)
+		}
+		failures = append(failures, handleImgMsgs(iden3, recorder, sessionizer.New(0), imgMsgs)...)
+		refailed = append(refailed, refail(pending, failures)...)
+		for _, dls := range pending {
+			for _, dl := range dls {
+				server.ObserveRedrive(dl.Stage, true)
+				succ++
+			}
+		}
+
+		failed += int64(len(refailed))
+		if len(refailed) != 0 {
+			if err = dlq.Push(refailed...); err != nil {
+				return
+			}
+		}
+		if err = dlq.Ack(letters...); err != nil {
+			return
+		}
+	}
+	log.Infof("re-drove dead letters, %d succeeded, %d failed again", succ, failed)
+	return
+}
+
+// refail fails the pending letters by the failures of their images, each failure
+// fails one of them. The failures of no pending letter are new letters. The letters
+// left in pending are succeeded.
+func refail(pending map[string][]*server.DeadLetter, failures []*server.DeadLetter) (refailed []*server.DeadLetter) {
+	for _, f := range failures {
+		dls := pending[f.ObjID]
+		if len(dls) == 0 {
+			refailed = append(refailed, f)
+			continue
+		}
+		dl := dls[0]
+		pending[f.ObjID] = dls[1:]
+		server.ObserveRedrive(dl.Stage, false)
+		dl.Fail(f.Stage, errors.New(f.Error))
+		if f.Visit != nil {
+			dl.Visit, dl.Sinks = f.Visit, f.Sinks
+		}
+		refailed = append(refailed, dl)
+	}
+	return
+}
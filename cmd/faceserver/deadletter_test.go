@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefailDuplicatedLetters(t *testing.T) {
+	img := server.ImgMsg{ObjID: "obj1"}
+	first := server.NewDeadLetter(img, server.StageIdentify, errors.New("down"))
+	second := server.NewDeadLetter(img, server.StageIdentify, errors.New("down"))
+	other := server.NewDeadLetter(server.ImgMsg{ObjID: "obj2"}, server.StageIdentify, errors.New("down"))
+	pending := map[string][]*server.DeadLetter{"obj1": {first, second}, "obj2": {other}}
+
+	// one of the letters of obj1 fails again, the other succeeds
+	refailed := refail(pending, []*server.DeadLetter{
+		server.NewDeadLetter(img, server.StageRecord, errors.New("db down")),
+		server.NewDeadLetter(server.ImgMsg{ObjID: "obj3"}, server.StageRecord, errors.New("db down")),
+	})
+	require.Len(t, refailed, 2)
+	require.Equal(t, first, refailed[0])
+	require.Equal(t, server.StageRecord, first.Stage)
+	require.Equal(t, 2, first.Attempts)
+	require.Equal(t, "obj3", refailed[1].ObjID)
+	require.Equal(t, []*server.DeadLetter{second}, pending["obj1"])
+	require.Equal(t, []*server.DeadLetter{other}, pending["obj2"])
+}
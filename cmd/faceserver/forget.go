@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/fagongzi/log"
+	"github.com/infinivision/filesyncer/pkg/forget"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/infinivision/filesyncer/pkg/sink"
+	"github.com/pkg/errors"
+)
+
+const (
+	// s3 DeleteObjects accepts at most 1000 keys
+	s3MaxDeleteKeys = 1000
+)
+
+type s3Objects struct {
+	srv *s3.S3
+}
+
+func (o *s3Objects) Delete(keys []string) (deleted int, err error) {
+	for start := 0; start < len(keys); start += s3MaxDeleteKeys {
+		end := start + s3MaxDeleteKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+		objs := make([]*s3.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objs = append(objs, &s3.ObjectIdentifier{Key: aws.String(key)})
+		}
+		var out *s3.DeleteObjectsOutput
+		if out, err = o.srv.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: ossBucket,
+			Delete: &s3.Delete{Objects: objs, Quiet: aws.Bool(true)},
+		}); err != nil {
+			err = errors.Wrap(err, "")
+			return
+		}
+		// quiet mode returns the failed ones only
+		deleted += len(objs) - len(out.Errors)
+		if len(out.Errors) > 0 {
+			err = errors.Errorf("failed to delete %d objects, the first: %s", len(out.Errors), out.Errors[0].String())
+			return
+		}
+	}
+	return
+}
+
+// forgetDeadLetters removes the dead letters of a forgotten uid
+type forgetDeadLetters struct {
+	dlq server.DeadLetterQueue
+}
+
+func (d *forgetDeadLetters) Forget(uid int64, xids []int64, pictureIds []string, dryRun bool) (removed int64, err error) {
+	pictures := make(map[string]bool, len(pictureIds))
+	for _, id := range pictureIds {
+		pictures[id] = true
+	}
+	ofUid := make(map[int64]bool, len(xids))
+	for _, xid := range xids {
+		ofUid[xid] = true
+	}
+	return d.dlq.Remove(func(dl *server.DeadLetter) bool {
+		if pictures[dl.ObjID] {
+			return true
+		}
+		if len(dl.Visit) == 0 {
+			return false
+		}
+		visit, err := server.DecodeVisit(dl.Visit)
+		return err == nil && (int64(visit.Uid) == uid || ofUid[visit.Xid])
+	}, dryRun)
+}
+
+// sinkKept returns the notes of the visit sinks the visits can't be erased from,
+// only the PostgreSQL rows are erased by Recorder.Forget
+func sinkKept(cfg sink.Cfg) (notes []string) {
+	for _, kind := range cfg.Kinds {
+		switch kind {
+		case sink.KindPostgres:
+		case sink.KindKafka:
+			notes = append(notes, fmt.Sprintf("visits produced to the kafka topic %s", cfg.KafkaTopic))
+		case sink.KindRedis:
+			notes = append(notes, fmt.Sprintf("visits appended to the redis list %s of %s", cfg.RedisKey, cfg.RedisAddr))
+		case sink.KindFile:
+			notes = append(notes, fmt.Sprintf("visits appended to the file %s", cfg.File))
+		default:
+			notes = append(notes, fmt.Sprintf("visits written to the %s sink", kind))
+		}
+	}
+	return
+}
+
+// runForget erases --forget-uid from every store, then quit
+func runForget(iden3 *Identifier3, recorder *Recorder, dlq server.DeadLetterQueue) (err error) {
+	var uid int64
+	if uid, err = parseUid(*forgetUid); err != nil {
+		return
+	}
+	f := forget.NewForgetter(iden3.ids, iden3.vdb,
+		forget.NewRedisVisits(iden3.rcli, server.VisitQueueKey, server.VisitIndexKey),
+		&s3Objects{srv: newS3()},
+		forget.Histories(recorder, iden3.history),
+		forget.NewRedisAuditLog(iden3.rcli, forget.AuditKey))
+	if iden3.xidDbs != nil {
+		f.SetScrubber(iden3.xidDbs, *vectorDim)
+	}
+	f.SetDeadLetters(&forgetDeadLetters{dlq: dlq})
+	// the images not identified yet can't be attributed to the uid
+	f.SetKept(append([]string{"images in the image queue not consumed yet",
+		"dead letters failed before identification"}, sinkKept(parseSinkCfg())...)...)
+	r, err := f.Forget(uid, *forgetReason, *forgetDryRun)
+	if r != nil {
+		log.Infof("forget report: %+v", r)
+		if r.Incomplete {
+			log.Warnf("uid %d is not forgotten completely, kept: %q", uid, r.Kept)
+		}
+	}
+	return
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/infinivision/filesyncer/pkg/sink"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSinkKept(t *testing.T) {
+	require.Empty(t, sinkKept(sink.Cfg{Kinds: []string{sink.KindPostgres}}))
+	notes := sinkKept(sink.Cfg{Kinds: []string{sink.KindPostgres, sink.KindKafka, sink.KindFile}, KafkaTopic: "visits3", File: "visits.json"})
+	require.Equal(t, []string{"visits produced to the kafka topic visits3", "visits appended to the file visits.json"}, notes)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/fagongzi/log"
+	"github.com/infinivision/filesyncer/pkg/server"
+)
+
+const (
+	defaultHistoryLimit = 100
+)
+
+// historyResponse is the history of a uid and its latest visits
+type historyResponse struct {
+	*server.History
+	Returning bool            `json:"returning"`
+	Latest    []*server.Visit `json:"latest"`
+}
+
+// historyHandler returns a http handler that looks up the visit history of a uid.
+// Usage: GET /visits/history?uid=12&start=2019-03-01T00:00:00%2B08:00&end=2019-03-02T00:00:00%2B08:00&limit=100
+// start and end are optional RFC3339 dates, limit is the max of the latest visits listed.
+// The token is required in the X-Admin-Token header, the handler is disabled if it's empty.
+func historyHandler(history server.HistoryStore, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !checkAdminToken(w, r, token, "visit history") {
+			return
+		}
+		q := r.URL.Query()
+		uid, err := parseUid(q.Get("uid"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tsStart, tsEnd, err := server.ParseTimeRange(q.Get("start"), q.Get("end"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if q.Get("end") == "" {
+			// the visits of this second
+			tsEnd++
+		}
+		limit := int64(defaultHistoryLimit)
+		if s := q.Get("limit"); s != "" {
+			if limit, err = strconv.ParseInt(s, 10, 64); err != nil || limit <= 0 {
+				http.Error(w, "invalid limit "+s, http.StatusBadRequest)
+				return
+			}
+		}
+
+		rsp := &historyResponse{}
+		if rsp.History, err = history.Get(uid); err == nil {
+			rsp.Latest, err = history.ListVisits(uid, tsStart, tsEnd, limit)
+		}
+		if err != nil {
+			log.Errorf("look up history of uid %d failed, errors:%+v", uid, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if rsp.History.Visits == 0 {
+			http.Error(w, "uid "+strconv.FormatInt(uid, 10)+" has no history", http.StatusNotFound)
+			return
+		}
+		rsp.Returning = rsp.History.Visits > 1
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rsp)
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentifyReturning(t *testing.T) {
+	iden, _, _ := newTestIdentifier()
+	history := server.NewMemoryHistoryStore(time.Minute, 100)
+	iden.SetHistory(history)
+
+	visit, err := iden.Identify(VecMsg{ObjID: "obj1", Vec: []float32{1, 0, 0, 0}, ModTime: 1000, Shop: 8})
+	require.NoError(t, err)
+	require.False(t, visit.IsReturning)
+	visit, err = iden.Identify(VecMsg{ObjID: "obj2", Vec: []float32{1, 0, 0, 0}, ModTime: 5000, Shop: 8})
+	require.NoError(t, err)
+	require.True(t, visit.IsReturning)
+	require.Equal(t, uint64(1000), visit.PrevVisitTime)
+
+	h, err := history.Get(int64(visit.Uid))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), h.Visits)
+
+	get := func(token, url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		if token != "" {
+			req.Header.Set(adminTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		historyHandler(history, testToken).ServeHTTP(w, req)
+		return w
+	}
+	w := get(testToken, "/visits/history?uid=1&limit=1")
+	require.Equal(t, 200, w.Code)
+	var rsp historyResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rsp))
+	require.True(t, rsp.Returning)
+	require.Equal(t, int64(1000), rsp.FirstSeen)
+	require.Equal(t, int64(5000), rsp.LastSeen)
+	require.Len(t, rsp.Latest, 1)
+	require.Equal(t, "obj2", rsp.Latest[0].PictureId)
+
+	require.Equal(t, 404, get(testToken, "/visits/history?uid=2").Code)
+	require.Equal(t, 400, get(testToken, "/visits/history?uid=x").Code)
+	require.Equal(t, 401, get("", "/visits/history?uid=1").Code)
+	require.Equal(t, 401, get("wrong", "/visits/history?uid=1").Code)
+	w = httptest.NewRecorder()
+	historyHandler(history, "").ServeHTTP(w, httptest.NewRequest("GET", "/visits/history?uid=1", nil))
+	require.Equal(t, 403, w.Code)
+}
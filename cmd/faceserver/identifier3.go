@@ -1,22 +1,17 @@
 package main
 
 import (
-	"encoding/base64"
-	"fmt"
-	"hash"
+	"encoding/binary"
 	math "math"
-	"net/http"
-	"reflect"
-	"strconv"
 	"sync"
 	"time"
-	"unsafe"
 
 	"github.com/cespare/xxhash"
 	"github.com/fagongzi/log"
 	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/embed"
 	"github.com/infinivision/filesyncer/pkg/server"
-	"github.com/infinivision/hyena/pkg/proxy"
+	"github.com/infinivision/filesyncer/pkg/vecindex"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -24,7 +19,7 @@ import (
 const (
 	SIZEOF_FLOAT32     int = 4
 	HyenaSearchTimeout int = 2 //in seconds
-	HttpRRTimeout      int = 2 //in seconds
+	MaxXidsPerUid      int = 8 //vectors kept for a uid
 )
 
 var (
@@ -33,6 +28,7 @@ var (
 	idenSearchDuration prometheus.Histogram
 	idenAddDuration    prometheus.Histogram
 	idenUpdateDuration prometheus.Histogram
+	idenNoFaceCount    prometheus.Counter
 )
 
 type AgeGender struct {
@@ -40,38 +36,33 @@ type AgeGender struct {
 	Gender int `json:"gender"`
 }
 
-//RspPred refers to https://github.com/deepinsight/mxnet-serving/tree/master/tvm
-type RspPred struct {
-	FileName  string  `json:"filename"`
-	Embedding string  `json:"embedding"`
-	Age       int     `json:"age"`
-	Gender    int     `json:"gender"`
-	PoseType  int     `json:"post_type"`
-	State     int     `json:"state"`
-	Quality   float32 `json:"quality"`
-}
-
 type Identifier3 struct {
 	distThr2 float32
 	distThr3 float32
 	flatThr  int
-	vdb      proxy.Proxy
-	h64      hash.Hash64
+	vdb      vecindex.VectorIndex
+
+	embedder embed.Embedder
+	gate     embed.Gate
+	history  server.HistoryStore
+	ids      server.IdentityStore
+	rcli     *redis.Client
+	pubs     []VisitPublisher
+	xidDbs   server.XidDbStore
+}
 
-	servURL string
-	hc      *http.Client
-	rcli    *redis.Client
+// VisitPublisher receives the identified visits, Publish must not block
+type VisitPublisher interface {
+	Publish(visits ...*server.Visit)
 }
 
-func NewIdentifier3(vdb proxy.Proxy, distThr2, distThr3 float32, servURL, redisAddr string) (iden *Identifier3) {
+func NewIdentifier3(vdb vecindex.VectorIndex, distThr2, distThr3 float32, embedder embed.Embedder, redisAddr string) (iden *Identifier3) {
 	iden = &Identifier3{
 		distThr2: distThr2,
 		distThr3: distThr3,
 		vdb:      vdb,
-		h64:      xxhash.New(),
 
-		servURL: servURL,
-		hc:      &http.Client{Timeout: time.Duration(HttpRRTimeout) * time.Second},
+		embedder: embedder,
 	}
 	var err error
 
@@ -84,7 +75,12 @@ func NewIdentifier3(vdb proxy.Proxy, distThr2, distThr3 float32, servURL, redisA
 		err = errors.Wrap(err, "")
 		log.Errorf("got error %+v", err)
 	}
+	iden.ids = server.NewRedisIdentityStore(iden.rcli)
+	initIdenMetrics()
+	return
+}
 
+func initIdenMetrics() {
 	idenOnce.Do(func() {
 		idenPredDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
 			Namespace: "mcd",
@@ -115,78 +111,58 @@ func NewIdentifier3(vdb proxy.Proxy, distThr2, distThr3 float32, servURL, redisA
 			Buckets:   prometheus.LinearBuckets(0, 0.01, 100), //100 buckets, each is 10 ms.
 		})
 
+		idenNoFaceCount = prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "idendify_noface_images",
+			Help:      "Images dropped since they have no usable face.",
+		})
+
 		prometheus.MustRegister(idenPredDuration)
 		prometheus.MustRegister(idenSearchDuration)
 		prometheus.MustRegister(idenAddDuration)
 		prometheus.MustRegister(idenUpdateDuration)
+		prometheus.MustRegister(idenNoFaceCount)
 	})
-	return
 }
 
-func (this *Identifier3) allocateUid() (uid int64, err error) {
-	if uid, err = this.rcli.Incr("faceserver_next_uid").Result(); err != nil {
-		err = errors.Wrap(err, "")
-		return
-	}
-	log.Infof("allocated uid %v", uid)
-	return
+// SetGate sets the quality gate, the rejected faces are identified with low confidence
+func (this *Identifier3) SetGate(gate embed.Gate) {
+	this.gate = gate
 }
 
-func (this *Identifier3) getUid(xid int64) (uid int64, err error) {
-	var strUid string
-	keyXid := fmt.Sprintf("xid_%016x", uint64(xid))
-	if strUid, err = this.rcli.Get(keyXid).Result(); err != nil {
-		err = errors.Wrapf(err, "keyXid %v", keyXid)
-		return
-	}
-	if uid, err = strconv.ParseInt(strUid, 10, 64); err != nil {
-		err = errors.Wrapf(err, "strUid %v", strUid)
-		return
-	}
-	return
+// SetHistory sets the history store, the visits are marked returning or not by it
+func (this *Identifier3) SetHistory(history server.HistoryStore) {
+	this.history = history
 }
 
-func (this *Identifier3) getXidsLen(uid int64) (xl int64, err error) {
-	keyUid := fmt.Sprintf("uid_%v", uid)
-	if xl, err = this.rcli.LLen(keyUid).Result(); err != nil {
-		err = errors.Wrapf(err, "keyUid %v", keyUid)
-		return
-	}
-	return
+// SetXidDbs sets the store the dbs of the matched xids are recorded in, a vector
+// of an index that can't remove it is scrubbed in its db when its uid is forgotten
+func (this *Identifier3) SetXidDbs(xidDbs server.XidDbStore) {
+	this.xidDbs = xidDbs
+}
+
+// AddPublisher adds a publisher the identified visits are published to, such as the live stream
+func (this *Identifier3) AddPublisher(pub VisitPublisher) {
+	this.pubs = append(this.pubs, pub)
 }
 
 func (this *Identifier3) associateUidXid(uid, xid int64) (err error) {
-	keyUid := fmt.Sprintf("uid_%v", uid)
-	keyXid := fmt.Sprintf("xid_%016x", uint64(xid))
-	strUid := strconv.FormatInt(uid, 10)
-	strXid := fmt.Sprintf("%016x", uint64(xid))
-	if err = this.rcli.Set(keyXid, strUid, 0).Err(); err != nil {
-		err = errors.Wrapf(err, "keyXid %v", keyXid)
-		return
-	}
-	if err = this.rcli.LPush(keyUid, strXid).Err(); err != nil {
-		err = errors.Wrapf(err, "keyUid %v", keyUid)
+	if err = this.ids.Associate(uid, xid); err != nil {
 		return
 	}
-	log.Infof("associated xid %v with uid %v", strXid, strUid)
+	log.Infof("associated xid %016x with uid %v", uint64(xid), uid)
 	return
 }
 
 // allocateXid uses hash of vec as xid. This also helps to deduplicate vectors per content.
 func (this *Identifier3) allocateXid(vec []float32) (xid int64) {
-	// https://stackoverflow.com/questions/11924196/convert-between-slices-of-different-types
-	// Get the slice header
-	vec2 := vec
-	header := *(*reflect.SliceHeader)(unsafe.Pointer(&vec2))
-	// The length and capacity of the slice are different.
-	header.Len *= SIZEOF_FLOAT32
-	header.Cap *= SIZEOF_FLOAT32
-	// Convert slice header to an []byte
-	data := *(*[]byte)(unsafe.Pointer(&header))
-
-	this.h64.Reset()
-	this.h64.Write(data)
-	xid = int64(this.h64.Sum64())
+	data := make([]byte, len(vec)*SIZEOF_FLOAT32)
+	for i, f := range vec {
+		binary.LittleEndian.PutUint32(data[i*SIZEOF_FLOAT32:], math.Float32bits(f))
+	}
+
+	xid = int64(xxhash.Sum64(data))
 	log.Infof("allocated xid %016x", uint64(xid))
 	return
 }
@@ -203,68 +179,78 @@ func normalize(vec []float32) {
 	return
 }
 
-func (this *Identifier3) DoBatch(imgMsgs []server.ImgMsg) (visits []*server.Visit, err error) {
-	var filenames []string
-	var imgs [][]byte
-	var visit *server.Visit
-	var duration time.Duration
+// DoBatch identifies the images, an image failed in inference or identification
+// is returned as a dead letter and doesn't affect the others
+func (this *Identifier3) DoBatch(imgMsgs []server.ImgMsg) (visits []*server.Visit, failures []*server.DeadLetter) {
 	if len(imgMsgs) == 0 {
 		return
 	}
+	imgs := make([]embed.Image, 0, len(imgMsgs))
 	for _, img := range imgMsgs {
-		filenames = append(filenames, img.ObjID)
-		imgs = append(imgs, img.Img)
-	}
-	rspPreds := make([]RspPred, len(imgMsgs))
-	if duration, err = server.PostFiles(this.hc, this.servURL, filenames, imgs, &rspPreds); err != nil {
-		log.Errorf("got error %+v", err)
-		return
+		imgs = append(imgs, embed.Image{Name: img.ObjID, Data: img.Img})
 	}
-	idenPredDuration.Observe(duration.Seconds())
-	log.Debugf("imgMsgs length %d, rspPreds (length %d): %+v", len(imgMsgs), len(rspPreds), rspPreds)
-	for i, rspPred := range rspPreds {
-		if rspPred.State != 0 {
-			// state!=0 indicates error, embedding could be empty.
+	t0 := time.Now()
+	results := this.embedder.Embed(imgs)
+	idenPredDuration.Observe(time.Since(t0).Seconds())
+	for i, rst := range results {
+		imgMsg := imgMsgs[i]
+		if rst.Err != nil {
+			log.Warnf("infer %s failed, errors:%+v", imgMsg.ObjID, rst.Err)
+			failures = append(failures, server.NewDeadLetter(imgMsg, server.StagePredict, rst.Err))
 			continue
 		}
-		var data []byte
-		if data, err = base64.StdEncoding.DecodeString(rspPred.Embedding); err != nil {
-			err = errors.Wrapf(err, "base64 decode error")
-			return
-		}
-		if len(data)%SIZEOF_FLOAT32 != 0 {
-			err = errors.Errorf("rspPred.Embedding length is incorrect, want times of %d, have %d", SIZEOF_FLOAT32, len(data))
-			return
+		if rst.NoFace {
+			// inferring it again gets the same, it's dropped
+			log.Infof("%s has no usable face, dropped", imgMsg.ObjID)
+			idenNoFaceCount.Inc()
+			continue
 		}
-		header := *(*reflect.SliceHeader)(unsafe.Pointer(&data))
-		// The length and capacity of the slice are different.
-		header.Len /= SIZEOF_FLOAT32
-		header.Cap /= SIZEOF_FLOAT32
-		// Convert slice header to an []float32
-		vec := *(*[]float32)(unsafe.Pointer(&header))
-		log.Debugf("vec (length %d): %+v", len(vec), vec)
+		log.Debugf("vec (length %d): %+v", len(rst.Vec), rst.Vec)
 		//predict result needs normalization
-		normalize(vec)
+		normalize(rst.Vec)
 
-		imgMsg := imgMsgs[i]
-		vecMsg := VecMsg{Shop: imgMsg.Shop, Position: imgMsg.Position, ModTime: imgMsg.ModTime, ObjID: imgMsg.ObjID, Img: imgMsg.Img, Vec: vec, Age: rspPred.Age, Gender: rspPred.Gender, Quality: rspPred.Quality}
-		if visit, err = this.Identify(vecMsg); err != nil {
-			return
+		vecMsg := VecMsg{Shop: imgMsg.Shop, Position: imgMsg.Position, ModTime: imgMsg.ModTime, ObjID: imgMsg.ObjID, Img: imgMsg.Img, Vec: rst.Vec, Age: rst.Age, Gender: rst.Gender, Quality: rst.Quality, Camera: imgMsg.Camera, Mac: imgMsg.Mac, CameraIp: imgMsg.CameraIp}
+		if result := this.gate.Check(rst); result != embed.GatePassed {
+			log.Infof("%s is rejected by the %s gate, quality %v, pose type %d", imgMsg.ObjID, result, rst.Quality, rst.PoseType)
+			vecMsg.LowConfidence = true
+		}
+		visit, err := this.identify(vecMsg)
+		if err != nil {
+			log.Errorf("identify %s failed, errors:%+v", imgMsg.ObjID, err)
+			failures = append(failures, server.NewDeadLetter(imgMsg, server.StageIdentify, err))
+			continue
 		}
 		visits = append(visits, visit)
-		if data, err = visit.Marshal(); err != nil {
-			err = errors.Wrapf(err, "protobuf encoding error: %+v", visit)
-			return
+	}
+	this.recordHistory(visits...)
+
+	for _, visit := range visits {
+		// visit_queue and visit_index are for replaying, the visit is identified anyway
+		data, err := visit.Marshal()
+		if err != nil {
+			log.Errorf("protobuf encoding error: %+v, errors:%+v", visit, err)
+			continue
 		}
-		if err = this.rcli.RPush("visit_queue", data).Err(); err != nil {
-			err = errors.Wrapf(err, "failed to append record to visit_queue")
-			return
+		if err = server.AppendVisit(this.rcli, server.VisitQueueKey, server.VisitIndexKey, visit, data); err != nil {
+			log.Errorf("failed to append record, errors:%+v", err)
 		}
 	}
+	for _, pub := range this.pubs {
+		pub.Publish(visits...)
+	}
 	return
 }
 
+// Identify identifies a face and marks the visit returning or not
 func (this *Identifier3) Identify(vecMsg VecMsg) (visit *server.Visit, err error) {
+	if visit, err = this.identify(vecMsg); err == nil {
+		this.recordHistory(visit)
+	}
+	return
+}
+
+// identify identifies a face, the visit is not marked by the history
+func (this *Identifier3) identify(vecMsg VecMsg) (visit *server.Visit, err error) {
 	var uid int64
 	var dbs []uint64
 	var distances []float32
@@ -276,47 +262,74 @@ func (this *Identifier3) Identify(vecMsg VecMsg) (visit *server.Visit, err error
 	}
 	duration := time.Since(t0).Seconds()
 	idenSearchDuration.Observe(duration)
-	log.Infof("hyena search result: dbs %v, distances %v, xids %v", dbs, distances, xids)
+	log.Infof("vector search result: dbs %v, distances %v, xids %v", dbs, distances, xids)
+	if this.xidDbs != nil && xids[0] != int64(-1) {
+		if e := this.xidDbs.SetDb(xids[0], dbs[0]); e != nil {
+			log.Errorf("record db of xid %016x failed, errors:%+v", uint64(xids[0]), e)
+		}
+	}
+
+	if vecMsg.LowConfidence {
+		return this.identifyLowConfidence(vecMsg, xids[0], distances[0])
+	}
 
 	var cnt1, cnt2, cnt3, cnt4 int
+	var decision server.Decision
 	var newXid int64
 	var newXids []int64
 	if xids[0] == int64(-1) {
 		cnt1++
+		decision = server.DecisionNew
 		newXid = this.allocateXid(vecMsg.Vec)
-		if uid, err = this.allocateUid(); err != nil {
+		if uid, err = this.ids.AllocateUid(); err != nil {
 			return
 		}
+		log.Infof("allocated uid %v", uid)
 		if err = this.associateUidXid(uid, newXid); err != nil {
 			return
 		}
 		newXids = append(newXids, newXid)
-	} else {
-		if uid, err = this.getUid(xids[0]); err != nil {
+	} else if uid, err = this.ids.GetUid(xids[0]); errors.Cause(err) == server.ErrIdentityNotFound {
+		// the vector of a forgotten uid, which can't be removed from the index.
+		// A new uid takes it over and overwrites it.
+		cnt4++
+		decision = server.DecisionTakenOver
+		if uid, err = this.ids.AllocateUid(); err != nil {
 			return
 		}
+		log.Infof("allocated uid %v to take over forgotten xid %016x", uid, uint64(xids[0]))
+		if err = this.associateUidXid(uid, xids[0]); err != nil {
+			return
+		}
+	} else if err != nil {
+		return
+	} else {
 		if distances[0] < this.distThr2 {
 			cnt2++
-			var xl int64
-			if xl, err = this.getXidsLen(uid); err != nil {
+			decision = server.DecisionIgnored
+			var uidXids []int64
+			if uidXids, err = this.ids.ListXids(uid); err != nil {
 				return
 			}
-			if xl < 8 {
+			if len(uidXids) < MaxXidsPerUid {
 				newXid = this.allocateXid(vecMsg.Vec)
 				if err = this.associateUidXid(uid, newXid); err != nil {
 					return
 				}
 				newXids = append(newXids, newXid)
+				decision = server.DecisionMerged
 			}
 		} else if distances[0] < this.distThr3 {
 			cnt3++
+			decision = server.DecisionIgnored
 		} else {
 			cnt4++
+			decision = server.DecisionUpdated
 		}
 	}
 	if len(newXids) != 0 {
 		t0 = time.Now()
-		log.Infof("hyena added xids %+v, %016x", newXids, uint64(newXids[0]))
+		log.Infof("vector added xids %+v, %016x", newXids, uint64(newXids[0]))
 		if err = this.vdb.AddWithIds(vecMsg.Vec, newXids); err != nil {
 			err = errors.Wrap(err, "")
 			return
@@ -326,7 +339,7 @@ func (this *Identifier3) Identify(vecMsg VecMsg) (visit *server.Visit, err error
 	}
 	if cnt4 != 0 {
 		t0 = time.Now()
-		log.Infof("hyena updated xids %+v, %016x", xids[0], uint64(xids[0]))
+		log.Infof("vector updated xids %+v, %016x", xids[0], uint64(xids[0]))
 		if err = this.vdb.UpdateWithIds(dbs[0], xids[0], vecMsg.Vec); err != nil {
 			err = errors.Wrap(err, "")
 			return
@@ -335,7 +348,51 @@ func (this *Identifier3) Identify(vecMsg VecMsg) (visit *server.Visit, err error
 		idenUpdateDuration.Observe(duration)
 	}
 
-	visit = &server.Visit{
+	// the vector the visit is identified by, a split of the uid moves the visit with it
+	visitXid := xids[0]
+	if len(newXids) != 0 {
+		visitXid = newXids[0]
+	}
+	visit = this.newVisit(vecMsg, uid, visitXid, decision)
+	visit.MatchedXid, visit.Distance = xids[0], distances[0]
+	log.Infof("objID: %+v, visit3: %+v", vecMsg.ObjID, visit)
+	return
+}
+
+// identifyLowConfidence identifies a face rejected by the gate. It's the uid of
+// the best xid if any, or else 0. The vector index is never changed.
+func (this *Identifier3) identifyLowConfidence(vecMsg VecMsg, xid int64, distance float32) (visit *server.Visit, err error) {
+	var uid int64
+	matchedXid := xid
+	if xid != int64(-1) {
+		if uid, err = this.ids.GetUid(xid); errors.Cause(err) == server.ErrIdentityNotFound {
+			uid, xid, err = 0, 0, nil
+		} else if err != nil {
+			return
+		}
+	} else {
+		xid = 0
+	}
+	visit = this.newVisit(vecMsg, uid, xid, server.DecisionLowConfidence)
+	visit.MatchedXid, visit.Distance = matchedXid, distance
+	visit.LowConfidence = true
+	log.Infof("objID: %+v, low confidence visit3: %+v", vecMsg.ObjID, visit)
+	return
+}
+
+// recordHistory marks the visits returning or not, they're identified anyway if the history fails
+func (this *Identifier3) recordHistory(visits ...*server.Visit) {
+	if this.history == nil || len(visits) == 0 {
+		return
+	}
+	if err := this.history.Record(visits...); err != nil {
+		log.Errorf("record history of %d visits failed, errors:%+v", len(visits), err)
+	}
+}
+
+func (this *Identifier3) newVisit(vecMsg VecMsg, uid, xid int64, decision server.Decision) *server.Visit {
+	return &server.Visit{
+		Version:   server.VisitVersion,
 		PictureId: vecMsg.ObjID,
 		Uid:       uint64(uid),
 		VisitTime: uint64(vecMsg.ModTime),
@@ -344,7 +401,15 @@ func (this *Identifier3) Identify(vecMsg VecMsg) (visit *server.Visit, err error
 		Age:       uint32(vecMsg.Age),
 		Gender:    uint32(vecMsg.Gender),
 		Quality:   vecMsg.Quality,
+		Zone:      vecMsg.Camera.Zone,
+		Entrance:  vecMsg.Camera.Entrance,
+		Exit:      vecMsg.Camera.Exit,
+		Xid:       xid,
+		Decision:  decision,
+		Mac:       vecMsg.Mac,
+		CameraIp:  vecMsg.CameraIp,
+		Direction: vecMsg.Camera.Direction,
+		DistThr2:  this.distThr2,
+		DistThr3:  this.distThr3,
 	}
-	log.Infof("objID: %+v, visit3: %+v", vecMsg.ObjID, visit)
-	return
 }
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/infinivision/filesyncer/pkg/vecindex"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIdentifier() (*Identifier3, *vecindex.MemoryIndex, *server.MemoryIdentityStore) {
+	initIdenMetrics()
+	vdb := vecindex.NewMemoryIndex(4, 0.5)
+	ids := server.NewMemoryIdentityStore()
+	return &Identifier3{distThr2: 0.7, distThr3: 0.9, vdb: vdb, ids: ids}, vdb, ids
+}
+
+func identify(t *testing.T, iden *Identifier3, vec ...float32) uint64 {
+	visit, err := iden.Identify(VecMsg{ObjID: "obj", Vec: vec})
+	require.NoError(t, err)
+	return visit.Uid
+}
+
+func TestIdentify(t *testing.T) {
+	iden, vdb, ids := newTestIdentifier()
+
+	// not found
+	uid := identify(t, iden, 1, 0, 0, 0)
+	require.Equal(t, uint64(1), uid)
+	require.Equal(t, 1, vdb.Len())
+
+	// less than distThr2, one more vector of the uid
+	require.Equal(t, uid, identify(t, iden, 0.6, 0.8, 0, 0))
+	xids, err := ids.ListXids(int64(uid))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(xids))
+	require.Equal(t, 2, vdb.Len())
+
+	// between distThr2 and distThr3, nothing changes
+	require.Equal(t, uid, identify(t, iden, 0.8, 0, 0.6, 0))
+	require.Equal(t, 2, vdb.Len())
+
+	// not less than distThr3, the best vector is updated
+	v4 := []float32{0.95, 0, 0, float32(math.Sqrt(1 - 0.95*0.95))}
+	require.Equal(t, uid, identify(t, iden, v4...))
+	require.Equal(t, 2, vdb.Len())
+	_, ds, _, err := vdb.Search(v4)
+	require.NoError(t, err)
+	require.InDelta(t, 1, ds[0], 1e-6)
+
+	// less than the threshold of the index, a new uid
+	require.Equal(t, uint64(2), identify(t, iden, 0, 0, 1, 0))
+}
+
+func TestIdentifyMaxXids(t *testing.T) {
+	iden, vdb, ids := newTestIdentifier()
+	uid := identify(t, iden, 1, 0, 0, 0)
+	for i := 1; i < MaxXidsPerUid; i++ {
+		require.NoError(t, ids.Associate(int64(uid), int64(i)))
+	}
+
+	require.Equal(t, uid, identify(t, iden, 0.6, 0.8, 0, 0))
+	xids, err := ids.ListXids(int64(uid))
+	require.NoError(t, err)
+	require.Equal(t, MaxXidsPerUid, len(xids))
+	require.Equal(t, 1, vdb.Len())
+}
+
+func TestIdentifyForgotten(t *testing.T) {
+	iden, vdb, ids := newTestIdentifier()
+	uid := identify(t, iden, 1, 0, 0, 0)
+	forgotten, err := ids.Forget(int64(uid))
+	require.NoError(t, err)
+
+	// the vector can't be removed from hyena, it's taken over by a new uid
+	v := []float32{0.8, 0.6, 0, 0}
+	newUid := identify(t, iden, v...)
+	require.NotEqual(t, uid, newUid)
+	xids, err := ids.ListXids(int64(newUid))
+	require.NoError(t, err)
+	require.Equal(t, forgotten, xids)
+	require.Equal(t, 1, vdb.Len())
+	_, ds, _, err := vdb.Search(v)
+	require.NoError(t, err)
+	require.InDelta(t, 1, ds[0], 1e-6)
+}
+
+func TestIdentifyXidDbs(t *testing.T) {
+	iden, _, ids := newTestIdentifier()
+	xidDbs := server.NewMemoryXidDbStore()
+	iden.SetXidDbs(xidDbs)
+	uid := identify(t, iden, 1, 0, 0, 0)
+	xids, err := ids.ListXids(int64(uid))
+	require.NoError(t, err)
+	// an added vector is recorded once it's matched
+	dbs, err := xidDbs.GetDbs(xids)
+	require.NoError(t, err)
+	require.Empty(t, dbs)
+	identify(t, iden, 1, 0, 0, 0)
+	dbs, err = xidDbs.GetDbs(xids)
+	require.NoError(t, err)
+	require.Equal(t, map[int64]uint64{xids[0]: 0}, dbs)
+}
+
+func TestIdentifyLowConfidence(t *testing.T) {
+	iden, vdb, _ := newTestIdentifier()
+	uid := identify(t, iden, 1, 0, 0, 0)
+
+	// neither added nor updated
+	for _, vec := range [][]float32{{0.6, 0.8, 0, 0}, {1, 0, 0, 0}} {
+		visit, err := iden.Identify(VecMsg{ObjID: "obj", Vec: vec, LowConfidence: true})
+		require.NoError(t, err)
+		require.True(t, visit.LowConfidence)
+		require.Equal(t, uid, visit.Uid)
+	}
+	require.Equal(t, 1, vdb.Len())
+
+	// nobody known
+	visit, err := iden.Identify(VecMsg{ObjID: "obj", Vec: []float32{0, 0, 1, 0}, LowConfidence: true})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), visit.Uid)
+	require.Equal(t, int64(0), visit.Xid)
+	require.Equal(t, 1, vdb.Len())
+}
+
+func TestIdentifyDecision(t *testing.T) {
+	iden, _, _ := newTestIdentifier()
+	for _, c := range []struct {
+		vec      []float32
+		decision server.Decision
+	}{
+		{[]float32{1, 0, 0, 0}, server.DecisionNew},
+		{[]float32{0.6, 0.8, 0, 0}, server.DecisionMerged},
+		{[]float32{0.8, 0, 0.6, 0}, server.DecisionIgnored},
+		{[]float32{1, 0, 0, 0}, server.DecisionUpdated},
+	} {
+		visit, err := iden.Identify(VecMsg{ObjID: "obj", Vec: c.vec, Mac: "309c233431b2"})
+		require.NoError(t, err)
+		require.Equal(t, c.decision, visit.Decision)
+		require.Equal(t, server.VisitVersion, visit.Version)
+		require.Equal(t, "309c233431b2", visit.Mac)
+		require.Equal(t, float32(0.7), visit.DistThr2)
+		if c.decision != server.DecisionNew {
+			require.NotEqual(t, int64(-1), visit.MatchedXid)
+			require.True(t, visit.Distance >= 0.5)
+		}
+	}
+}
+
+func TestNewVectorIndexThreshold(t *testing.T) {
+	defer func(kind string, thr, thr2 float64) {
+		*vectorIndex, *vectorDistThr, *identifyDisThr2 = kind, thr, thr2
+	}(*vectorIndex, *vectorDistThr, *identifyDisThr2)
+
+	*vectorIndex = vecindex.KindMemory
+	_, err := newVectorIndex()
+	require.NoError(t, err)
+
+	// vectors could never be merged
+	*vectorDistThr, *identifyDisThr2 = 0.6, 0.6
+	_, err = newVectorIndex()
+	require.Error(t, err)
+}
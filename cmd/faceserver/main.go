@@ -24,6 +24,7 @@ import (
 	"os/signal"
 	runPprof "runtime/pprof"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -33,10 +34,17 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/fagongzi/log"
 	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/aggregate"
+	"github.com/infinivision/filesyncer/pkg/embed"
+	"github.com/infinivision/filesyncer/pkg/live"
+	"github.com/infinivision/filesyncer/pkg/queue"
 	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/infinivision/filesyncer/pkg/sessionizer"
+	"github.com/infinivision/filesyncer/pkg/sink"
+	"github.com/infinivision/filesyncer/pkg/vecindex"
 	"github.com/infinivision/filesyncer/pkg/version"
-	"github.com/infinivision/hyena/pkg/proxy"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -60,23 +68,98 @@ var (
 	retryIntervalSec    = flag.Int("retry-interval", 10, "Interval(sec): interval seconds between two retries")
 	retryIntervalFactor = flag.Int("retry-interval-factor", 2, "Factor: retry interval factor")
 
+	cameraSilentSec   = flag.Int("camera-silent-timeout", 0, "Timeout(sec): alert if a camera has no upload during opening hours, 0 disables the detector")
+	cameraCheckSec    = flag.Int("camera-check-interval", 60, "Interval(sec): interval of checking silent cameras")
+	openingHours      = flag.String("opening-hours", "8-22", "Hours: shop opening hours in local time")
+	alertWebhook      = flag.String("alert-webhook", "", "URL: post alerts as json to the webhook. Empty means log alerts only")
+	alertWebhookTOSec = flag.Int("alert-webhook-timeout", 5, "Timeout(sec): timeout of posting alerts to the webhook")
+
+	vectorIndex    = flag.String("vector-index", vecindex.KindHyena, "Vector index: hyena or memory (exact search in process, for tests and small single-store deployments)")
+	vectorFile     = flag.String("vector-index-file", "", "File: persist the memory vector index in the file, empty means nothing is persisted")
+	vectorDim      = flag.Int("vector-dim", 512, "Dim of the face vectors in the memory vector index, and of the zero vectors scrubbing a forgotten uid in hyena")
+	vectorDistThr  = flag.Float64("vector-distance-threshold", 0.5, "Distance threshold of the memory vector index, a vector is not found if the best distance is less than it. It must be less than --identify-distance-threshold2")
 	hyenaMqAddr    = flag.String("hyena-mq-addr", "172.19.0.107:9092", "List of hyena-mq addr.")
 	hyenaPdAddr    = flag.String("hyena-pd-addr", "172.19.0.101:9529,172.19.0.103:9529,172.19.0.104:9529", "List of hyena-pd addr.")
 	predictServURL = flag.String("predict-serv-url", "http://172.19.0.104:8081/", "Face predict server url")
+	predictTOMs    = flag.Int("predict-timeout", 2000, "Timeout(ms): timeout of a face predict request")
+	predictRetries = flag.Int("predict-retries", 2, "Max: retry times of a face predict request failed for the server")
+	predictBackMs  = flag.Int("predict-backoff", 100, "Interval(ms): the first backoff between retries, it doubles every retry")
 
 	identifyDisThr2 = flag.Float64("identify-distance-threshold2", 0.6, "Distance threshold of merging new vector.")
 	identifyDisThr3 = flag.Float64("identify-distance-threshold3", 0.8, "Distance threshold of discarding new vector.")
 
+	gateMinQuality = flag.Float64("gate-min-quality", 0, "Faces of less quality are identified with low confidence and never added to the vector index, 0 disables the gate")
+	gatePoseTypes  = flag.String("gate-pose-types", "", "List of pose types allowed to add to the vector index, empty means any")
+
+	sessionGapSec  = flag.Int("session-gap", 60, "Gap(sec): sightings of a uid at a shop are recorded as one visit if each is within the gap of the last, 0 disables merging")
+	sessionIdleSec = flag.Int("session-max-idle", 600, "Idle(sec): an open visit is closed if no sighting of it arrives for the idle by the server clock, in case its shop sends nothing any more")
+
+	historyMaxVisits = flag.Int64("history-max-visits", 1000, "Max latest visits kept in the history of a uid")
+
+	identifyWorkers   = flag.Int("identify-workers", 4, "Workers identify batches in parallel, images of a shop are always identified by the same worker in order")
+	identifyBatchSize = flag.Int("identify-batch-size", 5, "Max images of a batch")
+	identifyFlushMs   = flag.Int("identify-flush-interval", 50, "Interval(ms): identify a batch that is not full after the interval, must be > 0")
+
 	redisAddr = flag.String("redis-addr", "127.0.0.1:6379", "Addr: redis address")
 
+	role          = flag.String("role", roleAll, "Role: all, ingest (receive images only) or identify (consume the image queue only)")
+	queueKind     = flag.String("queue", queue.KindMemory, "Image queue: memory, redis-list, redis-stream or kafka")
+	queueAddr     = flag.String("queue-addr", "", "Addr: Redis address or list of Kafka brokers of the image queue, the default is --redis-addr")
+	queueName     = flag.String("queue-name", "image_queue", "Redis key or Kafka topic of the image queue")
+	queueGroup    = flag.String("queue-group", "identifier", "Consumer group of the image queue")
+	queueConsumer = flag.String("queue-consumer", "", "Unique consumer name in the group, the default is the hostname")
+	queueCapacity = flag.Int("queue-capacity", 10000, "Max images in the memory queue")
+	queueDrop     = flag.Bool("queue-drop-when-full", false, "Drop the images once the memory queue is full instead of blocking the uploads")
+	queueMaxLen   = flag.Int64("queue-max-len", 1000000, "Trim the redis-stream queue to about the number of images on publish, 0 is unlimited")
+
+	visitSinks      = flag.String("visit-sinks", sink.KindPostgres, "List of sinks the visits are written to: postgres, kafka, redis and file")
+	visitSinkMqAddr = flag.String("visit-sink-mq-addr", "", "List of Kafka brokers of the kafka visit sink")
+	visitSinkTopic  = flag.String("visit-sink-topic", "visits3", "Kafka topic of the kafka visit sink")
+	visitSinkRedis  = flag.String("visit-sink-redis-addr", "", "Addr: Redis address of the redis visit sink, the default is --redis-addr")
+	visitSinkKey    = flag.String("visit-sink-redis-key", "visit_sink", "Redis list of the redis visit sink")
+	visitSinkFile   = flag.String("visit-sink-file", "", "File: the file visit sink appends the visits as JSON lines to it")
+
+	liveBuffer      = flag.Int("live-buffer", 256, "Max visits buffered for a subscriber of the live visit stream, a slower subscriber misses visits")
+	liveMaxBackfill = flag.Int("live-max-backfill", 60, "Max minutes of visits a subscriber of the live visit stream may ask for backfill")
+	liveOrigins     = flag.String("live-origins", "", "List of origins of the dashboards allowed to subscribe the live visit stream besides the same origin, * allows any")
+	liveToken       = flag.String("live-token", "", "Token required by the subscribers of the live visit stream, as the query token or a bearer token, the stream is disabled if it's empty")
+
+	footfallWindows     = flag.String("footfall-windows", "15m,1h", "List of rolling windows of the footfall aggregation, the first is the default of /footfall")
+	footfallCheckpoint  = flag.String("footfall-checkpoint", "redis", "Checkpoint of the footfall aggregation across restarts: redis, file or empty to disable")
+	footfallCpFile      = flag.String("footfall-checkpoint-file", "footfall.json", "File: the file checkpoint of the footfall aggregation")
+	footfallCpIntervalS = flag.Int("footfall-checkpoint-interval", 60, "Interval(sec): save the footfall aggregation to the checkpoint")
+	footfallShared      = flag.Bool("footfall-shared", false, "Aggregate the footfall of all the identify processes in Redis, so a uid is counted once, no checkpoint is needed")
+	footfallRefreshS    = flag.Int("footfall-shared-refresh", 5, "Interval(sec): load the shared footfall aggregation from Redis at most once an interval")
+
+	deadLetterFile = flag.String("dead-letter-file", "", "File: keep the failed images in the file instead of the Redis list dead_letter_queue")
+	redrive        = flag.Bool("redrive-dead-letters", false, "Re-drive the dead letters through identification and recording, then quit")
+
+	mergeUids  = flag.String("merge-uids", "", "Uids: dst,src merges uid src into dst, then quit")
+	splitUid   = flag.String("split-uid", "", "Uid: splits --split-xids of the uid to a new uid, then quit")
+	splitXids  = flag.String("split-xids", "", "List of xids in 16 hex digits to split")
+	adminToken = flag.String("admin-token", "", "Token required in the X-Admin-Token header by /identity/merge, /identity/split, /visits/history and /command, they are disabled if it's empty")
+
+	forgetUid    = flag.String("forget-uid", "", "Uid: erases the customer from the vector index, Redis, OSS and PostgreSQL, then quit")
+	forgetReason = flag.String("forget-reason", "", "Reason of the erasure kept in the audit, for example the request ticket")
+	forgetDryRun = flag.Bool("forget-dry-run", false, "Lists what --forget-uid would remove without removing")
+
 	eurekaAddr = flag.String("eureka-addr", "http://127.0.0.1:8761/eureka", "eureka server address list, seperated by comma.")
 	eurekaApp  = flag.String("eureka-app", "iot-backend", "CMDB service name which been registered with eureka.")
 
+	cmdbCacheTTLSec    = flag.Int("cmdb-cache-ttl", 3600, "TTL(sec): cache terminals found in CMDB")
+	cmdbNegativeTTLSec = flag.Int("cmdb-negative-ttl", 300, "TTL(sec): cache terminals not found in CMDB")
+	cmdbBatchWindowMs  = flag.Int("cmdb-batch-window", 20, "Window(ms): lookups of different terminals inside it are sent to CMDB in one request, 0 to disable")
+	cmdbBatchSize      = flag.Int("cmdb-batch-size", 50, "Max terminals of a CMDB batch request")
+	positionFile       = flag.String("position-file", "", "YAML or JSON file maps (mac, camera) to (shop, position), CMDB is not used if it's set")
+	positionReloadSec  = flag.Int("position-reload", 10, "Interval(sec): check the position file for reload, 0 to disable")
+
 	showVer = flag.Bool("version", false, "Show version and quit.")
 )
 
 const (
-	InferBatchSize int = 5
+	roleAll      = "all"
+	roleIngest   = "ingest"
+	roleIdentify = "identify"
 )
 
 type VecMsg struct {
@@ -89,18 +172,58 @@ type VecMsg struct {
 	Age      int
 	Gender   int
 	Quality  float32
+	Camera   server.CameraInfo
+	Mac      string
+	CameraIp string
+	// LowConfidence the face is rejected by the quality gate
+	LowConfidence bool
 }
 
-func handleImgMsgs(iden3 *Identifier3, recorder *Recorder, imgMsgs []server.ImgMsg) {
+// handleImgMsgs identifies the images and records the visits closed by them, and returns the failed ones
+func handleImgMsgs(iden3 *Identifier3, recorder *Recorder, sess *sessionizer.Sessionizer, imgMsgs []server.ImgMsg) (failures []*server.DeadLetter) {
 	var visits []*server.Visit
-	var err error
-	if visits, err = iden3.DoBatch(imgMsgs); err != nil {
-		log.Errorf("got error: %+v", err)
+	visits, failures = iden3.DoBatch(imgMsgs)
+	return append(failures, recordVisits(recorder, sess.Add(visits...), imgMsgs)...)
+}
+
+// recordVisits records the visits in a batch. If the batch fails, it records them one by one
+// to the failed sinks only, so that a failed visit doesn't affect the others.
+func recordVisits(recorder *Recorder, visits []*server.Visit, imgMsgs []server.ImgMsg) (failures []*server.DeadLetter) {
+	return recordVisitsTo(recorder.sink, visits, imgMsgs)
+}
+
+// recordVisitsTo is recordVisits to the sink, the failed sinks of a failed visit are kept in its dead letter
+func recordVisitsTo(to sink.VisitSink, visits []*server.Visit, imgMsgs []server.ImgMsg) (failures []*server.DeadLetter) {
+	if len(visits) == 0 {
 		return
 	}
-	if err = recorder.Record(visits); err != nil {
-		log.Errorf("got error: %+v", err)
-		return
+	if len(visits) > 1 {
+		err := to.Write(visits)
+		if err == nil {
+			return
+		}
+		log.Warnf("record a batch of %d visits failed, record them one by one, errors:%+v", len(visits), err)
+		to = sink.Retry(to, err)
+	}
+	for _, visit := range visits {
+		err := to.Write([]*server.Visit{visit})
+		if err == nil {
+			continue
+		}
+		log.Errorf("record %s failed, errors:%+v", visit.PictureId, err)
+		img := server.ImgMsg{Shop: visit.Shop, Position: visit.Position, ModTime: int64(visit.VisitTime), ObjID: visit.PictureId}
+		for _, imgMsg := range imgMsgs {
+			if imgMsg.ObjID == visit.PictureId {
+				img = imgMsg
+				break
+			}
+		}
+		dl := server.NewDeadLetter(img, server.StageRecord, err)
+		dl.Sinks = sink.Kinds(sink.Retry(to, err))
+		if dl.Visit, err = visit.Marshal(); err != nil {
+			log.Errorf("protobuf encoding error: %+v, errors:%+v", visit, err)
+		}
+		failures = append(failures, dl)
 	}
 	return
 }
@@ -139,53 +262,140 @@ func main() {
 		syscall.SIGUSR2,
 	)
 
-	imgCh := make(chan server.ImgMsg, 10000)
-	mqs := strings.Split(*hyenaMqAddr, ",")
-	prophets := strings.Split(*hyenaPdAddr, ",")
-	var vdb proxy.Proxy
+	if *role != roleAll && *role != roleIngest && *role != roleIdentify {
+		log.Fatalf("unknown role %q", *role)
+	}
+	qCfg := parseQueueCfg()
+	if qCfg.Kind == queue.KindMemory && *role != roleAll {
+		log.Fatalf("memory queue works only in role %s", roleAll)
+	}
+
 	var err error
-	if vdb, err = proxy.NewMQBasedProxy("hyena", mqs, prophets, proxy.WithSearchTimeout(time.Duration(HyenaSearchTimeout)*time.Second)); err != nil {
-		log.Fatalf("got error %+v", err)
+	var producer queue.Producer
+	var consumer queue.Consumer
+	if qCfg.Kind == queue.KindMemory {
+		memQ := queue.NewMemoryQueue(qCfg.Capacity, qCfg.DropWhenFull)
+		producer, consumer = memQ, memQ
+	} else {
+		if *role != roleIdentify {
+			if producer, err = queue.NewProducer(qCfg); err != nil {
+				log.Fatalf("got error %+v", err)
+			}
+		}
+		if *role != roleIngest {
+			if consumer, err = queue.NewConsumer(qCfg); err != nil {
+				log.Fatalf("got error %+v", err)
+			}
+		}
 	}
 
-	iden3 := NewIdentifier3(vdb, float32(*identifyDisThr2), float32(*identifyDisThr3), *predictServURL, *redisAddr)
+	var iden3 *Identifier3
 	var recorder *Recorder
-	if recorder, err = NewRecorder(*destPgUrl); err != nil {
-		log.Errorf("got error: %+v", err)
-		return
+	var dlq server.DeadLetterQueue
+	correcting := *mergeUids != "" || *splitUid != ""
+	if *role != roleIngest || *replayAddr != "" || *redrive || correcting || *forgetUid != "" {
+		var vdb vecindex.VectorIndex
+		if vdb, err = newVectorIndex(); err != nil {
+			log.Fatalf("got error %+v", err)
+		}
+
+		embedder := embed.NewMxnetEmbedder(*predictServURL, embed.Cfg{
+			Timeout:  time.Millisecond * time.Duration(*predictTOMs),
+			Retries:  *predictRetries,
+			Backoff:  time.Millisecond * time.Duration(*predictBackMs),
+			MaxBatch: *identifyBatchSize,
+		})
+		iden3 = NewIdentifier3(vdb, float32(*identifyDisThr2), float32(*identifyDisThr3), embedder, *redisAddr)
+		var gate embed.Gate
+		if gate, err = embed.ParseGate(*gateMinQuality, *gatePoseTypes); err != nil {
+			log.Fatalf("got error %+v", err)
+		}
+		iden3.SetGate(gate)
+		if _, removable := vdb.(vecindex.Remover); !removable {
+			// the vectors of a forgotten uid are scrubbed in their dbs
+			iden3.SetXidDbs(server.NewRedisXidDbStore(iden3.rcli))
+		}
+		if *historyMaxVisits <= 0 {
+			log.Fatalf("--history-max-visits must be positive")
+		}
+		iden3.SetHistory(server.NewRedisHistoryStore(iden3.rcli, time.Second*time.Duration(*sessionGapSec), *historyMaxVisits))
+		if recorder, err = NewRecorder(*destPgUrl, *identifyWorkers, parseSinkCfg()); err != nil {
+			log.Errorf("got error: %+v", err)
+			return
+		}
+		defer recorder.Close()
+		dlq = newDeadLetterQueue()
 	}
 	if *replayAddr != "" {
-		if err = replayVisitRecords(iden3, recorder); err != nil {
+		if err = replayVisitRecords(iden3, recorder, dlq); err != nil {
+			log.Errorf("got error: %+v", err)
+		}
+		return
+	}
+	if *redrive {
+		if err = redriveDeadLetters(iden3, recorder, dlq); err != nil {
+			log.Errorf("got error: %+v", err)
+		}
+		return
+	}
+	if correcting {
+		if err = runCorrection(newCorrector(iden3, recorder, *adminToken)); err != nil {
+			log.Errorf("got error: %+v", err)
+		}
+		return
+	}
+	if *forgetUid != "" {
+		if err = runForget(iden3, recorder, dlq); err != nil {
 			log.Errorf("got error: %+v", err)
 		}
 		return
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	s := server.NewFileServer(parseCfg(), imgCh)
-	go s.Start()
-	go func() {
-		var imgMsgs []server.ImgMsg
-		tickCh := time.Tick(50 * time.Millisecond)
-		for {
-			select {
-			case <-ctx.Done():
-				log.Infof("image process goroutine exited")
-				return
-			case img := <-imgCh:
-				imgMsgs = append(imgMsgs, img)
-				if len(imgMsgs) >= InferBatchSize {
-					handleImgMsgs(iden3, recorder, imgMsgs)
-					imgMsgs = make([]server.ImgMsg, 0)
-				}
-			case <-tickCh:
-				if len(imgMsgs) != 0 {
-					handleImgMsgs(iden3, recorder, imgMsgs)
-					imgMsgs = make([]server.ImgMsg, 0)
-				}
-			}
+	// the goroutines stopped by cancel
+	var wg sync.WaitGroup
+	var s *server.FileServer
+	if *role != roleIdentify {
+		s = server.NewFileServer(parseCfg(), producer)
+		http.Handle("/command", s.CommandHandler(*adminToken))
+		http.Handle("/cmdb/invalidate", s.CmdbInvalidateHandler())
+		go s.Start()
+	}
+	var corrector *Corrector
+	if *role != roleIngest {
+		hub := live.NewHub(*liveBuffer, live.NewRedisBackfill(iden3.rcli, server.VisitIndexKey), time.Minute*time.Duration(*liveMaxBackfill))
+		var origins []string
+		if *liveOrigins != "" {
+			origins = strings.Split(*liveOrigins, ",")
 		}
-	}()
+		hub.SetAuth(origins, *liveToken)
+		if *liveToken == "" {
+			log.Warnf("the live visit stream is disabled without --live-token")
+		}
+		// the sightings are published as they are identified, before they're sessionized
+		iden3.AddPublisher(hub)
+		http.Handle("/visits/ws", hub.WebSocketHandler())
+		http.Handle("/visits/sse", hub.SSEHandler())
+		var agg *aggregate.Aggregator
+		if agg, err = newAggregator(ctx, &wg, iden3.rcli); err != nil {
+			log.Fatalf("got error %+v", err)
+		}
+		iden3.AddPublisher(agg)
+		http.Handle("/footfall", agg.Handler())
+		var pool *workerPool
+		if pool, err = newWorkerPool(*identifyWorkers, *identifyBatchSize, time.Millisecond*time.Duration(*identifyFlushMs), time.Second*time.Duration(*sessionGapSec), time.Second*time.Duration(*sessionIdleSec), consumer, iden3, recorder, dlq); err != nil {
+			log.Fatalf("got error %+v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.run(ctx)
+		}()
+		corrector = newCorrector(iden3, recorder, *adminToken)
+		http.Handle("/identity/merge", corrector.MergeHandler())
+		http.Handle("/identity/split", corrector.SplitHandler())
+		http.Handle("/visits/history", historyHandler(iden3.history, *adminToken))
+	}
 
 	for {
 		sig := <-sc
@@ -194,7 +404,7 @@ func main() {
 			buf := bytes.NewBuffer([]byte{})
 			_ = runPprof.Lookup("goroutine").WriteTo(buf, 1)
 			log.Infof("got signal=<%d>.", sig)
-			log.Infof(buf.String())
+			log.Info(buf.String())
 			continue
 		case syscall.SIGUSR2:
 			log.Infof("got signal=<%d>.", sig)
@@ -212,9 +422,18 @@ func main() {
 				retVal = 1
 			}
 			log.Infof("exit with signal=<%d>.", sig)
-			s.Stop()
+			if s != nil {
+				s.Stop()
+			}
 			cancel()
-			time.Sleep(5 * time.Second)
+			// the workers record their open sessions
+			wg.Wait()
+			if corrector != nil {
+				corrector.Wait()
+			}
+			if recorder != nil {
+				recorder.Close()
+			}
 			log.Infof(" bye :-).")
 			os.Exit(retVal)
 		}
@@ -236,11 +455,166 @@ func parseCfg() *server.Cfg {
 	cfg.Retry.RetryFactor = *retryIntervalFactor
 	cfg.Retry.RetryInterval = time.Second * time.Duration(*retryIntervalSec)
 
+	var err error
+	cfg.Liveness.SilentTimeout = time.Second * time.Duration(*cameraSilentSec)
+	cfg.Liveness.CheckInterval = time.Second * time.Duration(*cameraCheckSec)
+	if cfg.Liveness.OpenHour, cfg.Liveness.CloseHour, err = server.ParseOpeningHours(*openingHours); err != nil {
+		log.Fatalf("%+v", err)
+	}
+	cfg.Liveness.Webhook = *alertWebhook
+	cfg.Liveness.WebhookTimeout = time.Second * time.Duration(*alertWebhookTOSec)
+
 	cfg.EurekaAddr = *eurekaAddr
 	cfg.EurekaApp = *eurekaApp
+	cfg.CmdbCacheTTL = time.Second * time.Duration(*cmdbCacheTTLSec)
+	cfg.CmdbNegativeTTL = time.Second * time.Duration(*cmdbNegativeTTLSec)
+	cfg.CmdbBatchWindow = time.Millisecond * time.Duration(*cmdbBatchWindowMs)
+	cfg.CmdbBatchSize = *cmdbBatchSize
+	cfg.PositionFile = *positionFile
+	cfg.PositionReload = time.Second * time.Duration(*positionReloadSec)
 	return cfg
 }
 
+func parseQueueCfg() queue.Cfg {
+	cfg := queue.Cfg{
+		Kind:         *queueKind,
+		Name:         *queueName,
+		Group:        *queueGroup,
+		Consumer:     *queueConsumer,
+		Capacity:     *queueCapacity,
+		DropWhenFull: *queueDrop,
+		MaxLen:       *queueMaxLen,
+	}
+	addr := *queueAddr
+	if addr == "" {
+		addr = *redisAddr
+	}
+	cfg.Addrs = strings.Split(addr, ",")
+	if cfg.Consumer == "" {
+		cfg.Consumer, _ = os.Hostname()
+	}
+	return cfg
+}
+
+// newFootfallCheckpoint returns the checkpoint of the footfall aggregation, nil if it's disabled
+func newFootfallCheckpoint(rcli *redis.Client) (cp aggregate.Checkpoint, err error) {
+	switch *footfallCheckpoint {
+	case "":
+	case "redis":
+		cp = aggregate.NewRedisCheckpoint(rcli, footfallCheckpointKey())
+	case "file":
+		cp = aggregate.NewFileCheckpoint(*footfallCpFile)
+	default:
+		err = errors.Errorf("unknown footfall checkpoint %s", *footfallCheckpoint)
+	}
+	return
+}
+
+// footfallCheckpointKey returns the Redis key of the footfall checkpoint, each identify
+// process aggregates its own visits, it's keyed by the consumer name
+func footfallCheckpointKey() string {
+	return "footfall_checkpoint:" + parseQueueCfg().Consumer
+}
+
+// newAggregator returns the footfall aggregator restored from the checkpoint, it's saved till ctx is done
+func newAggregator(ctx context.Context, wg *sync.WaitGroup, rcli *redis.Client) (agg *aggregate.Aggregator, err error) {
+	var windows []time.Duration
+	for _, s := range strings.Split(*footfallWindows, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		var w time.Duration
+		if w, err = time.ParseDuration(s); err != nil {
+			err = errors.Wrapf(err, "invalid footfall window %s", s)
+			return
+		}
+		windows = append(windows, w)
+	}
+	if len(windows) == 0 {
+		err = errors.New("no footfall window")
+		return
+	}
+	agg = aggregate.New(windows)
+	prometheus.MustRegister(agg)
+	if *footfallShared {
+		// the buckets live in Redis, they survive the restarts
+		agg.SetShared(ctx, rcli, "footfall_", time.Second*time.Duration(*footfallRefreshS))
+		return
+	}
+
+	var cp aggregate.Checkpoint
+	if cp, err = newFootfallCheckpoint(rcli); err != nil || cp == nil {
+		return
+	}
+	if err = agg.Load(cp); err != nil {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		agg.Run(ctx, cp, time.Second*time.Duration(*footfallCpIntervalS))
+	}()
+	return
+}
+
+func parseSinkCfg() sink.Cfg {
+	cfg := sink.Cfg{
+		Kinds:      sink.ParseKinds(*visitSinks),
+		KafkaTopic: *visitSinkTopic,
+		RedisAddr:  *visitSinkRedis,
+		RedisKey:   *visitSinkKey,
+		File:       *visitSinkFile,
+	}
+	if *visitSinkMqAddr != "" {
+		cfg.KafkaAddrs = strings.Split(*visitSinkMqAddr, ",")
+	}
+	if cfg.RedisAddr == "" {
+		cfg.RedisAddr = *redisAddr
+	}
+	return cfg
+}
+
+// newVectorIndex returns the vector index of --vector-index. The memory index is
+// private to the process, so it doesn't work with multiple identify processes.
+func newVectorIndex() (vdb vecindex.VectorIndex, err error) {
+	switch *vectorIndex {
+	case vecindex.KindHyena:
+		mqs := strings.Split(*hyenaMqAddr, ",")
+		prophets := strings.Split(*hyenaPdAddr, ",")
+		return vecindex.NewHyenaIndex(mqs, prophets, time.Duration(HyenaSearchTimeout)*time.Second)
+	case vecindex.KindMemory:
+		if *vectorDistThr >= *identifyDisThr2 {
+			// every match would be at least distThr2, so vectors are never merged
+			err = errors.Errorf("vector distance threshold %v must be less than identify distance threshold2 %v",
+				*vectorDistThr, *identifyDisThr2)
+			return
+		}
+		if *vectorFile == "" {
+			return vecindex.NewMemoryIndex(*vectorDim, float32(*vectorDistThr)), nil
+		}
+		var idx *vecindex.MemoryIndex
+		if idx, err = vecindex.OpenMemoryIndex(*vectorFile, *vectorDim, float32(*vectorDistThr)); err != nil {
+			return
+		}
+		log.Infof("loaded %d vectors from %s", idx.Len(), *vectorFile)
+		vdb = idx
+	default:
+		err = errors.Errorf("unsupported vector index %q", *vectorIndex)
+	}
+	return
+}
+
+func newS3() *s3.S3 {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(*ossKey, *ossSecretKey, ""),
+		Endpoint:         aws.String(*ossAddr),
+		DisableSSL:       aws.Bool(true),
+		S3ForcePathStyle: aws.Bool(true),
+		Region:           aws.String("default"),
+	}))
+	return s3.New(sess)
+}
+
 func s3Get(srv *s3.S3, key string) (value []byte, err error) {
 	var out *s3.GetObjectOutput
 	out, err = srv.GetObject(&s3.GetObjectInput{
@@ -258,44 +632,27 @@ func s3Get(srv *s3.S3, key string) (value []byte, err error) {
 	return
 }
 
-func replayVisitRecords(iden3 *Identifier3, recorder *Recorder) (err error) {
+func replayVisitRecords(iden3 *Identifier3, recorder *Recorder, dlq server.DeadLetterQueue) (err error) {
 	log.Infof("replaying visit records from %v...", *replayAddr)
-	que := "visit_queue"
-	var sess *session.Session
-	sess = session.Must(session.NewSession(&aws.Config{
-		Credentials:      credentials.NewStaticCredentials(*ossKey, *ossSecretKey, ""),
-		Endpoint:         aws.String(*ossAddr),
-		DisableSSL:       aws.Bool(true),
-		S3ForcePathStyle: aws.Bool(true),
-		Region:           aws.String("default"),
-	}))
-	srv := s3.New(sess)
+	srv := newS3()
 	rcli := redis.NewClient(&redis.Options{
 		Addr:     *replayAddr,
 		Password: "", // no password set
 		DB:       0,  // use default DB
 	})
 
-	var idxStart, idxEnd int64
-	if idxStart, idxEnd, err = server.GetVisitIdxRange(rcli, que, *replayDateStart, *replayDateEnd); err != nil {
-		log.Fatal(err)
+	sess := sessionizer.New(time.Second * time.Duration(*sessionGapSec))
+
+	var tsStart, tsEnd int64
+	if tsStart, tsEnd, err = server.ParseTimeRange(*replayDateStart, *replayDateEnd); err != nil {
+		return
 	}
 
-	for idxCur := idxStart; idxCur < idxEnd; idxCur += int64(InferBatchSize) {
-		var recs []string
-		if recs, err = rcli.LRange(que, idxCur, idxCur+int64(InferBatchSize)-1).Result(); err != nil {
-			err = errors.Wrapf(err, "")
-			return
-		}
+	var replayed int
+	if err = server.RangeVisits(rcli, server.VisitIndexKey, tsStart, tsEnd, int64(*identifyBatchSize), func(visits []*server.Visit) (err error) {
 		var imgMsgs []server.ImgMsg
 		var img []byte
-		for _, rec := range recs {
-			var visit server.Visit
-			if err = visit.Unmarshal([]byte(rec)); err != nil {
-				err = errors.Wrapf(err, "")
-				return
-			}
-
+		for _, visit := range visits {
 			objID := visit.PictureId
 			if img, err = s3Get(srv, objID); err != nil {
 				err = errors.Wrapf(err, "")
@@ -307,11 +664,21 @@ func replayVisitRecords(iden3 *Identifier3, recorder *Recorder) (err error) {
 				ModTime:  int64(visit.VisitTime),
 				ObjID:    objID,
 				Img:      img,
+				Camera:   visit.Camera(),
+				Mac:      visit.Mac,
+				CameraIp: visit.CameraIp,
 			}
 			imgMsgs = append(imgMsgs, imgMsg)
 		}
-		handleImgMsgs(iden3, recorder, imgMsgs)
+		pushDeadLetters(dlq, handleImgMsgs(iden3, recorder, sess, imgMsgs))
+		// history is sessionized by the visit time
+		pushDeadLetters(dlq, recordVisits(recorder, sess.Expire(sess.Watermark()), nil))
+		replayed += len(visits)
+		return
+	}); err != nil {
+		return
 	}
-	log.Infof("replayed %v visit records from %v...", idxEnd-idxStart, *replayAddr)
+	pushDeadLetters(dlq, recordVisits(recorder, sess.Flush(), nil))
+	log.Infof("replayed %v visit records from %v...", replayed, *replayAddr)
 	return
 }
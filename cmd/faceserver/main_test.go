@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFootfallCheckpointDefault(t *testing.T) {
+	// the default flags start the identify role
+	cp, err := newFootfallCheckpoint(nil)
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	hostname, _ := os.Hostname()
+	require.Equal(t, "footfall_checkpoint:"+hostname, footfallCheckpointKey())
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	rcli := redis.NewClient(&redis.Options{Addr: addr, DB: 15})
+	defer rcli.Close()
+	require.NoError(t, rcli.FlushDB().Err())
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	agg, err := newAggregator(ctx, &wg, rcli)
+	require.NoError(t, err)
+	require.NotNil(t, agg)
+	cancel()
+	wg.Wait()
+}
@@ -4,45 +4,90 @@ import (
 	"time"
 
 	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/infinivision/filesyncer/pkg/sink"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 )
 
+// Recorder writes the visits to the sinks, and keeps the visit history in PostgreSQL
+// consistent with the identity corrections
 type Recorder struct {
 	destPgUrl string
 	db        *sqlx.DB
+	sink      sink.VisitSink
 }
 
-func NewRecorder(destPgUrl string) (rcd *Recorder, err error) {
-	// this Pings the database trying to connect, panics on error
-	// use sqlx.Open() for sql.Open() semantics
-	var db *sqlx.DB
-	db, err = sqlx.Connect("postgres", destPgUrl)
-	if err != nil {
-		err = errors.Wrapf(err, "")
-		return
-	}
-	db.SetMaxOpenConns(1)
+// NewRecorder returns a recorder with at most maxConns connections to PostgreSQL.
+// The database is optional unless the postgres sink, corrections or forgetting are used.
+func NewRecorder(destPgUrl string, maxConns int, sinkCfg sink.Cfg) (rcd *Recorder, err error) {
 	rcd = &Recorder{
 		destPgUrl: destPgUrl,
-		db:        db,
+	}
+	if destPgUrl != "" {
+		// this Pings the database trying to connect, panics on error
+		// use sqlx.Open() for sql.Open() semantics
+		if rcd.db, err = sqlx.Connect("postgres", destPgUrl); err != nil {
+			err = errors.Wrapf(err, "")
+			return
+		}
+		rcd.db.SetMaxOpenConns(maxConns)
+	}
+	sinkCfg.DB = rcd.db
+	if rcd.sink, err = sink.New(sinkCfg); err != nil {
+		return
 	}
 	return
 }
 
+// Record writes the visits to the sinks
 func (this *Recorder) Record(visits []*server.Visit) (err error) {
-	for _, visit := range visits {
-		vt := time.Unix(int64(visit.VisitTime), 0).Format(time.RFC3339)
-		// Note: If db.Query is used, then the connection will not be released to pool since the cursor is not closed.
-		if _, err = this.db.Exec("SELECT insert_user($1, $2, $3, $4, $5, $6)", visit.Uid, visit.PictureId, visit.Quality, visit.Gender, visit.Age, vt); err != nil {
+	return this.sink.Write(visits)
+}
+
+// Close closes the sinks
+func (this *Recorder) Close() error {
+	return this.sink.Close()
+}
+
+// Correct applies the correction event to the visit history
+func (this *Recorder) Correct(ev *server.CorrectionEvent) (err error) {
+	if this.db == nil {
+		err = errors.New("correcting the history requires --dest-pg-url")
+		return
+	}
+	switch ev.Kind {
+	case server.CorrectionMerge:
+		if _, err = this.db.Exec("SELECT merge_uid($1, $2)", ev.Uid, ev.From); err != nil {
 			err = errors.Wrapf(err, "")
-			return
 		}
-		if _, err = this.db.Exec("SELECT insert_visit_event($1, $2, $3, $4, $5, $6)", visit.Shop, visit.Uid, visit.Position, visit.Gender, visit.Age, vt); err != nil {
+	case server.CorrectionSplit:
+		vts := make([]string, 0, len(ev.VisitTimes))
+		for _, ts := range ev.VisitTimes {
+			vts = append(vts, time.Unix(int64(ts), 0).Format(time.RFC3339))
+		}
+		if _, err = this.db.Exec("SELECT split_uid($1, $2, $3, $4)", ev.From, ev.Uid, pq.Array(ev.PictureIds), pq.Array(vts)); err != nil {
 			err = errors.Wrapf(err, "")
-			return
 		}
+	default:
+		err = errors.Errorf("unknown correction %q", ev.Kind)
+	}
+	return
+}
+
+// Forget implements forget.History
+func (this *Recorder) Forget(uid int64, dryRun bool) (rows int64, err error) {
+	if this.db == nil {
+		err = errors.New("forgetting the history requires --dest-pg-url")
+		return
+	}
+	if dryRun {
+		err = this.db.Get(&rows, "SELECT (SELECT count(*) FROM users WHERE uid=$1) + (SELECT count(*) FROM visit_events WHERE uid=$1) + (SELECT count(*) FROM visit_stats_user WHERE uid=$1)", uid)
+	} else {
+		err = this.db.Get(&rows, "SELECT forget_uid($1)", uid)
+	}
+	if err != nil {
+		err = errors.Wrapf(err, "")
 	}
 	return
 }
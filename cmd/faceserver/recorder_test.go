@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/infinivision/filesyncer/pkg/sink"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// testSink fails the batches of more than one visit, and the visit failed
+type testSink struct {
+	batchFails bool
+	failed     string
+	written    []string
+}
+
+func (s *testSink) Write(visits []*server.Visit) error {
+	if s.batchFails && len(visits) > 1 {
+		return errors.New("down")
+	}
+	for _, visit := range visits {
+		if visit.PictureId == s.failed {
+			return errors.New("down")
+		}
+	}
+	for _, visit := range visits {
+		s.written = append(s.written, visit.PictureId)
+	}
+	return nil
+}
+
+func (s *testSink) Close() error {
+	return nil
+}
+
+func TestRecordVisitsRetryFailedSinks(t *testing.T) {
+	good, failing := &testSink{}, &testSink{batchFails: true, failed: "obj2"}
+	visits := []*server.Visit{{PictureId: "obj1"}, {PictureId: "obj2"}}
+	failures := recordVisitsTo(sink.NewFanOut(good, failing), visits, nil)
+	require.Equal(t, 1, len(failures))
+	require.Equal(t, "obj2", failures[0].ObjID)
+	require.NotEmpty(t, failures[0].Visit)
+	// the succeeded sink is not written again
+	require.Equal(t, []string{"obj1", "obj2"}, good.written)
+	require.Equal(t, []string{"obj1"}, failing.written)
+}
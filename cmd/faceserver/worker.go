@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/infinivision/filesyncer/pkg/queue"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/infinivision/filesyncer/pkg/sessionizer"
+	"github.com/pkg/errors"
+)
+
+type job struct {
+	msg    *queue.Message
+	imgMsg server.ImgMsg
+}
+
+// workerPool identifies the images in the image queue with several workers.
+// Images of a shop are always dispatched to the same worker, so they are
+// identified in order, and sessionized by the worker. A message is acked once
+// it's put into the dead letter queue, or the session its visit is merged into
+// is closed and recorded, so the open sessions of a crashed process are built
+// again from the messages delivered again.
+type workerPool struct {
+	batchSize   int
+	flush       time.Duration
+	sessionGap  time.Duration
+	sessionIdle time.Duration
+	consumer    queue.Consumer
+	dlq         server.DeadLetterQueue
+	jobCs       []chan job
+	wg          sync.WaitGroup
+
+	// identify is Identifier3.DoBatch
+	identify func(imgMsgs []server.ImgMsg) ([]*server.Visit, []*server.DeadLetter)
+	// record is recordVisits with the recorder
+	record func(visits []*server.Visit, imgMsgs []server.ImgMsg) []*server.DeadLetter
+}
+
+func newWorkerPool(workers, batchSize int, flush, sessionGap, sessionIdle time.Duration, consumer queue.Consumer, iden3 *Identifier3, recorder *Recorder, dlq server.DeadLetterQueue) (wp *workerPool, err error) {
+	if workers <= 0 {
+		workers = 1
+	}
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if flush <= 0 {
+		err = errors.Errorf("invalid flush interval %v, expect > 0", flush)
+		return
+	}
+	wp = &workerPool{
+		batchSize:   batchSize,
+		flush:       flush,
+		sessionGap:  sessionGap,
+		sessionIdle: sessionIdle,
+		consumer:    consumer,
+		dlq:         dlq,
+		jobCs:       make([]chan job, workers),
+		identify:    iden3.DoBatch,
+		record: func(visits []*server.Visit, imgMsgs []server.ImgMsg) []*server.DeadLetter {
+			return recordVisits(recorder, visits, imgMsgs)
+		},
+	}
+	for i := range wp.jobCs {
+		wp.jobCs[i] = make(chan job, batchSize)
+	}
+	return
+}
+
+// run fetches the image queue until ctx is done
+func (wp *workerPool) run(ctx context.Context) {
+	for i, jobC := range wp.jobCs {
+		wp.wg.Add(1)
+		go wp.work(i, jobC)
+	}
+	defer func() {
+		for _, jobC := range wp.jobCs {
+			close(jobC)
+		}
+		wp.wg.Wait()
+		wp.consumer.Close()
+		log.Infof("image process goroutine exited")
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		msgs, err := wp.consumer.Fetch(wp.batchSize, wp.flush)
+		if err != nil {
+			log.Errorf("fetch images failed, errors:%+v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		for _, msg := range msgs {
+			var imgMsg server.ImgMsg
+			if err = imgMsg.Unmarshal(msg.Data); err != nil {
+				log.Errorf("drop malformed image message, errors:%+v", err)
+				wp.ack(msg)
+				continue
+			}
+			// blocks if the worker is busy, so we stop fetching
+			wp.jobCs[imgMsg.Shop%uint64(len(wp.jobCs))] <- job{msg: msg, imgMsg: imgMsg}
+		}
+	}
+}
+
+func (wp *workerPool) work(id int, jobC <-chan job) {
+	defer wp.wg.Done()
+
+	ticker := time.NewTicker(wp.flush)
+	defer ticker.Stop()
+
+	sess := sessionizer.New(wp.sessionGap)
+	var jobs []job
+	for {
+		select {
+		case j, ok := <-jobC:
+			if !ok {
+				wp.handle(id, sess, jobs)
+				wp.close(sess, sess.Flush())
+				return
+			}
+			jobs = append(jobs, j)
+			if len(jobs) >= wp.batchSize {
+				wp.handle(id, sess, jobs)
+				jobs = nil
+			}
+		case <-ticker.C:
+			if len(jobs) != 0 {
+				wp.handle(id, sess, jobs)
+				jobs = nil
+			}
+			wp.close(sess, sess.ExpireShops(wp.sessionIdle))
+		}
+	}
+}
+
+func (wp *workerPool) handle(id int, sess *sessionizer.Sessionizer, jobs []job) {
+	if len(jobs) == 0 {
+		return
+	}
+	imgMsgs := make([]server.ImgMsg, 0, len(jobs))
+	msgs := make([]*queue.Message, 0, len(jobs))
+	for _, j := range jobs {
+		imgMsgs = append(imgMsgs, j.imgMsg)
+		msgs = append(msgs, j.msg)
+	}
+	log.Debugf("worker-%d: identify %d images", id, len(imgMsgs))
+	visits, failures := wp.identify(imgMsgs)
+
+	// the messages of the visits are acked once their sessions are closed
+	byObjID := make(map[string][]*queue.Message, len(jobs))
+	for _, j := range jobs {
+		byObjID[j.imgMsg.ObjID] = append(byObjID[j.imgMsg.ObjID], j.msg)
+	}
+	sources := make([]interface{}, len(visits))
+	for i, visit := range visits {
+		if ms := byObjID[visit.PictureId]; len(ms) != 0 {
+			sources[i], byObjID[visit.PictureId] = ms[0], ms[1:]
+		}
+	}
+	// the others are failed or have no usable face
+	var failed []*queue.Message
+	for _, ms := range byObjID {
+		failed = append(failed, ms...)
+	}
+	if pushDeadLetters(wp.dlq, failures) == nil {
+		wp.ack(failed...)
+	}
+	wp.close(sess, sess.AddSources(visits, sources))
+}
+
+// close records the closed visits, then acks their messages. The messages are
+// delivered again if the failed ones are lost.
+func (wp *workerPool) close(sess *sessionizer.Sessionizer, closed []*server.Visit) {
+	err := pushDeadLetters(wp.dlq, wp.record(closed, nil))
+	done := sess.Done()
+	if err != nil {
+		return
+	}
+	msgs := make([]*queue.Message, 0, len(done))
+	for _, src := range done {
+		msgs = append(msgs, src.(*queue.Message))
+	}
+	wp.ack(msgs...)
+}
+
+func (wp *workerPool) ack(msgs ...*queue.Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	if err := wp.consumer.Ack(msgs...); err != nil {
+		log.Errorf("ack images failed, they will be identified again, errors:%+v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/infinivision/filesyncer/pkg/queue"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/stretchr/testify/require"
+)
+
+// ackRecorder is a memory queue that records the acked messages
+type ackRecorder struct {
+	*queue.MemoryQueue
+	sync.Mutex
+	acked []string
+}
+
+func (q *ackRecorder) Ack(msgs ...*queue.Message) error {
+	q.Lock()
+	defer q.Unlock()
+	for _, msg := range msgs {
+		var imgMsg server.ImgMsg
+		if err := imgMsg.Unmarshal(msg.Data); err != nil {
+			q.acked = append(q.acked, string(msg.Data))
+			continue
+		}
+		q.acked = append(q.acked, imgMsg.ObjID)
+	}
+	return nil
+}
+
+func (q *ackRecorder) getAcked() []string {
+	q.Lock()
+	defer q.Unlock()
+	return append([]string(nil), q.acked...)
+}
+
+// testPool is a worker pool of fake identification and recording
+type testPool struct {
+	*workerPool
+	q *ackRecorder
+
+	sync.Mutex
+	// batches are the ObjIDs of the identified batches
+	batches [][]string
+	// recorded are the ObjIDs of the recorded visits when they are recorded
+	recorded []string
+	// ackedOnRecord are the acked ObjIDs when a visit is recorded
+	ackedOnRecord map[string][]string
+}
+
+func newTestPool(t *testing.T, workers, batchSize int, flush, sessionGap time.Duration) *testPool {
+	q := &ackRecorder{MemoryQueue: queue.NewMemoryQueue(0, false)}
+	wp, err := newWorkerPool(workers, batchSize, flush, sessionGap, time.Hour, q, nil, nil, server.NewFileDeadLetterQueue(filepath.Join(t.TempDir(), "dead_letters")))
+	require.NoError(t, err)
+	tp := &testPool{workerPool: wp, q: q, ackedOnRecord: make(map[string][]string)}
+	wp.identify = func(imgMsgs []server.ImgMsg) (visits []*server.Visit, failures []*server.DeadLetter) {
+		var batch []string
+		for _, img := range imgMsgs {
+			batch = append(batch, img.ObjID)
+			if strings.HasPrefix(img.ObjID, "bad") {
+				failures = append(failures, server.NewDeadLetter(img, server.StageIdentify, errors.New("bad image")))
+				continue
+			}
+			visits = append(visits, &server.Visit{PictureId: img.ObjID, Shop: img.Shop, Uid: 1, VisitTime: uint64(img.ModTime)})
+		}
+		tp.Lock()
+		tp.batches = append(tp.batches, batch)
+		tp.Unlock()
+		return
+	}
+	wp.record = func(visits []*server.Visit, imgMsgs []server.ImgMsg) []*server.DeadLetter {
+		tp.Lock()
+		defer tp.Unlock()
+		for _, visit := range visits {
+			tp.recorded = append(tp.recorded, visit.PictureId)
+			tp.ackedOnRecord[visit.PictureId] = q.getAcked()
+		}
+		return nil
+	}
+	return tp
+}
+
+func (tp *testPool) publish(t *testing.T, shop uint64, objID string, modTime int64) {
+	data, err := (&server.ImgMsg{Shop: shop, ObjID: objID, ModTime: modTime}).Marshal()
+	require.NoError(t, err)
+	require.NoError(t, tp.q.Publish(data))
+}
+
+// runUntil runs the pool until n messages are acked
+func (tp *testPool) runUntil(t *testing.T, n int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tp.run(ctx)
+		close(done)
+	}()
+	for i := 0; i < 200 && len(tp.q.getAcked()) < n; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker pool doesn't stop")
+	}
+}
+
+func TestNewWorkerPool(t *testing.T) {
+	q := queue.NewMemoryQueue(0, false)
+	_, err := newWorkerPool(1, 1, 0, 0, 0, q, nil, nil, nil)
+	require.Error(t, err)
+	_, err = newWorkerPool(1, 1, -time.Second, 0, 0, q, nil, nil, nil)
+	require.Error(t, err)
+
+	wp, err := newWorkerPool(0, 0, time.Millisecond, 0, 0, q, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(wp.jobCs))
+	require.Equal(t, 1, wp.batchSize)
+}
+
+func TestWorkerPoolPartition(t *testing.T) {
+	tp := newTestPool(t, 3, 2, 10*time.Millisecond, 0)
+	for i := 0; i < 20; i++ {
+		tp.publish(t, uint64(i%4), fmt.Sprintf("%d-%02d", i%4, i), int64(i))
+	}
+	tp.publish(t, 0, "", 0)
+	// malformed messages are acked and dropped
+	require.NoError(t, tp.q.Publish([]byte("junk")))
+	tp.runUntil(t, 22)
+
+	acked := tp.q.getAcked()
+	require.Equal(t, 22, len(acked))
+	require.Contains(t, acked, "junk")
+
+	// images of a shop are identified in order, in batches of at most batch size
+	tp.Lock()
+	defer tp.Unlock()
+	last := make(map[byte]string)
+	for _, batch := range tp.batches {
+		require.True(t, len(batch) <= 2)
+		for _, objID := range batch {
+			if objID == "" {
+				continue
+			}
+			require.True(t, objID > last[objID[0]], "%s after %s", objID, last[objID[0]])
+			last[objID[0]] = objID
+		}
+	}
+	require.Equal(t, 21, len(tp.recorded))
+}
+
+func TestWorkerPoolFlush(t *testing.T) {
+	// a batch that never fills up is identified after the flush interval
+	tp := newTestPool(t, 1, 100, 10*time.Millisecond, 0)
+	tp.publish(t, 1, "obj1", 1)
+	tp.runUntil(t, 1)
+	require.Equal(t, []string{"obj1"}, tp.q.getAcked())
+	tp.Lock()
+	defer tp.Unlock()
+	require.Equal(t, [][]string{{"obj1"}}, tp.batches)
+}
+
+func TestWorkerPoolAckAfterRecord(t *testing.T) {
+	tp := newTestPool(t, 1, 1, 10*time.Millisecond, 0)
+	tp.publish(t, 1, "obj1", 1)
+	tp.runUntil(t, 1)
+	tp.Lock()
+	defer tp.Unlock()
+	require.Equal(t, []string{"obj1"}, tp.recorded)
+	require.NotContains(t, tp.ackedOnRecord["obj1"], "obj1")
+}
+
+func TestWorkerPoolAckAfterSession(t *testing.T) {
+	tp := newTestPool(t, 1, 1, 10*time.Millisecond, 30*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tp.run(ctx)
+		close(done)
+	}()
+	identified := func(n int) {
+		for i := 0; i < 200; i++ {
+			tp.Lock()
+			batches := len(tp.batches)
+			tp.Unlock()
+			if batches >= n {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("%d images are not identified", n)
+	}
+	acked := func(n int) []string {
+		for i := 0; i < 200 && len(tp.q.getAcked()) < n; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		return tp.q.getAcked()
+	}
+
+	tp.publish(t, 1, "obj1", 1000)
+	tp.publish(t, 1, "obj2", 1010)
+	// a failed one is acked once it's in the dead letter queue
+	tp.publish(t, 1, "bad1", 1020)
+	identified(3)
+	require.Equal(t, []string{"bad1"}, acked(1))
+	n, err := tp.dlq.Len()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	// the session is closed by the next visit
+	tp.publish(t, 1, "obj3", 1100)
+	identified(4)
+	require.Equal(t, []string{"bad1", "obj1", "obj2"}, acked(3))
+
+	// and the open one is recorded when the pool stops
+	cancel()
+	<-done
+	require.Equal(t, []string{"bad1", "obj1", "obj2", "obj3"}, tp.q.getAcked())
+	tp.Lock()
+	defer tp.Unlock()
+	require.Equal(t, []string{"obj1", "obj3"}, tp.recorded)
+	require.Equal(t, []string{"bad1"}, tp.ackedOnRecord["obj1"])
+	require.Equal(t, []string{"bad1", "obj1", "obj2"}, tp.ackedOnRecord["obj3"])
+}
+
+func TestWorkerPoolExpire(t *testing.T) {
+	tp := newTestPool(t, 1, 1, 10*time.Millisecond, 30*time.Second)
+	// shop 2 sends nothing after obj1, its session is closed after the idle
+	tp.sessionIdle = 50 * time.Millisecond
+	tp.publish(t, 2, "obj1", 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tp.run(ctx)
+	for i := 0; i < 200 && len(tp.q.getAcked()) < 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, []string{"obj1"}, tp.q.getAcked())
+}
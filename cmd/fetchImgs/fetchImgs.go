@@ -6,14 +6,13 @@ import (
 	"io/ioutil"
 	"os"
 	"os/user"
-	"strconv"
-	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/export"
 	"github.com/infinivision/filesyncer/pkg/server"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -59,17 +58,9 @@ func main() {
 		}
 		*output = usr.HomeDir
 	}
-	intUids := make(map[uint64]int)
-	var intUid uint64
-	for _, sUid := range strings.Split(*uids, ",") {
-		if sUid == "" {
-			continue
-		}
-		if intUid, err = strconv.ParseUint(sUid, 10, 64); err != nil {
-			err = errors.Errorf("invalid uid: %v", sUid)
-			log.Fatal(err)
-		}
-		intUids[intUid] = 0
+	filter := &export.Filter{Gender: export.AnyGender}
+	if filter.Uids, err = export.ParseUints(*uids); err != nil {
+		log.Fatal(err)
 	}
 
 	var sess *session.Session
@@ -88,30 +79,17 @@ func main() {
 		DB:       0,  // use default DB
 	})
 
-	que := "visit_queue"
-	var idxStart, idxEnd int64
-	if idxStart, idxEnd, err = server.GetVisitIdxRange(rcli, que, *dateStart, *dateEnd); err != nil {
+	var tsStart, tsEnd int64
+	if tsStart, tsEnd, err = server.ParseTimeRange(*dateStart, *dateEnd); err != nil {
 		log.Fatal(err)
 	}
 
 	numFetched := 0
 	batchSize := int64(1000)
-	for idxCur := idxStart; idxCur < idxEnd; idxCur += batchSize {
-		var recs []string
-		if recs, err = rcli.LRange(que, idxCur, idxCur+batchSize-1).Result(); err != nil {
-			err = errors.Wrapf(err, "")
-			log.Fatal(err)
-		}
-		for _, rec := range recs {
-			var visit server.Visit
-			if err = visit.Unmarshal([]byte(rec)); err != nil {
-				err = errors.Wrapf(err, "")
-				log.Fatal(err)
-			}
-			if len(intUids) != 0 {
-				if _, found := intUids[visit.Uid]; !found {
-					continue
-				}
+	if err = server.RangeVisits(rcli, server.VisitIndexKey, tsStart, tsEnd, batchSize, func(visits []*server.Visit) (err error) {
+		for _, visit := range visits {
+			if !filter.Match(visit) {
+				continue
 			}
 			numFetched += 1
 
@@ -140,6 +118,9 @@ func main() {
 			}
 			jpg.Close()
 		}
+		return nil
+	}); err != nil {
+		log.Fatal(err)
 	}
 
 	log.Infof("Fetched %v pictures", numFetched)
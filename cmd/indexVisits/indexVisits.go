@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/server"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	redisAddr = flag.String("redis-addr", "127.0.0.1:6379", "Addr: redis address")
+	batchSize = flag.Int64("batch-size", 1000, "Max visits per round trip")
+)
+
+// indexVisits adds the visits of visit_queue written before visit_index to visit_index.
+// It can be run again, the visits already indexed are skipped.
+func main() {
+	flag.Parse()
+
+	var err error
+
+	rcli := redis.NewClient(&redis.Options{
+		Addr:     *redisAddr,
+		Password: "", // no password set
+		DB:       0,  // use default DB
+	})
+
+	var indexed int64
+	if indexed, err = server.IndexVisits(rcli, server.VisitQueueKey, server.VisitIndexKey, *batchSize); err != nil {
+		log.Fatalf("got error %+v", err)
+	}
+
+	log.Infof("Indexed %v visits of %v to %v", indexed, server.VisitQueueKey, server.VisitIndexKey)
+	return
+}
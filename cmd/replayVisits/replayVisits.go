@@ -3,13 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
-	"strconv"
 	"strings"
-	"time"
 
-	"github.com/Shopify/sarama"
 	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/export"
 	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/infinivision/filesyncer/pkg/sink"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 
@@ -24,53 +23,65 @@ var (
 	dateStart  = flag.String("date-start", "", "Datatime: date start in RFC3339 format. For example: 2019-03-01T00:00:00+08:00")
 	dateEnd    = flag.String("date-end", "", "Datatime: date end in RFC3339 format")
 	uids       = flag.String("uids", "", "interested user ids. Empyt means all. For example: 1226,3495")
+
+	visitSinks     = flag.String("visit-sinks", "", "List of sinks the visits are written to: postgres, kafka, redis and file. Empty means the ones of dest-pg-url and dest-mq-addr")
+	visitSinkTopic = flag.String("visit-sink-topic", "visits3", "Kafka topic of the kafka visit sink")
+	visitSinkRedis = flag.String("visit-sink-redis-addr", "", "Addr: Redis address of the redis visit sink, the default is --redis-addr")
+	visitSinkKey   = flag.String("visit-sink-redis-key", "visit_sink", "Redis list of the redis visit sink")
+	visitSinkFile  = flag.String("visit-sink-file", "", "File: the file visit sink appends the visits as JSON lines to it")
 )
 
 func main() {
 	var err error
 	flag.Parse()
-	intUids := make(map[uint64]int)
-	var intUid uint64
-	for _, sUid := range strings.Split(*uids, ",") {
-		if sUid == "" {
-			continue
+	filter := &export.Filter{Gender: export.AnyGender}
+	if filter.Uids, err = export.ParseUints(*uids); err != nil {
+		log.Errorf("got error %+v", err)
+		return
+	}
+
+	sinkCfg := sink.Cfg{
+		Kinds:      sink.ParseKinds(*visitSinks),
+		KafkaTopic: *visitSinkTopic,
+		RedisAddr:  *visitSinkRedis,
+		RedisKey:   *visitSinkKey,
+		File:       *visitSinkFile,
+	}
+	if len(sinkCfg.Kinds) == 0 {
+		// the sinks of the destinations given
+		if *destPgUrl != "" {
+			sinkCfg.Kinds = append(sinkCfg.Kinds, sink.KindPostgres)
 		}
-		if intUid, err = strconv.ParseUint(sUid, 10, 64); err != nil {
-			err = errors.Errorf("invalid uid: %v", sUid)
-			log.Errorf("got error %+v", err)
-			return
+		if *destMqAddr != "" {
+			sinkCfg.Kinds = append(sinkCfg.Kinds, sink.KindKafka)
 		}
-		intUids[intUid] = 0
 	}
-
-	var db *sqlx.DB
-	var mqProd sarama.SyncProducer
-
+	if len(sinkCfg.Kinds) == 0 {
+		fmt.Println("requires --visit-sinks, or one of dest-pg-url and dest-mq-addr")
+		return
+	}
+	if *destMqAddr != "" {
+		sinkCfg.KafkaAddrs = strings.Split(*destMqAddr, ",")
+	}
+	if sinkCfg.RedisAddr == "" {
+		sinkCfg.RedisAddr = *redisAddr
+	}
 	if *destPgUrl != "" {
 		// this Pings the database trying to connect, panics on error
 		// use sqlx.Open() for sql.Open() semantics
-		db, err = sqlx.Connect("postgres", *destPgUrl)
-		if err != nil {
+		if sinkCfg.DB, err = sqlx.Connect("postgres", *destPgUrl); err != nil {
 			err = errors.Wrapf(err, "")
 			log.Errorf("got error: %+v", err)
 			return
 		}
-		db.SetMaxOpenConns(1)
-	} else if *destMqAddr != "" {
-		config := sarama.NewConfig()
-		config.Producer.RequiredAcks = sarama.WaitForAll
-		config.Producer.Retry.Max = 10
-		config.Producer.Return.Successes = true
-		mqAddrs := strings.Split(*destMqAddr, ",")
-		if mqProd, err = sarama.NewSyncProducer(mqAddrs, config); err != nil {
-			err = errors.Wrap(err, "")
-			log.Errorf("got error %+v", err)
-			return
-		}
-	} else {
-		fmt.Println("requires one of dest-pg-url and dest-mq-addr")
+		sinkCfg.DB.SetMaxOpenConns(1)
+	}
+	var visitSink sink.VisitSink
+	if visitSink, err = sink.New(sinkCfg); err != nil {
+		log.Errorf("got error: %+v", err)
 		return
 	}
+	defer visitSink.Close()
 
 	rcli := redis.NewClient(&redis.Options{
 		Addr:     *redisAddr,
@@ -78,62 +89,34 @@ func main() {
 		DB:       0,  // use default DB
 	})
 
-	que := "visit_queue"
-	var idxStart, idxEnd int64
-	if idxStart, idxEnd, err = server.GetVisitIdxRange(rcli, que, *dateStart, *dateEnd); err != nil {
+	var tsStart, tsEnd int64
+	if tsStart, tsEnd, err = server.ParseTimeRange(*dateStart, *dateEnd); err != nil {
 		log.Errorf("got error %+v", err)
 		return
 	}
 
 	numFetched := 0
 	batchSize := int64(1000)
-	for idxCur := idxStart; idxCur < idxEnd; idxCur += batchSize {
-		var recs []string
-		if recs, err = rcli.LRange(que, idxCur, idxCur+batchSize-1).Result(); err != nil {
-			err = errors.Wrapf(err, "")
-			log.Errorf("got error %+v", err)
-			return
-		}
-		for _, rec := range recs {
-			var visit server.Visit
-			if err = visit.Unmarshal([]byte(rec)); err != nil {
-				err = errors.Wrapf(err, "")
-				log.Fatal(err)
-			}
-			if len(intUids) != 0 {
-				if _, found := intUids[visit.Uid]; !found {
-					continue
-				}
-			}
-			numFetched += 1
-			fmt.Printf("\rfetched %d", numFetched)
-
-			if db != nil {
-				vt := time.Unix(int64(visit.VisitTime), 0).Format(time.RFC3339)
-				// Note: If db.Query is used, then the connection will not be released to pool since the cursor is not closed.
-				if _, err = db.Exec("SELECT insert_user($1, $2, $3, $4, $5, $6)", visit.Uid, visit.PictureId, visit.Quality, visit.Gender, visit.Age, vt); err != nil {
-					err = errors.Wrapf(err, "")
-					log.Errorf("got error %+v", err)
-					return
-				}
-				if _, err = db.Exec("SELECT insert_visit_event($1, $2, $3, $4, $5, $6)", visit.Shop, visit.Uid, visit.Position, visit.Gender, visit.Age, vt); err != nil {
-					err = errors.Wrapf(err, "")
-					log.Errorf("got error %+v", err)
-					return
-				}
-			}
-
-			if mqProd != nil {
-				_, _, err = mqProd.SendMessage(&sarama.ProducerMessage{
-					Topic: "visits3",
-					Value: sarama.ByteEncoder([]byte(rec)),
-				})
-				if err != nil {
-					err = errors.Wrap(err, "")
-					log.Fatal(err)
-				}
+	if err = server.RangeRawVisits(rcli, server.VisitIndexKey, tsStart, tsEnd, batchSize, func(recs []*server.Visit, raws [][]byte) (err error) {
+		var visits []*server.Visit
+		var matched [][]byte
+		for i, visit := range recs {
+			if !filter.Match(visit) {
+				continue
 			}
+			visits = append(visits, visit)
+			matched = append(matched, raws[i])
+		}
+		// kafka and redis sinks get the records as they are in visit_queue
+		if err = sink.WriteRaw(visitSink, visits, matched); err != nil {
+			return
 		}
+		numFetched += len(visits)
+		fmt.Printf("\rfetched %d", numFetched)
+		return
+	}); err != nil {
+		log.Errorf("got error %+v", err)
+		return
 	}
 
 	fmt.Println()
@@ -42,6 +42,14 @@ var (
 	retryIntervalSec    = flag.Int("retry-interval", 10, "Interval(sec): interval seconds between two retries")
 	retryIntervalFactor = flag.Int("retry-interval-factor", 2, "Factor: retry interval factor")
 
+	cameraSilentSec   = flag.Int("camera-silent-timeout", 0, "Timeout(sec): alert if a camera has no upload during opening hours, 0 disables the detector")
+	cameraCheckSec    = flag.Int("camera-check-interval", 60, "Interval(sec): interval of checking silent cameras")
+	openingHours      = flag.String("opening-hours", "8-22", "Hours: shop opening hours in local time")
+	alertWebhook      = flag.String("alert-webhook", "", "URL: post alerts as json to the webhook. Empty means log alerts only")
+	alertWebhookTOSec = flag.Int("alert-webhook-timeout", 5, "Timeout(sec): timeout of posting alerts to the webhook")
+
+	adminToken = flag.String("admin-token", "", "Token required in the X-Admin-Token header by /command, it's disabled if it's empty")
+
 	showVer = flag.Bool("version", false, "Show version and quit.")
 )
 
@@ -64,6 +72,11 @@ func main() {
 	}
 
 	s := server.NewFileServer(parseCfg(), nil)
+	// commands to terminals are served at the pprof http server
+	if *adminToken == "" {
+		log.Warnf("/command is disabled without --admin-token")
+	}
+	http.Handle("/command", s.CommandHandler(*adminToken))
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc,
 		syscall.SIGINT,
@@ -100,5 +113,14 @@ func parseCfg() *server.Cfg {
 	cfg.Retry.RetryFactor = *retryIntervalFactor
 	cfg.Retry.RetryInterval = time.Second * time.Duration(*retryIntervalSec)
 
+	var err error
+	cfg.Liveness.SilentTimeout = time.Second * time.Duration(*cameraSilentSec)
+	cfg.Liveness.CheckInterval = time.Second * time.Duration(*cameraCheckSec)
+	if cfg.Liveness.OpenHour, cfg.Liveness.CloseHour, err = server.ParseOpeningHours(*openingHours); err != nil {
+		log.Fatalf("%+v", err)
+	}
+	cfg.Liveness.Webhook = *alertWebhook
+	cfg.Liveness.WebhookTimeout = time.Second * time.Duration(*alertWebhookTOSec)
+
 	return cfg
 }
@@ -0,0 +1,387 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// AllPositions queries the stats of all positions of a shop
+	AllPositions int64 = -1
+	// AgeBands is the number of age bands, they're 5 years each as age_0 - age_19 of visit_stats_pv
+	AgeBands = 20
+)
+
+// AgeBand returns the age band of the age, the same as insert_visit_event
+func AgeBand(age uint32) uint32 {
+	if band := age / 5; band < AgeBands {
+		return band
+	}
+	return AgeBands - 1
+}
+
+// series is the buckets of a position by minute
+type series map[int64]*bucket
+
+// person is a uid seen in a bucket, Gender and Age are of the last sighting
+type person struct {
+	Gender uint32 `json:"gender"`
+	Age    uint32 `json:"age"`
+	New    bool   `json:"new"`
+}
+
+// bucket is a minute of sightings at a position
+type bucket struct {
+	Sightings uint64             `json:"sightings"`
+	Uids      map[uint64]*person `json:"uids"`
+}
+
+// Stats is the aggregation of a shop, or a position of it, in the window till now.
+// Gender and AgeBands count the unique uids.
+type Stats struct {
+	Shop      uint64         `json:"shop"`
+	Position  int64          `json:"position"`
+	Window    string         `json:"window"`
+	Uniques   int            `json:"uniques"`
+	Sightings uint64         `json:"sightings"`
+	New       int            `json:"new"`
+	Returning int            `json:"returning"`
+	Gender    map[string]int `json:"gender"`
+	AgeBands  map[string]int `json:"ageBands"`
+}
+
+// Aggregator keeps the rolling windows of footfall and demographics per shop and position.
+// It's fed by the identified visits, a visit is new if the identifier allocated its uid.
+type Aggregator struct {
+	sync.Mutex
+	windows []time.Duration
+	// span is the minutes of the longest window
+	span  int64
+	shops map[uint64]map[uint32]series
+	now   func() time.Time
+	// expiredAt is the minute the buckets are expired last
+	expiredAt int64
+	shared    *shared
+
+	uniquesDesc   *prometheus.Desc
+	sightingsDesc *prometheus.Desc
+	customersDesc *prometheus.Desc
+	genderDesc    *prometheus.Desc
+	ageDesc       *prometheus.Desc
+}
+
+// New returns an aggregator of the windows, they're rounded up to minutes
+func New(windows []time.Duration) *Aggregator {
+	a := &Aggregator{
+		shops: make(map[uint64]map[uint32]series),
+		now:   time.Now,
+	}
+	for _, w := range windows {
+		if w < time.Minute {
+			w = time.Minute
+		}
+		a.windows = append(a.windows, w)
+		if m := minutes(w); m > a.span {
+			a.span = m
+		}
+	}
+	labels := []string{"shop", "position", "window"}
+	a.uniquesDesc = prometheus.NewDesc("mcd_faceserver_footfall_uniques", "Unique uids in the window.", labels, nil)
+	a.sightingsDesc = prometheus.NewDesc("mcd_faceserver_footfall_sightings", "Sightings in the window.", labels, nil)
+	a.customersDesc = prometheus.NewDesc("mcd_faceserver_footfall_customers", "Unique uids in the window by kind: new, returning.", append(labels, "kind"), nil)
+	a.genderDesc = prometheus.NewDesc("mcd_faceserver_footfall_gender", "Unique uids in the window by gender.", append(labels, "gender"), nil)
+	a.ageDesc = prometheus.NewDesc("mcd_faceserver_footfall_age_band", "Unique uids in the window by age band of 5 years.", append(labels, "band"), nil)
+	return a
+}
+
+func minutes(w time.Duration) int64 {
+	return int64((w + time.Minute - 1) / time.Minute)
+}
+
+// Windows returns the windows
+func (a *Aggregator) Windows() []time.Duration {
+	return a.windows
+}
+
+// Publish aggregates the visits, the ones older than the longest window are ignored.
+// It never blocks, the visits are added to the shared buckets in the background.
+func (a *Aggregator) Publish(visits ...*server.Visit) {
+	a.Lock()
+	now := a.nowMinute()
+	if now != a.expiredAt {
+		a.expire()
+	}
+	oldest := now - a.span
+	a.add(visits, oldest)
+	sh := a.shared
+	a.Unlock()
+
+	if sh != nil {
+		sh.publish(visits, oldest)
+	}
+}
+
+func (a *Aggregator) add(visits []*server.Visit, oldest int64) {
+	for _, visit := range visits {
+		minute := int64(visit.VisitTime) / 60
+		if minute <= oldest {
+			continue
+		}
+		positions, ok := a.shops[visit.Shop]
+		if !ok {
+			positions = make(map[uint32]series)
+			a.shops[visit.Shop] = positions
+		}
+		buckets, ok := positions[visit.Position]
+		if !ok {
+			buckets = make(series)
+			positions[visit.Position] = buckets
+		}
+		b, ok := buckets[minute]
+		if !ok {
+			b = &bucket{Uids: make(map[uint64]*person)}
+			buckets[minute] = b
+		}
+		b.Sightings++
+		if visit.Uid == 0 {
+			// a low confidence face of nobody known
+			continue
+		}
+		p, ok := b.Uids[visit.Uid]
+		if !ok {
+			p = &person{}
+			b.Uids[visit.Uid] = p
+		}
+		p.Gender, p.Age = visit.Gender, visit.Age
+		p.New = p.New || isNew(visit)
+	}
+}
+
+// isNew returns whether the identifier allocated the uid of the visit
+func isNew(visit *server.Visit) bool {
+	return visit.Decision == server.DecisionNew || visit.Decision == server.DecisionTakenOver
+}
+
+func (a *Aggregator) nowMinute() int64 {
+	return a.now().Unix() / 60
+}
+
+// expire removes the buckets older than the longest window
+func (a *Aggregator) expire() {
+	a.expiredAt = a.nowMinute()
+	oldest := a.expiredAt - a.span
+	for shop, positions := range a.shops {
+		for pos, buckets := range positions {
+			for minute := range buckets {
+				if minute <= oldest {
+					delete(buckets, minute)
+				}
+			}
+			if len(buckets) == 0 {
+				delete(positions, pos)
+			}
+		}
+		if len(positions) == 0 {
+			delete(a.shops, shop)
+		}
+	}
+}
+
+// Query returns the stats of the shop in the window till now, position is AllPositions or a position
+func (a *Aggregator) Query(shop uint64, position int64, window time.Duration) Stats {
+	a.Lock()
+	defer a.Unlock()
+	a.load()
+	return a.query(shop, position, window)
+}
+
+// load replaces the buckets with the shared ones if it's time to refresh
+func (a *Aggregator) load() {
+	if a.shared == nil || a.now().Sub(a.shared.loadedAt) < a.shared.refresh {
+		return
+	}
+	now := a.nowMinute()
+	shops, err := a.shared.load(now-a.span, now)
+	if err != nil {
+		log.Errorf("load shared footfall failed, use the local one, errors:%+v", err)
+		return
+	}
+	a.shops, a.shared.loadedAt = shops, a.now()
+}
+
+func (a *Aggregator) query(shop uint64, position int64, window time.Duration) (st Stats) {
+	st = Stats{
+		Shop:     shop,
+		Position: position,
+		Window:   window.String(),
+		Gender:   make(map[string]int),
+		AgeBands: make(map[string]int),
+	}
+	oldest := a.nowMinute() - minutes(window)
+	uids := make(map[uint64]*person)
+	// the last sighting wins, a uid is new if it's new in any bucket
+	var seen []int64
+	merged := make(map[int64][]*bucket)
+	for pos, buckets := range a.shops[shop] {
+		if position != AllPositions && int64(pos) != position {
+			continue
+		}
+		for minute, b := range buckets {
+			if minute > oldest {
+				if _, ok := merged[minute]; !ok {
+					seen = append(seen, minute)
+				}
+				merged[minute] = append(merged[minute], b)
+			}
+		}
+	}
+	sort.Slice(seen, func(i, j int) bool { return seen[i] < seen[j] })
+	for _, minute := range seen {
+		for _, b := range merged[minute] {
+			st.Sightings += b.Sightings
+			for uid, p := range b.Uids {
+				if q, ok := uids[uid]; ok {
+					uids[uid] = &person{Gender: p.Gender, Age: p.Age, New: p.New || q.New}
+				} else {
+					uids[uid] = p
+				}
+			}
+		}
+	}
+	st.Uniques = len(uids)
+	for _, p := range uids {
+		if p.New {
+			st.New++
+		} else {
+			st.Returning++
+		}
+		st.Gender[strconv.FormatUint(uint64(p.Gender), 10)]++
+		st.AgeBands[strconv.FormatUint(uint64(AgeBand(p.Age)), 10)]++
+	}
+	return
+}
+
+// QueryAll returns the stats of every shop, and each position of it if positions is set, in the window
+func (a *Aggregator) QueryAll(window time.Duration, positions bool) (stats []Stats) {
+	a.Lock()
+	defer a.Unlock()
+	a.load()
+	a.expire()
+	return a.queryAll(window, positions)
+}
+
+func (a *Aggregator) queryAll(window time.Duration, positions bool) (stats []Stats) {
+	shops := make([]uint64, 0, len(a.shops))
+	for shop := range a.shops {
+		shops = append(shops, shop)
+	}
+	sort.Slice(shops, func(i, j int) bool { return shops[i] < shops[j] })
+	for _, shop := range shops {
+		stats = append(stats, a.query(shop, AllPositions, window))
+		if !positions {
+			continue
+		}
+		poss := make([]uint32, 0, len(a.shops[shop]))
+		for pos := range a.shops[shop] {
+			poss = append(poss, pos)
+		}
+		sort.Slice(poss, func(i, j int) bool { return poss[i] < poss[j] })
+		for _, pos := range poss {
+			stats = append(stats, a.query(shop, int64(pos), window))
+		}
+	}
+	return
+}
+
+// Describe implements prometheus.Collector
+func (a *Aggregator) Describe(ch chan<- *prometheus.Desc) {
+	ch <- a.uniquesDesc
+	ch <- a.sightingsDesc
+	ch <- a.customersDesc
+	ch <- a.genderDesc
+	ch <- a.ageDesc
+}
+
+// Collect implements prometheus.Collector, the position of a shop's total is "all"
+func (a *Aggregator) Collect(ch chan<- prometheus.Metric) {
+	a.Lock()
+	defer a.Unlock()
+	a.load()
+	a.expire()
+	for _, w := range a.windows {
+		for _, st := range a.queryAll(w, true) {
+			shop := strconv.FormatUint(st.Shop, 10)
+			pos := "all"
+			if st.Position != AllPositions {
+				pos = strconv.FormatInt(st.Position, 10)
+			}
+			ch <- prometheus.MustNewConstMetric(a.uniquesDesc, prometheus.GaugeValue, float64(st.Uniques), shop, pos, st.Window)
+			ch <- prometheus.MustNewConstMetric(a.sightingsDesc, prometheus.GaugeValue, float64(st.Sightings), shop, pos, st.Window)
+			ch <- prometheus.MustNewConstMetric(a.customersDesc, prometheus.GaugeValue, float64(st.New), shop, pos, st.Window, "new")
+			ch <- prometheus.MustNewConstMetric(a.customersDesc, prometheus.GaugeValue, float64(st.Returning), shop, pos, st.Window, "returning")
+			for gender, n := range st.Gender {
+				ch <- prometheus.MustNewConstMetric(a.genderDesc, prometheus.GaugeValue, float64(n), shop, pos, st.Window, gender)
+			}
+			for band, n := range st.AgeBands {
+				ch <- prometheus.MustNewConstMetric(a.ageDesc, prometheus.GaugeValue, float64(n), shop, pos, st.Window, band)
+			}
+		}
+	}
+}
+
+// snapshot is the encoding of the buckets, map keys of json must be strings
+type snapshot struct {
+	Shop     uint64 `json:"shop"`
+	Position uint32 `json:"position"`
+	Buckets  series `json:"buckets"`
+}
+
+// Snapshot encodes the buckets for a checkpoint
+func (a *Aggregator) Snapshot() (data []byte, err error) {
+	a.Lock()
+	defer a.Unlock()
+	a.expire()
+	var snaps []snapshot
+	for shop, positions := range a.shops {
+		for pos, buckets := range positions {
+			snaps = append(snaps, snapshot{Shop: shop, Position: pos, Buckets: buckets})
+		}
+	}
+	if data, err = json.Marshal(snaps); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+// Restore replaces the buckets with the snapshot, the expired ones are dropped
+func (a *Aggregator) Restore(data []byte) (err error) {
+	var snaps []snapshot
+	if err = json.Unmarshal(data, &snaps); err != nil {
+		err = errors.Wrap(err, "restore aggregation")
+		return
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.shops = make(map[uint64]map[uint32]series)
+	for _, snap := range snaps {
+		for _, b := range snap.Buckets {
+			if b.Uids == nil {
+				b.Uids = make(map[uint64]*person)
+			}
+		}
+		if a.shops[snap.Shop] == nil {
+			a.shops[snap.Shop] = make(map[uint32]series)
+		}
+		a.shops[snap.Shop][snap.Position] = snap.Buckets
+	}
+	a.expire()
+	return
+}
@@ -0,0 +1,149 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAggregator(now *time.Time) *Aggregator {
+	a := New([]time.Duration{15 * time.Minute, time.Hour})
+	a.now = func() time.Time { return *now }
+	return a
+}
+
+func visitAt(t time.Time, shop uint64, pos uint32, uid uint64, decision server.Decision, gender, age uint32) *server.Visit {
+	return &server.Visit{Shop: shop, Position: pos, Uid: uid, VisitTime: uint64(t.Unix()), Decision: decision, Gender: gender, Age: age}
+}
+
+func TestAggregateWindows(t *testing.T) {
+	now := time.Unix(1540000000, 0)
+	a := newTestAggregator(&now)
+	a.Publish(
+		visitAt(now.Add(-50*time.Minute), 1, 1, 10, server.DecisionMerged, 0, 30),
+		visitAt(now.Add(-5*time.Minute), 1, 1, 11, server.DecisionNew, 1, 22),
+		visitAt(now.Add(-4*time.Minute), 1, 1, 11, server.DecisionMerged, 1, 23),
+		visitAt(now.Add(-3*time.Minute), 1, 2, 12, server.DecisionUpdated, 0, 99),
+		visitAt(now.Add(-2*time.Minute), 1, 2, 0, server.DecisionLowConfidence, 0, 0),
+		visitAt(now.Add(-2*time.Hour), 1, 1, 13, server.DecisionNew, 0, 30),
+		visitAt(now, 2, 1, 20, server.DecisionTakenOver, 1, 40),
+	)
+
+	st := a.Query(1, AllPositions, 15*time.Minute)
+	require.Equal(t, 2, st.Uniques)
+	require.Equal(t, uint64(4), st.Sightings)
+	require.Equal(t, 1, st.New)
+	require.Equal(t, 1, st.Returning)
+	require.Equal(t, map[string]int{"0": 1, "1": 1}, st.Gender)
+	require.Equal(t, map[string]int{"4": 1, "19": 1}, st.AgeBands)
+
+	st = a.Query(1, AllPositions, time.Hour)
+	require.Equal(t, 3, st.Uniques)
+	require.Equal(t, uint64(5), st.Sightings)
+
+	st = a.Query(1, 2, time.Hour)
+	require.Equal(t, 1, st.Uniques)
+	require.Equal(t, uint64(2), st.Sightings)
+	require.Equal(t, 1, st.Returning)
+
+	st = a.Query(2, AllPositions, 15*time.Minute)
+	require.Equal(t, 1, st.New)
+
+	// the oldest visit expires from the longest window
+	now = now.Add(15 * time.Minute)
+	st = a.Query(1, AllPositions, time.Hour)
+	require.Equal(t, 2, st.Uniques)
+	require.Equal(t, 0, a.Query(1, AllPositions, 15*time.Minute).Uniques)
+
+	stats := a.QueryAll(time.Hour, true)
+	require.Len(t, stats, 5)
+	require.Equal(t, AllPositions, stats[0].Position)
+	require.Equal(t, int64(1), stats[1].Position)
+	require.Equal(t, int64(2), stats[2].Position)
+	require.Equal(t, uint64(2), stats[3].Shop)
+	require.Len(t, a.QueryAll(time.Hour, false), 2)
+
+	now = now.Add(time.Hour)
+	require.Len(t, a.QueryAll(time.Hour, true), 0)
+}
+
+func TestAggregateExpireOnPublish(t *testing.T) {
+	now := time.Unix(1540000000, 0)
+	a := newTestAggregator(&now)
+	a.Publish(visitAt(now, 1, 1, 10, server.DecisionNew, 0, 30))
+	// nobody queries, the publishing expires the buckets
+	now = now.Add(2 * time.Hour)
+	a.Publish(visitAt(now, 2, 1, 11, server.DecisionNew, 0, 30))
+	a.Lock()
+	_, ok := a.shops[1]
+	a.Unlock()
+	require.False(t, ok)
+}
+
+func TestAggregateCheckpoint(t *testing.T) {
+	now := time.Unix(1540000000, 0)
+	a := newTestAggregator(&now)
+	a.Publish(
+		visitAt(now.Add(-time.Minute), 1, 1, 11, server.DecisionNew, 1, 22),
+		visitAt(now, 1, 2, 12, server.DecisionMerged, 0, 40),
+	)
+	cp := NewFileCheckpoint(filepath.Join(t.TempDir(), "footfall.json"))
+
+	b := newTestAggregator(&now)
+	require.NoError(t, b.Load(cp))
+	require.Len(t, b.QueryAll(time.Hour, false), 0)
+
+	require.NoError(t, a.Save(cp))
+	require.NoError(t, b.Load(cp))
+	require.Equal(t, a.Query(1, AllPositions, time.Hour), b.Query(1, AllPositions, time.Hour))
+
+	// publishing after a restore keeps counting
+	b.Publish(visitAt(now, 1, 1, 13, server.DecisionNew, 0, 30))
+	require.Equal(t, 3, b.Query(1, AllPositions, time.Hour).Uniques)
+}
+
+func TestAggregateHandler(t *testing.T) {
+	now := time.Unix(1540000000, 0)
+	a := newTestAggregator(&now)
+	a.Publish(
+		visitAt(now.Add(-20*time.Minute), 1, 1, 10, server.DecisionMerged, 0, 30),
+		visitAt(now, 1, 2, 11, server.DecisionNew, 1, 22),
+		visitAt(now, 2, 1, 12, server.DecisionNew, 1, 22),
+	)
+	h := a.Handler()
+	get := func(query string) (code int, stats []Stats) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/footfall?"+query, nil))
+		if code = w.Code; code == 200 {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		}
+		return
+	}
+
+	code, stats := get("")
+	require.Equal(t, 200, code)
+	require.Len(t, stats, 2)
+	require.Equal(t, "15m0s", stats[0].Window)
+	require.Equal(t, 1, stats[0].Uniques)
+
+	_, stats = get("window=1h&shop=1")
+	require.Len(t, stats, 1)
+	require.Equal(t, 2, stats[0].Uniques)
+
+	_, stats = get("window=1h&shop=1&position=1")
+	require.Equal(t, 1, stats[0].Uniques)
+	require.Equal(t, 1, stats[0].Returning)
+
+	_, stats = get("window=1h&positions=true")
+	require.Len(t, stats, 5)
+
+	code, _ = get("window=2h")
+	require.Equal(t, 400, code)
+	code, _ = get("shop=x")
+	require.Equal(t, 400, code)
+}
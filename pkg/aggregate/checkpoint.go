@@ -0,0 +1,112 @@
+package aggregate
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// Checkpoint keeps the snapshot of an aggregator across restarts
+type Checkpoint interface {
+	// Load returns nil if there is no snapshot
+	Load() ([]byte, error)
+	Save(data []byte) error
+}
+
+type fileCheckpoint struct {
+	path string
+}
+
+// NewFileCheckpoint returns a checkpoint of the file, it's replaced atomically
+func NewFileCheckpoint(path string) Checkpoint {
+	return &fileCheckpoint{path: path}
+}
+
+func (c *fileCheckpoint) Load() (data []byte, err error) {
+	if data, err = ioutil.ReadFile(c.path); os.IsNotExist(err) {
+		data, err = nil, nil
+	} else if err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+func (c *fileCheckpoint) Save(data []byte) (err error) {
+	tmp := c.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = os.Rename(tmp, c.path); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+type redisCheckpoint struct {
+	rcli *redis.Client
+	key  string
+}
+
+// NewRedisCheckpoint returns a checkpoint of the Redis key
+func NewRedisCheckpoint(rcli *redis.Client, key string) Checkpoint {
+	return &redisCheckpoint{rcli: rcli, key: key}
+}
+
+func (c *redisCheckpoint) Load() (data []byte, err error) {
+	if data, err = c.rcli.Get(c.key).Bytes(); err == redis.Nil {
+		data, err = nil, nil
+	} else if err != nil {
+		err = errors.Wrapf(err, "load %s", c.key)
+	}
+	return
+}
+
+func (c *redisCheckpoint) Save(data []byte) (err error) {
+	if err = c.rcli.Set(c.key, data, 0).Err(); err != nil {
+		err = errors.Wrapf(err, "save %s", c.key)
+	}
+	return
+}
+
+// Load restores the aggregator from the checkpoint if there is a snapshot
+func (a *Aggregator) Load(cp Checkpoint) (err error) {
+	var data []byte
+	if data, err = cp.Load(); err != nil || data == nil {
+		return
+	}
+	return a.Restore(data)
+}
+
+// Save saves the snapshot of the aggregator to the checkpoint
+func (a *Aggregator) Save(cp Checkpoint) (err error) {
+	var data []byte
+	if data, err = a.Snapshot(); err != nil {
+		return
+	}
+	return cp.Save(data)
+}
+
+// Run saves the aggregator to the checkpoint every interval, and at last when ctx is done
+func (a *Aggregator) Run(ctx context.Context, cp Checkpoint, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := a.Save(cp); err != nil {
+				log.Errorf("save footfall aggregation failed, errors:%+v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := a.Save(cp); err != nil {
+				log.Errorf("save footfall aggregation failed, errors:%+v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Handler serves the stats as json. The query parameters are all optional: window is one
+// of the windows, the first one by default; shop selects a shop; position selects a
+// position of the shop; positions=true lists each position besides the shop's total.
+func (a *Aggregator) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		window := a.windows[0]
+		if s := q.Get("window"); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || !a.hasWindow(d) {
+				http.Error(w, "unknown window "+s, http.StatusBadRequest)
+				return
+			}
+			window = d
+		}
+
+		var stats []Stats
+		if s := q.Get("shop"); s != "" {
+			shop, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid shop "+s, http.StatusBadRequest)
+				return
+			}
+			position := AllPositions
+			if s = q.Get("position"); s != "" {
+				pos, err := strconv.ParseUint(s, 10, 32)
+				if err != nil {
+					http.Error(w, "invalid position "+s, http.StatusBadRequest)
+					return
+				}
+				position = int64(pos)
+			}
+			stats = append(stats, a.Query(shop, position, window))
+		} else {
+			stats = a.QueryAll(window, q.Get("positions") == "true")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	})
+}
+
+func (a *Aggregator) hasWindow(d time.Duration) bool {
+	for _, w := range a.windows {
+		if w == d {
+			return true
+		}
+	}
+	return false
+}
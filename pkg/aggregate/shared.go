@@ -0,0 +1,213 @@
+package aggregate
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sharedBuffer is the number of the publishings waiting to be added to Redis
+const sharedBuffer = 1024
+
+var (
+	sharedDroppedCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "footfall_shared_dropped",
+			Help:      "Visits not added to the shared footfall since Redis is too slow to keep up.",
+		})
+	sharedOnce sync.Once
+)
+
+// sharedUpdate is the visits of a publishing to add to Redis
+type sharedUpdate struct {
+	visits []*server.Visit
+	oldest int64
+}
+
+// shared keeps the buckets of all the aggregators in Redis, so that the uids
+// identified by several processes are counted once. A minute is a hash of
+// <prefix><minute>, its fields are:
+//
+//	<shop>:<position>              sightings
+//	<shop>:<position>:<uid>        gender:age of the last sighting
+//	<shop>:<position>:<uid>:new    1 if the uid is new
+type shared struct {
+	rcli    *redis.Client
+	prefix  string
+	refresh time.Duration
+	// loadedAt is the last time the buckets are loaded
+	loadedAt time.Time
+	updates  chan sharedUpdate
+}
+
+// SetShared makes the aggregator share the buckets with the others in Redis,
+// the keys are prefixed by prefix. The buckets are loaded at most once a refresh,
+// the visits published meanwhile are counted locally. The visits are added to
+// Redis in the background till ctx is done, publishing never blocks, they're
+// dropped if Redis is too slow to keep up.
+func (a *Aggregator) SetShared(ctx context.Context, rcli *redis.Client, prefix string, refresh time.Duration) {
+	sharedOnce.Do(func() {
+		prometheus.MustRegister(sharedDroppedCount)
+	})
+	s := &shared{rcli: rcli, prefix: prefix, refresh: refresh, updates: make(chan sharedUpdate, sharedBuffer)}
+	a.Lock()
+	a.shared = s
+	a.Unlock()
+	go s.run(ctx, a.span)
+}
+
+// publish queues the visits to add to Redis, they're dropped if the queue is full
+func (s *shared) publish(visits []*server.Visit, oldest int64) {
+	select {
+	case s.updates <- sharedUpdate{visits: visits, oldest: oldest}:
+	default:
+		sharedDroppedCount.Add(float64(len(visits)))
+	}
+}
+
+// run adds the queued visits to Redis till ctx is done
+func (s *shared) run(ctx context.Context, span int64) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case u := <-s.updates:
+			if err := s.add(u.visits, u.oldest, span); err != nil {
+				log.Errorf("share footfall failed, errors:%+v", err)
+			}
+		}
+	}
+}
+
+func (s *shared) key(minute int64) string {
+	return s.prefix + strconv.FormatInt(minute, 10)
+}
+
+// add adds the visits to the buckets in Redis, a minute key expires after the span
+func (s *shared) add(visits []*server.Visit, oldest, span int64) (err error) {
+	pipe := s.rcli.Pipeline()
+	defer pipe.Close()
+	expires := make(map[int64]bool)
+	for _, visit := range visits {
+		minute := int64(visit.VisitTime) / 60
+		if minute <= oldest {
+			continue
+		}
+		key := s.key(minute)
+		field := strconv.FormatUint(visit.Shop, 10) + ":" + strconv.FormatUint(uint64(visit.Position), 10)
+		pipe.HIncrBy(key, field, 1)
+		if !expires[minute] {
+			expires[minute] = true
+			pipe.ExpireAt(key, time.Unix((minute+span+1)*60, 0))
+		}
+		if visit.Uid == 0 {
+			continue
+		}
+		field += ":" + strconv.FormatUint(visit.Uid, 10)
+		pipe.HSet(key, field, strconv.FormatUint(uint64(visit.Gender), 10)+":"+strconv.FormatUint(uint64(visit.Age), 10))
+		if isNew(visit) {
+			pipe.HSet(key, field+":new", "1")
+		}
+	}
+	if len(expires) == 0 {
+		return
+	}
+	if _, err = pipe.Exec(); err != nil {
+		err = errors.Wrap(err, "add to shared footfall")
+	}
+	return
+}
+
+// load returns the buckets of the minutes after oldest till now
+func (s *shared) load(oldest, now int64) (shops map[uint64]map[uint32]series, err error) {
+	pipe := s.rcli.Pipeline()
+	defer pipe.Close()
+	cmds := make(map[int64]*redis.StringStringMapCmd)
+	for minute := oldest + 1; minute <= now; minute++ {
+		cmds[minute] = pipe.HGetAll(s.key(minute))
+	}
+	if _, err = pipe.Exec(); err != nil && err != redis.Nil {
+		err = errors.Wrap(err, "load shared footfall")
+		return
+	}
+	err = nil
+	shops = make(map[uint64]map[uint32]series)
+	for minute, cmd := range cmds {
+		decodeBuckets(shops, minute, cmd.Val())
+	}
+	return
+}
+
+// decodeBuckets adds the buckets of the hash of the minute to shops
+func decodeBuckets(shops map[uint64]map[uint32]series, minute int64, fields map[string]string) {
+	bucketOf := func(shop uint64, pos uint32) *bucket {
+		positions, ok := shops[shop]
+		if !ok {
+			positions = make(map[uint32]series)
+			shops[shop] = positions
+		}
+		buckets, ok := positions[pos]
+		if !ok {
+			buckets = make(series)
+			positions[pos] = buckets
+		}
+		b, ok := buckets[minute]
+		if !ok {
+			b = &bucket{Uids: make(map[uint64]*person)}
+			buckets[minute] = b
+		}
+		return b
+	}
+	personOf := func(b *bucket, uid uint64) *person {
+		p, ok := b.Uids[uid]
+		if !ok {
+			p = &person{}
+			b.Uids[uid] = p
+		}
+		return p
+	}
+
+	for field, value := range fields {
+		parts := strings.Split(field, ":")
+		if len(parts) < 2 || len(parts) > 4 {
+			log.Warnf("skip malformed footfall field %s", field)
+			continue
+		}
+		shop, err1 := strconv.ParseUint(parts[0], 10, 64)
+		pos, err2 := strconv.ParseUint(parts[1], 10, 32)
+		if err1 != nil || err2 != nil {
+			log.Warnf("skip malformed footfall field %s", field)
+			continue
+		}
+		b := bucketOf(shop, uint32(pos))
+		if len(parts) == 2 {
+			b.Sightings, _ = strconv.ParseUint(value, 10, 64)
+			continue
+		}
+		uid, err := strconv.ParseUint(parts[2], 10, 64)
+		if err != nil {
+			log.Warnf("skip malformed footfall field %s", field)
+			continue
+		}
+		p := personOf(b, uid)
+		if len(parts) == 4 {
+			p.New = true
+			continue
+		}
+		if i := strings.IndexByte(value, ':'); i >= 0 {
+			gender, _ := strconv.ParseUint(value[:i], 10, 32)
+			age, _ := strconv.ParseUint(value[i+1:], 10, 32)
+			p.Gender, p.Age = uint32(gender), uint32(age)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package aggregate
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeBuckets(t *testing.T) {
+	shops := make(map[uint64]map[uint32]series)
+	decodeBuckets(shops, 100, map[string]string{
+		"1:2":        "3",
+		"1:2:10":     "1:22",
+		"1:2:10:new": "1",
+		"1:2:11":     "0:40",
+		"1:x":        "1",
+		"1":          "1",
+	})
+	require.Len(t, shops, 1)
+	b := shops[1][2][100]
+	require.Equal(t, uint64(3), b.Sightings)
+	require.Equal(t, &person{Gender: 1, Age: 22, New: true}, b.Uids[10])
+	require.Equal(t, &person{Gender: 0, Age: 40}, b.Uids[11])
+}
+
+func TestAggregateShared(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	rcli := redis.NewClient(&redis.Options{Addr: addr, DB: 15})
+	defer rcli.Close()
+	require.NoError(t, rcli.FlushDB().Err())
+
+	// the keys expire by the wall clock
+	now := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, b := newTestAggregator(&now), newTestAggregator(&now)
+	a.SetShared(ctx, rcli, "footfall_", 0)
+	b.SetShared(ctx, rcli, "footfall_", 0)
+	a.Publish(visitAt(now, 1, 1, 10, server.DecisionNew, 1, 22))
+	b.Publish(visitAt(now, 1, 1, 10, server.DecisionMerged, 1, 22), visitAt(now, 1, 2, 11, server.DecisionMerged, 0, 40))
+
+	// the uid identified by both processes is counted once, once they're added in the background
+	for _, agg := range []*Aggregator{a, b} {
+		var st Stats
+		for i := 0; i < 100; i++ {
+			if st = agg.Query(1, AllPositions, time.Hour); st.Sightings == 3 {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		require.Equal(t, 2, st.Uniques)
+		require.Equal(t, uint64(3), st.Sightings)
+		require.Equal(t, 1, st.New)
+	}
+}
+
+func TestAggregateSharedNeverBlocks(t *testing.T) {
+	// the client is closed and the background adding is stopped, so the queue fills up
+	rcli := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	require.NoError(t, rcli.Close())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	now := time.Now()
+	a := newTestAggregator(&now)
+	a.SetShared(ctx, rcli, "footfall_", time.Hour)
+
+	dropped := testutil.ToFloat64(sharedDroppedCount)
+	for i := 0; i < sharedBuffer+100; i++ {
+		a.Publish(visitAt(now, 1, 1, 10, server.DecisionMerged, 1, 22))
+	}
+	require.True(t, testutil.ToFloat64(sharedDroppedCount)-dropped >= 10)
+	// the visits are still counted locally
+	require.Equal(t, uint64(sharedBuffer+100), a.Query(1, AllPositions, time.Hour).Sightings)
+}
@@ -50,6 +50,12 @@ func (codec *codec) Decode(in *goetty.ByteBuf) (bool, interface{}, error) {
 		value = &pb.Heartbeat{}
 	case pb.CmdSysUsage:
 		value = &pb.SysUsage{}
+	case pb.CmdCommand:
+		value = &pb.Command{}
+	case pb.CmdCommandAck:
+		value = &pb.CommandAck{}
+	case pb.CmdCommandRsp:
+		value = &pb.CommandRsp{}
 	}
 
 	if value != nil {
@@ -105,6 +111,18 @@ func (codec *codec) Encode(data interface{}, out *goetty.ByteBuf) error {
 		value = msg
 		size = msg.Size()
 		cmd = byte(pb.CmdSysUsage)
+	} else if msg, ok := data.(*pb.Command); ok {
+		value = msg
+		size = msg.Size()
+		cmd = byte(pb.CmdCommand)
+	} else if msg, ok := data.(*pb.CommandAck); ok {
+		value = msg
+		size = msg.Size()
+		cmd = byte(pb.CmdCommandAck)
+	} else if msg, ok := data.(*pb.CommandRsp); ok {
+		value = msg
+		size = msg.Size()
+		cmd = byte(pb.CmdCommandRsp)
 	}
 
 	if value != nil {
@@ -0,0 +1,64 @@
+package embed
+
+import (
+	"time"
+)
+
+const (
+	// DefaultTimeout timeout of an inference request
+	DefaultTimeout = 2 * time.Second
+	// DefaultRetries retries of a failed inference request
+	DefaultRetries = 2
+	// DefaultBackoff the first backoff between retries, it doubles every retry
+	DefaultBackoff = 100 * time.Millisecond
+	// DefaultMaxBatch max images of an inference request
+	DefaultMaxBatch = 5
+)
+
+// Image is an image to embed
+type Image struct {
+	Name string
+	Data []byte
+}
+
+// Result is the inference result of an image. Err is set if the image failed,
+// the other images of the same batch are not affected.
+type Result struct {
+	Vec      []float32
+	Age      int
+	Gender   int
+	Quality  float32
+	PoseType int
+	// NoFace is set if the image has no usable face, it's a result rather than a failure
+	NoFace bool
+	Err    error
+}
+
+// Embedder extracts face embeddings and attributes from images
+type Embedder interface {
+	// Embed returns a result for each image in the same order
+	Embed(imgs []Image) []Result
+}
+
+// Cfg is the policy of an Embedder
+type Cfg struct {
+	Timeout  time.Duration
+	Retries  int
+	Backoff  time.Duration
+	MaxBatch int
+}
+
+func (cfg *Cfg) adjust() {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = DefaultMaxBatch
+	}
+}
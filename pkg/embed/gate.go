@@ -0,0 +1,72 @@
+package embed
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// GatePassed the face is good for identification
+	GatePassed = "passed"
+	// GateQuality the face quality is less than the minimum
+	GateQuality = "quality"
+	// GatePose the face pose type is not allowed
+	GatePose = "pose"
+)
+
+var (
+	gateCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "face_gate",
+			Help:      "Faces checked by the quality gates by result: passed, or the gate rejected it: quality, pose",
+		}, []string{"result"})
+)
+
+// Gate rejects the faces not good enough to add to the vector index, for
+// example blurry or profile faces. The zero value passes every face.
+type Gate struct {
+	MinQuality float32
+	// PoseTypes are the allowed pose types, empty means any
+	PoseTypes []int
+}
+
+// ParseGate returns a gate with the comma separated pose types
+func ParseGate(minQuality float64, poseTypes string) (g Gate, err error) {
+	g.MinQuality = float32(minQuality)
+	for _, field := range strings.Split(poseTypes, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		var pt int
+		if pt, err = strconv.Atoi(field); err != nil {
+			err = errors.Wrapf(err, "pose type %q", field)
+			return
+		}
+		g.PoseTypes = append(g.PoseTypes, pt)
+	}
+	return
+}
+
+// Check returns GatePassed, or the gate rejected the face
+func (g Gate) Check(rst Result) (result string) {
+	metricOnce.Do(initMetrics)
+	result = GatePassed
+	if rst.Quality < g.MinQuality {
+		result = GateQuality
+	} else if len(g.PoseTypes) > 0 {
+		result = GatePose
+		for _, pt := range g.PoseTypes {
+			if pt == rst.PoseType {
+				result = GatePassed
+				break
+			}
+		}
+	}
+	gateCountVec.WithLabelValues(result).Inc()
+	return
+}
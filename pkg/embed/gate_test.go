@@ -0,0 +1,22 @@
+package embed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGate(t *testing.T) {
+	var g Gate
+	require.Equal(t, GatePassed, g.Check(Result{Quality: 0.1, PoseType: 3}))
+
+	g, err := ParseGate(0.5, "0, 1")
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1}, g.PoseTypes)
+	require.Equal(t, GatePassed, g.Check(Result{Quality: 0.5, PoseType: 1}))
+	require.Equal(t, GateQuality, g.Check(Result{Quality: 0.4, PoseType: 1}))
+	require.Equal(t, GatePose, g.Check(Result{Quality: 0.9, PoseType: 2}))
+
+	_, err = ParseGate(0, "front")
+	require.Error(t, err)
+}
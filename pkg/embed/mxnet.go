@@ -0,0 +1,241 @@
+package embed
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sync"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	SIZEOF_FLOAT32 int = 4
+)
+
+var (
+	inferCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "infer_request",
+			Help:      "Inference requests by result: succ, retry, split, failed",
+		}, []string{"result"})
+	inferImageCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "infer_image",
+			Help:      "Inferred images by result: succ, noface, failed",
+		}, []string{"result"})
+	metricOnce sync.Once
+)
+
+func initMetrics() {
+	prometheus.MustRegister(inferCountVec)
+	prometheus.MustRegister(inferImageCountVec)
+	prometheus.MustRegister(gateCountVec)
+}
+
+// RspPred refers to https://github.com/deepinsight/mxnet-serving/tree/master/tvm
+type RspPred struct {
+	FileName  string  `json:"filename"`
+	Embedding string  `json:"embedding"`
+	Age       int     `json:"age"`
+	Gender    int     `json:"gender"`
+	PoseType  int     `json:"post_type"`
+	State     int     `json:"state"`
+	Quality   float32 `json:"quality"`
+}
+
+// requestError is an error of an inference request, it's transient if the
+// service is faulty, or else the batch is bad
+type requestError struct {
+	error
+	transient bool
+}
+
+// MxnetEmbedder is the client of mxnet-serving which accepts the images in a
+// multipart form, and responds a RspPred for each image.
+type MxnetEmbedder struct {
+	cfg     Cfg
+	servURL string
+	hc      *http.Client
+}
+
+// NewMxnetEmbedder returns an Embedder of mxnet-serving
+func NewMxnetEmbedder(servURL string, cfg Cfg) *MxnetEmbedder {
+	metricOnce.Do(initMetrics)
+	cfg.adjust()
+	return &MxnetEmbedder{
+		cfg:     cfg,
+		servURL: servURL,
+		hc:      &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Embed implements Embedder. The images are sent in batches of Cfg.MaxBatch.
+// A batch failed for the service is retried with backoff, a batch failed
+// for its content is split until the bad image is isolated.
+func (me *MxnetEmbedder) Embed(imgs []Image) (results []Result) {
+	results = make([]Result, len(imgs))
+	for start := 0; start < len(imgs); start += me.cfg.MaxBatch {
+		end := start + me.cfg.MaxBatch
+		if end > len(imgs) {
+			end = len(imgs)
+		}
+		me.embedBatch(imgs[start:end], results[start:end])
+	}
+	for _, rst := range results {
+		if rst.Err != nil {
+			inferImageCountVec.WithLabelValues("failed").Inc()
+		} else if rst.NoFace {
+			inferImageCountVec.WithLabelValues("noface").Inc()
+		} else {
+			inferImageCountVec.WithLabelValues("succ").Inc()
+		}
+	}
+	return
+}
+
+func (me *MxnetEmbedder) embedBatch(imgs []Image, results []Result) {
+	preds, err := me.postWithRetry(imgs)
+	if err == nil {
+		for i, pred := range preds {
+			results[i] = decodePred(pred)
+		}
+		return
+	}
+
+	rerr, ok := err.(*requestError)
+	if len(imgs) == 1 || (ok && rerr.transient) {
+		log.Errorf("infer %d images failed, errors:%+v", len(imgs), err)
+		for i := range results {
+			results[i].Err = err
+		}
+		return
+	}
+
+	inferCountVec.WithLabelValues("split").Inc()
+	log.Warnf("infer %d images failed, split the batch, errors:%+v", len(imgs), err)
+	half := len(imgs) / 2
+	me.embedBatch(imgs[:half], results[:half])
+	me.embedBatch(imgs[half:], results[half:])
+}
+
+func (me *MxnetEmbedder) postWithRetry(imgs []Image) (preds []RspPred, err error) {
+	backoff := me.cfg.Backoff
+	for i := 0; ; i++ {
+		if preds, err = me.post(imgs); err == nil {
+			inferCountVec.WithLabelValues("succ").Inc()
+			return
+		}
+		if rerr, ok := err.(*requestError); !ok || !rerr.transient || i >= me.cfg.Retries {
+			inferCountVec.WithLabelValues("failed").Inc()
+			return
+		}
+		inferCountVec.WithLabelValues("retry").Inc()
+		log.Warnf("infer %d images failed, retry after %v, errors:%+v", len(imgs), backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (me *MxnetEmbedder) post(imgs []Image) (preds []RspPred, err error) {
+	var part io.Writer
+	reqBody := &bytes.Buffer{}
+	writer := multipart.NewWriter(reqBody)
+	for _, img := range imgs {
+		//part, err := writer.CreateFormFile("data", "image.jpg") //generates "Content-Type: application/octet-stream"
+		partHeader := textproto.MIMEHeader{}
+		disposition := fmt.Sprintf("form-data; name=\"data\"; filename=\"%s\"", img.Name)
+		partHeader.Add("Content-Disposition", disposition)
+		partHeader.Add("Content-Type", "image/jpeg")
+		if part, err = writer.CreatePart(partHeader); err != nil {
+			err = errors.Wrap(err, "")
+			return
+		}
+		if _, err = part.Write(img.Data); err != nil {
+			err = errors.Wrap(err, "")
+			return
+		}
+	}
+	if err = writer.Close(); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	var req *http.Request
+	if req, err = http.NewRequest("POST", me.servURL, reqBody); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	var resp *http.Response
+	if resp, err = me.hc.Do(req); err != nil {
+		err = &requestError{error: errors.Wrap(err, ""), transient: true}
+		return
+	}
+	var respBody []byte
+	respBody, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		err = &requestError{error: errors.Wrap(err, ""), transient: true}
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = &requestError{
+			error:     errors.Errorf("%s responds %s, respBody %s", me.servURL, resp.Status, string(respBody)),
+			transient: resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests,
+		}
+		return
+	}
+	if err = json.Unmarshal(respBody, &preds); err != nil {
+		err = &requestError{error: errors.Wrapf(err, "failed to decode respBody: %+v", string(respBody))}
+		return
+	}
+	if len(preds) != len(imgs) {
+		err = &requestError{error: errors.Errorf("want %d predications, have %d", len(imgs), len(preds))}
+		return
+	}
+	return
+}
+
+func decodePred(pred RspPred) (rst Result) {
+	if pred.State != 0 {
+		// state!=0 indicates no usable face, embedding could be empty.
+		log.Debugf("%s predication state %d", pred.FileName, pred.State)
+		rst.NoFace = true
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(pred.Embedding)
+	if err != nil {
+		rst.Err = errors.Wrapf(err, "base64 decode error")
+		return
+	}
+	if len(data)%SIZEOF_FLOAT32 != 0 {
+		rst.Err = errors.Errorf("embedding length is incorrect, want times of %d, have %d", SIZEOF_FLOAT32, len(data))
+		return
+	}
+	vec := make([]float32, len(data)/SIZEOF_FLOAT32)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*SIZEOF_FLOAT32:]))
+	}
+	rst.Vec = vec
+	rst.Age = pred.Age
+	rst.Gender = pred.Gender
+	rst.Quality = pred.Quality
+	rst.PoseType = pred.PoseType
+	return
+}
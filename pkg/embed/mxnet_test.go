@@ -0,0 +1,103 @@
+package embed
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func fakeEmbedding(f float32) string {
+	data := make([]byte, 2*SIZEOF_FLOAT32)
+	binary.LittleEndian.PutUint32(data, math.Float32bits(f))
+	binary.LittleEndian.PutUint32(data[SIZEOF_FLOAT32:], math.Float32bits(-f))
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// newFakeServing responds 503 for the first unavailable requests, and 400 for
+// the batches containing an image named "bad"
+func newFakeServing(t *testing.T, unavailable int32) (ts *httptest.Server, calls *int32) {
+	calls = new(int32)
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= unavailable {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		// t.FailNow doesn't stop the test out of its goroutine
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart form: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var preds []RspPred
+		for _, fh := range r.MultipartForm.File["data"] {
+			if fh.Filename == "bad" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			preds = append(preds, RspPred{FileName: fh.Filename, Embedding: fakeEmbedding(float32(fh.Size)), Age: 30})
+		}
+		json.NewEncoder(w).Encode(preds)
+	}))
+	return
+}
+
+func TestMxnetEmbedderRetry(t *testing.T) {
+	ts, calls := newFakeServing(t, 2)
+	defer ts.Close()
+
+	me := NewMxnetEmbedder(ts.URL, Cfg{Retries: 2, Backoff: time.Millisecond})
+	results := me.Embed([]Image{{Name: "a", Data: []byte("1")}, {Name: "b", Data: []byte("22")}})
+	require.Equal(t, int32(3), atomic.LoadInt32(calls))
+	require.NoError(t, results[0].Err)
+	require.Equal(t, []float32{1, -1}, results[0].Vec)
+	require.Equal(t, []float32{2, -2}, results[1].Vec)
+	require.Equal(t, 30, results[1].Age)
+
+	// gives up after retries
+	ts2, calls2 := newFakeServing(t, 10)
+	defer ts2.Close()
+	me = NewMxnetEmbedder(ts2.URL, Cfg{Retries: 1, Backoff: time.Millisecond})
+	results = me.Embed([]Image{{Name: "a"}, {Name: "b"}})
+	require.Equal(t, int32(2), atomic.LoadInt32(calls2))
+	require.Error(t, results[0].Err)
+	require.Error(t, results[1].Err)
+}
+
+func TestMxnetEmbedderSplit(t *testing.T) {
+	ts, _ := newFakeServing(t, 0)
+	defer ts.Close()
+
+	me := NewMxnetEmbedder(ts.URL, Cfg{MaxBatch: 5})
+	var imgs []Image
+	for _, name := range []string{"a", "b", "bad", "c", "d", "e"} {
+		imgs = append(imgs, Image{Name: name, Data: []byte(name)})
+	}
+	results := me.Embed(imgs)
+	require.Equal(t, len(imgs), len(results))
+	for i, rst := range results {
+		if imgs[i].Name == "bad" {
+			require.Error(t, rst.Err)
+		} else {
+			require.NoError(t, rst.Err)
+			require.Equal(t, float32(len(imgs[i].Name)), rst.Vec[0])
+		}
+	}
+}
+
+func TestDecodePredNoFace(t *testing.T) {
+	rst := decodePred(RspPred{FileName: "a", State: 1})
+	require.NoError(t, rst.Err)
+	require.True(t, rst.NoFace)
+
+	rst = decodePred(RspPred{FileName: "a", Embedding: "!"})
+	require.Error(t, rst.Err)
+	require.False(t, rst.NoFace)
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+)
+
+// Progress is the checkpoint of an export, it's saved after each batch
+type Progress struct {
+	// Query identifies the export, a progress of another query is not resumed
+	Query    string             `json:"query"`
+	Cursor   server.VisitCursor `json:"cursor"`
+	Size     int64              `json:"size"`
+	Exported int64              `json:"exported"`
+	Done     bool               `json:"done"`
+}
+
+// LoadProgress loads the progress of the query, a new progress from tsStart is returned if
+// the file doesn't exist. It fails if the file is the progress of another query.
+func LoadProgress(path, query string, tsStart int64) (p *Progress, err error) {
+	p = &Progress{Query: query, Cursor: server.VisitCursor{Time: tsStart}}
+	var data []byte
+	if data, err = ioutil.ReadFile(path); os.IsNotExist(err) {
+		err = nil
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = json.Unmarshal(data, p); err != nil {
+		err = errors.Wrapf(err, "load progress %s", path)
+		return
+	}
+	if p.Query != query {
+		err = errors.Errorf("progress %s is of another export %q, remove it to start over", path, p.Query)
+	}
+	return
+}
+
+// Save saves the progress to a temporary file renamed to path
+func (p *Progress) Save(path string) (err error) {
+	var data []byte
+	if data, err = json.Marshal(p); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+// Export writes the visits of the sorted set index in [p.Cursor, tsEnd) selected by the filter,
+// and saves the progress to path after each batch. The writer must be resumed at p.Size.
+func Export(rcli *redis.Client, index string, filter *Filter, tsEnd int64, batch int64, w Writer, p *Progress, path string) (err error) {
+	if p.Done {
+		return
+	}
+	if err = server.RangeVisitsFrom(rcli, index, p.Cursor, tsEnd, batch, func(visits []*server.Visit, next server.VisitCursor) (err error) {
+		selected := visits[:0]
+		for _, visit := range visits {
+			if filter.Match(visit) {
+				selected = append(selected, visit)
+			}
+		}
+		if err = w.Write(selected); err != nil {
+			return
+		}
+		if p.Size, err = w.Sync(); err != nil {
+			return
+		}
+		p.Cursor = next
+		p.Exported += int64(len(selected))
+		return p.Save(path)
+	}); err != nil {
+		return
+	}
+	p.Done = true
+	return p.Save(path)
+}
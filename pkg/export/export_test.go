@@ -0,0 +1,175 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	f := &Filter{Gender: AnyGender}
+	visit := &server.Visit{Uid: 12, Shop: 8, Position: 1, Gender: 1, Age: 30}
+	require.True(t, f.Match(visit))
+
+	var err error
+	f.Uids, err = ParseUints("12, 34")
+	require.NoError(t, err)
+	f.Shops, err = ParseUints("8")
+	require.NoError(t, err)
+	f.Positions, err = ParseUints("")
+	require.NoError(t, err)
+	f.AgeMin, f.AgeMax = 20, 30
+	require.True(t, f.Match(visit))
+
+	for _, v := range []server.Visit{
+		{Uid: 13, Shop: 8, Gender: 1, Age: 30},
+		{Uid: 12, Shop: 9, Gender: 1, Age: 30},
+		{Uid: 12, Shop: 8, Gender: 1, Age: 31},
+		{Uid: 12, Shop: 8, Gender: 1, Age: 19},
+	} {
+		require.False(t, f.Match(&v))
+	}
+	f.Gender = 0
+	require.False(t, f.Match(visit))
+
+	_, err = ParseUints("12,x")
+	require.Error(t, err)
+}
+
+func TestCSVWriterResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "visits.csv")
+
+	w, err := NewCSVWriter(path, 0)
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]*server.Visit{{PictureId: "obj1", Uid: 1, Decision: server.DecisionNew}}))
+	size, err := w.Sync()
+	require.NoError(t, err)
+	// a batch written but not checkpointed
+	require.NoError(t, w.Write([]*server.Visit{{PictureId: "obj2", Uid: 2}}))
+	require.NoError(t, w.Close())
+
+	// resumed at the checkpoint, the partial batch is dropped
+	w, err = NewCSVWriter(path, size)
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]*server.Visit{{PictureId: "obj3", Uid: 3}}))
+	require.NoError(t, w.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	recs, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, 3, len(recs))
+	require.Equal(t, csvHeader, recs[0])
+	require.Equal(t, "obj1", recs[1][0])
+	require.Equal(t, "DecisionNew", recs[1][14])
+	require.Equal(t, "obj3", recs[2][0])
+}
+
+func TestJSONLWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "visits.jsonl")
+
+	w, err := NewJSONLWriter(path, 0)
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]*server.Visit{{PictureId: "obj1"}, {PictureId: "obj2"}}))
+	require.NoError(t, w.Close())
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(data), "\n"))
+}
+
+func TestImageWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	staging := filepath.Join(dir, "visits.zip.d")
+
+	var gets int32
+	get := func(objID string) ([]byte, error) {
+		atomic.AddInt32(&gets, 1)
+		switch objID {
+		case "bad":
+			return nil, errors.New("oss is down")
+		case "gone":
+			return nil, errors.Wrap(ErrImageNotFound, objID)
+		}
+		return []byte("jpg of " + objID), nil
+	}
+	w, err := NewImageWriter(staging, 0, get, 3)
+	require.NoError(t, err)
+	visits := []*server.Visit{{PictureId: "obj1", Uid: 1}, {PictureId: "obj2", Uid: 2}, {PictureId: "obj3", Uid: 1}}
+	require.NoError(t, w.Write(visits))
+	require.Equal(t, int32(3), gets)
+	size, err := w.Sync()
+	require.NoError(t, err)
+
+	// a failed download fails the batch, no row is written
+	require.Error(t, w.Write([]*server.Visit{{PictureId: "obj4"}, {PictureId: "bad"}}))
+	require.NoError(t, w.Close())
+
+	// resumed, the downloaded image isn't downloaded again
+	gets = 0
+	w, err = NewImageWriter(staging, size, get, 3)
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]*server.Visit{{PictureId: "obj4"}}))
+	require.Equal(t, int32(0), gets)
+	// an image not found is skipped
+	require.NoError(t, w.Write([]*server.Visit{{PictureId: "gone", Uid: 3}}))
+	require.Equal(t, int64(1), w.Missing)
+	require.NoError(t, w.Close())
+
+	path := filepath.Join(dir, "visits.zip")
+	require.NoError(t, Pack(staging, path))
+	zr, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	require.Equal(t, []string{manifestName, "images/0_0_0_0_obj4.jpg", "images/1_0_0_0_obj1.jpg", "images/1_0_0_0_obj3.jpg", "images/2_0_0_0_obj2.jpg"}, names)
+
+	rc, err := zr.File[0].Open()
+	require.NoError(t, err)
+	defer rc.Close()
+	recs, err := csv.NewReader(rc).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, 6, len(recs))
+	require.Equal(t, []string{"file", "missing"}, recs[0][len(recs[0])-2:])
+	require.Equal(t, []string{"images/0_0_0_0_obj4.jpg", "false"}, recs[4][len(recs[4])-2:])
+	require.Equal(t, []string{"", "true"}, recs[5][len(recs[5])-2:])
+}
+
+func TestProgress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "visits.csv.progress")
+
+	p, err := LoadProgress(path, "q1", 100)
+	require.NoError(t, err)
+	require.Equal(t, server.VisitCursor{Time: 100}, p.Cursor)
+	p.Cursor, p.Size, p.Exported = server.VisitCursor{Time: 200, Skip: 2}, 1024, 7
+	require.NoError(t, p.Save(path))
+
+	loaded, err := LoadProgress(path, "q1", 100)
+	require.NoError(t, err)
+	require.Equal(t, p, loaded)
+	_, err = LoadProgress(path, "q2", 100)
+	require.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AnyGender matches both genders
+	AnyGender = -1
+)
+
+// Filter selects the visits to export, an empty set matches any
+type Filter struct {
+	Uids      map[uint64]bool
+	Shops     map[uint64]bool
+	Positions map[uint64]bool
+	Gender    int
+	// AgeMin and AgeMax are inclusive, AgeMax 0 means no limit
+	AgeMin uint32
+	AgeMax uint32
+}
+
+// Match returns if the filter selects the visit
+func (f *Filter) Match(visit *server.Visit) bool {
+	if len(f.Uids) != 0 && !f.Uids[visit.Uid] {
+		return false
+	}
+	if len(f.Shops) != 0 && !f.Shops[visit.Shop] {
+		return false
+	}
+	if len(f.Positions) != 0 && !f.Positions[uint64(visit.Position)] {
+		return false
+	}
+	if f.Gender != AnyGender && uint32(f.Gender) != visit.Gender {
+		return false
+	}
+	if visit.Age < f.AgeMin || (f.AgeMax != 0 && visit.Age > f.AgeMax) {
+		return false
+	}
+	return true
+}
+
+// ParseUints parses a comma separated list of integers, for example: 1226,3495
+func ParseUints(list string) (set map[uint64]bool, err error) {
+	set = make(map[uint64]bool)
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		var u uint64
+		if u, err = strconv.ParseUint(s, 10, 64); err != nil {
+			err = errors.Errorf("invalid integer: %v", s)
+			return
+		}
+		set[u] = true
+	}
+	return
+}
@@ -0,0 +1,186 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+)
+
+const (
+	manifestName = "manifest.csv"
+	imagesDir    = "images"
+)
+
+var (
+	// ErrImageNotFound is returned by the get of an ImageWriter if the image is not in OSS
+	ErrImageNotFound = errors.New("image not found")
+)
+
+// ImageWriter downloads the images of the visits to a staging directory with manifest.csv,
+// Pack zips the directory at last. An image already downloaded is not downloaded again.
+// A visit whose image is not found is exported without it, marked missing in the manifest.
+type ImageWriter struct {
+	*CSVWriter
+	dir     string
+	get     func(objID string) ([]byte, error)
+	workers int
+
+	mu sync.Mutex
+	// missing are the pictures of the batch not found
+	missing map[string]bool
+	// Missing is the number of the images not found
+	Missing int64
+}
+
+// NewImageWriter returns a writer of the staging directory resumed at the manifest size,
+// get reads an image from OSS and returns ErrImageNotFound if it's not there, at most
+// workers images are downloaded in parallel.
+func NewImageWriter(dir string, size int64, get func(objID string) ([]byte, error), workers int) (w *ImageWriter, err error) {
+	if err = os.MkdirAll(filepath.Join(dir, imagesDir), 0755); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	w = &ImageWriter{dir: dir, get: get, workers: workers, missing: make(map[string]bool)}
+	w.CSVWriter, err = newCSVWriter(filepath.Join(dir, manifestName), size, []string{"file", "missing"}, func(visit *server.Visit) []string {
+		if w.missing[visit.PictureId] {
+			return []string{"", "true"}
+		}
+		return []string{imageName(visit), "false"}
+	})
+	return
+}
+
+// imageName is the path of the image in the zip, it's named as cmd/fetchImgs does
+func imageName(visit *server.Visit) string {
+	return fmt.Sprintf("%s/%d_%d_%d_%d_%s.jpg", imagesDir, visit.Uid, visit.VisitTime, visit.Gender, visit.Age, visit.PictureId)
+}
+
+// Write downloads the images, and writes the rows of the manifest if all of them are downloaded
+// or not found
+func (w *ImageWriter) Write(visits []*server.Visit) (err error) {
+	w.missing = make(map[string]bool)
+	jobs := make(chan *server.Visit)
+	errs := make(chan error, len(visits))
+	var wg sync.WaitGroup
+	for i := 0; i < w.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for visit := range jobs {
+				if e := w.download(visit); e != nil {
+					errs <- e
+				}
+			}
+		}()
+	}
+	for _, visit := range visits {
+		jobs <- visit
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	if err = <-errs; err != nil {
+		return
+	}
+	return w.CSVWriter.Write(visits)
+}
+
+// download writes the image to a temporary file renamed at last, so that a partial image is never taken as downloaded
+func (w *ImageWriter) download(visit *server.Visit) (err error) {
+	fp := filepath.Join(w.dir, imageName(visit))
+	if _, err = os.Stat(fp); err == nil {
+		return
+	}
+	var img []byte
+	if img, err = w.get(visit.PictureId); errors.Cause(err) == ErrImageNotFound {
+		w.mu.Lock()
+		w.missing[visit.PictureId] = true
+		w.Missing++
+		w.mu.Unlock()
+		err = nil
+		return
+	} else if err != nil {
+		err = errors.Wrapf(err, "download %s", visit.PictureId)
+		return
+	}
+	tmp := fp + ".tmp"
+	if err = ioutil.WriteFile(tmp, img, 0644); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = os.Rename(tmp, fp); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+// Pack zips manifest.csv and the images of the staging directory to path.
+// The images are stored as they are, JPEG doesn't compress further.
+func Pack(dir, path string) (err error) {
+	var names []string
+	if names, err = filepath.Glob(filepath.Join(dir, imagesDir, "*.jpg")); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	sort.Strings(names)
+
+	tmp := path + ".tmp"
+	var f *os.File
+	if f, err = os.Create(tmp); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	zw := zip.NewWriter(f)
+	if err = addToZip(zw, filepath.Join(dir, manifestName), manifestName, zip.Deflate); err == nil {
+		for _, name := range names {
+			if err = addToZip(zw, name, imagesDir+"/"+filepath.Base(name), zip.Store); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		if err = zw.Close(); err != nil {
+			err = errors.Wrap(err, "")
+		}
+	}
+	if e := f.Close(); e != nil && err == nil {
+		err = errors.Wrap(e, "")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+func addToZip(zw *zip.Writer, src, name string, method uint16) (err error) {
+	var f *os.File
+	if f, err = os.Open(src); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	defer f.Close()
+	var w io.Writer
+	if w, err = zw.CreateHeader(&zip.FileHeader{Name: name, Method: method}); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if _, err = io.Copy(w, f); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
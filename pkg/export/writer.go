@@ -0,0 +1,214 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+)
+
+const (
+	// FormatCSV writes a CSV file with a header
+	FormatCSV = "csv"
+	// FormatJSONL writes a JSON line per visit
+	FormatJSONL = "jsonl"
+	// FormatZip writes a zip of the images with manifest.csv
+	FormatZip = "zip"
+)
+
+// Writer writes the exported visits
+type Writer interface {
+	Write(visits []*server.Visit) error
+	// Sync flushes the output, and returns its size to resume from
+	Sync() (size int64, err error)
+	Close() error
+}
+
+var csvHeader = []string{"picture_id", "uid", "visit_time", "last_seen", "shop", "position", "age", "gender", "quality",
+	"zone", "entrance", "exit", "xid", "low_confidence", "decision", "distance", "mac", "camera_ip", "version",
+	"is_returning", "prev_visit_time"}
+
+func csvRecord(visit *server.Visit) []string {
+	return []string{
+		visit.PictureId,
+		strconv.FormatUint(visit.Uid, 10),
+		time.Unix(int64(visit.VisitTime), 0).Format(time.RFC3339),
+		time.Unix(int64(visit.LastSeen), 0).Format(time.RFC3339),
+		strconv.FormatUint(visit.Shop, 10),
+		strconv.FormatUint(uint64(visit.Position), 10),
+		strconv.FormatUint(uint64(visit.Age), 10),
+		strconv.FormatUint(uint64(visit.Gender), 10),
+		strconv.FormatFloat(float64(visit.Quality), 'f', -1, 32),
+		visit.Zone,
+		strconv.FormatBool(visit.Entrance),
+		strconv.FormatBool(visit.Exit),
+		fmt.Sprintf("%016x", uint64(visit.Xid)),
+		strconv.FormatBool(visit.LowConfidence),
+		visit.Decision.String(),
+		strconv.FormatFloat(float64(visit.Distance), 'f', -1, 32),
+		visit.Mac,
+		visit.CameraIp,
+		strconv.FormatUint(uint64(visit.Version), 10),
+		strconv.FormatBool(visit.IsReturning),
+		formatTime(visit.PrevVisitTime),
+	}
+}
+
+// formatTime formats a unix time, 0 is empty
+func formatTime(ts uint64) string {
+	if ts == 0 {
+		return ""
+	}
+	return time.Unix(int64(ts), 0).Format(time.RFC3339)
+}
+
+// file is an output file resumed at a size, what's after it is the partial output of a failed run
+type file struct {
+	f    *os.File
+	w    *bufio.Writer
+	size int64
+}
+
+func openFile(path string, size int64) (f *file, err error) {
+	f = &file{size: size}
+	if f.f, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = f.f.Truncate(size); err != nil {
+		f.f.Close()
+		err = errors.Wrap(err, "")
+		return
+	}
+	if _, err = f.f.Seek(size, io.SeekStart); err != nil {
+		f.f.Close()
+		err = errors.Wrap(err, "")
+		return
+	}
+	f.w = bufio.NewWriter(f)
+	return
+}
+
+// Write counts the bytes written
+func (f *file) Write(p []byte) (n int, err error) {
+	n, err = f.f.Write(p)
+	f.size += int64(n)
+	return
+}
+
+func (f *file) Sync() (size int64, err error) {
+	if err = f.w.Flush(); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = f.f.Sync(); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	size = f.size
+	return
+}
+
+func (f *file) Close() (err error) {
+	if _, err = f.Sync(); err != nil {
+		f.f.Close()
+		return
+	}
+	if err = f.f.Close(); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+// CSVWriter writes the visits as CSV
+type CSVWriter struct {
+	*file
+	w *csv.Writer
+	// extra returns the extra columns of a visit
+	extra func(visit *server.Visit) []string
+}
+
+// NewCSVWriter returns a writer of the file resumed at size, the header is written to a new file
+func NewCSVWriter(path string, size int64) (w *CSVWriter, err error) {
+	return newCSVWriter(path, size, nil, nil)
+}
+
+func newCSVWriter(path string, size int64, extraHeader []string, extra func(visit *server.Visit) []string) (w *CSVWriter, err error) {
+	w = &CSVWriter{extra: extra}
+	if w.file, err = openFile(path, size); err != nil {
+		return
+	}
+	w.w = csv.NewWriter(w.file.w)
+	if size == 0 {
+		if err = w.w.Write(append(append([]string(nil), csvHeader...), extraHeader...)); err != nil {
+			w.file.Close()
+			err = errors.Wrap(err, "")
+		}
+	}
+	return
+}
+
+// Write writes a row per visit
+func (w *CSVWriter) Write(visits []*server.Visit) (err error) {
+	for _, visit := range visits {
+		rec := csvRecord(visit)
+		if w.extra != nil {
+			rec = append(rec, w.extra(visit)...)
+		}
+		if err = w.w.Write(rec); err != nil {
+			err = errors.Wrap(err, "")
+			return
+		}
+	}
+	return
+}
+
+// Sync flushes the rows
+func (w *CSVWriter) Sync() (size int64, err error) {
+	w.w.Flush()
+	if err = w.w.Error(); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	return w.file.Sync()
+}
+
+// Close flushes the rows and closes the file
+func (w *CSVWriter) Close() (err error) {
+	w.w.Flush()
+	return w.file.Close()
+}
+
+// JSONLWriter writes a JSON line per visit
+type JSONLWriter struct {
+	*file
+	enc *json.Encoder
+}
+
+// NewJSONLWriter returns a writer of the file resumed at size
+func NewJSONLWriter(path string, size int64) (w *JSONLWriter, err error) {
+	w = &JSONLWriter{}
+	if w.file, err = openFile(path, size); err != nil {
+		return
+	}
+	w.enc = json.NewEncoder(w.file.w)
+	return
+}
+
+// Write writes a line per visit
+func (w *JSONLWriter) Write(visits []*server.Visit) (err error) {
+	for _, visit := range visits {
+		if err = w.enc.Encode(visit); err != nil {
+			err = errors.Wrap(err, "")
+			return
+		}
+	}
+	return
+}
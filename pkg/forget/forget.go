@@ -0,0 +1,355 @@
+package forget
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/infinivision/filesyncer/pkg/vecindex"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AuditKey is the Redis list of audit entries
+	AuditKey = "forget_audit"
+)
+
+// Record is a visit and its encoding in the visit store
+type Record struct {
+	Visit *server.Visit
+	Raw   string
+}
+
+// Visits is the store of the identified visits
+type Visits interface {
+	// Find returns the visits of uid or identified by one of xids
+	Find(uid int64, xids []int64) ([]Record, error)
+	Remove(recs []Record) (removed int64, err error)
+}
+
+// Objects is the store of the images
+type Objects interface {
+	Delete(keys []string) (deleted int, err error)
+}
+
+// History is the visit history, it only counts the rows in dry run
+type History interface {
+	Forget(uid int64, dryRun bool) (rows int64, err error)
+}
+
+type histories []History
+
+// Histories returns a History forgetting uid from each of hs, the rows are summed.
+// A failed one doesn't stop the others, the first error is returned.
+func Histories(hs ...History) History {
+	return histories(hs)
+}
+
+func (hs histories) Forget(uid int64, dryRun bool) (rows int64, err error) {
+	for _, h := range hs {
+		n, e := h.Forget(uid, dryRun)
+		rows += n
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// DeadLetters is the store of the images failed in identification or recording
+type DeadLetters interface {
+	// Forget removes the letters of the pictures or identified as uid or one of xids,
+	// it only counts them in dry run
+	Forget(uid int64, xids []int64, pictureIds []string, dryRun bool) (removed int64, err error)
+}
+
+// XidDbs finds the dbs of xids in an index that can't remove vectors
+type XidDbs interface {
+	GetDbs(xids []int64) (dbs map[int64]uint64, err error)
+	Remove(xids []int64) error
+}
+
+// AuditLog keeps the reports
+type AuditLog interface {
+	Append(r *Report) error
+}
+
+// Report is what a forget removed, or would remove in dry run
+type Report struct {
+	Uid    int64  `json:"uid"`
+	Reason string `json:"reason,omitempty"`
+	DryRun bool   `json:"dryRun"`
+	At     int64  `json:"at"`
+
+	Xids []int64 `json:"xids"`
+	// VectorsRemoved are removed from the vector index. The ones of an index
+	// can't remove are scrubbed, overwritten by zero vectors in their dbs.
+	// The ones of unknown dbs are only unlinked, they're still in the index.
+	VectorsRemoved  int      `json:"vectorsRemoved"`
+	VectorsScrubbed int      `json:"vectorsScrubbed"`
+	VectorsUnlinked int      `json:"vectorsUnlinked"`
+	PictureIds      []string `json:"pictureIds"`
+	ObjectsDeleted  int      `json:"objectsDeleted"`
+	VisitRecords    int64    `json:"visitRecords"`
+	HistoryRows     int64    `json:"historyRows"`
+	DeadLetters     int64    `json:"deadLetters"`
+	// Incomplete is set if some data of uid is kept, as Kept says
+	Incomplete bool     `json:"incomplete"`
+	Kept       []string `json:"kept,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// Forgetter erases a uid from every store
+type Forgetter struct {
+	ids     server.IdentityStore
+	index   vecindex.VectorIndex
+	visits  Visits
+	objects Objects
+	history History
+	audit   AuditLog
+
+	dim         int
+	xidDbs      XidDbs
+	deadLetters DeadLetters
+	kept        []string
+}
+
+// NewForgetter returns a Forgetter
+func NewForgetter(ids server.IdentityStore, index vecindex.VectorIndex, visits Visits, objects Objects, history History, audit AuditLog) *Forgetter {
+	return &Forgetter{
+		ids:     ids,
+		index:   index,
+		visits:  visits,
+		objects: objects,
+		history: history,
+		audit:   audit,
+	}
+}
+
+// SetScrubber sets the dbs of xids, the vectors of an index that can't remove
+// them are overwritten by zero vectors of dim in their dbs
+func (f *Forgetter) SetScrubber(xidDbs XidDbs, dim int) {
+	f.xidDbs = xidDbs
+	f.dim = dim
+}
+
+// SetDeadLetters sets the dead letters the images of uid are removed from
+func (f *Forgetter) SetDeadLetters(deadLetters DeadLetters) {
+	f.deadLetters = deadLetters
+}
+
+// SetKept sets the notes of the data can't be attributed to a uid, such as the
+// images not identified yet, they're reported as kept
+func (f *Forgetter) SetKept(notes ...string) {
+	f.kept = notes
+}
+
+// Forget erases uid. A failed step doesn't stop the others, but the identity
+// is kept so that Forget can be retried to find the left artifacts.
+// The report is audited anyway.
+func (f *Forgetter) Forget(uid int64, reason string, dryRun bool) (r *Report, err error) {
+	r = &Report{
+		Uid:    uid,
+		Reason: reason,
+		DryRun: dryRun,
+		At:     time.Now().Unix(),
+	}
+	defer func() {
+		if aerr := f.audit.Append(r); aerr != nil {
+			log.Errorf("lost audit %+v, errors:%+v", r, aerr)
+			if err == nil {
+				err = aerr
+			}
+		}
+	}()
+
+	if r.Xids, err = f.ids.ListXids(uid); err != nil {
+		return
+	}
+	var recs []Record
+	if recs, err = f.visits.Find(uid, r.Xids); err != nil {
+		return
+	}
+	for _, rec := range recs {
+		r.PictureIds = append(r.PictureIds, rec.Visit.PictureId)
+	}
+	r.VisitRecords = int64(len(recs))
+	r.Kept = append(r.Kept, f.kept...)
+	remover, removable := f.index.(vecindex.Remover)
+	var dbs map[int64]uint64
+	if !removable && f.xidDbs != nil {
+		if dbs, err = f.xidDbs.GetDbs(r.Xids); err != nil {
+			return
+		}
+	}
+	defer func() {
+		r.Incomplete = len(r.Kept) != 0
+	}()
+
+	if dryRun {
+		r.ObjectsDeleted = len(r.PictureIds)
+		if removable {
+			r.VectorsRemoved = len(r.Xids)
+		} else {
+			r.VectorsScrubbed = len(dbs)
+			r.VectorsUnlinked = len(r.Xids) - len(dbs)
+			r.Kept = append(r.Kept, f.unscrubbed(r.Xids, dbs)...)
+		}
+		if f.deadLetters != nil {
+			if r.DeadLetters, err = f.deadLetters.Forget(uid, r.Xids, r.PictureIds, true); err != nil {
+				return
+			}
+		}
+		r.HistoryRows, err = f.history.Forget(uid, true)
+		return
+	}
+
+	fail := func(e error) {
+		log.Errorf("forget uid %d failed, errors:%+v", uid, e)
+		r.Errors = append(r.Errors, e.Error())
+		if err == nil {
+			err = e
+		}
+	}
+	var e error
+	if r.ObjectsDeleted, e = f.objects.Delete(r.PictureIds); e != nil {
+		fail(e)
+	}
+	if r.VisitRecords, e = f.visits.Remove(recs); e != nil {
+		fail(e)
+	}
+	if r.HistoryRows, e = f.history.Forget(uid, false); e != nil {
+		fail(e)
+	}
+	if f.deadLetters != nil {
+		if r.DeadLetters, e = f.deadLetters.Forget(uid, r.Xids, r.PictureIds, false); e != nil {
+			fail(e)
+		}
+	}
+	if len(r.Xids) > 0 {
+		if removable {
+			if e = remover.RemoveIds(r.Xids); e != nil {
+				fail(e)
+			} else {
+				r.VectorsRemoved = len(r.Xids)
+			}
+		} else if e = f.scrub(r, dbs); e != nil {
+			fail(e)
+		}
+	}
+	if err != nil {
+		return
+	}
+	if _, e = f.ids.Forget(uid); e != nil {
+		fail(e)
+	}
+	return
+}
+
+// scrub overwrites the vectors of the xids in their dbs with zero vectors,
+// which never match any face
+func (f *Forgetter) scrub(r *Report, dbs map[int64]uint64) (err error) {
+	var scrubbed []int64
+	zero := make([]float32, f.dim)
+	for _, xid := range r.Xids {
+		db, ok := dbs[xid]
+		if !ok {
+			continue
+		}
+		if err = f.index.UpdateWithIds(db, xid, zero); err != nil {
+			err = errors.Wrapf(err, "scrub xid %016x in db %d", uint64(xid), db)
+			break
+		}
+		scrubbed = append(scrubbed, xid)
+	}
+	r.VectorsScrubbed = len(scrubbed)
+	r.VectorsUnlinked = len(r.Xids) - len(scrubbed)
+	done := make(map[int64]uint64, len(scrubbed))
+	for _, xid := range scrubbed {
+		done[xid] = dbs[xid]
+	}
+	r.Kept = append(r.Kept, f.unscrubbed(r.Xids, done)...)
+	if e := f.xidDbs.Remove(scrubbed); e != nil && err == nil {
+		err = e
+	}
+	return
+}
+
+// unscrubbed returns the notes of the vectors can't be scrubbed
+func (f *Forgetter) unscrubbed(xids []int64, dbs map[int64]uint64) (notes []string) {
+	for _, xid := range xids {
+		if _, ok := dbs[xid]; !ok {
+			notes = append(notes, fmt.Sprintf("vector of xid %016x is still in the index, its db is unknown", uint64(xid)))
+		}
+	}
+	return
+}
+
+type redisVisits struct {
+	rcli  *redis.Client
+	que   string
+	index string
+}
+
+// NewRedisVisits returns the visits in the Redis list que, they're removed from the sorted set index as well
+func NewRedisVisits(rcli *redis.Client, que, index string) Visits {
+	return &redisVisits{rcli: rcli, que: que, index: index}
+}
+
+func (v *redisVisits) Find(uid int64, xids []int64) (recs []Record, err error) {
+	set := make(map[int64]bool, len(xids))
+	for _, xid := range xids {
+		set[xid] = true
+	}
+	err = server.ScanVisits(v.rcli, v.que, 1000, func(visit *server.Visit, raw string) {
+		if visit.Uid == uint64(uid) || (visit.Xid != 0 && set[visit.Xid]) {
+			recs = append(recs, Record{Visit: visit, Raw: raw})
+		}
+	})
+	return
+}
+
+func (v *redisVisits) Remove(recs []Record) (removed int64, err error) {
+	if len(recs) == 0 {
+		return
+	}
+	pipe := v.rcli.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(recs))
+	for _, rec := range recs {
+		cmds = append(cmds, pipe.LRem(v.que, 0, rec.Raw))
+		pipe.ZRem(v.index, rec.Raw)
+	}
+	if _, err = pipe.Exec(); err != nil {
+		err = errors.Wrapf(err, "remove from %s", v.que)
+		return
+	}
+	for _, cmd := range cmds {
+		removed += cmd.Val()
+	}
+	return
+}
+
+type redisAuditLog struct {
+	rcli *redis.Client
+	key  string
+}
+
+// NewRedisAuditLog returns the audit log in the Redis list key
+func NewRedisAuditLog(rcli *redis.Client, key string) AuditLog {
+	return &redisAuditLog{rcli: rcli, key: key}
+}
+
+func (a *redisAuditLog) Append(r *Report) (err error) {
+	var data []byte
+	if data, err = json.Marshal(r); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = a.rcli.RPush(a.key, data).Err(); err != nil {
+		err = errors.Wrapf(err, "append audit to %s", a.key)
+	}
+	return
+}
@@ -0,0 +1,210 @@
+package forget
+
+import (
+	"testing"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/infinivision/filesyncer/pkg/vecindex"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeVisits struct {
+	visits []*server.Visit
+}
+
+func (v *fakeVisits) Find(uid int64, xids []int64) (recs []Record, err error) {
+	for _, visit := range v.visits {
+		if visit.Uid == uint64(uid) {
+			recs = append(recs, Record{Visit: visit, Raw: visit.PictureId})
+		}
+	}
+	return
+}
+
+func (v *fakeVisits) Remove(recs []Record) (removed int64, err error) {
+	var left []*server.Visit
+	for _, visit := range v.visits {
+		found := false
+		for _, rec := range recs {
+			found = found || rec.Raw == visit.PictureId
+		}
+		if !found {
+			left = append(left, visit)
+		}
+	}
+	removed = int64(len(v.visits) - len(left))
+	v.visits = left
+	return
+}
+
+type fakeObjects struct {
+	objects map[string]bool
+	err     error
+}
+
+func (o *fakeObjects) Delete(keys []string) (deleted int, err error) {
+	if o.err != nil {
+		return 0, o.err
+	}
+	for _, key := range keys {
+		if o.objects[key] {
+			delete(o.objects, key)
+			deleted++
+		}
+	}
+	return
+}
+
+type fakeHistory map[int64]int64
+
+func (h fakeHistory) Forget(uid int64, dryRun bool) (rows int64, err error) {
+	rows = h[uid]
+	if !dryRun {
+		delete(h, uid)
+	}
+	return
+}
+
+type fakeAudit []*Report
+
+func (a *fakeAudit) Append(r *Report) error {
+	*a = append(*a, r)
+	return nil
+}
+
+func TestForget(t *testing.T) {
+	ids := server.NewMemoryIdentityStore()
+	uid, _ := ids.AllocateUid()
+	other, _ := ids.AllocateUid()
+	require.NoError(t, ids.Associate(uid, 10))
+	require.NoError(t, ids.Associate(uid, 11))
+	require.NoError(t, ids.Associate(other, 20))
+	index := vecindex.NewMemoryIndex(2, 0.5)
+	require.NoError(t, index.AddWithIds([]float32{1, 0, 0.6, 0.8, 0, 1}, []int64{10, 11, 20}))
+	visits := &fakeVisits{visits: []*server.Visit{
+		{PictureId: "a", Uid: uint64(uid)},
+		{PictureId: "b", Uid: uint64(other)},
+		{PictureId: "c", Uid: uint64(uid)},
+	}}
+	objects := &fakeObjects{objects: map[string]bool{"a": true, "b": true, "c": true}, err: errors.New("oss is down")}
+	history := fakeHistory{uid: 5, other: 3}
+	audit := &fakeAudit{}
+	f := NewForgetter(ids, index, visits, objects, history, audit)
+
+	r, err := f.Forget(uid, "ticket 1", true)
+	require.NoError(t, err)
+	require.Equal(t, []int64{11, 10}, r.Xids)
+	require.Equal(t, []string{"a", "c"}, r.PictureIds)
+	require.Equal(t, 2, r.VectorsRemoved)
+	require.Equal(t, int64(5), r.HistoryRows)
+	require.Equal(t, 3, index.Len())
+	require.Equal(t, 3, len(visits.visits))
+	require.Equal(t, 1, len(*audit))
+
+	// the identity is kept if a step failed
+	r, err = f.Forget(uid, "ticket 1", false)
+	require.Error(t, err)
+	require.Equal(t, 1, len(r.Errors))
+	require.Equal(t, 2, len(*audit))
+	xids, err := ids.ListXids(uid)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(xids))
+
+	objects.err = nil
+	visits.visits = append(visits.visits, &server.Visit{PictureId: "a", Uid: uint64(uid)})
+	r, err = f.Forget(uid, "ticket 1", false)
+	require.NoError(t, err)
+	require.Equal(t, 1, r.ObjectsDeleted)
+	require.Equal(t, int64(1), r.VisitRecords)
+	require.Equal(t, 3, len(*audit))
+	xids, err = ids.ListXids(uid)
+	require.NoError(t, err)
+	require.Empty(t, xids)
+	_, err = ids.GetUid(10)
+	require.Equal(t, server.ErrIdentityNotFound, errors.Cause(err))
+	require.Equal(t, 1, index.Len())
+	require.Equal(t, 1, len(visits.visits))
+	require.Equal(t, fakeHistory{other: 3}, history)
+}
+
+// fakeIndex can't remove vectors, like hyena
+type fakeIndex struct {
+	vecindex.VectorIndex
+	updated map[int64][]float32
+}
+
+func (i *fakeIndex) UpdateWithIds(db uint64, xid int64, xb []float32) error {
+	i.updated[xid] = xb
+	return nil
+}
+
+type fakeDeadLetters map[string]int64
+
+func (d fakeDeadLetters) Forget(uid int64, xids []int64, pictureIds []string, dryRun bool) (removed int64, err error) {
+	for _, id := range pictureIds {
+		if _, ok := d[id]; ok {
+			removed++
+			if !dryRun {
+				delete(d, id)
+			}
+		}
+	}
+	return
+}
+
+func TestForgetScrub(t *testing.T) {
+	ids := server.NewMemoryIdentityStore()
+	uid, _ := ids.AllocateUid()
+	require.NoError(t, ids.Associate(uid, 10))
+	require.NoError(t, ids.Associate(uid, 11))
+	index := &fakeIndex{VectorIndex: vecindex.NewMemoryIndex(2, 0.5), updated: make(map[int64][]float32)}
+	visits := &fakeVisits{visits: []*server.Visit{{PictureId: "a", Uid: uint64(uid)}}}
+	objects := &fakeObjects{objects: map[string]bool{"a": true}}
+	audit := &fakeAudit{}
+	xidDbs := server.NewMemoryXidDbStore()
+	// the db of xid 11 is unknown, it's never matched
+	require.NoError(t, xidDbs.SetDb(10, 3))
+	deadLetters := fakeDeadLetters{"a": 1, "b": 1}
+	f := NewForgetter(ids, index, visits, objects, fakeHistory{}, audit)
+	f.SetScrubber(xidDbs, 2)
+	f.SetDeadLetters(deadLetters)
+	f.SetKept("queued images")
+
+	r, err := f.Forget(uid, "ticket 2", true)
+	require.NoError(t, err)
+	require.Equal(t, 1, r.VectorsScrubbed)
+	require.Equal(t, 1, r.VectorsUnlinked)
+	require.Equal(t, int64(1), r.DeadLetters)
+	require.True(t, r.Incomplete)
+	require.Len(t, r.Kept, 2)
+	require.Empty(t, index.updated)
+	require.Len(t, deadLetters, 2)
+
+	r, err = f.Forget(uid, "ticket 2", false)
+	require.NoError(t, err)
+	require.Equal(t, 1, r.VectorsScrubbed)
+	require.Equal(t, 1, r.VectorsUnlinked)
+	require.Equal(t, int64(1), r.DeadLetters)
+	require.True(t, r.Incomplete)
+	require.Equal(t, []string{"queued images", "vector of xid 000000000000000b is still in the index, its db is unknown"}, r.Kept)
+	require.Equal(t, map[int64][]float32{10: {0, 0}}, index.updated)
+	require.Equal(t, fakeDeadLetters{"b": 1}, deadLetters)
+	dbs, err := xidDbs.GetDbs([]int64{10})
+	require.NoError(t, err)
+	require.Empty(t, dbs)
+	require.True(t, (*audit)[1].Incomplete)
+}
+
+func TestHistories(t *testing.T) {
+	pg, visits := fakeHistory{1: 5, 2: 1}, fakeHistory{1: 3}
+	hs := Histories(pg, visits)
+	rows, err := hs.Forget(1, true)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), rows)
+	rows, err = hs.Forget(1, false)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), rows)
+	require.Equal(t, fakeHistory{2: 1}, pg)
+	require.Empty(t, visits)
+}
@@ -0,0 +1,346 @@
+package live
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/go-redis/redis"
+	"github.com/gorilla/websocket"
+	"github.com/infinivision/filesyncer/pkg/export"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	heartbeatInterval = 15 * time.Second
+	writeTimeout      = 10 * time.Second
+)
+
+var (
+	liveSubscribers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "live_subscribers",
+			Help:      "Subscribers of the live visit stream by transport: ws, sse.",
+		}, []string{"transport"})
+	liveDroppedCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "live_dropped",
+			Help:      "Visits dropped for slow subscribers of the live visit stream.",
+		})
+	liveOnce sync.Once
+)
+
+// Backfill returns the visits since the unix time in time order
+type Backfill func(since int64) ([]*server.Visit, error)
+
+// NewRedisBackfill returns the backfill of the sorted set of visits index
+func NewRedisBackfill(rcli *redis.Client, index string) Backfill {
+	return func(since int64) (visits []*server.Visit, err error) {
+		err = server.RangeVisits(rcli, index, since, time.Now().Unix()+1, 1000, func(batch []*server.Visit) error {
+			visits = append(visits, batch...)
+			return nil
+		})
+		return
+	}
+}
+
+type subscriber struct {
+	filter *export.Filter
+	ch     chan *server.Visit
+}
+
+// Hub publishes the visits to the subscribers of the live stream. A subscriber
+// too slow to keep up with its buffer misses the visits, publishing never blocks.
+// The visits are the sightings as they are identified, several sightings of one
+// stay at a shop are published before the sessionizer merges them into one visit.
+type Hub struct {
+	sync.RWMutex
+	subs        map[*subscriber]struct{}
+	buffer      int
+	backfill    Backfill
+	maxBackfill time.Duration
+	upgrader    websocket.Upgrader
+
+	// origins are the origins allowed besides the same one, "*" allows any
+	origins []string
+	// token is required by the subscribers if it's not empty
+	token string
+}
+
+// NewHub returns a hub buffering at most buffer visits per subscriber, a subscriber
+// may ask for the visits of at most maxBackfill ago. backfill is optional.
+func NewHub(buffer int, backfill Backfill, maxBackfill time.Duration) *Hub {
+	liveOnce.Do(func() {
+		prometheus.MustRegister(liveSubscribers)
+		prometheus.MustRegister(liveDroppedCount)
+	})
+	h := &Hub{
+		subs:        make(map[*subscriber]struct{}),
+		buffer:      buffer,
+		backfill:    backfill,
+		maxBackfill: maxBackfill,
+	}
+	h.upgrader.CheckOrigin = h.checkOrigin
+	return h
+}
+
+// SetAuth sets the origins of the dashboards allowed to subscribe besides the
+// same origin, "*" allows any. A subscriber must pass the token as the query token,
+// or the header Authorization: Bearer <token>. The stream is disabled if it's empty.
+func (h *Hub) SetAuth(origins []string, token string) {
+	h.origins = origins
+	h.token = token
+}
+
+// checkOrigin allows the requests of no origin, which are not from browsers,
+// the same origin and the allowed ones
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, o := range h.origins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// authorize replies the error if r is not allowed to subscribe
+func (h *Hub) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if !h.checkOrigin(r) {
+		http.Error(w, "origin is not allowed", http.StatusForbidden)
+		return false
+	}
+	if h.token == "" {
+		http.Error(w, "the live visit stream is disabled without --live-token", http.StatusForbidden)
+		return false
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return false
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+	}
+	return true
+}
+
+// Publish sends the visits to the subscribers interested in them
+func (h *Hub) Publish(visits ...*server.Visit) {
+	h.RLock()
+	defer h.RUnlock()
+	for _, visit := range visits {
+		for sub := range h.subs {
+			if !sub.filter.Match(visit) {
+				continue
+			}
+			select {
+			case sub.ch <- visit:
+			default:
+				liveDroppedCount.Inc()
+			}
+		}
+	}
+}
+
+// Len returns the number of subscribers
+func (h *Hub) Len() int {
+	h.RLock()
+	defer h.RUnlock()
+	return len(h.subs)
+}
+
+func (h *Hub) subscribe(filter *export.Filter) *subscriber {
+	sub := &subscriber{filter: filter, ch: make(chan *server.Visit, h.buffer)}
+	h.Lock()
+	h.subs[sub] = struct{}{}
+	h.Unlock()
+	return sub
+}
+
+func (h *Hub) unsubscribe(sub *subscriber) {
+	h.Lock()
+	delete(h.subs, sub)
+	h.Unlock()
+}
+
+// parseRequest parses the query: shops and positions are comma separated lists, empty means any,
+// backfill is the minutes of the visits before now to send first.
+func (h *Hub) parseRequest(r *http.Request) (filter *export.Filter, backfill time.Duration, err error) {
+	q := r.URL.Query()
+	filter = &export.Filter{Gender: export.AnyGender}
+	if filter.Shops, err = export.ParseUints(q.Get("shops")); err != nil {
+		return
+	}
+	if filter.Positions, err = export.ParseUints(q.Get("positions")); err != nil {
+		return
+	}
+	if s := q.Get("backfill"); s != "" {
+		var minutes int
+		if minutes, err = strconv.Atoi(s); err != nil || minutes < 0 {
+			err = errors.Errorf("invalid backfill: %v", s)
+			return
+		}
+		backfill = time.Duration(minutes) * time.Minute
+		if h.backfill == nil || backfill > h.maxBackfill {
+			err = errors.Errorf("backfill is at most %v", h.maxBackfill)
+		}
+	}
+	return
+}
+
+// stream subscribes, and calls send with the backfill and then the live visits till done is closed or send fails.
+// A live visit already sent in the backfill is skipped.
+func (h *Hub) stream(filter *export.Filter, backfill time.Duration, done <-chan struct{}, heartbeat func() error, send func(visit *server.Visit) error) (err error) {
+	// subscribe first, so that nothing is missed between the backfill and the live visits
+	sub := h.subscribe(filter)
+	defer h.unsubscribe(sub)
+
+	sent := make(map[string]bool)
+	if backfill != 0 {
+		var visits []*server.Visit
+		if visits, err = h.backfill(time.Now().Add(-backfill).Unix()); err != nil {
+			return
+		}
+		for _, visit := range visits {
+			if !filter.Match(visit) {
+				continue
+			}
+			if err = send(visit); err != nil {
+				return
+			}
+			sent[visit.PictureId] = true
+		}
+	}
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err = heartbeat(); err != nil {
+				return
+			}
+		case visit := <-sub.ch:
+			if sent[visit.PictureId] {
+				delete(sent, visit.PictureId)
+				continue
+			}
+			if err = send(visit); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// SSEHandler streams the visits as Server-Sent Events named visit, the data is the visit in json
+func (h *Hub) SSEHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorize(w, r) {
+			return
+		}
+		filter, backfill, err := h.parseRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		liveSubscribers.WithLabelValues("sse").Inc()
+		defer liveSubscribers.WithLabelValues("sse").Dec()
+		err = h.stream(filter, backfill, r.Context().Done(), func() (err error) {
+			if _, err = fmt.Fprint(w, ": heartbeat\n\n"); err == nil {
+				flusher.Flush()
+			}
+			return
+		}, func(visit *server.Visit) (err error) {
+			var data []byte
+			if data, err = json.Marshal(visit); err != nil {
+				return
+			}
+			if _, err = fmt.Fprintf(w, "event: visit\ndata: %s\n\n", data); err == nil {
+				flusher.Flush()
+			}
+			return
+		})
+		if err != nil {
+			log.Warnf("sse stream to %s stopped, errors:%+v", r.RemoteAddr, err)
+		}
+	})
+}
+
+// WebSocketHandler streams the visits as WebSocket text messages of the visit in json
+func (h *Hub) WebSocketHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorize(w, r) {
+			return
+		}
+		filter, backfill, err := h.parseRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var conn *websocket.Conn
+		if conn, err = h.upgrader.Upgrade(w, r, nil); err != nil {
+			// the upgrader replied the error
+			log.Warnf("upgrade %s to websocket failed, errors:%+v", r.RemoteAddr, err)
+			return
+		}
+		defer conn.Close()
+
+		// the client sends nothing, reading detects the close
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		liveSubscribers.WithLabelValues("ws").Inc()
+		defer liveSubscribers.WithLabelValues("ws").Dec()
+		err = h.stream(filter, backfill, done, func() error {
+			return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+		}, func(visit *server.Visit) error {
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			return conn.WriteJSON(visit)
+		})
+		if err != nil {
+			log.Warnf("websocket stream to %s stopped, errors:%+v", r.RemoteAddr, err)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package live
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/infinivision/filesyncer/pkg/export"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret"
+
+// newTestHub returns a hub of the token testToken
+func newTestHub(buffer int, backfill Backfill) *Hub {
+	h := NewHub(buffer, backfill, time.Hour)
+	h.SetAuth(nil, testToken)
+	return h
+}
+
+func waitSubscribers(t *testing.T, h *Hub, n int) {
+	for i := 0; i < 100; i++ {
+		if h.Len() == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expect %d subscribers, got %d", n, h.Len())
+}
+
+// readEvents reads n SSE events of the stream
+func readEvents(t *testing.T, r *bufio.Reader, n int) (visits []*server.Visit) {
+	for len(visits) < n {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		visit := &server.Visit{}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), visit))
+		visits = append(visits, visit)
+	}
+	return
+}
+
+func TestSSE(t *testing.T) {
+	h := newTestHub(16, nil)
+	ts := httptest.NewServer(h.SSEHandler())
+	defer ts.Close()
+
+	rsp, err := http.Get(ts.URL + "?shops=8&positions=1,2&token=" + testToken)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "text/event-stream", rsp.Header.Get("Content-Type"))
+	waitSubscribers(t, h, 1)
+
+	h.Publish(
+		&server.Visit{PictureId: "obj1", Shop: 8, Position: 1},
+		&server.Visit{PictureId: "obj2", Shop: 9, Position: 1},
+		&server.Visit{PictureId: "obj3", Shop: 8, Position: 3},
+		&server.Visit{PictureId: "obj4", Shop: 8, Position: 2},
+	)
+	visits := readEvents(t, bufio.NewReader(rsp.Body), 2)
+	require.Equal(t, "obj1", visits[0].PictureId)
+	require.Equal(t, "obj4", visits[1].PictureId)
+
+	rsp.Body.Close()
+	waitSubscribers(t, h, 0)
+}
+
+func TestSSEBadRequest(t *testing.T) {
+	h := newTestHub(16, nil)
+	ts := httptest.NewServer(h.SSEHandler())
+	defer ts.Close()
+
+	for _, q := range []string{"?shops=x", "?backfill=-1", "?backfill=10"} {
+		rsp, err := http.Get(ts.URL + q + "&token=" + testToken)
+		require.NoError(t, err)
+		rsp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, rsp.StatusCode, q)
+	}
+}
+
+func TestSSEBackfill(t *testing.T) {
+	var since int64
+	backfill := func(s int64) ([]*server.Visit, error) {
+		since = s
+		return []*server.Visit{
+			{PictureId: "old1", Shop: 8},
+			{PictureId: "old2", Shop: 9},
+			{PictureId: "old3", Shop: 8},
+		}, nil
+	}
+	h := newTestHub(16, backfill)
+	ts := httptest.NewServer(h.SSEHandler())
+	defer ts.Close()
+
+	rsp, err := http.Get(ts.URL + "?shops=8&backfill=10&token=" + testToken)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	r := bufio.NewReader(rsp.Body)
+	visits := readEvents(t, r, 2)
+	require.Equal(t, "old1", visits[0].PictureId)
+	require.Equal(t, "old3", visits[1].PictureId)
+	require.InDelta(t, time.Now().Add(-10*time.Minute).Unix(), since, 2)
+
+	// a visit published while backfilling is sent once
+	h.Publish(&server.Visit{PictureId: "old3", Shop: 8}, &server.Visit{PictureId: "new1", Shop: 8})
+	visits = readEvents(t, r, 1)
+	require.Equal(t, "new1", visits[0].PictureId)
+}
+
+func TestWebSocket(t *testing.T) {
+	h := newTestHub(16, nil)
+	ts := httptest.NewServer(h.WebSocketHandler())
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"?shops=8&token="+testToken, nil)
+	require.NoError(t, err)
+	waitSubscribers(t, h, 1)
+
+	h.Publish(&server.Visit{PictureId: "obj1", Shop: 9}, &server.Visit{PictureId: "obj2", Shop: 8, Uid: 12})
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var visit server.Visit
+	require.NoError(t, conn.ReadJSON(&visit))
+	require.Equal(t, "obj2", visit.PictureId)
+	require.Equal(t, uint64(12), visit.Uid)
+
+	require.NoError(t, conn.Close())
+	waitSubscribers(t, h, 0)
+}
+
+func TestPublishNeverBlocks(t *testing.T) {
+	h := NewHub(1, nil, time.Hour)
+	sub := h.subscribe(&export.Filter{Gender: export.AnyGender})
+	defer h.unsubscribe(sub)
+	h.Publish(&server.Visit{PictureId: "obj1"}, &server.Visit{PictureId: "obj2"})
+	require.Equal(t, "obj1", (<-sub.ch).PictureId)
+	require.Equal(t, 0, len(sub.ch))
+}
+
+func TestAuth(t *testing.T) {
+	h := NewHub(16, nil, time.Hour)
+	h.SetAuth([]string{"http://dashboard.example.com"}, "secret")
+	for _, handler := range []http.Handler{h.SSEHandler(), h.WebSocketHandler()} {
+		ts := httptest.NewServer(handler)
+		get := func(q string, header http.Header) int {
+			req, err := http.NewRequest("GET", ts.URL+q, nil)
+			require.NoError(t, err)
+			req.Header = header
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			rsp.Body.Close()
+			return rsp.StatusCode
+		}
+		require.Equal(t, http.StatusUnauthorized, get("", http.Header{}))
+		require.Equal(t, http.StatusUnauthorized, get("?token=x", http.Header{}))
+		require.Equal(t, http.StatusForbidden, get("?token=secret", http.Header{"Origin": {"http://evil.example.com"}}))
+		ts.Close()
+	}
+
+	// disabled without a token
+	h2 := NewHub(16, nil, time.Hour)
+	ts := httptest.NewServer(h2.SSEHandler())
+	rsp, err := http.Get(ts.URL)
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusForbidden, rsp.StatusCode)
+	ts.Close()
+
+	// a dashboard of the allowed origin with the token
+	ts = httptest.NewServer(h.WebSocketHandler())
+	defer ts.Close()
+	_, _, err = websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"?token=secret",
+		http.Header{"Origin": {"http://evil.example.com"}})
+	require.Error(t, err)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"?shops=8",
+		http.Header{"Origin": {"http://dashboard.example.com"}, "Authorization": {"Bearer secret"}})
+	require.NoError(t, err)
+	waitSubscribers(t, h, 1)
+	require.NoError(t, conn.Close())
+	waitSubscribers(t, h, 0)
+}
@@ -25,6 +25,7 @@ type Cfg struct {
 	RetriesInterval  time.Duration
 	RetriesPerServer int
 	UsageInterval    time.Duration
+	LogFile          string
 }
 
 // LastFileName returns file name that store the process info
@@ -32,11 +33,40 @@ func (c *Cfg) LastFileName() string {
 	return fmt.Sprintf("%s/.last", c.Target)
 }
 
-func (c *Cfg) getFiles() ([]string, error) {
-	var files []string
+// backlog is the files waiting for upload
+type backlog struct {
+	count  int
+	bytes  int64
+	oldest time.Time
+}
+
+// getFiles returns at most batch files, and the backlog found in the same walk
+func (c *Cfg) getFiles(batch int) (files []string, bl backlog, err error) {
+	err = c.walkFiles(func(path string, f os.FileInfo) {
+		if len(files) < batch {
+			files = append(files, path)
+		}
+		bl.count++
+		bl.bytes += f.Size()
+		if bl.oldest.IsZero() || f.ModTime().Before(bl.oldest) {
+			bl.oldest = f.ModTime()
+		}
+	})
+
+	return
+}
 
+func (c *Cfg) walkFiles(fn func(path string, f os.FileInfo)) error {
 	// Walk the file tree rooted at c.Target, skip subdirectories who's depth is larger than 1.
-	err := filepath.Walk(c.Target, func(path string, f os.FileInfo, err error) error {
+	return filepath.Walk(c.Target, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			if path == c.Target {
+				return err
+			}
+			// the file may be removed after uploaded
+			return nil
+		}
+
 		if f.IsDir() {
 			if path == c.Target {
 				return nil
@@ -52,11 +82,7 @@ func (c *Cfg) getFiles() ([]string, error) {
 		if path == c.LastFileName() {
 			return nil
 		}
-		if len(files) < c.BatchFetch {
-			files = append(files, path)
-		}
+		fn(path, f)
 		return nil
 	})
-
-	return files, err
 }
@@ -0,0 +1,185 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/infinivision/filesyncer/pkg/pb"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxLogUpload is the max bytes of the log file tail sent to the server
+	maxLogUpload = 1024 * 1024
+)
+
+func (m *Monitor) handleCommand(addr string, cmd *pb.Command) {
+	log.Infof("command-%d: received %s %q from %s",
+		cmd.ID,
+		cmd.Type,
+		cmd.Value,
+		addr)
+
+	m.doSend(addr, &pb.CommandAck{
+		ID:  cmd.ID,
+		Mac: m.cfg.ID,
+	})
+
+	rsp := &pb.CommandRsp{
+		ID:   cmd.ID,
+		Mac:  m.cfg.ID,
+		Code: pb.CodeSucc,
+	}
+
+	switch cmd.Type {
+	case pb.CommandSetLogLevel:
+		m.setLogLevel(cmd.Value, rsp)
+	case pb.CommandSetRateLimit:
+		m.setRateLimit(cmd.Value, rsp)
+	case pb.CommandSetBatchSize:
+		m.setBatchSize(cmd.Value, rsp)
+	case pb.CommandRescan:
+		m.rescan(rsp)
+	case pb.CommandUploadLog:
+		m.uploadLog(rsp)
+	default:
+		rsp.Code = pb.CodeUnsupported
+		rsp.Result = fmt.Sprintf("unsupported command type %d", cmd.Type)
+	}
+
+	log.Infof("command-%d: complete with %s %s",
+		cmd.ID,
+		rsp.Code,
+		rsp.Result)
+	m.doSend(addr, rsp)
+}
+
+func (m *Monitor) setLogLevel(value string, rsp *pb.CommandRsp) {
+	switch value {
+	case "fatal", "error", "warn", "warning", "info", "debug":
+		log.SetLevelByString(value)
+		rsp.Result = fmt.Sprintf("log level changed to %s", value)
+	default:
+		rsp.Code = pb.CodeInvalidArgs
+		rsp.Result = fmt.Sprintf("invalid log level %q", value)
+	}
+}
+
+func (m *Monitor) setRateLimit(value string, rsp *pb.CommandRsp) {
+	kb, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || kb*1024 < m.cfg.Chunk {
+		rsp.Code = pb.CodeInvalidArgs
+		rsp.Result = fmt.Sprintf("invalid rate limit %q, expect KB not less than one chunk", value)
+		return
+	}
+
+	m.Lock()
+	m.cfg.LimitTraffic = kb * 1024
+	m.limiter = newLimiter(m.cfg.LimitTraffic, m.cfg.Chunk)
+	m.Unlock()
+	rsp.Result = fmt.Sprintf("rate limit changed to %d KB", kb)
+}
+
+func (m *Monitor) setBatchSize(value string, rsp *pb.CommandRsp) {
+	// a batch is queued to readyC at once, a larger one blocks the fetch
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 || n > bufC {
+		rsp.Code = pb.CodeInvalidArgs
+		rsp.Result = fmt.Sprintf("invalid batch size %q, expect 1 - %d", value, bufC)
+		return
+	}
+
+	m.Lock()
+	m.cfg.BatchFetch = n
+	m.Unlock()
+	rsp.Result = fmt.Sprintf("batch size changed to %d", n)
+}
+
+// rescan fetches the target dir immediately if the monitor is waiting for the next fetch.
+// If some files are still in uploading, the rescan is rejected to avoid duplicate upload.
+// The fetch walks the target dir, so it runs apart from the session read loop.
+func (m *Monitor) rescan(rsp *pb.CommandRsp) {
+	m.RLock()
+	t := m.fetchTimeout
+	m.RUnlock()
+
+	if !t.Stop() {
+		rsp.Code = pb.CodeBusy
+		rsp.Result = "files are in uploading, retry later"
+		return
+	}
+
+	go m.doFetchFiles(nil)
+	rsp.Result = "rescan triggered"
+}
+
+func (m *Monitor) uploadLog(rsp *pb.CommandRsp) {
+	if m.cfg.LogFile == "" {
+		rsp.Code = pb.CodeUnsupported
+		rsp.Result = "log to console, no log file"
+		return
+	}
+
+	data, err := readTail(m.cfg.LogFile, maxLogUpload)
+	if err != nil {
+		rsp.Code = pb.CodeFailed
+		rsp.Result = fmt.Sprintf("read %s failed, errors:%+v", m.cfg.LogFile, err)
+		return
+	}
+
+	rsp.Data = data
+	rsp.Result = fmt.Sprintf("%s last %d bytes", m.cfg.LogFile, len(data))
+}
+
+func (m *Monitor) getLimiter() *rate.Limiter {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.limiter
+}
+
+func (m *Monitor) getBatchFetch() int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.cfg.BatchFetch
+}
+
+func newLimiter(limitTraffic, chunk int64) *rate.Limiter {
+	n := int(limitTraffic / chunk)
+	return rate.NewLimiter(rate.Every(time.Second/time.Duration(n)), n)
+}
+
+func readTail(file string, max int64) ([]byte, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	info, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := info.Size() - max
+	if offset < 0 {
+		offset = 0
+	}
+
+	if _, err = fd.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, info.Size()-offset)
+	n, err := io.ReadFull(fd, data)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+
+	return data[:n], nil
+}
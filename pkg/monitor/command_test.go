@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/infinivision/filesyncer/pkg/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMonitor(t *testing.T) *Monitor {
+	return NewMonitor(&Cfg{
+		Target:          t.TempDir(),
+		MonitorInterval: time.Hour,
+		BatchFetch:      10,
+		LimitTraffic:    1024 * 1024,
+		Chunk:           64 * 1024,
+	})
+}
+
+func TestSetBatchSize(t *testing.T) {
+	m := newTestMonitor(t)
+	for _, c := range []struct {
+		value string
+		code  pb.Code
+		batch int
+	}{
+		{"20", pb.CodeSucc, 20},
+		{"0", pb.CodeInvalidArgs, 20},
+		{"x", pb.CodeInvalidArgs, 20},
+		{"129", pb.CodeInvalidArgs, 20},
+		{"128", pb.CodeSucc, 128},
+	} {
+		rsp := &pb.CommandRsp{Code: pb.CodeSucc}
+		m.setBatchSize(c.value, rsp)
+		require.Equal(t, c.code, rsp.Code, c.value)
+		require.Equal(t, c.batch, m.getBatchFetch())
+	}
+}
+
+func TestSetRateLimit(t *testing.T) {
+	m := newTestMonitor(t)
+	rsp := &pb.CommandRsp{Code: pb.CodeSucc}
+	m.setRateLimit("32", rsp)
+	require.Equal(t, pb.CodeInvalidArgs, rsp.Code)
+
+	rsp = &pb.CommandRsp{Code: pb.CodeSucc}
+	m.setRateLimit("2048", rsp)
+	require.Equal(t, pb.CodeSucc, rsp.Code)
+	require.Equal(t, int64(2048*1024), m.cfg.LimitTraffic)
+}
+
+func TestRescan(t *testing.T) {
+	m := newTestMonitor(t)
+	file := filepath.Join(m.cfg.Target, "a.jpg")
+	require.NoError(t, ioutil.WriteFile(file, []byte("jpg"), 0644))
+
+	// waiting for the next fetch
+	m.triggerFetch()
+	rsp := &pb.CommandRsp{Code: pb.CodeSucc}
+	done := make(chan struct{})
+	go func() {
+		m.rescan(rsp)
+		close(done)
+	}()
+	// the fetch blocks on completeC since nobody waits for it, the command doesn't
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("rescan blocks on the fetch")
+	}
+	require.Equal(t, pb.CodeSucc, rsp.Code)
+	select {
+	case <-m.completeC:
+	case <-time.After(time.Second):
+		t.Fatal("rescan doesn't fetch")
+	}
+	require.Equal(t, file, <-m.readyC)
+
+	// files are in uploading
+	rsp = &pb.CommandRsp{Code: pb.CodeSucc}
+	m.rescan(rsp)
+	require.Equal(t, pb.CodeBusy, rsp.Code)
+}
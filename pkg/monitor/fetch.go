@@ -26,17 +26,29 @@ func (m *Monitor) startWaittingCompleteTask() {
 }
 
 func (m *Monitor) triggerFetch() {
-	m.tw.Schedule(m.cfg.MonitorInterval, m.doFetchFiles, nil)
+	t, err := m.tw.Schedule(m.cfg.MonitorInterval, m.doFetchFiles, nil)
+	if err != nil {
+		log.Errorf("fetch: schedule failed, errors:%+v", err)
+		return
+	}
+
+	m.Lock()
+	m.fetchTimeout = t
+	m.Unlock()
 }
 
 func (m *Monitor) doFetchFiles(arg interface{}) {
 	log.Debugf("fetch: do")
-	files, err := m.cfg.getFiles()
+	files, bl, err := m.cfg.getFiles(m.getBatchFetch())
 	if err != nil {
 		log.Errorf("fetch: fetch files failed, errors:%+v", err)
 		return
 	}
 
+	m.Lock()
+	m.backlog = bl
+	m.Unlock()
+
 	log.Infof("fetch: get files: %+v", files)
 
 	// If empty, later retry. Otherwise, wait complete notify.
@@ -52,6 +64,15 @@ func (m *Monitor) doFetchFiles(arg interface{}) {
 	}
 }
 
+// getBacklog returns the backlog found by the last fetch, the sys usage report
+// doesn't walk the target dir again
+func (m *Monitor) getBacklog() backlog {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.backlog
+}
+
 func (m *Monitor) resetCompleteWG(count int) {
 	m.completeWG = &sync.WaitGroup{}
 	m.completeWG.Add(count)
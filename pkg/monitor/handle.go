@@ -113,6 +113,7 @@ func (m *Monitor) handleUploadRsp(msg *pb.UploadRsp) {
 		stat := m.getUploadingStat(msg.ID)
 		m.uploadings.Delete(msg.ID)
 		stat.close(false)
+		m.failed.Incr()
 
 		// retry with init upload, and choose another server
 		m.addFile(stat.file)
@@ -140,12 +141,14 @@ func (m *Monitor) handleUploadCompleteRsp(msg *pb.UploadCompleteRsp) {
 		msg.Code == pb.CodeMaxRetries ||
 		msg.Code == pb.CodeMissing {
 		stat.close(false)
+		m.failed.Incr()
 		// retry with init upload, and choose another server
 		m.addFile(stat.file)
 		return
 	}
 
 	stat.close(true)
+	m.succ.Incr()
 	m.completeNotify()
 }
 
@@ -156,7 +159,7 @@ func (m *Monitor) handleNextChunk(stat *status) {
 		return
 	}
 
-	m.limiter.Wait(context.Background())
+	m.getLimiter().Wait(context.Background())
 	m.sendUploading(stat.id, &pb.UploadReq{
 		ID:    stat.id,
 		Index: idx,
@@ -170,6 +173,7 @@ func (m *Monitor) sendInit(msg *pb.InitUploadReq) {
 	if err != nil {
 		stat.close(false)
 		m.prepares.Delete(msg.Seq)
+		m.failed.Incr()
 
 		// retry after a while
 		time.Sleep(5 * time.Second)
@@ -192,6 +196,7 @@ func (m *Monitor) sendUploading(id uint64, msg interface{}) {
 				stat.retries)
 			m.uploadings.Delete(id)
 			stat.close(false)
+			m.failed.Incr()
 
 			// retry with init upload, and choose another server
 			m.addFile(stat.file)
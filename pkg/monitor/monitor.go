@@ -21,16 +21,18 @@ type Monitor struct {
 	sync.RWMutex
 
 	cfg                  *Cfg
-	rate                 *rate.Limiter
 	runner               *task.Runner
 	tw                   *goetty.TimeoutWheel
 	pool                 *goetty.AddressBasedPool
 	idx, fileSeq         *atomic.Uint64
+	succ, failed         *atomic.Uint64
+	backlog              backlog
 	fileServers          []string
 	prepares, uploadings *sync.Map
 	readyC               chan string
 	completeC            chan *sync.WaitGroup
 	completeWG           *sync.WaitGroup
+	fetchTimeout         goetty.Timeout
 
 	limiter *rate.Limiter
 }
@@ -65,13 +67,13 @@ func (m *Monitor) init() {
 	m.pool = goetty.NewAddressBasedPool(m.connFactory, m)
 	m.idx = &atomic.Uint64{}
 	m.fileSeq = &atomic.Uint64{}
+	m.succ = &atomic.Uint64{}
+	m.failed = &atomic.Uint64{}
 	m.prepares = &sync.Map{}
 	m.uploadings = &sync.Map{}
 	m.readyC = make(chan string, bufC)
 	m.completeC = make(chan *sync.WaitGroup)
-
-	n := int(m.cfg.LimitTraffic / m.cfg.Chunk)
-	m.limiter = rate.NewLimiter(rate.Every(time.Second/time.Duration(n)), int(n))
+	m.limiter = newLimiter(m.cfg.LimitTraffic, m.cfg.Chunk)
 }
 
 func (m *Monitor) startRefreshTask() {
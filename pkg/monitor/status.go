@@ -54,7 +54,8 @@ func (stat *status) read(chunkSize int64) ([]byte, int32, error) {
 	if err != nil && err != io.EOF {
 		log.Errorf("read %s for %d chunk failed, errors:%+v",
 			stat.file,
-			stat.nextIdx)
+			stat.nextIdx,
+			err)
 		return nil, 0, err
 	}
 
@@ -72,6 +72,8 @@ func (m *Monitor) startReadLoop(addr string, conn goetty.IOSession) {
 			m.handleUploadRsp(value)
 		} else if value, ok := msg.(*pb.UploadCompleteRsp); ok {
 			m.handleUploadCompleteRsp(value)
+		} else if value, ok := msg.(*pb.Command); ok {
+			m.handleCommand(addr, value)
 		}
 	}
 }
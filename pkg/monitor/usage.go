@@ -1,6 +1,11 @@
 package monitor
 
 import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fagongzi/goetty"
@@ -8,10 +13,17 @@ import (
 	"github.com/pkg/errors"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/host"
 	"github.com/shirou/gopsutil/load"
 	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
 
 	"github.com/infinivision/filesyncer/pkg/pb"
+	"github.com/infinivision/filesyncer/pkg/version"
+)
+
+const (
+	thermalDir = "/sys/class/thermal"
 )
 
 var (
@@ -60,6 +72,97 @@ func (m *Monitor) getSysUsage() (usage *pb.SysUsage, err2 error) {
 	} else {
 		usage.LoadAverage1 = avg.Load1 //load average 1 minute
 	}
+
+	usage.Version = fmt.Sprintf("%s-%s", version.Version, version.GitSHA)
+
+	// The following items are best effort, a failure doesn't drop the whole report.
+	bl := m.getBacklog()
+	usage.QueueDepth = uint32(bl.count)
+	usage.BacklogBytes = uint64(bl.bytes)
+	if bl.count > 0 {
+		usage.OldestPendingAge = int64(time.Since(bl.oldest).Seconds())
+	}
+
+	if uptime, err := host.Uptime(); err != nil {
+		log.Warnf("get uptime failed with error: %+v", err)
+	} else {
+		usage.Uptime = uptime
+	}
+
+	if counters, err := net.IOCounters(true); err != nil {
+		log.Warnf("get net counters failed with error: %+v", err)
+	} else {
+		for _, c := range counters {
+			if c.Name == "lo" {
+				continue
+			}
+			usage.Nets = append(usage.Nets, pb.NetCounters{
+				Name:        c.Name,
+				BytesSent:   c.BytesSent,
+				BytesRecv:   c.BytesRecv,
+				PacketsSent: c.PacketsSent,
+				PacketsRecv: c.PacketsRecv,
+				Errin:       c.Errin,
+				Errout:      c.Errout,
+			})
+		}
+	}
+
+	if temp, err := getCpuTemperature(thermalDir); err != nil {
+		log.Debugf("get CPU temperature failed with error: %+v", err)
+	} else {
+		usage.CpuTemperature = temp
+	}
+
+	// cumulative totals since started, not deltas, a lost report loses nothing and every server gets the same
+	usage.UploadSuccTotal = m.succ.Get()
+	usage.UploadFailedTotal = m.failed.Get()
+	return
+}
+
+// getCpuTemperature returns the max temperature in Celsius of the CPU thermal zones.
+// All zones are considered if none of them is typed as CPU.
+func getCpuTemperature(dir string) (temp float64, err error) {
+	var zones []string
+	if zones, err = filepath.Glob(filepath.Join(dir, "thermal_zone*")); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+
+	var cpuTemps, allTemps []float64
+	for _, zone := range zones {
+		var data []byte
+		if data, err = ioutil.ReadFile(filepath.Join(zone, "temp")); err != nil {
+			continue
+		}
+		var milli int64
+		if milli, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err != nil {
+			continue
+		}
+		value := float64(milli) / 1000
+		allTemps = append(allTemps, value)
+		if data, err = ioutil.ReadFile(filepath.Join(zone, "type")); err == nil &&
+			strings.Contains(strings.ToLower(string(data)), "cpu") {
+			cpuTemps = append(cpuTemps, value)
+		}
+	}
+	err = nil
+
+	temps := cpuTemps
+	if len(temps) == 0 {
+		temps = allTemps
+	}
+	if len(temps) == 0 {
+		err = errors.Errorf("no thermal zone found in %s", dir)
+		return
+	}
+
+	temp = temps[0]
+	for _, value := range temps[1:] {
+		if value > temp {
+			temp = value
+		}
+	}
 	return
 }
 
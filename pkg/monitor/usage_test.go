@@ -0,0 +1,43 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFilesBacklog(t *testing.T) {
+	m := newTestMonitor(t)
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(m.cfg.Target, name), []byte("jpg"), 0644))
+	}
+	files, bl, err := m.cfg.getFiles(2)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, 3, bl.count)
+	require.Equal(t, int64(9), bl.bytes)
+	require.False(t, bl.oldest.IsZero())
+}
+
+func TestSysUsageTotals(t *testing.T) {
+	m := newTestMonitor(t)
+	m.backlog = backlog{count: 3, bytes: 9}
+	m.succ.Add(5)
+	m.failed.Add(1)
+
+	usage, err := m.getSysUsage()
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), usage.QueueDepth)
+	require.Equal(t, uint64(9), usage.BacklogBytes)
+	require.Equal(t, uint64(5), usage.UploadSuccTotal)
+	require.Equal(t, uint64(1), usage.UploadFailedTotal)
+
+	// a report is the totals, not the delta since the last one
+	m.succ.Add(2)
+	usage, err = m.getSysUsage()
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), usage.UploadSuccTotal)
+	require.Equal(t, uint64(1), usage.UploadFailedTotal)
+}
@@ -1,33 +1,17 @@
 // Code generated by protoc-gen-gogo. DO NOT EDIT.
 // source: pb.proto
 
-/*
-	Package pb is a generated protocol buffer package.
-
-	It is generated from these files:
-		pb.proto
-
-	It has these top-level messages:
-		Heartbeat
-		InitUploadReq
-		InitUploadRsp
-		UploadReq
-		UploadRsp
-		UploadCompleteReq
-		UploadCompleteRsp
-		UploadContinue
-		SysUsage
-*/
 package pb
 
-import proto "github.com/golang/protobuf/proto"
-import fmt "fmt"
-import math "math"
-import _ "github.com/gogo/protobuf/gogoproto"
+import (
+	encoding_binary "encoding/binary"
+	fmt "fmt"
+	io "io"
+	math "math"
 
-import binary "encoding/binary"
-
-import io "io"
+	_ "github.com/gogo/protobuf/gogoproto"
+	proto "github.com/golang/protobuf/proto"
+)
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -50,6 +34,9 @@ const (
 	CodeInvalidChecksum Code = 4
 	CodeOSSError        Code = 5
 	CodeMaxRetries      Code = 6
+	CodeUnsupported     Code = 7
+	CodeInvalidArgs     Code = 8
+	CodeFailed          Code = 9
 )
 
 var Code_name = map[int32]string{
@@ -60,7 +47,11 @@ var Code_name = map[int32]string{
 	4: "CodeInvalidChecksum",
 	5: "CodeOSSError",
 	6: "CodeMaxRetries",
+	7: "CodeUnsupported",
+	8: "CodeInvalidArgs",
+	9: "CodeFailed",
 }
+
 var Code_value = map[string]int32{
 	"CodeSucc":            0,
 	"CodeBusy":            1,
@@ -69,6 +60,9 @@ var Code_value = map[string]int32{
 	"CodeInvalidChecksum": 4,
 	"CodeOSSError":        5,
 	"CodeMaxRetries":      6,
+	"CodeUnsupported":     7,
+	"CodeInvalidArgs":     8,
+	"CodeFailed":          9,
 }
 
 func (x Code) Enum() *Code {
@@ -76,9 +70,11 @@ func (x Code) Enum() *Code {
 	*p = x
 	return p
 }
+
 func (x Code) String() string {
 	return proto.EnumName(Code_name, int32(x))
 }
+
 func (x *Code) UnmarshalJSON(data []byte) error {
 	value, err := proto.UnmarshalJSONEnum(Code_value, data, "Code")
 	if err != nil {
@@ -87,7 +83,10 @@ func (x *Code) UnmarshalJSON(data []byte) error {
 	*x = Code(value)
 	return nil
 }
-func (Code) EnumDescriptor() ([]byte, []int) { return fileDescriptorPb, []int{0} }
+
+func (Code) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{0}
+}
 
 type Cmd int32
 
@@ -101,19 +100,26 @@ const (
 	CmdUploadCompleteRsp Cmd = 6
 	CmdUploadContinue    Cmd = 7
 	CmdSysUsage          Cmd = 8
+	CmdCommand           Cmd = 9
+	CmdCommandAck        Cmd = 10
+	CmdCommandRsp        Cmd = 11
 )
 
 var Cmd_name = map[int32]string{
-	0: "CmdHB",
-	1: "CmdUploadInit",
-	2: "CmdUploadInitRsp",
-	3: "CmdUpload",
-	4: "CmdUploadRsp",
-	5: "CmdUploadComplete",
-	6: "CmdUploadCompleteRsp",
-	7: "CmdUploadContinue",
-	8: "CmdSysUsage",
+	0:  "CmdHB",
+	1:  "CmdUploadInit",
+	2:  "CmdUploadInitRsp",
+	3:  "CmdUpload",
+	4:  "CmdUploadRsp",
+	5:  "CmdUploadComplete",
+	6:  "CmdUploadCompleteRsp",
+	7:  "CmdUploadContinue",
+	8:  "CmdSysUsage",
+	9:  "CmdCommand",
+	10: "CmdCommandAck",
+	11: "CmdCommandRsp",
 }
+
 var Cmd_value = map[string]int32{
 	"CmdHB":                0,
 	"CmdUploadInit":        1,
@@ -124,6 +130,9 @@ var Cmd_value = map[string]int32{
 	"CmdUploadCompleteRsp": 6,
 	"CmdUploadContinue":    7,
 	"CmdSysUsage":          8,
+	"CmdCommand":           9,
+	"CmdCommandAck":        10,
+	"CmdCommandRsp":        11,
 }
 
 func (x Cmd) Enum() *Cmd {
@@ -131,9 +140,11 @@ func (x Cmd) Enum() *Cmd {
 	*p = x
 	return p
 }
+
 func (x Cmd) String() string {
 	return proto.EnumName(Cmd_name, int32(x))
 }
+
 func (x *Cmd) UnmarshalJSON(data []byte) error {
 	value, err := proto.UnmarshalJSONEnum(Cmd_value, data, "Cmd")
 	if err != nil {
@@ -142,17 +153,99 @@ func (x *Cmd) UnmarshalJSON(data []byte) error {
 	*x = Cmd(value)
 	return nil
 }
-func (Cmd) EnumDescriptor() ([]byte, []int) { return fileDescriptorPb, []int{1} }
+
+func (Cmd) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{1}
+}
+
+type CommandType int32
+
+const (
+	CommandSetLogLevel  CommandType = 0
+	CommandSetRateLimit CommandType = 1
+	CommandSetBatchSize CommandType = 2
+	CommandRescan       CommandType = 3
+	CommandUploadLog    CommandType = 4
+)
+
+var CommandType_name = map[int32]string{
+	0: "CommandSetLogLevel",
+	1: "CommandSetRateLimit",
+	2: "CommandSetBatchSize",
+	3: "CommandRescan",
+	4: "CommandUploadLog",
+}
+
+var CommandType_value = map[string]int32{
+	"CommandSetLogLevel":  0,
+	"CommandSetRateLimit": 1,
+	"CommandSetBatchSize": 2,
+	"CommandRescan":       3,
+	"CommandUploadLog":    4,
+}
+
+func (x CommandType) Enum() *CommandType {
+	p := new(CommandType)
+	*p = x
+	return p
+}
+
+func (x CommandType) String() string {
+	return proto.EnumName(CommandType_name, int32(x))
+}
+
+func (x *CommandType) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(CommandType_value, data, "CommandType")
+	if err != nil {
+		return err
+	}
+	*x = CommandType(value)
+	return nil
+}
+
+func (CommandType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{2}
+}
 
 type Heartbeat struct {
-	Mac              string `protobuf:"bytes,1,opt,name=mac" json:"mac"`
-	XXX_unrecognized []byte `json:"-"`
+	Mac                  string   `protobuf:"bytes,1,opt,name=mac" json:"mac"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Heartbeat) Reset()         { *m = Heartbeat{} }
+func (m *Heartbeat) String() string { return proto.CompactTextString(m) }
+func (*Heartbeat) ProtoMessage()    {}
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{0}
+}
+func (m *Heartbeat) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Heartbeat) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Heartbeat.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Heartbeat) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Heartbeat.Merge(m, src)
+}
+func (m *Heartbeat) XXX_Size() int {
+	return m.Size()
+}
+func (m *Heartbeat) XXX_DiscardUnknown() {
+	xxx_messageInfo_Heartbeat.DiscardUnknown(m)
 }
 
-func (m *Heartbeat) Reset()                    { *m = Heartbeat{} }
-func (m *Heartbeat) String() string            { return proto.CompactTextString(m) }
-func (*Heartbeat) ProtoMessage()               {}
-func (*Heartbeat) Descriptor() ([]byte, []int) { return fileDescriptorPb, []int{0} }
+var xxx_messageInfo_Heartbeat proto.InternalMessageInfo
 
 func (m *Heartbeat) GetMac() string {
 	if m != nil {
@@ -162,20 +255,50 @@ func (m *Heartbeat) GetMac() string {
 }
 
 type InitUploadReq struct {
-	Seq              uint64 `protobuf:"varint,1,opt,name=seq" json:"seq"`
-	ContentType      string `protobuf:"bytes,2,opt,name=contentType" json:"contentType"`
-	ContentLength    int64  `protobuf:"varint,3,opt,name=contentLength" json:"contentLength"`
-	ChunkCount       int32  `protobuf:"varint,4,opt,name=chunkCount" json:"chunkCount"`
-	ModTime          int64  `protobuf:"varint,5,opt,name=modTime" json:"modTime"`
-	Camera           string `protobuf:"bytes,6,opt,name=camera" json:"camera"`
-	Mac              string `protobuf:"bytes,7,opt,name=mac" json:"mac"`
-	XXX_unrecognized []byte `json:"-"`
+	Seq                  uint64   `protobuf:"varint,1,opt,name=seq" json:"seq"`
+	ContentType          string   `protobuf:"bytes,2,opt,name=contentType" json:"contentType"`
+	ContentLength        int64    `protobuf:"varint,3,opt,name=contentLength" json:"contentLength"`
+	ChunkCount           int32    `protobuf:"varint,4,opt,name=chunkCount" json:"chunkCount"`
+	ModTime              int64    `protobuf:"varint,5,opt,name=modTime" json:"modTime"`
+	Camera               string   `protobuf:"bytes,6,opt,name=camera" json:"camera"`
+	Mac                  string   `protobuf:"bytes,7,opt,name=mac" json:"mac"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InitUploadReq) Reset()         { *m = InitUploadReq{} }
+func (m *InitUploadReq) String() string { return proto.CompactTextString(m) }
+func (*InitUploadReq) ProtoMessage()    {}
+func (*InitUploadReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{1}
+}
+func (m *InitUploadReq) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *InitUploadReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_InitUploadReq.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *InitUploadReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InitUploadReq.Merge(m, src)
+}
+func (m *InitUploadReq) XXX_Size() int {
+	return m.Size()
+}
+func (m *InitUploadReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_InitUploadReq.DiscardUnknown(m)
 }
 
-func (m *InitUploadReq) Reset()                    { *m = InitUploadReq{} }
-func (m *InitUploadReq) String() string            { return proto.CompactTextString(m) }
-func (*InitUploadReq) ProtoMessage()               {}
-func (*InitUploadReq) Descriptor() ([]byte, []int) { return fileDescriptorPb, []int{1} }
+var xxx_messageInfo_InitUploadReq proto.InternalMessageInfo
 
 func (m *InitUploadReq) GetSeq() uint64 {
 	if m != nil {
@@ -227,16 +350,46 @@ func (m *InitUploadReq) GetMac() string {
 }
 
 type InitUploadRsp struct {
-	Seq              uint64 `protobuf:"varint,1,opt,name=seq" json:"seq"`
-	ID               uint64 `protobuf:"varint,2,opt,name=id" json:"id"`
-	Code             Code   `protobuf:"varint,3,opt,name=code,enum=pb.Code" json:"code"`
-	XXX_unrecognized []byte `json:"-"`
+	Seq                  uint64   `protobuf:"varint,1,opt,name=seq" json:"seq"`
+	ID                   uint64   `protobuf:"varint,2,opt,name=id" json:"id"`
+	Code                 Code     `protobuf:"varint,3,opt,name=code,enum=pb.Code" json:"code"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InitUploadRsp) Reset()         { *m = InitUploadRsp{} }
+func (m *InitUploadRsp) String() string { return proto.CompactTextString(m) }
+func (*InitUploadRsp) ProtoMessage()    {}
+func (*InitUploadRsp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{2}
+}
+func (m *InitUploadRsp) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *InitUploadRsp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_InitUploadRsp.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *InitUploadRsp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InitUploadRsp.Merge(m, src)
+}
+func (m *InitUploadRsp) XXX_Size() int {
+	return m.Size()
+}
+func (m *InitUploadRsp) XXX_DiscardUnknown() {
+	xxx_messageInfo_InitUploadRsp.DiscardUnknown(m)
 }
 
-func (m *InitUploadRsp) Reset()                    { *m = InitUploadRsp{} }
-func (m *InitUploadRsp) String() string            { return proto.CompactTextString(m) }
-func (*InitUploadRsp) ProtoMessage()               {}
-func (*InitUploadRsp) Descriptor() ([]byte, []int) { return fileDescriptorPb, []int{2} }
+var xxx_messageInfo_InitUploadRsp proto.InternalMessageInfo
 
 func (m *InitUploadRsp) GetSeq() uint64 {
 	if m != nil {
@@ -260,16 +413,46 @@ func (m *InitUploadRsp) GetCode() Code {
 }
 
 type UploadReq struct {
-	ID               uint64 `protobuf:"varint,1,opt,name=id" json:"id"`
-	Index            int32  `protobuf:"varint,2,opt,name=index" json:"index"`
-	Data             []byte `protobuf:"bytes,3,opt,name=data" json:"data,omitempty"`
-	XXX_unrecognized []byte `json:"-"`
+	ID                   uint64   `protobuf:"varint,1,opt,name=id" json:"id"`
+	Index                int32    `protobuf:"varint,2,opt,name=index" json:"index"`
+	Data                 []byte   `protobuf:"bytes,3,opt,name=data" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UploadReq) Reset()         { *m = UploadReq{} }
+func (m *UploadReq) String() string { return proto.CompactTextString(m) }
+func (*UploadReq) ProtoMessage()    {}
+func (*UploadReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{3}
+}
+func (m *UploadReq) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *UploadReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_UploadReq.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *UploadReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UploadReq.Merge(m, src)
+}
+func (m *UploadReq) XXX_Size() int {
+	return m.Size()
+}
+func (m *UploadReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_UploadReq.DiscardUnknown(m)
 }
 
-func (m *UploadReq) Reset()                    { *m = UploadReq{} }
-func (m *UploadReq) String() string            { return proto.CompactTextString(m) }
-func (*UploadReq) ProtoMessage()               {}
-func (*UploadReq) Descriptor() ([]byte, []int) { return fileDescriptorPb, []int{3} }
+var xxx_messageInfo_UploadReq proto.InternalMessageInfo
 
 func (m *UploadReq) GetID() uint64 {
 	if m != nil {
@@ -293,16 +476,46 @@ func (m *UploadReq) GetData() []byte {
 }
 
 type UploadRsp struct {
-	ID               uint64 `protobuf:"varint,1,opt,name=id" json:"id"`
-	Index            int32  `protobuf:"varint,2,opt,name=index" json:"index"`
-	Code             Code   `protobuf:"varint,3,opt,name=code,enum=pb.Code" json:"code"`
-	XXX_unrecognized []byte `json:"-"`
+	ID                   uint64   `protobuf:"varint,1,opt,name=id" json:"id"`
+	Index                int32    `protobuf:"varint,2,opt,name=index" json:"index"`
+	Code                 Code     `protobuf:"varint,3,opt,name=code,enum=pb.Code" json:"code"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UploadRsp) Reset()         { *m = UploadRsp{} }
+func (m *UploadRsp) String() string { return proto.CompactTextString(m) }
+func (*UploadRsp) ProtoMessage()    {}
+func (*UploadRsp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{4}
+}
+func (m *UploadRsp) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *UploadRsp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_UploadRsp.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *UploadRsp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UploadRsp.Merge(m, src)
+}
+func (m *UploadRsp) XXX_Size() int {
+	return m.Size()
+}
+func (m *UploadRsp) XXX_DiscardUnknown() {
+	xxx_messageInfo_UploadRsp.DiscardUnknown(m)
 }
 
-func (m *UploadRsp) Reset()                    { *m = UploadRsp{} }
-func (m *UploadRsp) String() string            { return proto.CompactTextString(m) }
-func (*UploadRsp) ProtoMessage()               {}
-func (*UploadRsp) Descriptor() ([]byte, []int) { return fileDescriptorPb, []int{4} }
+var xxx_messageInfo_UploadRsp proto.InternalMessageInfo
 
 func (m *UploadRsp) GetID() uint64 {
 	if m != nil {
@@ -326,14 +539,44 @@ func (m *UploadRsp) GetCode() Code {
 }
 
 type UploadCompleteReq struct {
-	ID               uint64 `protobuf:"varint,1,opt,name=id" json:"id"`
-	XXX_unrecognized []byte `json:"-"`
+	ID                   uint64   `protobuf:"varint,1,opt,name=id" json:"id"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UploadCompleteReq) Reset()         { *m = UploadCompleteReq{} }
+func (m *UploadCompleteReq) String() string { return proto.CompactTextString(m) }
+func (*UploadCompleteReq) ProtoMessage()    {}
+func (*UploadCompleteReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{5}
+}
+func (m *UploadCompleteReq) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *UploadCompleteReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_UploadCompleteReq.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *UploadCompleteReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UploadCompleteReq.Merge(m, src)
+}
+func (m *UploadCompleteReq) XXX_Size() int {
+	return m.Size()
+}
+func (m *UploadCompleteReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_UploadCompleteReq.DiscardUnknown(m)
 }
 
-func (m *UploadCompleteReq) Reset()                    { *m = UploadCompleteReq{} }
-func (m *UploadCompleteReq) String() string            { return proto.CompactTextString(m) }
-func (*UploadCompleteReq) ProtoMessage()               {}
-func (*UploadCompleteReq) Descriptor() ([]byte, []int) { return fileDescriptorPb, []int{5} }
+var xxx_messageInfo_UploadCompleteReq proto.InternalMessageInfo
 
 func (m *UploadCompleteReq) GetID() uint64 {
 	if m != nil {
@@ -343,15 +586,45 @@ func (m *UploadCompleteReq) GetID() uint64 {
 }
 
 type UploadCompleteRsp struct {
-	ID               uint64 `protobuf:"varint,1,opt,name=id" json:"id"`
-	Code             Code   `protobuf:"varint,2,opt,name=code,enum=pb.Code" json:"code"`
-	XXX_unrecognized []byte `json:"-"`
+	ID                   uint64   `protobuf:"varint,1,opt,name=id" json:"id"`
+	Code                 Code     `protobuf:"varint,2,opt,name=code,enum=pb.Code" json:"code"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UploadCompleteRsp) Reset()         { *m = UploadCompleteRsp{} }
+func (m *UploadCompleteRsp) String() string { return proto.CompactTextString(m) }
+func (*UploadCompleteRsp) ProtoMessage()    {}
+func (*UploadCompleteRsp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{6}
+}
+func (m *UploadCompleteRsp) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *UploadCompleteRsp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_UploadCompleteRsp.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *UploadCompleteRsp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UploadCompleteRsp.Merge(m, src)
+}
+func (m *UploadCompleteRsp) XXX_Size() int {
+	return m.Size()
+}
+func (m *UploadCompleteRsp) XXX_DiscardUnknown() {
+	xxx_messageInfo_UploadCompleteRsp.DiscardUnknown(m)
 }
 
-func (m *UploadCompleteRsp) Reset()                    { *m = UploadCompleteRsp{} }
-func (m *UploadCompleteRsp) String() string            { return proto.CompactTextString(m) }
-func (*UploadCompleteRsp) ProtoMessage()               {}
-func (*UploadCompleteRsp) Descriptor() ([]byte, []int) { return fileDescriptorPb, []int{6} }
+var xxx_messageInfo_UploadCompleteRsp proto.InternalMessageInfo
 
 func (m *UploadCompleteRsp) GetID() uint64 {
 	if m != nil {
@@ -368,14 +641,44 @@ func (m *UploadCompleteRsp) GetCode() Code {
 }
 
 type UploadContinue struct {
-	ID               uint64 `protobuf:"varint,1,opt,name=id" json:"id"`
-	XXX_unrecognized []byte `json:"-"`
+	ID                   uint64   `protobuf:"varint,1,opt,name=id" json:"id"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UploadContinue) Reset()         { *m = UploadContinue{} }
+func (m *UploadContinue) String() string { return proto.CompactTextString(m) }
+func (*UploadContinue) ProtoMessage()    {}
+func (*UploadContinue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{7}
+}
+func (m *UploadContinue) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *UploadContinue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_UploadContinue.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *UploadContinue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UploadContinue.Merge(m, src)
+}
+func (m *UploadContinue) XXX_Size() int {
+	return m.Size()
+}
+func (m *UploadContinue) XXX_DiscardUnknown() {
+	xxx_messageInfo_UploadContinue.DiscardUnknown(m)
 }
 
-func (m *UploadContinue) Reset()                    { *m = UploadContinue{} }
-func (m *UploadContinue) String() string            { return proto.CompactTextString(m) }
-func (*UploadContinue) ProtoMessage()               {}
-func (*UploadContinue) Descriptor() ([]byte, []int) { return fileDescriptorPb, []int{7} }
+var xxx_messageInfo_UploadContinue proto.InternalMessageInfo
 
 func (m *UploadContinue) GetID() uint64 {
 	if m != nil {
@@ -385,21 +688,64 @@ func (m *UploadContinue) GetID() uint64 {
 }
 
 type SysUsage struct {
-	Mac              string  `protobuf:"bytes,1,opt,name=mac" json:"mac"`
-	CpuTotal         uint64  `protobuf:"varint,2,opt,name=CpuTotal" json:"CpuTotal"`
-	MemTotal         uint64  `protobuf:"varint,3,opt,name=MemTotal" json:"MemTotal"`
-	DiskTotal        uint64  `protobuf:"varint,4,opt,name=DiskTotal" json:"DiskTotal"`
-	CpuUsedPercent   uint32  `protobuf:"varint,5,opt,name=CpuUsedPercent" json:"CpuUsedPercent"`
-	MemUsedPercent   uint32  `protobuf:"varint,6,opt,name=MemUsedPercent" json:"MemUsedPercent"`
-	DiskUsedPercent  uint32  `protobuf:"varint,7,opt,name=DiskUsedPercent" json:"DiskUsedPercent"`
-	LoadAverage1     float64 `protobuf:"fixed64,8,opt,name=LoadAverage1" json:"LoadAverage1"`
-	XXX_unrecognized []byte  `json:"-"`
-}
-
-func (m *SysUsage) Reset()                    { *m = SysUsage{} }
-func (m *SysUsage) String() string            { return proto.CompactTextString(m) }
-func (*SysUsage) ProtoMessage()               {}
-func (*SysUsage) Descriptor() ([]byte, []int) { return fileDescriptorPb, []int{8} }
+	Mac             string  `protobuf:"bytes,1,opt,name=mac" json:"mac"`
+	CpuTotal        uint64  `protobuf:"varint,2,opt,name=CpuTotal" json:"CpuTotal"`
+	MemTotal        uint64  `protobuf:"varint,3,opt,name=MemTotal" json:"MemTotal"`
+	DiskTotal       uint64  `protobuf:"varint,4,opt,name=DiskTotal" json:"DiskTotal"`
+	CpuUsedPercent  uint32  `protobuf:"varint,5,opt,name=CpuUsedPercent" json:"CpuUsedPercent"`
+	MemUsedPercent  uint32  `protobuf:"varint,6,opt,name=MemUsedPercent" json:"MemUsedPercent"`
+	DiskUsedPercent uint32  `protobuf:"varint,7,opt,name=DiskUsedPercent" json:"DiskUsedPercent"`
+	LoadAverage1    float64 `protobuf:"fixed64,8,opt,name=LoadAverage1" json:"LoadAverage1"`
+	// files waiting for upload in the target dir
+	QueueDepth   uint32 `protobuf:"varint,9,opt,name=QueueDepth" json:"QueueDepth"`
+	BacklogBytes uint64 `protobuf:"varint,10,opt,name=BacklogBytes" json:"BacklogBytes"`
+	// age in seconds of the oldest file waiting for upload
+	OldestPendingAge int64 `protobuf:"varint,11,opt,name=OldestPendingAge" json:"OldestPendingAge"`
+	// cumulative uploads since the terminal started, not the delta since the last report
+	UploadSuccTotal   uint64        `protobuf:"varint,12,opt,name=UploadSuccTotal" json:"UploadSuccTotal"`
+	UploadFailedTotal uint64        `protobuf:"varint,13,opt,name=UploadFailedTotal" json:"UploadFailedTotal"`
+	Nets              []NetCounters `protobuf:"bytes,14,rep,name=Nets" json:"Nets"`
+	CpuTemperature    float64       `protobuf:"fixed64,15,opt,name=CpuTemperature" json:"CpuTemperature"`
+	// uptime in seconds
+	Uptime               uint64   `protobuf:"varint,16,opt,name=Uptime" json:"Uptime"`
+	Version              string   `protobuf:"bytes,17,opt,name=Version" json:"Version"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SysUsage) Reset()         { *m = SysUsage{} }
+func (m *SysUsage) String() string { return proto.CompactTextString(m) }
+func (*SysUsage) ProtoMessage()    {}
+func (*SysUsage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{8}
+}
+func (m *SysUsage) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SysUsage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SysUsage.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SysUsage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SysUsage.Merge(m, src)
+}
+func (m *SysUsage) XXX_Size() int {
+	return m.Size()
+}
+func (m *SysUsage) XXX_DiscardUnknown() {
+	xxx_messageInfo_SysUsage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SysUsage proto.InternalMessageInfo
 
 func (m *SysUsage) GetMac() string {
 	if m != nil {
@@ -457,183 +803,467 @@ func (m *SysUsage) GetLoadAverage1() float64 {
 	return 0
 }
 
-func init() {
-	proto.RegisterType((*Heartbeat)(nil), "pb.Heartbeat")
-	proto.RegisterType((*InitUploadReq)(nil), "pb.InitUploadReq")
-	proto.RegisterType((*InitUploadRsp)(nil), "pb.InitUploadRsp")
-	proto.RegisterType((*UploadReq)(nil), "pb.UploadReq")
-	proto.RegisterType((*UploadRsp)(nil), "pb.UploadRsp")
-	proto.RegisterType((*UploadCompleteReq)(nil), "pb.UploadCompleteReq")
-	proto.RegisterType((*UploadCompleteRsp)(nil), "pb.UploadCompleteRsp")
-	proto.RegisterType((*UploadContinue)(nil), "pb.UploadContinue")
-	proto.RegisterType((*SysUsage)(nil), "pb.SysUsage")
-	proto.RegisterEnum("pb.Code", Code_name, Code_value)
-	proto.RegisterEnum("pb.Cmd", Cmd_name, Cmd_value)
+func (m *SysUsage) GetQueueDepth() uint32 {
+	if m != nil {
+		return m.QueueDepth
+	}
+	return 0
 }
-func (m *Heartbeat) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+
+func (m *SysUsage) GetBacklogBytes() uint64 {
+	if m != nil {
+		return m.BacklogBytes
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *Heartbeat) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	dAtA[i] = 0xa
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(len(m.Mac)))
-	i += copy(dAtA[i:], m.Mac)
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+func (m *SysUsage) GetOldestPendingAge() int64 {
+	if m != nil {
+		return m.OldestPendingAge
 	}
-	return i, nil
+	return 0
 }
 
-func (m *InitUploadReq) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (m *SysUsage) GetUploadSuccTotal() uint64 {
+	if m != nil {
+		return m.UploadSuccTotal
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *InitUploadReq) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	dAtA[i] = 0x8
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.Seq))
-	dAtA[i] = 0x12
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(len(m.ContentType)))
-	i += copy(dAtA[i:], m.ContentType)
-	dAtA[i] = 0x18
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.ContentLength))
-	dAtA[i] = 0x20
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.ChunkCount))
-	dAtA[i] = 0x28
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.ModTime))
-	dAtA[i] = 0x32
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(len(m.Camera)))
-	i += copy(dAtA[i:], m.Camera)
-	dAtA[i] = 0x3a
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(len(m.Mac)))
-	i += copy(dAtA[i:], m.Mac)
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+func (m *SysUsage) GetUploadFailedTotal() uint64 {
+	if m != nil {
+		return m.UploadFailedTotal
 	}
-	return i, nil
+	return 0
 }
 
-func (m *InitUploadRsp) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (m *SysUsage) GetNets() []NetCounters {
+	if m != nil {
+		return m.Nets
 	}
-	return dAtA[:n], nil
+	return nil
 }
 
-func (m *InitUploadRsp) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	dAtA[i] = 0x8
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.Seq))
-	dAtA[i] = 0x10
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.ID))
-	dAtA[i] = 0x18
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.Code))
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+func (m *SysUsage) GetCpuTemperature() float64 {
+	if m != nil {
+		return m.CpuTemperature
 	}
-	return i, nil
+	return 0
 }
 
-func (m *UploadReq) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (m *SysUsage) GetUptime() uint64 {
+	if m != nil {
+		return m.Uptime
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *UploadReq) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	dAtA[i] = 0x8
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.ID))
-	dAtA[i] = 0x10
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.Index))
-	if m.Data != nil {
-		dAtA[i] = 0x1a
-		i++
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Data)))
-		i += copy(dAtA[i:], m.Data)
+func (m *SysUsage) GetVersion() string {
+	if m != nil {
+		return m.Version
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	return ""
+}
+
+type NetCounters struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=Name" json:"Name"`
+	BytesSent            uint64   `protobuf:"varint,2,opt,name=BytesSent" json:"BytesSent"`
+	BytesRecv            uint64   `protobuf:"varint,3,opt,name=BytesRecv" json:"BytesRecv"`
+	PacketsSent          uint64   `protobuf:"varint,4,opt,name=PacketsSent" json:"PacketsSent"`
+	PacketsRecv          uint64   `protobuf:"varint,5,opt,name=PacketsRecv" json:"PacketsRecv"`
+	Errin                uint64   `protobuf:"varint,6,opt,name=Errin" json:"Errin"`
+	Errout               uint64   `protobuf:"varint,7,opt,name=Errout" json:"Errout"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NetCounters) Reset()         { *m = NetCounters{} }
+func (m *NetCounters) String() string { return proto.CompactTextString(m) }
+func (*NetCounters) ProtoMessage()    {}
+func (*NetCounters) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{9}
+}
+func (m *NetCounters) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *NetCounters) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_NetCounters.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	return i, nil
+}
+func (m *NetCounters) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NetCounters.Merge(m, src)
+}
+func (m *NetCounters) XXX_Size() int {
+	return m.Size()
+}
+func (m *NetCounters) XXX_DiscardUnknown() {
+	xxx_messageInfo_NetCounters.DiscardUnknown(m)
 }
 
-func (m *UploadRsp) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+var xxx_messageInfo_NetCounters proto.InternalMessageInfo
+
+func (m *NetCounters) GetName() string {
+	if m != nil {
+		return m.Name
 	}
-	return dAtA[:n], nil
+	return ""
 }
 
-func (m *UploadRsp) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	dAtA[i] = 0x8
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.ID))
-	dAtA[i] = 0x10
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.Index))
-	dAtA[i] = 0x18
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.Code))
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+func (m *NetCounters) GetBytesSent() uint64 {
+	if m != nil {
+		return m.BytesSent
 	}
-	return i, nil
+	return 0
 }
 
-func (m *UploadCompleteReq) Marshal() (dAtA []byte, err error) {
+func (m *NetCounters) GetBytesRecv() uint64 {
+	if m != nil {
+		return m.BytesRecv
+	}
+	return 0
+}
+
+func (m *NetCounters) GetPacketsSent() uint64 {
+	if m != nil {
+		return m.PacketsSent
+	}
+	return 0
+}
+
+func (m *NetCounters) GetPacketsRecv() uint64 {
+	if m != nil {
+		return m.PacketsRecv
+	}
+	return 0
+}
+
+func (m *NetCounters) GetErrin() uint64 {
+	if m != nil {
+		return m.Errin
+	}
+	return 0
+}
+
+func (m *NetCounters) GetErrout() uint64 {
+	if m != nil {
+		return m.Errout
+	}
+	return 0
+}
+
+// Command is sent by the server to a terminal
+type Command struct {
+	ID                   uint64      `protobuf:"varint,1,opt,name=id" json:"id"`
+	Mac                  string      `protobuf:"bytes,2,opt,name=mac" json:"mac"`
+	Type                 CommandType `protobuf:"varint,3,opt,name=type,enum=pb.CommandType" json:"type"`
+	Value                string      `protobuf:"bytes,4,opt,name=value" json:"value"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *Command) Reset()         { *m = Command{} }
+func (m *Command) String() string { return proto.CompactTextString(m) }
+func (*Command) ProtoMessage()    {}
+func (*Command) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{10}
+}
+func (m *Command) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Command) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Command.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Command) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Command.Merge(m, src)
+}
+func (m *Command) XXX_Size() int {
+	return m.Size()
+}
+func (m *Command) XXX_DiscardUnknown() {
+	xxx_messageInfo_Command.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Command proto.InternalMessageInfo
+
+func (m *Command) GetID() uint64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *Command) GetMac() string {
+	if m != nil {
+		return m.Mac
+	}
+	return ""
+}
+
+func (m *Command) GetType() CommandType {
+	if m != nil {
+		return m.Type
+	}
+	return CommandSetLogLevel
+}
+
+func (m *Command) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+// CommandAck is sent by the terminal as soon as a command is received
+type CommandAck struct {
+	ID                   uint64   `protobuf:"varint,1,opt,name=id" json:"id"`
+	Mac                  string   `protobuf:"bytes,2,opt,name=mac" json:"mac"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommandAck) Reset()         { *m = CommandAck{} }
+func (m *CommandAck) String() string { return proto.CompactTextString(m) }
+func (*CommandAck) ProtoMessage()    {}
+func (*CommandAck) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{11}
+}
+func (m *CommandAck) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *CommandAck) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_CommandAck.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *CommandAck) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommandAck.Merge(m, src)
+}
+func (m *CommandAck) XXX_Size() int {
+	return m.Size()
+}
+func (m *CommandAck) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommandAck.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommandAck proto.InternalMessageInfo
+
+func (m *CommandAck) GetID() uint64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *CommandAck) GetMac() string {
+	if m != nil {
+		return m.Mac
+	}
+	return ""
+}
+
+// CommandRsp is sent by the terminal after the command is executed
+type CommandRsp struct {
+	ID                   uint64   `protobuf:"varint,1,opt,name=id" json:"id"`
+	Mac                  string   `protobuf:"bytes,2,opt,name=mac" json:"mac"`
+	Code                 Code     `protobuf:"varint,3,opt,name=code,enum=pb.Code" json:"code"`
+	Result               string   `protobuf:"bytes,4,opt,name=result" json:"result"`
+	Data                 []byte   `protobuf:"bytes,5,opt,name=data" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommandRsp) Reset()         { *m = CommandRsp{} }
+func (m *CommandRsp) String() string { return proto.CompactTextString(m) }
+func (*CommandRsp) ProtoMessage()    {}
+func (*CommandRsp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{12}
+}
+func (m *CommandRsp) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *CommandRsp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_CommandRsp.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *CommandRsp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommandRsp.Merge(m, src)
+}
+func (m *CommandRsp) XXX_Size() int {
+	return m.Size()
+}
+func (m *CommandRsp) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommandRsp.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommandRsp proto.InternalMessageInfo
+
+func (m *CommandRsp) GetID() uint64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *CommandRsp) GetMac() string {
+	if m != nil {
+		return m.Mac
+	}
+	return ""
+}
+
+func (m *CommandRsp) GetCode() Code {
+	if m != nil {
+		return m.Code
+	}
+	return CodeSucc
+}
+
+func (m *CommandRsp) GetResult() string {
+	if m != nil {
+		return m.Result
+	}
+	return ""
+}
+
+func (m *CommandRsp) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("pb.Code", Code_name, Code_value)
+	proto.RegisterEnum("pb.Cmd", Cmd_name, Cmd_value)
+	proto.RegisterEnum("pb.CommandType", CommandType_name, CommandType_value)
+	proto.RegisterType((*Heartbeat)(nil), "pb.Heartbeat")
+	proto.RegisterType((*InitUploadReq)(nil), "pb.InitUploadReq")
+	proto.RegisterType((*InitUploadRsp)(nil), "pb.InitUploadRsp")
+	proto.RegisterType((*UploadReq)(nil), "pb.UploadReq")
+	proto.RegisterType((*UploadRsp)(nil), "pb.UploadRsp")
+	proto.RegisterType((*UploadCompleteReq)(nil), "pb.UploadCompleteReq")
+	proto.RegisterType((*UploadCompleteRsp)(nil), "pb.UploadCompleteRsp")
+	proto.RegisterType((*UploadContinue)(nil), "pb.UploadContinue")
+	proto.RegisterType((*SysUsage)(nil), "pb.SysUsage")
+	proto.RegisterType((*NetCounters)(nil), "pb.NetCounters")
+	proto.RegisterType((*Command)(nil), "pb.Command")
+	proto.RegisterType((*CommandAck)(nil), "pb.CommandAck")
+	proto.RegisterType((*CommandRsp)(nil), "pb.CommandRsp")
+}
+
+func init() { proto.RegisterFile("pb.proto", fileDescriptor_f80abaa17e25ccc8) }
+
+var fileDescriptor_f80abaa17e25ccc8 = []byte{
+	// 1091 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x56, 0xcd, 0x6e, 0xdb, 0x46,
+	0x10, 0x16, 0x25, 0xea, 0x6f, 0x64, 0x49, 0xeb, 0x8d, 0x9b, 0x12, 0x46, 0xa1, 0x08, 0x6a, 0x51,
+	0x28, 0x46, 0xe0, 0xb4, 0x7e, 0x82, 0x5a, 0x72, 0x8a, 0x18, 0x90, 0x1d, 0x57, 0xb2, 0xdb, 0xf3,
+	0x9a, 0x1c, 0xd0, 0x84, 0xc4, 0x1f, 0x73, 0x97, 0x46, 0xdc, 0x53, 0x0f, 0x7d, 0x86, 0xa2, 0x2f,
+	0xd3, 0x4b, 0x4f, 0x39, 0xe6, 0x09, 0x82, 0xd6, 0x3d, 0xf5, 0x09, 0x8a, 0xde, 0x82, 0xe5, 0x92,
+	0xd2, 0x52, 0x8e, 0xed, 0x20, 0x27, 0x71, 0xbf, 0xf9, 0xf6, 0xdb, 0x99, 0xd9, 0xd9, 0x19, 0x41,
+	0x23, 0x3a, 0xdf, 0x8d, 0xe2, 0x50, 0x84, 0xb4, 0x1c, 0x9d, 0x6f, 0x6f, 0xb9, 0xa1, 0x1b, 0xa6,
+	0xcb, 0xe7, 0xf2, 0x4b, 0x59, 0x06, 0x5f, 0x42, 0xf3, 0x25, 0xb2, 0x58, 0x9c, 0x23, 0x13, 0xf4,
+	0x31, 0x54, 0x7c, 0x66, 0x5b, 0x46, 0xdf, 0x18, 0x36, 0x47, 0xe6, 0x9b, 0x77, 0x4f, 0x4a, 0x53,
+	0x09, 0x0c, 0xfe, 0x33, 0xa0, 0x7d, 0x18, 0x78, 0xe2, 0x2c, 0x5a, 0x84, 0xcc, 0x99, 0xe2, 0xa5,
+	0x64, 0x72, 0xbc, 0x4c, 0x99, 0x66, 0xce, 0xe4, 0x78, 0x49, 0xbf, 0x86, 0x96, 0x1d, 0x06, 0x02,
+	0x03, 0x71, 0x7a, 0x1d, 0xa1, 0x55, 0xd6, 0x94, 0x74, 0x03, 0xdd, 0x81, 0x76, 0xb6, 0x9c, 0x60,
+	0xe0, 0x8a, 0x0b, 0xab, 0xd2, 0x37, 0x86, 0x95, 0x8c, 0x59, 0x34, 0xd1, 0xaf, 0x00, 0xec, 0x8b,
+	0x24, 0x98, 0x8f, 0xc3, 0x24, 0x10, 0x96, 0xd9, 0x37, 0x86, 0xd5, 0x8c, 0xa8, 0xe1, 0xb4, 0x07,
+	0x75, 0x3f, 0x74, 0x4e, 0x3d, 0x1f, 0xad, 0xaa, 0xa6, 0x95, 0x83, 0xf4, 0x0b, 0xa8, 0xd9, 0xcc,
+	0xc7, 0x98, 0x59, 0x35, 0xcd, 0xa9, 0x0c, 0xcb, 0x23, 0xaf, 0xaf, 0x47, 0xee, 0x16, 0x02, 0xe7,
+	0xd1, 0x9d, 0x81, 0x6f, 0x43, 0xd9, 0x73, 0xd2, 0x78, 0xcd, 0x11, 0x48, 0xf8, 0xe6, 0xdd, 0x93,
+	0xf2, 0xe1, 0xc1, 0xb4, 0xec, 0x39, 0x74, 0x00, 0xa6, 0x1d, 0x3a, 0x98, 0xc6, 0xd8, 0xd9, 0x6b,
+	0xec, 0x46, 0xe7, 0xbb, 0xe3, 0xd0, 0xc1, 0x6c, 0x7b, 0x6a, 0x1b, 0xfc, 0x04, 0xcd, 0x55, 0x76,
+	0x95, 0x98, 0xf1, 0x41, 0xb1, 0x6d, 0xa8, 0x7a, 0x81, 0x83, 0xaf, 0xad, 0xb2, 0x96, 0x08, 0x05,
+	0x51, 0x0a, 0xa6, 0xc3, 0x04, 0x4b, 0x0f, 0xda, 0x98, 0xa6, 0xdf, 0x03, 0x77, 0x29, 0xcc, 0xa3,
+	0x4f, 0x16, 0xfe, 0x98, 0x08, 0x9e, 0xc3, 0xa6, 0x3a, 0x68, 0x1c, 0xfa, 0xd1, 0x02, 0x05, 0x3e,
+	0x10, 0xc9, 0x60, 0x76, 0x6b, 0xc3, 0x03, 0x1e, 0xe6, 0x5e, 0x94, 0xef, 0xf1, 0xe2, 0x19, 0x74,
+	0x72, 0xd1, 0x40, 0x78, 0x41, 0x82, 0xf7, 0xba, 0xf0, 0x47, 0x15, 0x1a, 0xb3, 0x6b, 0x7e, 0xc6,
+	0x99, 0x8b, 0x77, 0x55, 0x3f, 0xed, 0x43, 0x63, 0x1c, 0x25, 0xa7, 0xa1, 0x60, 0x8b, 0xec, 0x82,
+	0x95, 0x71, 0x89, 0x4a, 0xc6, 0x11, 0xfa, 0x8a, 0x51, 0xd1, 0x19, 0x39, 0x4a, 0x07, 0xd0, 0x3c,
+	0xf0, 0xf8, 0x5c, 0x51, 0x4c, 0x8d, 0xb2, 0x82, 0xe9, 0x33, 0xe8, 0x8c, 0xa3, 0xe4, 0x8c, 0xa3,
+	0x73, 0x82, 0xb1, 0x8d, 0x81, 0x48, 0x0b, 0xb9, 0x9d, 0x11, 0xd7, 0x6c, 0x92, 0x7d, 0x84, 0xbe,
+	0xce, 0xae, 0xe9, 0xec, 0xa2, 0x8d, 0xee, 0x42, 0x57, 0x1e, 0xa4, 0xd3, 0xeb, 0x1a, 0x7d, 0xdd,
+	0x48, 0x87, 0xb0, 0x31, 0x09, 0x99, 0xb3, 0x7f, 0x85, 0x31, 0x73, 0xf1, 0x5b, 0xab, 0xd1, 0x37,
+	0x86, 0x46, 0x46, 0x2e, 0x58, 0xe4, 0xeb, 0xfc, 0x21, 0xc1, 0x04, 0x0f, 0x30, 0x12, 0x17, 0x56,
+	0x53, 0x13, 0xd5, 0x70, 0xa9, 0x37, 0x62, 0xf6, 0x7c, 0x11, 0xba, 0xa3, 0x6b, 0x81, 0xdc, 0x02,
+	0x2d, 0x05, 0x05, 0x0b, 0xfd, 0x06, 0xc8, 0xab, 0x85, 0x83, 0x5c, 0x9c, 0x60, 0xe0, 0x78, 0x81,
+	0xbb, 0xef, 0xa2, 0xd5, 0xd2, 0x1e, 0xf4, 0x2d, 0xab, 0x8c, 0x4d, 0x5d, 0xf9, 0x2c, 0xb1, 0x6d,
+	0x95, 0xe1, 0x0d, 0x4d, 0x7e, 0xdd, 0x48, 0xf7, 0xf2, 0xba, 0xfb, 0x9e, 0x79, 0x0b, 0x74, 0xd4,
+	0x8e, 0xb6, 0xb6, 0xe3, 0xb6, 0x99, 0x3e, 0x05, 0xf3, 0x18, 0x05, 0xb7, 0x3a, 0xfd, 0xca, 0xb0,
+	0xb5, 0xd7, 0x95, 0xa5, 0x77, 0x8c, 0x22, 0xed, 0x3c, 0x18, 0xf3, 0xbc, 0x02, 0x25, 0x25, 0xbb,
+	0xc6, 0x53, 0xf4, 0x23, 0x8c, 0x99, 0x48, 0x62, 0xb4, 0xba, 0x5a, 0xf2, 0xd6, 0x6c, 0xb2, 0x2d,
+	0x9d, 0x45, 0x42, 0x76, 0x2d, 0xa2, 0x79, 0x90, 0x61, 0xb2, 0xa9, 0xfd, 0x88, 0x31, 0xf7, 0xc2,
+	0xc0, 0xda, 0xd4, 0xca, 0x32, 0x07, 0x07, 0xff, 0x1b, 0xd0, 0xd2, 0xfc, 0xa0, 0x16, 0x98, 0xc7,
+	0xcc, 0xc7, 0x42, 0x0d, 0xa7, 0x88, 0x2c, 0xc0, 0x34, 0xbf, 0x33, 0x79, 0xf5, 0x7a, 0x15, 0xaf,
+	0xe0, 0x25, 0x67, 0x8a, 0xf6, 0x55, 0xa1, 0x8e, 0x57, 0xb0, 0x6c, 0xf0, 0x27, 0xcc, 0x9e, 0xa3,
+	0x50, 0x4a, 0x7a, 0x29, 0xeb, 0x06, 0x8d, 0x97, 0xaa, 0x55, 0x3f, 0xc0, 0x4b, 0xf5, 0xb6, 0xa1,
+	0xfa, 0x22, 0x8e, 0xbd, 0xc0, 0xaa, 0x69, 0x0c, 0x05, 0xc9, 0xdc, 0xbc, 0x88, 0xe3, 0x30, 0x51,
+	0xb5, 0xba, 0xcc, 0x8d, 0xc2, 0x06, 0xbf, 0x1a, 0x50, 0x1f, 0x87, 0xbe, 0xcf, 0x02, 0xe7, 0xde,
+	0xae, 0x91, 0x3d, 0xeb, 0xf2, 0xfa, 0xb3, 0x7e, 0x0a, 0xa6, 0x90, 0x33, 0x4a, 0xf5, 0xb4, 0xae,
+	0xea, 0x26, 0xa9, 0x9c, 0x9c, 0x50, 0x79, 0xf2, 0x24, 0x45, 0x3a, 0x79, 0xc5, 0x16, 0x09, 0x5a,
+	0xa6, 0x26, 0xa2, 0xa0, 0xc1, 0x77, 0x00, 0xd9, 0xb6, 0x7d, 0x7b, 0xfe, 0x29, 0x8e, 0x0c, 0x7e,
+	0x33, 0x96, 0x12, 0x0f, 0x75, 0xc0, 0xbb, 0x62, 0xf9, 0x88, 0xfe, 0x2c, 0xb3, 0x19, 0x23, 0x4f,
+	0x16, 0xa2, 0x10, 0x45, 0x86, 0x2d, 0x47, 0x47, 0x75, 0x35, 0x3a, 0x76, 0xfe, 0x34, 0xc0, 0x94,
+	0x32, 0x74, 0x03, 0x1a, 0xf2, 0x57, 0x3e, 0x21, 0x52, 0xca, 0x57, 0xa3, 0x84, 0x5f, 0x13, 0x83,
+	0x76, 0xa1, 0x25, 0x57, 0x47, 0x1e, 0xe7, 0x5e, 0xe0, 0x92, 0x32, 0xdd, 0x02, 0x22, 0x81, 0xc3,
+	0xe0, 0x8a, 0x2d, 0x3c, 0x67, 0x2c, 0x27, 0x34, 0xa9, 0xd0, 0xcf, 0xe1, 0x51, 0x01, 0x45, 0x7b,
+	0xce, 0x13, 0x9f, 0x98, 0x94, 0xc0, 0x86, 0x34, 0xbc, 0x9a, 0xcd, 0xe4, 0xbd, 0xc6, 0xa4, 0x4a,
+	0x29, 0x74, 0x52, 0x45, 0xf6, 0x7a, 0x8a, 0x22, 0xf6, 0x90, 0x93, 0x1a, 0x7d, 0x04, 0x5d, 0x89,
+	0x9d, 0x05, 0x3c, 0x89, 0xa2, 0x30, 0x16, 0xe8, 0x90, 0x7a, 0x0e, 0x66, 0x9a, 0xfb, 0xb1, 0xcb,
+	0x49, 0x83, 0x76, 0x64, 0x32, 0x1d, 0x54, 0x8f, 0x97, 0x34, 0x77, 0xfe, 0x35, 0xa0, 0x32, 0xf6,
+	0x1d, 0xda, 0x84, 0xea, 0xd8, 0x77, 0x5e, 0x8e, 0x48, 0x89, 0x6e, 0x42, 0x7b, 0xec, 0x3b, 0xea,
+	0x91, 0xcb, 0xe9, 0x4e, 0x8c, 0xd4, 0x69, 0x1d, 0x9a, 0xf2, 0x88, 0x94, 0x69, 0x1b, 0x9a, 0x4b,
+	0x94, 0x54, 0x52, 0x57, 0xf3, 0xa5, 0x24, 0x98, 0xf4, 0x33, 0xd8, 0x5c, 0x22, 0xf9, 0x14, 0x23,
+	0x55, 0x6a, 0xc1, 0xd6, 0x2d, 0x58, 0x6e, 0xa8, 0xad, 0x6d, 0x50, 0x13, 0x8a, 0xd4, 0xd3, 0x24,
+	0xfa, 0x4e, 0x3e, 0x89, 0xb2, 0x28, 0x7c, 0x27, 0xab, 0x0a, 0xd2, 0xcc, 0x5c, 0x5e, 0x15, 0x1a,
+	0x81, 0x22, 0x24, 0xd5, 0x5b, 0x3b, 0xbf, 0x18, 0xd0, 0xca, 0x80, 0xf4, 0x5f, 0xd6, 0x63, 0xa0,
+	0xd9, 0x72, 0x86, 0x62, 0x12, 0xba, 0x13, 0xbc, 0xc2, 0x05, 0x29, 0xa9, 0xcb, 0xc8, 0xf1, 0x29,
+	0x13, 0x38, 0xf1, 0xfc, 0x34, 0x0d, 0x05, 0xc3, 0x88, 0x09, 0xfb, 0x62, 0xe6, 0xfd, 0x8c, 0xa4,
+	0x9c, 0x1e, 0x96, 0x9d, 0x84, 0xdc, 0x66, 0x01, 0xa9, 0xa8, 0x7b, 0x4e, 0x21, 0x15, 0xce, 0x24,
+	0x74, 0x89, 0x39, 0xda, 0x7a, 0xfb, 0x77, 0xaf, 0xf4, 0xe6, 0xa6, 0x67, 0xbc, 0xbd, 0xe9, 0x19,
+	0x7f, 0xdd, 0xf4, 0x8c, 0xdf, 0xff, 0xe9, 0x95, 0xde, 0x0f, 0x00, 0x00, 0x0e, 0xca, 0xf3, 0x8a,
+	0x0a, 0x00, 0x00,
+}
+
+func (m *Heartbeat) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -643,21 +1273,67 @@ func (m *UploadCompleteReq) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *UploadCompleteReq) MarshalTo(dAtA []byte) (int, error) {
+func (m *Heartbeat) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	dAtA[i] = 0xa
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Mac)))
+	i += copy(dAtA[i:], m.Mac)
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *InitUploadReq) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *InitUploadReq) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
 	dAtA[i] = 0x8
 	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.ID))
+	i = encodeVarintPb(dAtA, i, uint64(m.Seq))
+	dAtA[i] = 0x12
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.ContentType)))
+	i += copy(dAtA[i:], m.ContentType)
+	dAtA[i] = 0x18
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.ContentLength))
+	dAtA[i] = 0x20
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.ChunkCount))
+	dAtA[i] = 0x28
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.ModTime))
+	dAtA[i] = 0x32
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Camera)))
+	i += copy(dAtA[i:], m.Camera)
+	dAtA[i] = 0x3a
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Mac)))
+	i += copy(dAtA[i:], m.Mac)
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *UploadCompleteRsp) Marshal() (dAtA []byte, err error) {
+func (m *InitUploadRsp) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -667,16 +1343,19 @@ func (m *UploadCompleteRsp) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *UploadCompleteRsp) MarshalTo(dAtA []byte) (int, error) {
+func (m *InitUploadRsp) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
 	dAtA[i] = 0x8
 	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.ID))
+	i = encodeVarintPb(dAtA, i, uint64(m.Seq))
 	dAtA[i] = 0x10
 	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.ID))
+	dAtA[i] = 0x18
+	i++
 	i = encodeVarintPb(dAtA, i, uint64(m.Code))
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
@@ -684,7 +1363,7 @@ func (m *UploadCompleteRsp) MarshalTo(dAtA []byte) (int, error) {
 	return i, nil
 }
 
-func (m *UploadContinue) Marshal() (dAtA []byte, err error) {
+func (m *UploadReq) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -694,7 +1373,7 @@ func (m *UploadContinue) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *UploadContinue) MarshalTo(dAtA []byte) (int, error) {
+func (m *UploadReq) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
@@ -702,13 +1381,22 @@ func (m *UploadContinue) MarshalTo(dAtA []byte) (int, error) {
 	dAtA[i] = 0x8
 	i++
 	i = encodeVarintPb(dAtA, i, uint64(m.ID))
+	dAtA[i] = 0x10
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.Index))
+	if m.Data != nil {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Data)))
+		i += copy(dAtA[i:], m.Data)
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *SysUsage) Marshal() (dAtA []byte, err error) {
+func (m *UploadRsp) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -718,184 +1406,1328 @@ func (m *SysUsage) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *SysUsage) MarshalTo(dAtA []byte) (int, error) {
+func (m *UploadRsp) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	dAtA[i] = 0xa
+	dAtA[i] = 0x8
 	i++
-	i = encodeVarintPb(dAtA, i, uint64(len(m.Mac)))
-	i += copy(dAtA[i:], m.Mac)
+	i = encodeVarintPb(dAtA, i, uint64(m.ID))
 	dAtA[i] = 0x10
 	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.CpuTotal))
+	i = encodeVarintPb(dAtA, i, uint64(m.Index))
 	dAtA[i] = 0x18
 	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.MemTotal))
-	dAtA[i] = 0x20
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.DiskTotal))
-	dAtA[i] = 0x28
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.CpuUsedPercent))
-	dAtA[i] = 0x30
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.MemUsedPercent))
-	dAtA[i] = 0x38
-	i++
-	i = encodeVarintPb(dAtA, i, uint64(m.DiskUsedPercent))
-	dAtA[i] = 0x41
-	i++
-	binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.LoadAverage1))))
-	i += 8
+	i = encodeVarintPb(dAtA, i, uint64(m.Code))
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func encodeVarintPb(dAtA []byte, offset int, v uint64) int {
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *UploadCompleteReq) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	dAtA[offset] = uint8(v)
-	return offset + 1
+	return dAtA[:n], nil
 }
-func (m *Heartbeat) Size() (n int) {
+
+func (m *UploadCompleteReq) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Mac)
-	n += 1 + l + sovPb(uint64(l))
+	dAtA[i] = 0x8
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.ID))
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	return i, nil
 }
 
-func (m *InitUploadReq) Size() (n int) {
+func (m *UploadCompleteRsp) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *UploadCompleteRsp) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	n += 1 + sovPb(uint64(m.Seq))
-	l = len(m.ContentType)
-	n += 1 + l + sovPb(uint64(l))
-	n += 1 + sovPb(uint64(m.ContentLength))
-	n += 1 + sovPb(uint64(m.ChunkCount))
-	n += 1 + sovPb(uint64(m.ModTime))
-	l = len(m.Camera)
-	n += 1 + l + sovPb(uint64(l))
-	l = len(m.Mac)
-	n += 1 + l + sovPb(uint64(l))
+	dAtA[i] = 0x8
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.ID))
+	dAtA[i] = 0x10
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.Code))
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	return i, nil
 }
 
-func (m *InitUploadRsp) Size() (n int) {
+func (m *UploadContinue) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *UploadContinue) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	n += 1 + sovPb(uint64(m.Seq))
-	n += 1 + sovPb(uint64(m.ID))
-	n += 1 + sovPb(uint64(m.Code))
+	dAtA[i] = 0x8
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.ID))
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	return i, nil
 }
 
-func (m *UploadReq) Size() (n int) {
+func (m *SysUsage) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SysUsage) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	n += 1 + sovPb(uint64(m.ID))
-	n += 1 + sovPb(uint64(m.Index))
-	if m.Data != nil {
-		l = len(m.Data)
-		n += 1 + l + sovPb(uint64(l))
+	dAtA[i] = 0xa
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Mac)))
+	i += copy(dAtA[i:], m.Mac)
+	dAtA[i] = 0x10
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.CpuTotal))
+	dAtA[i] = 0x18
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.MemTotal))
+	dAtA[i] = 0x20
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.DiskTotal))
+	dAtA[i] = 0x28
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.CpuUsedPercent))
+	dAtA[i] = 0x30
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.MemUsedPercent))
+	dAtA[i] = 0x38
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.DiskUsedPercent))
+	dAtA[i] = 0x41
+	i++
+	encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.LoadAverage1))))
+	i += 8
+	dAtA[i] = 0x48
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.QueueDepth))
+	dAtA[i] = 0x50
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.BacklogBytes))
+	dAtA[i] = 0x58
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.OldestPendingAge))
+	dAtA[i] = 0x60
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.UploadSuccTotal))
+	dAtA[i] = 0x68
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.UploadFailedTotal))
+	if len(m.Nets) > 0 {
+		for _, msg := range m.Nets {
+			dAtA[i] = 0x72
+			i++
+			i = encodeVarintPb(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
 	}
+	dAtA[i] = 0x79
+	i++
+	encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.CpuTemperature))))
+	i += 8
+	dAtA[i] = 0x80
+	i++
+	dAtA[i] = 0x1
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.Uptime))
+	dAtA[i] = 0x8a
+	i++
+	dAtA[i] = 0x1
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Version)))
+	i += copy(dAtA[i:], m.Version)
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	return i, nil
 }
 
-func (m *UploadRsp) Size() (n int) {
+func (m *NetCounters) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *NetCounters) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	n += 1 + sovPb(uint64(m.ID))
-	n += 1 + sovPb(uint64(m.Index))
-	n += 1 + sovPb(uint64(m.Code))
+	dAtA[i] = 0xa
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Name)))
+	i += copy(dAtA[i:], m.Name)
+	dAtA[i] = 0x10
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.BytesSent))
+	dAtA[i] = 0x18
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.BytesRecv))
+	dAtA[i] = 0x20
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.PacketsSent))
+	dAtA[i] = 0x28
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.PacketsRecv))
+	dAtA[i] = 0x30
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.Errin))
+	dAtA[i] = 0x38
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.Errout))
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	return i, nil
 }
 
-func (m *UploadCompleteReq) Size() (n int) {
+func (m *Command) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Command) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	n += 1 + sovPb(uint64(m.ID))
+	dAtA[i] = 0x8
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.ID))
+	dAtA[i] = 0x12
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Mac)))
+	i += copy(dAtA[i:], m.Mac)
+	dAtA[i] = 0x18
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.Type))
+	dAtA[i] = 0x22
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Value)))
+	i += copy(dAtA[i:], m.Value)
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	return i, nil
 }
 
-func (m *UploadCompleteRsp) Size() (n int) {
+func (m *CommandAck) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *CommandAck) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	n += 1 + sovPb(uint64(m.ID))
-	n += 1 + sovPb(uint64(m.Code))
+	dAtA[i] = 0x8
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.ID))
+	dAtA[i] = 0x12
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Mac)))
+	i += copy(dAtA[i:], m.Mac)
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	return i, nil
 }
 
-func (m *UploadContinue) Size() (n int) {
+func (m *CommandRsp) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *CommandRsp) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	n += 1 + sovPb(uint64(m.ID))
+	dAtA[i] = 0x8
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.ID))
+	dAtA[i] = 0x12
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Mac)))
+	i += copy(dAtA[i:], m.Mac)
+	dAtA[i] = 0x18
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(m.Code))
+	dAtA[i] = 0x22
+	i++
+	i = encodeVarintPb(dAtA, i, uint64(len(m.Result)))
+	i += copy(dAtA[i:], m.Result)
+	if m.Data != nil {
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Data)))
+		i += copy(dAtA[i:], m.Data)
+	}
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	return i, nil
 }
 
-func (m *SysUsage) Size() (n int) {
+func encodeVarintPb(dAtA []byte, offset int, v uint64) int {
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return offset + 1
+}
+func (m *Heartbeat) Size() (n int) {
+	if m == nil {
+		return 0
+	}
 	var l int
 	_ = l
 	l = len(m.Mac)
 	n += 1 + l + sovPb(uint64(l))
-	n += 1 + sovPb(uint64(m.CpuTotal))
-	n += 1 + sovPb(uint64(m.MemTotal))
-	n += 1 + sovPb(uint64(m.DiskTotal))
-	n += 1 + sovPb(uint64(m.CpuUsedPercent))
-	n += 1 + sovPb(uint64(m.MemUsedPercent))
-	n += 1 + sovPb(uint64(m.DiskUsedPercent))
-	n += 9
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
-	return n
-}
+	return n
+}
+
+func (m *InitUploadReq) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovPb(uint64(m.Seq))
+	l = len(m.ContentType)
+	n += 1 + l + sovPb(uint64(l))
+	n += 1 + sovPb(uint64(m.ContentLength))
+	n += 1 + sovPb(uint64(m.ChunkCount))
+	n += 1 + sovPb(uint64(m.ModTime))
+	l = len(m.Camera)
+	n += 1 + l + sovPb(uint64(l))
+	l = len(m.Mac)
+	n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InitUploadRsp) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovPb(uint64(m.Seq))
+	n += 1 + sovPb(uint64(m.ID))
+	n += 1 + sovPb(uint64(m.Code))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *UploadReq) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovPb(uint64(m.ID))
+	n += 1 + sovPb(uint64(m.Index))
+	if m.Data != nil {
+		l = len(m.Data)
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *UploadRsp) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovPb(uint64(m.ID))
+	n += 1 + sovPb(uint64(m.Index))
+	n += 1 + sovPb(uint64(m.Code))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *UploadCompleteReq) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovPb(uint64(m.ID))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *UploadCompleteRsp) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovPb(uint64(m.ID))
+	n += 1 + sovPb(uint64(m.Code))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *UploadContinue) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovPb(uint64(m.ID))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *SysUsage) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Mac)
+	n += 1 + l + sovPb(uint64(l))
+	n += 1 + sovPb(uint64(m.CpuTotal))
+	n += 1 + sovPb(uint64(m.MemTotal))
+	n += 1 + sovPb(uint64(m.DiskTotal))
+	n += 1 + sovPb(uint64(m.CpuUsedPercent))
+	n += 1 + sovPb(uint64(m.MemUsedPercent))
+	n += 1 + sovPb(uint64(m.DiskUsedPercent))
+	n += 9
+	n += 1 + sovPb(uint64(m.QueueDepth))
+	n += 1 + sovPb(uint64(m.BacklogBytes))
+	n += 1 + sovPb(uint64(m.OldestPendingAge))
+	n += 1 + sovPb(uint64(m.UploadSuccTotal))
+	n += 1 + sovPb(uint64(m.UploadFailedTotal))
+	if len(m.Nets) > 0 {
+		for _, e := range m.Nets {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	n += 9
+	n += 2 + sovPb(uint64(m.Uptime))
+	l = len(m.Version)
+	n += 2 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *NetCounters) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	n += 1 + l + sovPb(uint64(l))
+	n += 1 + sovPb(uint64(m.BytesSent))
+	n += 1 + sovPb(uint64(m.BytesRecv))
+	n += 1 + sovPb(uint64(m.PacketsSent))
+	n += 1 + sovPb(uint64(m.PacketsRecv))
+	n += 1 + sovPb(uint64(m.Errin))
+	n += 1 + sovPb(uint64(m.Errout))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Command) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovPb(uint64(m.ID))
+	l = len(m.Mac)
+	n += 1 + l + sovPb(uint64(l))
+	n += 1 + sovPb(uint64(m.Type))
+	l = len(m.Value)
+	n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *CommandAck) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovPb(uint64(m.ID))
+	l = len(m.Mac)
+	n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *CommandRsp) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovPb(uint64(m.ID))
+	l = len(m.Mac)
+	n += 1 + l + sovPb(uint64(l))
+	n += 1 + sovPb(uint64(m.Code))
+	l = len(m.Result)
+	n += 1 + l + sovPb(uint64(l))
+	if m.Data != nil {
+		l = len(m.Data)
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func sovPb(x uint64) (n int) {
+	for {
+		n++
+		x >>= 7
+		if x == 0 {
+			break
+		}
+	}
+	return n
+}
+func sozPb(x uint64) (n int) {
+	return sovPb(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *Heartbeat) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Heartbeat: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Heartbeat: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Mac = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InitUploadReq: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InitUploadReq: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
+			}
+			m.Seq = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Seq |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ContentType", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ContentType = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ContentLength", wireType)
+			}
+			m.ContentLength = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ContentLength |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChunkCount", wireType)
+			}
+			m.ChunkCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ChunkCount |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ModTime", wireType)
+			}
+			m.ModTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ModTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Camera", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Camera = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Mac = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *InitUploadRsp) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InitUploadRsp: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InitUploadRsp: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
+			}
+			m.Seq = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Seq |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			m.ID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ID |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			}
+			m.Code = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Code |= Code(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *UploadReq) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: UploadReq: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: UploadReq: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			m.ID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ID |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+			}
+			m.Index = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Index |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *UploadRsp) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: UploadRsp: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: UploadRsp: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			m.ID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ID |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+			}
+			m.Index = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Index |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			}
+			m.Code = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Code |= Code(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *UploadCompleteReq) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: UploadCompleteReq: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: UploadCompleteReq: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			m.ID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ID |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
 
-func sovPb(x uint64) (n int) {
-	for {
-		n++
-		x >>= 7
-		if x == 0 {
-			break
-		}
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
 	}
-	return n
-}
-func sozPb(x uint64) (n int) {
-	return sovPb(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+	return nil
 }
-func (m *Heartbeat) Unmarshal(dAtA []byte) error {
+func (m *UploadCompleteRsp) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -910,7 +2742,7 @@ func (m *Heartbeat) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -918,17 +2750,17 @@ func (m *Heartbeat) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Heartbeat: wiretype end group for non-group")
+			return fmt.Errorf("proto: UploadCompleteRsp: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Heartbeat: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: UploadCompleteRsp: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
 			}
-			var stringLen uint64
+			m.ID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -938,21 +2770,30 @@ func (m *Heartbeat) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				m.ID |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
+			m.Code = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Code |= Code(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			m.Mac = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -962,6 +2803,9 @@ func (m *Heartbeat) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -975,7 +2819,7 @@ func (m *Heartbeat) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
+func (m *UploadContinue) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -990,7 +2834,7 @@ func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -998,17 +2842,17 @@ func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: InitUploadReq: wiretype end group for non-group")
+			return fmt.Errorf("proto: UploadContinue: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: InitUploadReq: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: UploadContinue: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
 			}
-			m.Seq = 0
+			m.ID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1018,14 +2862,68 @@ func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Seq |= (uint64(b) & 0x7F) << shift
+				m.ID |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SysUsage) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SysUsage: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SysUsage: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ContentType", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -1037,7 +2935,7 @@ func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1047,16 +2945,38 @@ func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthPb
 			}
 			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.ContentType = string(dAtA[iNdEx:postIndex])
+			m.Mac = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CpuTotal", wireType)
+			}
+			m.CpuTotal = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CpuTotal |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ContentLength", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field MemTotal", wireType)
 			}
-			m.ContentLength = 0
+			m.MemTotal = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1066,16 +2986,16 @@ func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ContentLength |= (int64(b) & 0x7F) << shift
+				m.MemTotal |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ChunkCount", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field DiskTotal", wireType)
 			}
-			m.ChunkCount = 0
+			m.DiskTotal = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1085,16 +3005,16 @@ func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ChunkCount |= (int32(b) & 0x7F) << shift
+				m.DiskTotal |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 5:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ModTime", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CpuUsedPercent", wireType)
 			}
-			m.ModTime = 0
+			m.CpuUsedPercent = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1104,16 +3024,16 @@ func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ModTime |= (int64(b) & 0x7F) << shift
+				m.CpuUsedPercent |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 6:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Camera", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MemUsedPercent", wireType)
 			}
-			var stringLen uint64
+			m.MemUsedPercent = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1123,26 +3043,65 @@ func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				m.MemUsedPercent |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DiskUsedPercent", wireType)
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex > l {
+			m.DiskUsedPercent = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DiskUsedPercent |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LoadAverage1", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Camera = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 7:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.LoadAverage1 = float64(math.Float64frombits(v))
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueueDepth", wireType)
+			}
+			m.QueueDepth = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.QueueDepth |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			var stringLen uint64
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BacklogBytes", wireType)
+			}
+			m.BacklogBytes = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1152,77 +3111,16 @@ func (m *InitUploadReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				m.BacklogBytes |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Mac = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *InitUploadRsp) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: InitUploadRsp: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: InitUploadRsp: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 11:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field OldestPendingAge", wireType)
 			}
-			m.Seq = 0
+			m.OldestPendingAge = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1232,16 +3130,16 @@ func (m *InitUploadRsp) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Seq |= (uint64(b) & 0x7F) << shift
+				m.OldestPendingAge |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+		case 12:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field UploadSuccTotal", wireType)
 			}
-			m.ID = 0
+			m.UploadSuccTotal = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1251,16 +3149,16 @@ func (m *InitUploadRsp) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ID |= (uint64(b) & 0x7F) << shift
+				m.UploadSuccTotal |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+		case 13:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field UploadFailedTotal", wireType)
 			}
-			m.Code = 0
+			m.UploadFailedTotal = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1270,67 +3168,16 @@ func (m *InitUploadRsp) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Code |= (Code(b) & 0x7F) << shift
+				m.UploadFailedTotal |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *UploadReq) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: UploadReq: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: UploadReq: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nets", wireType)
 			}
-			m.ID = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1340,16 +3187,42 @@ func (m *UploadReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ID |= (uint64(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Nets = append(m.Nets, NetCounters{})
+			if err := m.Nets[len(m.Nets)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 15:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CpuTemperature", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.CpuTemperature = float64(math.Float64frombits(v))
+		case 16:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Uptime", wireType)
 			}
-			m.Index = 0
+			m.Uptime = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1359,16 +3232,16 @@ func (m *UploadReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Index |= (int32(b) & 0x7F) << shift
+				m.Uptime |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+		case 17:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1378,22 +3251,23 @@ func (m *UploadReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + byteLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
-			if m.Data == nil {
-				m.Data = []byte{}
-			}
+			m.Version = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -1404,6 +3278,9 @@ func (m *UploadReq) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -1417,7 +3294,7 @@ func (m *UploadReq) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *UploadRsp) Unmarshal(dAtA []byte) error {
+func (m *NetCounters) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1432,7 +3309,7 @@ func (m *UploadRsp) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -1440,17 +3317,17 @@ func (m *UploadRsp) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: UploadRsp: wiretype end group for non-group")
+			return fmt.Errorf("proto: NetCounters: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: UploadRsp: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: NetCounters: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
 			}
-			m.ID = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1460,16 +3337,29 @@ func (m *UploadRsp) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ID |= (uint64(b) & 0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field BytesSent", wireType)
 			}
-			m.Index = 0
+			m.BytesSent = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1479,16 +3369,16 @@ func (m *UploadRsp) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Index |= (int32(b) & 0x7F) << shift
+				m.BytesSent |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field BytesRecv", wireType)
 			}
-			m.Code = 0
+			m.BytesRecv = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1498,67 +3388,73 @@ func (m *UploadRsp) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Code |= (Code(b) & 0x7F) << shift
+				m.BytesRecv |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PacketsSent", wireType)
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
+			m.PacketsSent = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PacketsSent |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *UploadCompleteReq) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PacketsRecv", wireType)
 			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+			m.PacketsRecv = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PacketsRecv |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Errin", wireType)
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: UploadCompleteReq: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: UploadCompleteReq: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.Errin = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Errin |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Errout", wireType)
 			}
-			m.ID = 0
+			m.Errout = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1568,7 +3464,7 @@ func (m *UploadCompleteReq) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ID |= (uint64(b) & 0x7F) << shift
+				m.Errout |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1582,6 +3478,9 @@ func (m *UploadCompleteReq) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -1595,7 +3494,7 @@ func (m *UploadCompleteReq) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *UploadCompleteRsp) Unmarshal(dAtA []byte) error {
+func (m *Command) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1610,7 +3509,7 @@ func (m *UploadCompleteRsp) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -1618,10 +3517,10 @@ func (m *UploadCompleteRsp) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: UploadCompleteRsp: wiretype end group for non-group")
+			return fmt.Errorf("proto: Command: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: UploadCompleteRsp: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Command: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -1638,16 +3537,67 @@ func (m *UploadCompleteRsp) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ID |= (uint64(b) & 0x7F) << shift
+				m.ID |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Mac = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
 			}
-			m.Code = 0
+			m.Type = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Type |= CommandType(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1657,11 +3607,24 @@ func (m *UploadCompleteRsp) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Code |= (Code(b) & 0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -1671,6 +3634,9 @@ func (m *UploadCompleteRsp) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -1684,7 +3650,7 @@ func (m *UploadCompleteRsp) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *UploadContinue) Unmarshal(dAtA []byte) error {
+func (m *CommandAck) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1699,7 +3665,7 @@ func (m *UploadContinue) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -1707,10 +3673,10 @@ func (m *UploadContinue) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: UploadContinue: wiretype end group for non-group")
+			return fmt.Errorf("proto: CommandAck: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: UploadContinue: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: CommandAck: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -1727,11 +3693,43 @@ func (m *UploadContinue) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ID |= (uint64(b) & 0x7F) << shift
+				m.ID |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Mac = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -1741,6 +3739,9 @@ func (m *UploadContinue) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -1754,7 +3755,7 @@ func (m *UploadContinue) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SysUsage) Unmarshal(dAtA []byte) error {
+func (m *CommandRsp) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1769,7 +3770,7 @@ func (m *SysUsage) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -1777,17 +3778,17 @@ func (m *SysUsage) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SysUsage: wiretype end group for non-group")
+			return fmt.Errorf("proto: CommandRsp: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SysUsage: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: CommandRsp: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
 			}
-			var stringLen uint64
+			m.ID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1797,26 +3798,16 @@ func (m *SysUsage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				m.ID |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Mac = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CpuTotal", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
 			}
-			m.CpuTotal = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1826,16 +3817,29 @@ func (m *SysUsage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.CpuTotal |= (uint64(b) & 0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Mac = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MemTotal", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
 			}
-			m.MemTotal = 0
+			m.Code = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1845,16 +3849,16 @@ func (m *SysUsage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MemTotal |= (uint64(b) & 0x7F) << shift
+				m.Code |= Code(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DiskTotal", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Result", wireType)
 			}
-			m.DiskTotal = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1864,54 +3868,29 @@ func (m *SysUsage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.DiskTotal |= (uint64(b) & 0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CpuUsedPercent", wireType)
-			}
-			m.CpuUsedPercent = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.CpuUsedPercent |= (uint32(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
 			}
-		case 6:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MemUsedPercent", wireType)
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
 			}
-			m.MemUsedPercent = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.MemUsedPercent |= (uint32(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
 			}
-		case 7:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DiskUsedPercent", wireType)
+			m.Result = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
 			}
-			m.DiskUsedPercent = 0
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -1921,22 +3900,26 @@ func (m *SysUsage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.DiskUsedPercent |= (uint32(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 8:
-			if wireType != 1 {
-				return fmt.Errorf("proto: wrong wireType = %d for field LoadAverage1", wireType)
+			if byteLen < 0 {
+				return ErrInvalidLengthPb
 			}
-			var v uint64
-			if (iNdEx + 8) > l {
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v = uint64(binary.LittleEndian.Uint64(dAtA[iNdEx:]))
-			iNdEx += 8
-			m.LoadAverage1 = float64(math.Float64frombits(v))
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -1946,6 +3929,9 @@ func (m *SysUsage) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2013,10 +3999,13 @@ func skipPb(dAtA []byte) (n int, err error) {
 					break
 				}
 			}
-			iNdEx += length
 			if length < 0 {
 				return 0, ErrInvalidLengthPb
 			}
+			iNdEx += length
+			if iNdEx < 0 {
+				return 0, ErrInvalidLengthPb
+			}
 			return iNdEx, nil
 		case 3:
 			for {
@@ -2045,6 +4034,9 @@ func skipPb(dAtA []byte) (n int, err error) {
 					return 0, err
 				}
 				iNdEx = start + next
+				if iNdEx < 0 {
+					return 0, ErrInvalidLengthPb
+				}
 			}
 			return iNdEx, nil
 		case 4:
@@ -2063,51 +4055,3 @@ var (
 	ErrInvalidLengthPb = fmt.Errorf("proto: negative length found during unmarshaling")
 	ErrIntOverflowPb   = fmt.Errorf("proto: integer overflow")
 )
-
-func init() { proto.RegisterFile("pb.proto", fileDescriptorPb) }
-
-var fileDescriptorPb = []byte{
-	// 663 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x93, 0x5b, 0x4e, 0xdb, 0x4c,
-	0x14, 0xc7, 0x33, 0x8e, 0x73, 0x3b, 0x24, 0x61, 0x98, 0x2f, 0x5f, 0x6b, 0xa1, 0x2a, 0x44, 0x69,
-	0x55, 0x45, 0x08, 0x05, 0xb5, 0x3b, 0x68, 0x4c, 0x25, 0x90, 0x40, 0xad, 0x12, 0x50, 0x9f, 0x27,
-	0x9e, 0x91, 0xb1, 0x88, 0x3d, 0xc6, 0x33, 0x46, 0xb0, 0x83, 0x2e, 0xa1, 0xfb, 0xe8, 0x06, 0xfa,
-	0xc8, 0x23, 0x2b, 0x40, 0x6d, 0xba, 0x88, 0xbe, 0x56, 0xe3, 0x4b, 0x70, 0x42, 0x81, 0xaa, 0x4f,
-	0xf6, 0xf9, 0xff, 0x7f, 0x73, 0x2e, 0xe3, 0x63, 0xa8, 0x87, 0xd3, 0x61, 0x18, 0x09, 0x25, 0x88,
-	0x11, 0x4e, 0x37, 0x3b, 0xae, 0x70, 0x45, 0x12, 0xee, 0xea, 0xb7, 0xd4, 0xe9, 0xbf, 0x84, 0xc6,
-	0x3e, 0xa7, 0x91, 0x9a, 0x72, 0xaa, 0xc8, 0x33, 0x28, 0xfb, 0xd4, 0xb1, 0x50, 0x0f, 0x0d, 0x1a,
-	0x23, 0xf3, 0xfa, 0x76, 0xab, 0x34, 0xd6, 0x42, 0xff, 0x17, 0x82, 0xd6, 0x41, 0xe0, 0xa9, 0x93,
-	0x70, 0x26, 0x28, 0x1b, 0xf3, 0x73, 0x4d, 0x4a, 0x7e, 0x9e, 0x90, 0x66, 0x4e, 0x4a, 0x7e, 0x4e,
-	0x5e, 0xc3, 0x9a, 0x23, 0x02, 0xc5, 0x03, 0x75, 0x7c, 0x15, 0x72, 0xcb, 0x28, 0x64, 0x2a, 0x1a,
-	0x64, 0x1b, 0x5a, 0x59, 0x78, 0xc8, 0x03, 0x57, 0x9d, 0x5a, 0xe5, 0x1e, 0x1a, 0x94, 0x33, 0x72,
-	0xd9, 0x22, 0xaf, 0x00, 0x9c, 0xd3, 0x38, 0x38, 0xb3, 0x45, 0x1c, 0x28, 0xcb, 0xec, 0xa1, 0x41,
-	0x25, 0x03, 0x0b, 0x3a, 0xe9, 0x42, 0xcd, 0x17, 0xec, 0xd8, 0xf3, 0xb9, 0x55, 0x29, 0xe4, 0xca,
-	0x45, 0xf2, 0x02, 0xaa, 0x0e, 0xf5, 0x79, 0x44, 0xad, 0x6a, 0xa1, 0xa9, 0x4c, 0xcb, 0x27, 0xaf,
-	0xad, 0x4e, 0xee, 0x2e, 0x0d, 0x2e, 0xc3, 0x07, 0x07, 0xdf, 0x04, 0xc3, 0x63, 0xc9, 0xbc, 0xe6,
-	0x08, 0xb4, 0x3c, 0xbf, 0xdd, 0x32, 0x0e, 0xf6, 0xc6, 0x86, 0xc7, 0x48, 0x1f, 0x4c, 0x47, 0x30,
-	0x9e, 0xcc, 0xd8, 0x7e, 0x5b, 0x1f, 0x86, 0xd3, 0xa1, 0x2d, 0x18, 0xcf, 0x8e, 0x27, 0x5e, 0xff,
-	0x13, 0x34, 0xee, 0x6e, 0x37, 0x4d, 0x86, 0xfe, 0x98, 0x6c, 0x13, 0x2a, 0x5e, 0xc0, 0xf8, 0x65,
-	0x52, 0x2b, 0xbf, 0x88, 0x54, 0x22, 0x04, 0x4c, 0x46, 0x15, 0x4d, 0x0a, 0x35, 0xc7, 0xc9, 0x7b,
-	0xdf, 0x5d, 0x24, 0x96, 0xe1, 0x3f, 0x27, 0xfe, 0x9b, 0x09, 0x76, 0x61, 0x23, 0x2d, 0x64, 0x0b,
-	0x3f, 0x9c, 0x71, 0xc5, 0x9f, 0x98, 0xa4, 0x3f, 0xb9, 0x77, 0xe0, 0x89, 0x0e, 0xf3, 0x2e, 0x8c,
-	0x47, 0xba, 0xd8, 0x81, 0x76, 0x9e, 0x34, 0x50, 0x5e, 0x10, 0xf3, 0x47, 0x5b, 0xf8, 0x66, 0x40,
-	0x7d, 0x72, 0x25, 0x4f, 0x24, 0x75, 0xf9, 0x43, 0xdb, 0x4f, 0x7a, 0x50, 0xb7, 0xc3, 0xf8, 0x58,
-	0x28, 0x3a, 0xcb, 0x3e, 0x70, 0x6a, 0x2e, 0x54, 0x4d, 0x1c, 0x71, 0x3f, 0x25, 0xca, 0x45, 0x22,
-	0x57, 0x49, 0x1f, 0x1a, 0x7b, 0x9e, 0x3c, 0x4b, 0x11, 0xb3, 0x80, 0xdc, 0xc9, 0x64, 0x07, 0xda,
-	0x76, 0x18, 0x9f, 0x48, 0xce, 0x3e, 0xf2, 0xc8, 0xe1, 0x81, 0x4a, 0x16, 0xb9, 0x95, 0x81, 0x2b,
-	0x9e, 0xa6, 0x8f, 0xb8, 0x5f, 0xa4, 0xab, 0x45, 0x7a, 0xd9, 0x23, 0x43, 0x58, 0xd7, 0x85, 0x8a,
-	0x78, 0xad, 0x80, 0xaf, 0x9a, 0x64, 0x00, 0xcd, 0x43, 0x41, 0xd9, 0xbb, 0x0b, 0x1e, 0x51, 0x97,
-	0xbf, 0xb1, 0xea, 0x3d, 0x34, 0x40, 0x19, 0xbc, 0xe4, 0x6c, 0x7f, 0x46, 0x60, 0xea, 0xaf, 0x40,
-	0x9a, 0x50, 0xd7, 0xcf, 0x49, 0xec, 0x38, 0xb8, 0x94, 0x47, 0xa3, 0x58, 0x5e, 0x61, 0x44, 0xd6,
-	0x61, 0x4d, 0x47, 0x47, 0x9e, 0x94, 0x5e, 0xe0, 0x62, 0x83, 0x74, 0x00, 0x6b, 0xe1, 0x20, 0xb8,
-	0xa0, 0x33, 0x8f, 0xd9, 0xfa, 0x37, 0xc6, 0x65, 0xf2, 0x1c, 0xfe, 0x5b, 0x52, 0xb9, 0x73, 0x26,
-	0x63, 0x1f, 0x9b, 0x04, 0x43, 0x53, 0x1b, 0x1f, 0x26, 0x93, 0xf7, 0x51, 0x24, 0x22, 0x5c, 0x21,
-	0x04, 0xda, 0x49, 0x46, 0x7a, 0x39, 0xe6, 0x2a, 0xf2, 0xb8, 0xc4, 0xd5, 0xed, 0xaf, 0x08, 0xca,
-	0xb6, 0xcf, 0x48, 0x03, 0x2a, 0xb6, 0xcf, 0xf6, 0x47, 0xb8, 0x44, 0x36, 0xa0, 0x65, 0xfb, 0x2c,
-	0xdd, 0x08, 0xfd, 0x23, 0x63, 0x94, 0x94, 0x2e, 0x4a, 0x63, 0x19, 0x62, 0x83, 0xb4, 0xa0, 0xb1,
-	0x50, 0x71, 0x39, 0x29, 0x98, 0x87, 0x1a, 0x30, 0xc9, 0xff, 0xb0, 0xb1, 0x50, 0xf2, 0x85, 0xc5,
-	0x15, 0x62, 0x41, 0xe7, 0x9e, 0xac, 0x0f, 0x54, 0x57, 0x0e, 0xa4, 0xcb, 0x88, 0x6b, 0xc9, 0x55,
-	0xf8, 0x2c, 0x5f, 0x3a, 0x5c, 0x1f, 0x75, 0x6e, 0x7e, 0x74, 0x4b, 0xd7, 0xf3, 0x2e, 0xba, 0x99,
-	0x77, 0xd1, 0xf7, 0x79, 0x17, 0x7d, 0xf9, 0xd9, 0x2d, 0xfd, 0x0e, 0x00, 0x00, 0xff, 0xff, 0x41,
-	0x34, 0x4d, 0xc8, 0xbc, 0x05, 0x00, 0x00,
-}
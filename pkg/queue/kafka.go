@@ -0,0 +1,209 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/fagongzi/log"
+	"github.com/pkg/errors"
+)
+
+type kafkaProducer struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+func newKafkaProducer(cfg Cfg) (p *kafkaProducer, err error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 10
+	config.Producer.Return.Successes = true
+	p = &kafkaProducer{topic: cfg.Name}
+	if p.producer, err = sarama.NewSyncProducer(cfg.Addrs, config); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+func (p *kafkaProducer) Publish(data []byte) (err error) {
+	if _, _, err = p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: p.topic,
+		Value: sarama.ByteEncoder(data),
+	}); err != nil {
+		err = errors.Wrapf(err, "publish to %s", p.topic)
+	}
+	return
+}
+
+func (p *kafkaProducer) Close() error {
+	return p.producer.Close()
+}
+
+type kafkaAck struct {
+	sess    sarama.ConsumerGroupSession
+	msg     *sarama.ConsumerMessage
+	tracker *offsetTracker
+}
+
+// offsetTracker tracks the acked offsets of a claimed partition. Messages are
+// acked out of order by several workers, only the offset below which all the
+// delivered ones are acked is marked, so a crash never skips unprocessed ones.
+type offsetTracker struct {
+	sync.Mutex
+	// delivered are the offsets delivered and not marked yet, in order
+	delivered []int64
+	acked     map[int64]bool
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{acked: make(map[int64]bool)}
+}
+
+func (t *offsetTracker) deliver(offset int64) {
+	t.Lock()
+	t.delivered = append(t.delivered, offset)
+	t.Unlock()
+}
+
+// ack returns the offset to mark, ok is false if it's unchanged
+func (t *offsetTracker) ack(offset int64) (mark int64, ok bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.acked[offset] = true
+	for len(t.delivered) > 0 && t.acked[t.delivered[0]] {
+		delete(t.acked, t.delivered[0])
+		mark, ok = t.delivered[0]+1, true
+		t.delivered = t.delivered[1:]
+	}
+	return
+}
+
+// kafkaConsumer reads the topic with a consumer group, the contiguously acked
+// messages are marked and committed periodically.
+type kafkaConsumer struct {
+	group  sarama.ConsumerGroup
+	topic  string
+	msgCh  chan *Message
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newKafkaConsumer(cfg Cfg) (c *kafkaConsumer, err error) {
+	if cfg.Group == "" {
+		err = errors.New("group is required by kafka queue")
+		return
+	}
+	config := sarama.NewConfig()
+	config.Version = sarama.V0_10_2_0
+	config.ClientID = cfg.Consumer
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	config.Consumer.Return.Errors = true
+
+	c = &kafkaConsumer{
+		topic: cfg.Name,
+		msgCh: make(chan *Message, 100),
+	}
+	if c.group, err = sarama.NewConsumerGroup(cfg.Addrs, cfg.Group, config); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.wg.Add(2)
+	go c.consume()
+	go func() {
+		defer c.wg.Done()
+		for err := range c.group.Errors() {
+			log.Errorf("queue: consume %s failed, errors:%+v", c.topic, err)
+		}
+	}()
+	return
+}
+
+func (c *kafkaConsumer) consume() {
+	defer c.wg.Done()
+	for {
+		// Consume returns on rebalance
+		if err := c.group.Consume(c.ctx, []string{c.topic}, c); err != nil {
+			log.Errorf("queue: consume %s failed, errors:%+v", c.topic, err)
+			select {
+			case <-c.ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+		if c.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Setup implements sarama.ConsumerGroupHandler
+func (c *kafkaConsumer) Setup(sess sarama.ConsumerGroupSession) error {
+	log.Infof("queue: claimed %+v of %s", sess.Claims(), c.topic)
+	return nil
+}
+
+// Cleanup implements sarama.ConsumerGroupHandler
+func (c *kafkaConsumer) Cleanup(sess sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler
+func (c *kafkaConsumer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	tracker := newOffsetTracker()
+	for msg := range claim.Messages() {
+		tracker.deliver(msg.Offset)
+		select {
+		case c.msgCh <- &Message{Data: msg.Value, ack: kafkaAck{sess: sess, msg: msg, tracker: tracker}}:
+		case <-sess.Context().Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+func (c *kafkaConsumer) Fetch(max int, wait time.Duration) (msgs []*Message, err error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case msg := <-c.msgCh:
+		msgs = append(msgs, msg)
+	case <-timer.C:
+		return
+	case <-c.ctx.Done():
+		err = ErrClosed
+		return
+	}
+	for len(msgs) < max {
+		select {
+		case msg := <-c.msgCh:
+			msgs = append(msgs, msg)
+		default:
+			return
+		}
+	}
+	return
+}
+
+func (c *kafkaConsumer) Ack(msgs ...*Message) error {
+	for _, msg := range msgs {
+		ack := msg.ack.(kafkaAck)
+		if mark, ok := ack.tracker.ack(ack.msg.Offset); ok {
+			ack.sess.MarkOffset(ack.msg.Topic, ack.msg.Partition, mark, "")
+		}
+	}
+	return nil
+}
+
+func (c *kafkaConsumer) Close() (err error) {
+	c.cancel()
+	if err = c.group.Close(); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	c.wg.Wait()
+	return
+}
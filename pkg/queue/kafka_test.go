@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/require"
+)
+
+type testSession struct {
+	sync.Mutex
+	ctx    context.Context
+	marked map[int32]int64
+}
+
+func newTestSession(ctx context.Context) *testSession {
+	return &testSession{ctx: ctx, marked: make(map[int32]int64)}
+}
+
+func (s *testSession) Claims() map[string][]int32 { return nil }
+func (s *testSession) MemberID() string           { return "m1" }
+func (s *testSession) GenerationID() int32        { return 1 }
+func (s *testSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.Lock()
+	defer s.Unlock()
+	if offset > s.marked[partition] {
+		s.marked[partition] = offset
+	}
+}
+func (s *testSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {}
+func (s *testSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+func (s *testSession) Context() context.Context { return s.ctx }
+
+func (s *testSession) getMarked(partition int32) int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.marked[partition]
+}
+
+type testClaim struct {
+	partition int32
+	msgs      chan *sarama.ConsumerMessage
+}
+
+func (c *testClaim) Topic() string                            { return "test_queue" }
+func (c *testClaim) Partition() int32                         { return c.partition }
+func (c *testClaim) InitialOffset() int64                     { return 0 }
+func (c *testClaim) HighWaterMarkOffset() int64               { return 0 }
+func (c *testClaim) Messages() <-chan *sarama.ConsumerMessage { return c.msgs }
+
+func newTestKafkaConsumer() *kafkaConsumer {
+	c := &kafkaConsumer{
+		topic: "test_queue",
+		msgCh: make(chan *Message, 100),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c
+}
+
+func TestKafkaConsumer(t *testing.T) {
+	c := newTestKafkaConsumer()
+	sess := newTestSession(context.Background())
+	claim := &testClaim{partition: 0, msgs: make(chan *sarama.ConsumerMessage, 10)}
+	for i := int64(0); i < 3; i++ {
+		claim.msgs <- &sarama.ConsumerMessage{Topic: "test_queue", Partition: 0, Offset: i, Value: []byte{byte('a' + i)}}
+	}
+	close(claim.msgs)
+	require.NoError(t, c.ConsumeClaim(sess, claim))
+
+	msgs, err := c.Fetch(2, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(msgs))
+	require.Equal(t, "a", string(msgs[0].Data))
+	require.NoError(t, c.Ack(msgs...))
+	require.Equal(t, int64(2), sess.getMarked(0))
+
+	msgs, err = c.Fetch(2, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(msgs))
+	require.Equal(t, "c", string(msgs[0].Data))
+
+	// waits at most wait if it's empty
+	msgs, err = c.Fetch(2, 10*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(msgs))
+
+	c.cancel()
+	_, err = c.Fetch(2, time.Second)
+	require.Equal(t, ErrClosed, err)
+}
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := newOffsetTracker()
+	// offsets may have gaps
+	for _, offset := range []int64{3, 4, 6, 7} {
+		tracker.deliver(offset)
+	}
+	_, ok := tracker.ack(6)
+	require.False(t, ok)
+	_, ok = tracker.ack(4)
+	require.False(t, ok)
+	mark, ok := tracker.ack(3)
+	require.True(t, ok)
+	require.Equal(t, int64(7), mark)
+	mark, ok = tracker.ack(7)
+	require.True(t, ok)
+	require.Equal(t, int64(8), mark)
+}
+
+func TestKafkaConsumerAckOutOfOrder(t *testing.T) {
+	c := newTestKafkaConsumer()
+	sess := newTestSession(context.Background())
+	claim := &testClaim{partition: 1, msgs: make(chan *sarama.ConsumerMessage, 10)}
+	for i := int64(0); i < 3; i++ {
+		claim.msgs <- &sarama.ConsumerMessage{Topic: "test_queue", Partition: 1, Offset: 10 + i}
+	}
+	close(claim.msgs)
+	require.NoError(t, c.ConsumeClaim(sess, claim))
+
+	msgs, err := c.Fetch(3, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(msgs))
+	// the later ones don't commit past the unprocessed one
+	require.NoError(t, c.Ack(msgs[2], msgs[1]))
+	require.Equal(t, int64(0), sess.getMarked(1))
+	require.NoError(t, c.Ack(msgs[0]))
+	require.Equal(t, int64(13), sess.getMarked(1))
+}
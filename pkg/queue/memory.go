@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryQueue is an in-process queue, it's both a Producer and a Consumer.
+// Messages are lost on restart, so Ack does nothing.
+type MemoryQueue struct {
+	sync.Mutex
+
+	capacity     int
+	dropWhenFull bool
+	msgs         [][]byte
+	notify       chan struct{}
+	notFull      *sync.Cond
+	closed       bool
+}
+
+// NewMemoryQueue returns a memory queue. Publish blocks once it has capacity
+// messages, or fails with ErrQueueFull if dropWhenFull is set.
+func NewMemoryQueue(capacity int, dropWhenFull bool) *MemoryQueue {
+	q := &MemoryQueue{
+		capacity:     capacity,
+		dropWhenFull: dropWhenFull,
+		notify:       make(chan struct{}, 1),
+	}
+	q.notFull = sync.NewCond(&q.Mutex)
+	return q
+}
+
+// Publish implements Producer
+func (q *MemoryQueue) Publish(data []byte) error {
+	q.Lock()
+	for !q.closed && q.capacity > 0 && len(q.msgs) >= q.capacity {
+		if q.dropWhenFull {
+			q.Unlock()
+			return ErrQueueFull
+		}
+		q.notFull.Wait()
+	}
+	if q.closed {
+		q.Unlock()
+		return ErrClosed
+	}
+	q.msgs = append(q.msgs, data)
+	q.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Fetch implements Consumer
+func (q *MemoryQueue) Fetch(max int, wait time.Duration) (msgs []*Message, err error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		q.Lock()
+		if q.closed {
+			q.Unlock()
+			return nil, ErrClosed
+		}
+		n := len(q.msgs)
+		if n > 0 {
+			if n > max {
+				n = max
+			}
+			for _, data := range q.msgs[:n] {
+				msgs = append(msgs, &Message{Data: data})
+			}
+			q.msgs = q.msgs[n:]
+			more := len(q.msgs) > 0
+			q.notFull.Broadcast()
+			q.Unlock()
+			if more {
+				// wake up the other consumers
+				select {
+				case q.notify <- struct{}{}:
+				default:
+				}
+			}
+			return
+		}
+		q.Unlock()
+
+		select {
+		case <-q.notify:
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+// Ack implements Consumer
+func (q *MemoryQueue) Ack(msgs ...*Message) error {
+	return nil
+}
+
+// Len returns the number of queued messages
+func (q *MemoryQueue) Len() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.msgs)
+}
+
+// Close implements Producer and Consumer
+func (q *MemoryQueue) Close() error {
+	q.Lock()
+	q.closed = true
+	q.notFull.Broadcast()
+	q.Unlock()
+	return nil
+}
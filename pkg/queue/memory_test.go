@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryQueue(t *testing.T) {
+	q := NewMemoryQueue(3, true)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, q.Publish([]byte(fmt.Sprintf("%d", i))))
+	}
+	require.Equal(t, ErrQueueFull, q.Publish([]byte("3")))
+
+	msgs, err := q.Fetch(2, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(msgs))
+	require.Equal(t, "0", string(msgs[0].Data))
+	require.NoError(t, q.Ack(msgs...))
+	require.Equal(t, 1, q.Len())
+
+	msgs, err = q.Fetch(2, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(msgs))
+	require.Equal(t, "2", string(msgs[0].Data))
+
+	// waits at most wait if it's empty
+	start := time.Now()
+	msgs, err = q.Fetch(2, 20*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(msgs))
+	require.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	require.NoError(t, q.Close())
+	require.Equal(t, ErrClosed, q.Publish([]byte("4")))
+}
+
+func TestMemoryQueueConsumers(t *testing.T) {
+	q := NewMemoryQueue(0, false)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	got := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				msgs, err := q.Fetch(3, 100*time.Millisecond)
+				require.NoError(t, err)
+				if len(msgs) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, msg := range msgs {
+					require.False(t, got[string(msg.Data)])
+					got[string(msg.Data)] = true
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		require.NoError(t, q.Publish([]byte(fmt.Sprintf("%d", i))))
+	}
+	wg.Wait()
+	require.Equal(t, 100, len(got))
+}
+
+func TestMemoryQueueBlockWhenFull(t *testing.T) {
+	q := NewMemoryQueue(1, false)
+	require.NoError(t, q.Publish([]byte("0")))
+
+	published := make(chan error, 1)
+	go func() {
+		published <- q.Publish([]byte("1"))
+	}()
+	select {
+	case <-published:
+		t.Fatal("publish doesn't block once it's full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	msgs, err := q.Fetch(1, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "0", string(msgs[0].Data))
+	select {
+	case err = <-published:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("publish isn't woken up")
+	}
+	require.Equal(t, 1, q.Len())
+
+	// close wakes up the blocked producers
+	go func() {
+		published <- q.Publish([]byte("2"))
+	}()
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, q.Close())
+	select {
+	case err = <-published:
+		require.Equal(t, ErrClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("publish isn't woken up on close")
+	}
+}
@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// KindMemory is an in-process queue, producers and consumers must be in the same process
+	KindMemory = "memory"
+	// KindRedisList is a Redis list, a consumer moves fetched messages to its own processing list
+	KindRedisList = "redis-list"
+	// KindRedisStream is a Redis stream read with a consumer group
+	KindRedisStream = "redis-stream"
+	// KindKafka is a Kafka topic read with a consumer group
+	KindKafka = "kafka"
+)
+
+var (
+	// ErrQueueFull the memory queue is full, and it drops messages instead of blocking
+	ErrQueueFull = errors.New("queue is full")
+	// ErrClosed the queue is closed
+	ErrClosed = errors.New("queue is closed")
+)
+
+// Message is a message fetched from a queue
+type Message struct {
+	Data []byte
+	// ack is the implementation specific handle to acknowledge the message
+	ack interface{}
+}
+
+// Producer publishes messages to a queue
+type Producer interface {
+	Publish(data []byte) error
+	Close() error
+}
+
+// Consumer fetches messages from a queue. A message is delivered at least once,
+// the ones not acknowledged are delivered again after the consumer restarts.
+type Consumer interface {
+	// Fetch returns at most max messages, it waits at most wait if there is none
+	Fetch(max int, wait time.Duration) ([]*Message, error)
+	Ack(msgs ...*Message) error
+	Close() error
+}
+
+// Cfg is the queue config
+type Cfg struct {
+	Kind string
+	// Addrs are the Redis address or the Kafka brokers
+	Addrs []string
+	// Name is the Redis key or the Kafka topic
+	Name string
+	// Group is the consumer group of Redis stream and Kafka
+	Group string
+	// Consumer is the unique name of a consumer in the group
+	Consumer string
+	// Capacity is the capacity of the memory queue
+	Capacity int
+	// DropWhenFull the memory queue drops messages once it's full instead of blocking the producer
+	DropWhenFull bool
+	// MaxLen trims the Redis stream to about MaxLen messages on publish, 0 is unlimited
+	MaxLen int64
+}
+
+// NewProducer returns a producer of the queue. A memory queue must be created
+// with NewMemoryQueue and shared with the consumers instead.
+func NewProducer(cfg Cfg) (p Producer, err error) {
+	switch cfg.Kind {
+	case KindRedisList:
+		p = newRedisListProducer(cfg)
+	case KindRedisStream:
+		p = newRedisStreamProducer(cfg)
+	case KindKafka:
+		p, err = newKafkaProducer(cfg)
+	default:
+		err = errors.Errorf("unsupported producer of queue kind %q", cfg.Kind)
+	}
+	return
+}
+
+// NewConsumer returns a consumer of the queue
+func NewConsumer(cfg Cfg) (c Consumer, err error) {
+	switch cfg.Kind {
+	case KindRedisList:
+		c, err = newRedisListConsumer(cfg)
+	case KindRedisStream:
+		c, err = newRedisStreamConsumer(cfg)
+	case KindKafka:
+		c, err = newKafkaConsumer(cfg)
+	default:
+		err = errors.Errorf("unsupported consumer of queue kind %q", cfg.Kind)
+	}
+	return
+}
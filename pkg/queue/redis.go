@@ -0,0 +1,310 @@
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	streamField = "data"
+
+	// listEnvelope prefixes a redis-list message with a unique id, so an ack removes
+	// exactly the fetched one even if the same data is published twice
+	listEnvelope = "\x00qid:"
+	listIdLen    = 16
+)
+
+// wrapListMessage returns the message with a unique id
+func wrapListMessage(data []byte) (value []byte, err error) {
+	id := make([]byte, listIdLen/2)
+	if _, err = rand.Read(id); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	value = make([]byte, 0, len(listEnvelope)+listIdLen+len(data))
+	value = append(value, listEnvelope...)
+	value = append(value, hex.EncodeToString(id)...)
+	value = append(value, data...)
+	return
+}
+
+// unwrapListMessage returns the data of the message, the ones published without
+// an id are returned as is
+func unwrapListMessage(value string) []byte {
+	if strings.HasPrefix(value, listEnvelope) && len(value) >= len(listEnvelope)+listIdLen {
+		return []byte(value[len(listEnvelope)+listIdLen:])
+	}
+	return []byte(value)
+}
+
+func newRedisClient(cfg Cfg) *redis.Client {
+	addr := "127.0.0.1:6379"
+	if len(cfg.Addrs) > 0 {
+		addr = cfg.Addrs[0]
+	}
+	return redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: "", // no password set
+		DB:       0,  // use default DB
+	})
+}
+
+type redisListProducer struct {
+	rcli *redis.Client
+	key  string
+}
+
+func newRedisListProducer(cfg Cfg) *redisListProducer {
+	return &redisListProducer{
+		rcli: newRedisClient(cfg),
+		key:  cfg.Name,
+	}
+}
+
+func (p *redisListProducer) Publish(data []byte) (err error) {
+	var value []byte
+	if value, err = wrapListMessage(data); err != nil {
+		return
+	}
+	if err = p.rcli.LPush(p.key, value).Err(); err != nil {
+		err = errors.Wrapf(err, "publish to %s", p.key)
+	}
+	return
+}
+
+func (p *redisListProducer) Close() error {
+	return p.rcli.Close()
+}
+
+// redisListConsumer moves the fetched messages to its processing list, and
+// removes them on ack. The processing list is delivered first after restart.
+// Messages are unique with the ids of the envelope, so LREM removes only the acked one.
+type redisListConsumer struct {
+	rcli       *redis.Client
+	key        string
+	processing string
+	recovered  bool
+}
+
+func newRedisListConsumer(cfg Cfg) (c *redisListConsumer, err error) {
+	if cfg.Consumer == "" {
+		err = errors.New("consumer name is required by redis-list queue")
+		return
+	}
+	c = &redisListConsumer{
+		rcli:       newRedisClient(cfg),
+		key:        cfg.Name,
+		processing: cfg.Name + ":processing:" + cfg.Consumer,
+	}
+	return
+}
+
+func (c *redisListConsumer) Fetch(max int, wait time.Duration) (msgs []*Message, err error) {
+	if !c.recovered {
+		var values []string
+		if values, err = c.rcli.LRange(c.processing, 0, -1).Result(); err != nil {
+			err = errors.Wrapf(err, "recover %s", c.processing)
+			return
+		}
+		c.recovered = true
+		if len(values) > 0 {
+			log.Infof("queue: redeliver %d messages of %s", len(values), c.processing)
+			for i := len(values) - 1; i >= 0; i-- {
+				msgs = append(msgs, &Message{Data: unwrapListMessage(values[i]), ack: values[i]})
+			}
+			return
+		}
+	}
+
+	if msgs, err = c.popN(max); err != nil || len(msgs) > 0 {
+		return
+	}
+	if wait < time.Second {
+		// BRPOPLPUSH is in seconds
+		time.Sleep(wait)
+		return c.popN(max)
+	}
+
+	var value string
+	if value, err = c.rcli.BRPopLPush(c.key, c.processing, wait).Result(); err != nil {
+		if err == redis.Nil {
+			err = nil
+			return
+		}
+		err = errors.Wrapf(err, "fetch from %s", c.key)
+		return
+	}
+	msgs = append(msgs, &Message{Data: unwrapListMessage(value), ack: value})
+	var more []*Message
+	if more, err = c.popN(max - 1); err != nil {
+		// the fetched ones are in processing list, deliver them anyway
+		log.Warnf("queue: fetch from %s failed, errors:%+v", c.key, err)
+		err = nil
+	}
+	msgs = append(msgs, more...)
+	return
+}
+
+func (c *redisListConsumer) popN(n int) (msgs []*Message, err error) {
+	for i := 0; i < n; i++ {
+		var value string
+		if value, err = c.rcli.RPopLPush(c.key, c.processing).Result(); err != nil {
+			if err == redis.Nil {
+				err = nil
+				return
+			}
+			err = errors.Wrapf(err, "fetch from %s", c.key)
+			return
+		}
+		msgs = append(msgs, &Message{Data: unwrapListMessage(value), ack: value})
+	}
+	return
+}
+
+func (c *redisListConsumer) Ack(msgs ...*Message) (err error) {
+	pipe := c.rcli.Pipeline()
+	for _, msg := range msgs {
+		pipe.LRem(c.processing, 1, msg.ack)
+	}
+	if _, err = pipe.Exec(); err != nil {
+		err = errors.Wrapf(err, "ack to %s", c.processing)
+	}
+	return
+}
+
+func (c *redisListConsumer) Close() error {
+	return c.rcli.Close()
+}
+
+// redisStreamProducer adds messages to the stream, and trims it to about
+// maxLen messages since consumers only ack them
+type redisStreamProducer struct {
+	rcli   *redis.Client
+	key    string
+	maxLen int64
+}
+
+func newRedisStreamProducer(cfg Cfg) *redisStreamProducer {
+	return &redisStreamProducer{
+		rcli:   newRedisClient(cfg),
+		key:    cfg.Name,
+		maxLen: cfg.MaxLen,
+	}
+}
+
+func (p *redisStreamProducer) Publish(data []byte) (err error) {
+	if err = p.rcli.XAdd(&redis.XAddArgs{
+		Stream:       p.key,
+		MaxLenApprox: p.maxLen,
+		ID:           "*",
+		Values:       map[string]interface{}{streamField: data},
+	}).Err(); err != nil {
+		err = errors.Wrapf(err, "publish to %s", p.key)
+	}
+	return
+}
+
+func (p *redisStreamProducer) Close() error {
+	return p.rcli.Close()
+}
+
+// redisStreamConsumer reads the stream with a consumer group. Its pending
+// messages are delivered first after restart. Acked messages are kept for the
+// other groups, the producer trims the stream.
+type redisStreamConsumer struct {
+	rcli      *redis.Client
+	key       string
+	group     string
+	consumer  string
+	recovered bool
+	// pending is the id the pending messages are read after, they're
+	// not acked until their sessions are closed
+	pending string
+}
+
+func newRedisStreamConsumer(cfg Cfg) (c *redisStreamConsumer, err error) {
+	if cfg.Group == "" || cfg.Consumer == "" {
+		err = errors.New("group and consumer name are required by redis-stream queue")
+		return
+	}
+	c = &redisStreamConsumer{
+		rcli:     newRedisClient(cfg),
+		key:      cfg.Name,
+		group:    cfg.Group,
+		consumer: cfg.Consumer,
+		pending:  "0",
+	}
+	if err = c.rcli.Do("XGROUP", "CREATE", c.key, c.group, "0", "MKSTREAM").Err(); err != nil {
+		if err.Error() != "BUSYGROUP Consumer Group name already exists" {
+			err = errors.Wrapf(err, "create group %s of %s", c.group, c.key)
+			return
+		}
+		err = nil
+	}
+	return
+}
+
+func (c *redisStreamConsumer) Fetch(max int, wait time.Duration) (msgs []*Message, err error) {
+	id, block := ">", wait
+	if !c.recovered {
+		// the pending messages of this consumer
+		id, block = c.pending, -1
+	} else if block <= 0 {
+		// 0 blocks forever
+		block = time.Millisecond
+	}
+
+	var streams []redis.XStream
+	if streams, err = c.rcli.XReadGroup(&redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.consumer,
+		Streams:  []string{c.key, id},
+		Count:    int64(max),
+		Block:    block,
+	}).Result(); err != nil {
+		if err == redis.Nil {
+			err = nil
+			return
+		}
+		err = errors.Wrapf(err, "fetch from %s", c.key)
+		return
+	}
+	for _, stream := range streams {
+		for _, xmsg := range stream.Messages {
+			data, _ := xmsg.Values[streamField].(string)
+			msgs = append(msgs, &Message{Data: []byte(data), ack: xmsg.ID})
+			c.pending = xmsg.ID
+		}
+	}
+	if !c.recovered && len(msgs) < max {
+		c.recovered = true
+		if len(msgs) > 0 {
+			log.Infof("queue: redeliver %d pending messages of %s", len(msgs), c.consumer)
+		}
+	}
+	return
+}
+
+func (c *redisStreamConsumer) Ack(msgs ...*Message) (err error) {
+	if len(msgs) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.ack.(string))
+	}
+	if err = c.rcli.XAck(c.key, c.group, ids...).Err(); err != nil {
+		err = errors.Wrapf(err, "ack to %s", c.key)
+	}
+	return
+}
+
+func (c *redisStreamConsumer) Close() error {
+	return c.rcli.Close()
+}
@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/require"
+)
+
+var redisAddr = os.Getenv("REDIS_ADDR")
+
+func newTestRedis(t *testing.T) *redis.Client {
+	if redisAddr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	rcli := redis.NewClient(&redis.Options{Addr: redisAddr})
+	require.NoError(t, rcli.Del("test_queue", "test_queue:processing:c1").Err())
+	return rcli
+}
+
+func TestListEnvelope(t *testing.T) {
+	value, err := wrapListMessage([]byte("img"))
+	require.NoError(t, err)
+	require.Equal(t, "img", string(unwrapListMessage(string(value))))
+	other, err := wrapListMessage([]byte("img"))
+	require.NoError(t, err)
+	require.NotEqual(t, value, other)
+
+	// published without an id
+	require.Equal(t, "img", string(unwrapListMessage("img")))
+}
+
+func TestRedisListQueue(t *testing.T) {
+	rcli := newTestRedis(t)
+	defer rcli.Close()
+
+	cfg := Cfg{Kind: KindRedisList, Addrs: []string{redisAddr}, Name: "test_queue", Consumer: "c1"}
+	p, err := NewProducer(cfg)
+	require.NoError(t, err)
+	defer p.Close()
+	for _, data := range []string{"a", "a", "b"} {
+		require.NoError(t, p.Publish([]byte(data)))
+	}
+
+	c, err := NewConsumer(cfg)
+	require.NoError(t, err)
+	msgs, err := c.Fetch(2, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(msgs))
+	require.Equal(t, "a", string(msgs[0].Data))
+	require.Equal(t, "a", string(msgs[1].Data))
+	// acking one of the same data keeps the other
+	require.NoError(t, c.Ack(msgs[0]))
+	require.Equal(t, int64(1), rcli.LLen("test_queue:processing:c1").Val())
+	require.NoError(t, c.Close())
+
+	// the unacked ones are delivered first after restart
+	c, err = NewConsumer(cfg)
+	require.NoError(t, err)
+	defer c.Close()
+	msgs, err = c.Fetch(10, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(msgs))
+	require.Equal(t, "a", string(msgs[0].Data))
+	require.NoError(t, c.Ack(msgs...))
+	msgs, err = c.Fetch(10, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(msgs))
+	require.Equal(t, "b", string(msgs[0].Data))
+	require.NoError(t, c.Ack(msgs...))
+	require.Equal(t, int64(0), rcli.LLen("test_queue:processing:c1").Val())
+}
+
+func TestRedisStreamQueue(t *testing.T) {
+	rcli := newTestRedis(t)
+	defer rcli.Close()
+
+	cfg := Cfg{Kind: KindRedisStream, Addrs: []string{redisAddr}, Name: "test_queue", Group: "g1", Consumer: "c1", MaxLen: 100}
+	c1, err := NewConsumer(cfg)
+	require.NoError(t, err)
+	defer c1.Close()
+	cfg2 := cfg
+	cfg2.Group = "g2"
+	c2, err := NewConsumer(cfg2)
+	require.NoError(t, err)
+	defer c2.Close()
+
+	p, err := NewProducer(cfg)
+	require.NoError(t, err)
+	defer p.Close()
+	require.NoError(t, p.Publish([]byte("a")))
+
+	// the pending ones first, then the new ones
+	msgs, err := c1.Fetch(10, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(msgs))
+	msgs, err = c1.Fetch(10, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(msgs))
+	require.Equal(t, "a", string(msgs[0].Data))
+	require.NoError(t, c1.Ack(msgs...))
+
+	// the acked message is still delivered to the other group
+	msgs, err = c2.Fetch(10, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(msgs))
+	msgs, err = c2.Fetch(10, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(msgs))
+	require.Equal(t, "a", string(msgs[0].Data))
+	require.Equal(t, int64(1), rcli.XLen("test_queue").Val())
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrMalformedCameraMeta the Hardware.Meta of a camera has malformed entries
+var ErrMalformedCameraMeta = errors.New("malformed camera meta")
+
+// CameraInfo is the typed attributes of a camera, it's parsed from Hardware.Meta
+// of CMDB, for example: "position=1,direction=north,zone=A,entrance=true,enabled=true"
+type CameraInfo struct {
+	Position  uint32
+	Direction string
+	Zone      string
+	// Entrance and Exit are set if the camera watches a door of the shop
+	Entrance bool
+	Exit     bool
+	Enabled  bool
+	// Extra keeps the unknown keys
+	Extra map[string]string
+}
+
+// ParseCameraMeta parses the Hardware.Meta. Well-formed entries are always kept in info,
+// err reports all the malformed ones.
+func ParseCameraMeta(meta string) (info CameraInfo, err error) {
+	info.Enabled = true
+	var malformed []string
+	for _, field := range strings.Split(meta, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			malformed = append(malformed, field)
+			continue
+		}
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if key == "" {
+			malformed = append(malformed, field)
+			continue
+		}
+
+		var perr error
+		switch key {
+		case "position":
+			var pos uint64
+			if pos, perr = strconv.ParseUint(val, 10, 32); perr == nil {
+				info.Position = uint32(pos)
+			}
+		case "direction":
+			info.Direction = val
+		case "zone":
+			info.Zone = val
+		case "entrance":
+			info.Entrance, perr = strconv.ParseBool(val)
+		case "exit":
+			info.Exit, perr = strconv.ParseBool(val)
+		case "enabled":
+			info.Enabled, perr = strconv.ParseBool(val)
+		default:
+			if info.Extra == nil {
+				info.Extra = make(map[string]string)
+			}
+			info.Extra[key] = val
+		}
+		if perr != nil {
+			malformed = append(malformed, field)
+		}
+	}
+
+	if len(malformed) > 0 {
+		err = errors.Wrapf(ErrMalformedCameraMeta, "%q: %s", meta, strings.Join(malformed, ", "))
+	}
+	return
+}
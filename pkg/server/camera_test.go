@@ -0,0 +1,33 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCameraMeta(t *testing.T) {
+	cam, err := ParseCameraMeta("position=3, direction=north,zone=A,entrance=true,floor=2")
+	require.NoError(t, err)
+	require.Equal(t, CameraInfo{
+		Position:  3,
+		Direction: "north",
+		Zone:      "A",
+		Entrance:  true,
+		Enabled:   true,
+		Extra:     map[string]string{"floor": "2"},
+	}, cam)
+
+	cam, err = ParseCameraMeta("")
+	require.NoError(t, err)
+	require.True(t, cam.Enabled)
+
+	// the well-formed entries are kept, the malformed ones are reported
+	cam, err = ParseCameraMeta("position=x,zone=B,exit,enabled=no,=1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "position=x")
+	require.Contains(t, err.Error(), "exit")
+	require.Contains(t, err.Error(), "enabled=no")
+	require.Contains(t, err.Error(), "=1")
+	require.Equal(t, "B", cam.Zone)
+}
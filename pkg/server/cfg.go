@@ -12,6 +12,16 @@ type Cfg struct {
 	Retry          RetryCfg
 	EurekaAddr     string
 	EurekaApp      string
+	Liveness       LivenessCfg
+	// CmdbCacheTTL and CmdbNegativeTTL are ttl of found and not found terminals
+	CmdbCacheTTL    time.Duration
+	CmdbNegativeTTL time.Duration
+	// CmdbBatchWindow lookups inside the window are sent in one request, 0 disables it
+	CmdbBatchWindow time.Duration
+	CmdbBatchSize   int
+	// PositionFile is a YAML or JSON file mapping cameras to positions, CMDB is used if it's empty
+	PositionFile   string
+	PositionReload time.Duration
 }
 
 // OssCfg oss cfg
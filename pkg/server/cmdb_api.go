@@ -7,18 +7,20 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fagongzi/log"
 	"github.com/hudl/fargo"
-	cache "github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
 )
 
 const (
-	MacLen               = 12
-	BlacklistMinutes     = 10
-	HttpRRTimeout    int = 2 //in seconds
+	MacLen            = 12
+	HttpRRTimeout int = 2 //in seconds
+	// EurekaPollInterval interval of refreshing CMDB instances from Eureka
+	EurekaPollInterval = 30 * time.Second
 )
 
 type Hardware struct {
@@ -42,41 +44,118 @@ type TermsRespBody struct {
 	Data `json:"data"`
 }
 
+type cmdbInstance struct {
+	url     string
+	breaker *breaker
+}
+
+// CmdbApi is safe for concurrent callers. It load balances the UP instances
+// in round robin, and skips the instances whose breaker is open.
 type CmdbApi struct {
+	sync.RWMutex
+
 	eurekaAddr string
 	eurekaApp  string
 	conn       fargo.EurekaConnection
-	app        *fargo.Application
-	nextInst   int
-	blacklist  *cache.Cache
+	instances  []*cmdbInstance
+	nextInst   uint64
 	hc         *http.Client
+	cache      *termCache
+	batcher    *termBatcher
+	stopC      chan struct{}
 }
 
+// NewCmdbApi returns a PositionResolver backed by the CMDB service registered in Eureka
 func NewCmdbApi(eurekaAddr, eurekaApp string) (ca *CmdbApi, err error) {
-	ca = &CmdbApi{
-		eurekaAddr: eurekaAddr,
-		eurekaApp:  eurekaApp,
-		nextInst:   0,
-		blacklist:  cache.New(time.Second*time.Duration(BlacklistMinutes), time.Minute),
-		hc:         &http.Client{Timeout: time.Duration(HttpRRTimeout) * time.Second},
-	}
+	ca = newCmdbApi(eurekaAddr, eurekaApp)
 	addrs := strings.Split(eurekaAddr, ",")
 	ca.conn = fargo.NewConn(addrs...)
-	if ca.app, err = ca.conn.GetApp(eurekaApp); err != nil {
+	var app *fargo.Application
+	if app, err = ca.conn.GetApp(eurekaApp); err != nil {
 		err = errors.Wrap(err, "")
 		return
 	}
-	regInfo, _ := json.Marshal(ca.app)
+	regInfo, _ := json.Marshal(app)
 	log.Infof("Application %v in Eureka: %+v", eurekaApp, string(regInfo))
-	// starts a goroutine that updates the application on poll interval
-	ca.conn.PollInterval = 30
-	ca.conn.UpdateApp(ca.app)
+	ca.setInstances(app)
+	go ca.startPoll(EurekaPollInterval)
 	return
 }
 
+func newCmdbApi(eurekaAddr, eurekaApp string) (ca *CmdbApi) {
+	ca = &CmdbApi{
+		eurekaAddr: eurekaAddr,
+		eurekaApp:  eurekaApp,
+		hc:         &http.Client{Timeout: time.Duration(HttpRRTimeout) * time.Second},
+		stopC:      make(chan struct{}),
+	}
+	ca.cache = newTermCache(DefaultCmdbCacheTTL, DefaultCmdbNegativeTTL, ca.getTerm)
+	return
+}
+
+// Close stops refreshing the instances from Eureka
+func (ca *CmdbApi) Close() {
+	close(ca.stopC)
+}
+
+// startPoll refreshes the instances, fargo's UpdateApp writes the application
+// in place which races with the readers, so we replace a snapshot instead.
+func (ca *CmdbApi) startPoll(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ca.stopC:
+			return
+		case <-ticker.C:
+			app, err := ca.conn.GetApp(ca.eurekaApp)
+			if err != nil {
+				log.Warnf("cmdb: refresh %s from eureka failed, keep the old instances, errors:%+v", ca.eurekaApp, err)
+				continue
+			}
+			ca.setInstances(app)
+		}
+	}
+}
+
+// setInstances replaces the instances with the UP ones of app, breakers of the
+// existing instances are kept.
+func (ca *CmdbApi) setInstances(app *fargo.Application) {
+	ca.Lock()
+	defer ca.Unlock()
+
+	breakers := make(map[string]*breaker, len(ca.instances))
+	for _, inst := range ca.instances {
+		breakers[inst.url] = inst.breaker
+	}
+	instances := make([]*cmdbInstance, 0, len(app.Instances))
+	for _, inst := range app.Instances {
+		if inst.Status != fargo.UP {
+			log.Debugf("cmdb: skip instance %s in status %s", inst.HomePageUrl, inst.Status)
+			continue
+		}
+		b, ok := breakers[inst.HomePageUrl]
+		if !ok {
+			b = newBreaker()
+		}
+		instances = append(instances, &cmdbInstance{url: inst.HomePageUrl, breaker: b})
+	}
+	if len(instances) != len(ca.instances) {
+		log.Infof("cmdb: %s has %d UP instances", ca.eurekaApp, len(instances))
+	}
+	ca.instances = instances
+}
+
+func (ca *CmdbApi) getInstances() []*cmdbInstance {
+	ca.RLock()
+	defer ca.RUnlock()
+	return ca.instances
+}
+
 func (ca *CmdbApi) GetShop(mac string) (shop uint64, found bool, err error) {
 	var terms *TermsRespBody
-	if terms, found, err = ca.getTerm(mac); err != nil || !found {
+	if terms, found, err = ca.cache.get(mac); err != nil || !found {
 		return
 	}
 	if shop, err = strconv.ParseUint(terms.Data.Items[0].AreaId, 10, 64); err != nil {
@@ -87,8 +166,17 @@ func (ca *CmdbApi) GetShop(mac string) (shop uint64, found bool, err error) {
 }
 
 func (ca *CmdbApi) GetPosition(mac, cameraIp string) (shop uint64, pos uint32, found bool, err error) {
+	var cam CameraInfo
+	shop, cam, found, err = ca.GetCamera(mac, cameraIp)
+	pos = cam.Position
+	return
+}
+
+// GetCamera implements PositionResolver. A malformed Hardware.Meta is reported as an error of
+// ErrMalformedCameraMeta, cam still has the well-formed entries.
+func (ca *CmdbApi) GetCamera(mac, cameraIp string) (shop uint64, cam CameraInfo, found bool, err error) {
 	var terms *TermsRespBody
-	if terms, found, err = ca.getTerm(mac); err != nil || !found {
+	if terms, found, err = ca.cache.get(mac); err != nil || !found {
 		return
 	}
 	if shop, err = strconv.ParseUint(terms.Data.Items[0].AreaId, 10, 64); err != nil {
@@ -99,95 +187,117 @@ func (ca *CmdbApi) GetPosition(mac, cameraIp string) (shop uint64, pos uint32, f
 	for _, hw := range terms.Data.Items[0].Hardwares {
 		if hw.Ip == cameraIp {
 			found = true
-			fields := strings.Split(hw.Meta, ",")
-			for _, field := range fields {
-				kv := strings.Split(field, "=")
-				if len(kv) == 2 && kv[0] == "position" {
-					var pos2 uint64
-					if pos2, err = strconv.ParseUint(kv[1], 10, 64); err != nil {
-						err = errors.Wrapf(err, "")
-						return
-					}
-					pos = uint32(pos2)
-				}
+			if cam, err = ParseCameraMeta(hw.Meta); err != nil {
+				err = errors.Wrapf(err, "terminal %s, camera %s", mac, cameraIp)
 			}
+			return
 		}
-
-	}
-	if !found {
-		log.Debugf("terms %+v", terms)
 	}
+	log.Debugf("terms %+v", terms)
 	return
 }
 
 func (ca *CmdbApi) getTerm(mac string) (terms *TermsRespBody, found bool, err error) {
-	// TODO: read-write race condition of *ca.app?
-	instances := ca.app.Instances
+	q := url.Values{}
+	q.Set("deviceId", mac)
+	err = ca.balance(func(instURL string) (retriable bool, err error) {
+		var respBody []byte
+		terms, respBody, _, retriable, err = ca.queryTerminals(instURL, q)
+		if err != nil {
+			return
+		}
+		found = false
+		if terms.Data.Total != "0" {
+			if terms.Data.Total != "1" || len(terms.Data.Items) != 1 {
+				log.Errorf("there are multiple terminals in respBody %+v", string(respBody))
+			} else if terms.Data.Items[0].DeviceId != mac {
+				log.Errorf("incorrect MAC, want %v, have %v, respBody %+v", mac, terms.Data.Items[0].DeviceId, string(respBody))
+			} else {
+				found = true
+			}
+		}
+		return
+	})
+	return
+}
+
+// balance calls do with the instances in round robin until one of them isn't faulty
+func (ca *CmdbApi) balance(do func(instURL string) (retriable bool, err error)) (err error) {
+	instances := ca.getInstances()
 	numInstances := len(instances)
 	if numInstances == 0 {
 		err = errors.Errorf("%s instances are empty", ca.eurekaApp)
 		return
 	}
-	var instURL string
+
+	start := atomic.AddUint64(&ca.nextInst, 1)
+	tried := false
 	for i := 0; i < numInstances; i++ {
-		instURL = instances[ca.nextInst%numInstances].HomePageUrl
-		ca.nextInst = (ca.nextInst + 1) % numInstances
-		if _, found = ca.blacklist.Get(instURL); found {
+		inst := instances[(start+uint64(i))%uint64(numInstances)]
+		if !inst.breaker.allow() {
 			continue
 		}
+		tried = true
 
-		var servURL string
-		if servURL, err = JoinURL(instURL, "/terminals"); err != nil {
+		var retriable bool
+		if retriable, err = do(inst.url); err == nil || !retriable {
+			// the instance is fine, the request may be not
+			inst.breaker.succeed()
 			return
 		}
-		var req *http.Request
-		req, err = http.NewRequest("GET", servURL, nil)
-		// https://stackoverflow.com/questions/30652577/go-doing-a-get-request-and-building-the-querystring/30657518
-		q := req.URL.Query()
-		q.Set("deviceId", mac)
-		req.URL.RawQuery = q.Encode()
-		req.Header.Set("__no_auth__", "foo")
-		log.Debugf("request url: %+v", req.URL.String())
-		var resp *http.Response
-		var respBody []byte
-		if resp, err = ca.hc.Do(req); err != nil {
-			err = errors.Wrap(err, "")
-			log.Warnf("got error %+v", err)
-			ca.blacklist.SetDefault(instURL, err)
-			continue
-		}
-		defer resp.Body.Close()
-		if respBody, err = ioutil.ReadAll(resp.Body); err != nil {
-			err = errors.Wrap(err, "")
-			log.Warnf("got error %+v", err)
-			ca.blacklist.SetDefault(instURL, err)
-			continue
-		}
-		log.Debugf("respBody: %+v", string(respBody))
-		terms = &TermsRespBody{}
-		if err = json.Unmarshal(respBody, terms); err != nil {
-			err = errors.Wrap(err, "")
-			log.Warnf("got error %+v", err)
-			ca.blacklist.SetDefault(instURL, err)
-			continue
-		}
-		if terms.Data.Total != "0" {
-			if terms.Data.Total != "1" {
-				log.Errorf("there are multiple terminals in respBody %+v", string(respBody))
-			} else if terms.Data.Items[0].DeviceId != mac {
-				log.Errorf("incorrect MAC, want %v, have %v, respBody %+v", mac, terms.Data.Items[0].DeviceId, string(respBody))
-			} else {
-				found = true
-			}
+		log.Warnf("cmdb: request %s failed, errors:%+v", inst.url, err)
+		if inst.breaker.fail() {
+			log.Warnf("cmdb: instance %s is open for errors", inst.url)
 		}
-		log.Debugf("respBody parsed as: %+v", terms)
+	}
+	if !tried {
+		err = errors.Errorf("%s breakers of all instances are open", ca.eurekaApp)
+	}
+	return
+}
+
+// queryTerminals queries /terminals of an instance, retriable is true if the
+// instance is faulty and the request could be sent to another one
+func (ca *CmdbApi) queryTerminals(instURL string, q url.Values) (terms *TermsRespBody, respBody []byte, status int, retriable bool, err error) {
+	var servURL string
+	if servURL, err = JoinURL(instURL, "/terminals"); err != nil {
 		return
 	}
-	if instURL == "" {
-		err = errors.Errorf("%s all instances are in blacklist", ca.eurekaApp)
+	var req *http.Request
+	if req, err = http.NewRequest("GET", servURL, nil); err != nil {
+		err = errors.Wrap(err, "")
 		return
 	}
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("__no_auth__", "foo")
+	log.Debugf("request url: %+v", req.URL.String())
 
+	retriable = true
+	var resp *http.Response
+	if resp, err = ca.hc.Do(req); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	defer resp.Body.Close()
+	status = resp.StatusCode
+	if respBody, err = ioutil.ReadAll(resp.Body); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if status != http.StatusOK {
+		// 5xx are the faults of the instance
+		retriable = status >= http.StatusInternalServerError
+		err = errors.Errorf("%s responds %s, respBody %s", instURL, resp.Status, string(respBody))
+		return
+	}
+	log.Debugf("respBody: %+v", string(respBody))
+	terms = &TermsRespBody{}
+	if err = json.Unmarshal(respBody, terms); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	retriable = false
+	log.Debugf("respBody parsed as: %+v", terms)
 	return
 }
 
@@ -2,22 +2,167 @@ package server
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/hudl/fargo"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
 var (
-	EurekaAddr = "http://192.168.150.138:8761/eureka"
+	EurekaAddr = os.Getenv("EUREKA_ADDR") // for example: http://192.168.150.138:8761/eureka
 	EurekaApp  = "iot-backend"
 	MyMac      = "309c233431b2"
 	MyCamera   = "192.168.150.243"
 )
 
+const (
+	fakeTermsRsp = `{"code":"0","data":{"items":[{"deviceId":"309c233431b2","areaId":"8",
+"hardwares":[{"ip":"192.168.150.243","Meta":"position=1"},{"ip":"192.168.150.244","Meta":"position=2,zone=A,entrance=true"},{"ip":"192.168.150.245","Meta":"position,zone=B"}]}],"total":"1"}}`
+	fakeEmptyRsp = `{"code":"0","data":{"items":[],"total":"0"}}`
+)
+
+// newFakeCmdb returns a CmdbApi whose only instance is a local http server
+func newFakeCmdb(t *testing.T) (*CmdbApi, *httptest.Server) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/terminals", r.URL.Path)
+		if r.URL.Query().Get("deviceId") == MyMac {
+			w.Write([]byte(fakeTermsRsp))
+		} else {
+			w.Write([]byte(fakeEmptyRsp))
+		}
+	}))
+
+	ca := newCmdbApi("", EurekaApp)
+	ca.setInstances(&fargo.Application{
+		Instances: []*fargo.Instance{{HomePageUrl: ts.URL + "/", Status: fargo.UP}},
+	})
+	return ca, ts
+}
+
+func TestCmdbBreaker(t *testing.T) {
+	var badCalls int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&badCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fakeTermsRsp))
+	}))
+	defer good.Close()
+
+	ca := newCmdbApi("", EurekaApp)
+	ca.setInstances(&fargo.Application{
+		Instances: []*fargo.Instance{
+			{HomePageUrl: bad.URL + "/", Status: fargo.UP},
+			{HomePageUrl: good.URL + "/", Status: fargo.UP},
+			{HomePageUrl: "http://127.0.0.1:1/", Status: fargo.DOWN},
+		},
+	})
+	require.Equal(t, 2, len(ca.getInstances()))
+
+	// concurrent callers always get the answer, the bad instance is skipped
+	// once its breaker is open
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				_, found, err := ca.getTerm(MyMac)
+				require.NoError(t, err)
+				require.True(t, found)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, breakerOpen, ca.getInstances()[0].breaker.getState())
+	require.True(t, atomic.LoadInt32(&badCalls) < 100)
+
+	// the breakers survive refreshing from eureka
+	ca.setInstances(&fargo.Application{
+		Instances: []*fargo.Instance{{HomePageUrl: bad.URL + "/", Status: fargo.UP}},
+	})
+	_, _, err := ca.getTerm(MyMac)
+	require.Error(t, err)
+}
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker()
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	for i := 1; i < BreakerFailures; i++ {
+		require.False(t, b.fail())
+		require.True(t, b.allow())
+	}
+	require.True(t, b.fail())
+	require.False(t, b.allow())
+
+	// one trial after the cooldown, its failure doubles the cooldown
+	now = now.Add(BreakerCooldown)
+	require.True(t, b.allow())
+	require.False(t, b.allow())
+	require.True(t, b.fail())
+	now = now.Add(BreakerCooldown)
+	require.False(t, b.allow())
+	now = now.Add(BreakerCooldown)
+	require.True(t, b.allow())
+	b.succeed()
+	require.Equal(t, breakerClosed, b.getState())
+	require.True(t, b.allow())
+}
+
+func TestFakeCmdbGetPosition(t *testing.T) {
+	cmdb, ts := newFakeCmdb(t)
+	defer ts.Close()
+
+	shop, found, err := cmdb.GetShop(MyMac)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, uint64(8), shop)
+
+	shop, pos, found, err := cmdb.GetPosition(MyMac, "192.168.150.244")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, uint64(8), shop)
+	require.Equal(t, uint32(2), pos)
+
+	_, cam, found, err := cmdb.GetCamera(MyMac, "192.168.150.244")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "A", cam.Zone)
+	require.True(t, cam.Entrance)
+
+	// the malformed key is reported
+	_, cam, found, err = cmdb.GetCamera(MyMac, "192.168.150.245")
+	require.Equal(t, ErrMalformedCameraMeta, errors.Cause(err))
+	require.True(t, found)
+	require.Equal(t, "B", cam.Zone)
+
+	_, _, found, err = cmdb.GetPosition(MyMac, "192.168.150.1")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	_, found, err = cmdb.GetShop("000000000000")
+	require.NoError(t, err)
+	require.False(t, found)
+}
+
 /*
 curl -X GET 'http://192.168.150.138:8000/terminals?deviceId=309c233431b2' -H '__no_auth__: foo'
 */
 func TestGetShop(t *testing.T) {
+	if EurekaAddr == "" {
+		t.Skip("EUREKA_ADDR is not set")
+	}
 	var err error
 	var cmdb *CmdbApi
 	var shop uint64
@@ -32,6 +177,9 @@ func TestGetShop(t *testing.T) {
 }
 
 func TestGetPosition(t *testing.T) {
+	if EurekaAddr == "" {
+		t.Skip("EUREKA_ADDR is not set")
+	}
 	var err error
 	var cmdb *CmdbApi
 	var shop uint64
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultCmdbBatchWindow lookups of different macs inside the window are sent in one request
+	DefaultCmdbBatchWindow = 20 * time.Millisecond
+	// DefaultCmdbBatchSize max macs of a batch request
+	DefaultCmdbBatchSize = 50
+	// DefaultCmdbBatchReprobe batch lookups are tried again after the interval once the CMDB turned out not to support them
+	DefaultCmdbBatchReprobe = 10 * time.Minute
+)
+
+var (
+	// errBatchUnsupported the CMDB doesn't support querying multiple deviceIds
+	errBatchUnsupported = errors.New("cmdb doesn't support batch lookup")
+
+	cmdbBatchSizeHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "cmdb_batch_size",
+			Help:      "Macs of CMDB batch lookups.",
+			Buckets:   prometheus.LinearBuckets(1, 5, 10),
+		})
+	cmdbBatchOnce sync.Once
+)
+
+func initMetricsForCmdbBatch() {
+	prometheus.MustRegister(cmdbBatchSizeHistogram)
+}
+
+type batchLookup struct {
+	mac   string
+	done  chan struct{}
+	terms *TermsRespBody
+	found bool
+	err   error
+}
+
+// termBatcher collects lookups of different macs inside a window, and resolves
+// them with one multi-id query. It falls back to parallel single queries once
+// the CMDB turns out not to support it, and probes it again after reprobe.
+type termBatcher struct {
+	sync.Mutex
+
+	window  time.Duration
+	maxSize int
+	reprobe time.Duration
+	pending []*batchLookup
+	timer   *time.Timer
+	// unsupportedAt is the unix nano when batch lookups turned out unsupported, 0 if they are supported
+	unsupportedAt int64
+	multi         func(macs []string) (map[string]*TermsRespBody, error)
+	single        func(mac string) (*TermsRespBody, bool, error)
+}
+
+func newTermBatcher(window time.Duration, maxSize int,
+	multi func(macs []string) (map[string]*TermsRespBody, error),
+	single func(mac string) (*TermsRespBody, bool, error)) *termBatcher {
+	cmdbBatchOnce.Do(initMetricsForCmdbBatch)
+	if maxSize <= 0 {
+		maxSize = DefaultCmdbBatchSize
+	}
+	return &termBatcher{
+		window:  window,
+		maxSize: maxSize,
+		reprobe: DefaultCmdbBatchReprobe,
+		multi:   multi,
+		single:  single,
+	}
+}
+
+func (tb *termBatcher) get(mac string) (terms *TermsRespBody, found bool, err error) {
+	l := &batchLookup{mac: mac, done: make(chan struct{})}
+
+	tb.Lock()
+	tb.pending = append(tb.pending, l)
+	if len(tb.pending) >= tb.maxSize {
+		batch := tb.takeLocked()
+		tb.Unlock()
+		go tb.flush(batch)
+	} else {
+		if len(tb.pending) == 1 {
+			tb.timer = time.AfterFunc(tb.window, tb.onTimeout)
+		}
+		tb.Unlock()
+	}
+
+	<-l.done
+	return l.terms, l.found, l.err
+}
+
+func (tb *termBatcher) onTimeout() {
+	tb.Lock()
+	batch := tb.takeLocked()
+	tb.Unlock()
+	tb.flush(batch)
+}
+
+func (tb *termBatcher) takeLocked() (batch []*batchLookup) {
+	if tb.timer != nil {
+		tb.timer.Stop()
+		tb.timer = nil
+	}
+	batch = tb.pending
+	tb.pending = nil
+	return
+}
+
+func (tb *termBatcher) flush(batch []*batchLookup) {
+	if len(batch) == 0 {
+		return
+	}
+	cmdbBatchSizeHistogram.Observe(float64(len(batch)))
+
+	if len(batch) > 1 && tb.batchable() {
+		macs := make([]string, 0, len(batch))
+		requested := make(map[string]bool, len(batch))
+		for _, l := range batch {
+			if !requested[l.mac] {
+				requested[l.mac] = true
+				macs = append(macs, l.mac)
+			}
+		}
+		results, err := tb.multi(macs)
+		if err == nil {
+			if atomic.SwapInt64(&tb.unsupportedAt, 0) != 0 {
+				log.Infof("cmdb: batch lookup is supported again")
+			}
+			for _, l := range batch {
+				l.terms, l.found = results[l.mac]
+				if !l.found {
+					l.terms = &TermsRespBody{Data: Data{Total: "0"}}
+				}
+				close(l.done)
+			}
+			return
+		}
+		if errors.Cause(err) == errBatchUnsupported {
+			atomic.StoreInt64(&tb.unsupportedAt, time.Now().UnixNano())
+			log.Warnf("cmdb: batch lookup is unsupported, fall back to single lookups for %v, errors:%+v",
+				tb.reprobe, err)
+		} else {
+			log.Warnf("cmdb: batch lookup of %d macs failed, retry with single lookups, errors:%+v", len(batch), err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, l := range batch {
+		wg.Add(1)
+		go func(l *batchLookup) {
+			defer wg.Done()
+			l.terms, l.found, l.err = tb.single(l.mac)
+			close(l.done)
+		}(l)
+	}
+	wg.Wait()
+}
+
+// batchable returns true if batch lookups are supported, or it's time to probe them again
+func (tb *termBatcher) batchable() bool {
+	at := atomic.LoadInt64(&tb.unsupportedAt)
+	return at == 0 || time.Since(time.Unix(0, at)) >= tb.reprobe
+}
+
+// SetBatch enables batch lookups of terminals, a window of 0 disables it
+func (ca *CmdbApi) SetBatch(window time.Duration, maxSize int) {
+	if window <= 0 {
+		ca.cache.setFetch(ca.getTerm)
+		return
+	}
+	ca.batcher = newTermBatcher(window, maxSize, ca.getTerms, ca.getTerm)
+	ca.cache.setFetch(ca.batcher.get)
+}
+
+// getTerms queries multiple terminals with /terminals?deviceIds=mac1,mac2
+func (ca *CmdbApi) getTerms(macs []string) (results map[string]*TermsRespBody, err error) {
+	q := url.Values{}
+	q.Set("deviceIds", strings.Join(macs, ","))
+	err = ca.balance(func(instURL string) (retriable bool, err error) {
+		var terms *TermsRespBody
+		var status int
+		if terms, _, status, retriable, err = ca.queryTerminals(instURL, q); err != nil {
+			switch status {
+			case http.StatusBadRequest, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+				retriable = false
+				err = errors.Wrap(errBatchUnsupported, err.Error())
+			}
+			return
+		}
+
+		requested := make(map[string]bool, len(macs))
+		for _, mac := range macs {
+			requested[mac] = true
+		}
+		duplicated := make(map[string]bool)
+		results = make(map[string]*TermsRespBody, len(terms.Data.Items))
+		for _, item := range terms.Data.Items {
+			if !requested[item.DeviceId] {
+				// deviceIds is ignored, the CMDB returns the terminals unfiltered
+				err = errors.Wrapf(errBatchUnsupported, "unrequested terminal %s in response", item.DeviceId)
+				return
+			}
+			if _, ok := results[item.DeviceId]; ok || duplicated[item.DeviceId] {
+				if !duplicated[item.DeviceId] {
+					log.Errorf("there are multiple terminals of %s in response", item.DeviceId)
+					duplicated[item.DeviceId] = true
+				}
+				continue
+			}
+			results[item.DeviceId] = &TermsRespBody{
+				Code: terms.Code,
+				Data: Data{Items: []Item{item}, Total: "1"},
+			}
+		}
+		for mac := range duplicated {
+			delete(results, mac)
+		}
+		return
+	})
+	return
+}
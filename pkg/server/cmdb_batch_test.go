@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hudl/fargo"
+	"github.com/stretchr/testify/require"
+)
+
+func newBatchCmdb(t *testing.T, supportBatch bool) (ca *CmdbApi, ts *httptest.Server, multiCalls, singleCalls *int32) {
+	multiCalls, singleCalls = new(int32), new(int32)
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var macs []string
+		if ids := r.URL.Query().Get("deviceIds"); ids != "" {
+			atomic.AddInt32(multiCalls, 1)
+			if !supportBatch {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			macs = strings.Split(ids, ",")
+		} else {
+			atomic.AddInt32(singleCalls, 1)
+			macs = []string{r.URL.Query().Get("deviceId")}
+		}
+
+		rsp := TermsRespBody{Code: "0"}
+		for _, mac := range macs {
+			// the odd macs are not in CMDB
+			if mac[len(mac)-1]%2 == 0 {
+				rsp.Data.Items = append(rsp.Data.Items, Item{DeviceId: mac, AreaId: "8"})
+			}
+		}
+		rsp.Data.Total = fmt.Sprintf("%d", len(rsp.Data.Items))
+		json.NewEncoder(w).Encode(rsp)
+	}))
+
+	ca = newCmdbApi("", EurekaApp)
+	ca.setInstances(&fargo.Application{
+		Instances: []*fargo.Instance{{HomePageUrl: ts.URL + "/", Status: fargo.UP}},
+	})
+	ca.SetBatch(50*time.Millisecond, 100)
+	return
+}
+
+func lookupConcurrently(t *testing.T, ca *CmdbApi, n int) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			mac := fmt.Sprintf("309c2334%04d", i)
+			shop, found, err := ca.GetShop(mac)
+			require.NoError(t, err)
+			require.Equal(t, i%2 == 0, found)
+			if found {
+				require.Equal(t, uint64(8), shop)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCmdbBatchLookup(t *testing.T) {
+	ca, ts, multiCalls, singleCalls := newBatchCmdb(t, true)
+	defer ts.Close()
+
+	lookupConcurrently(t, ca, 20)
+	require.Equal(t, int32(1), atomic.LoadInt32(multiCalls))
+	require.Equal(t, int32(0), atomic.LoadInt32(singleCalls))
+}
+
+func TestCmdbBatchFallback(t *testing.T) {
+	ca, ts, multiCalls, singleCalls := newBatchCmdb(t, false)
+	defer ts.Close()
+
+	lookupConcurrently(t, ca, 20)
+	require.Equal(t, int32(1), atomic.LoadInt32(multiCalls))
+	require.Equal(t, int32(20), atomic.LoadInt32(singleCalls))
+
+	// no more batch requests once it's known unsupported
+	ca.cache.invalidate("309c23340000")
+	ca.cache.invalidate("309c23340001")
+	lookupConcurrently(t, ca, 2)
+	require.Equal(t, int32(1), atomic.LoadInt32(multiCalls))
+	require.Equal(t, int32(22), atomic.LoadInt32(singleCalls))
+}
+
+func TestCmdbBatchReprobe(t *testing.T) {
+	ca, ts, multiCalls, singleCalls := newBatchCmdb(t, false)
+	defer ts.Close()
+
+	lookupConcurrently(t, ca, 2)
+	require.Equal(t, int32(1), atomic.LoadInt32(multiCalls))
+
+	// batch lookups are probed again after the interval
+	ca.batcher.reprobe = 0
+	ca.cache.invalidate("309c23340000")
+	ca.cache.invalidate("309c23340001")
+	lookupConcurrently(t, ca, 2)
+	require.Equal(t, int32(2), atomic.LoadInt32(multiCalls))
+	require.Equal(t, int32(4), atomic.LoadInt32(singleCalls))
+}
+
+func TestTermBatcherDedupe(t *testing.T) {
+	var requested [][]string
+	tb := newTermBatcher(time.Millisecond, 10, func(macs []string) (map[string]*TermsRespBody, error) {
+		requested = append(requested, macs)
+		return map[string]*TermsRespBody{"a": {Data: Data{Items: []Item{{DeviceId: "a"}}, Total: "1"}}}, nil
+	}, nil)
+
+	batch := []*batchLookup{
+		{mac: "a", done: make(chan struct{})},
+		{mac: "b", done: make(chan struct{})},
+		{mac: "a", done: make(chan struct{})},
+	}
+	tb.flush(batch)
+	require.Equal(t, [][]string{{"a", "b"}}, requested)
+	require.True(t, batch[0].found)
+	require.False(t, batch[1].found)
+	require.True(t, batch[2].found)
+}
+
+func TestCmdbBatchDuplicatedTerminals(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rsp := TermsRespBody{Code: "0"}
+		// three terminals of the same mac
+		for i := 0; i < 3; i++ {
+			rsp.Data.Items = append(rsp.Data.Items, Item{DeviceId: "309c23340000", AreaId: "8"})
+		}
+		rsp.Data.Items = append(rsp.Data.Items, Item{DeviceId: "309c23340002", AreaId: "8"})
+		rsp.Data.Total = fmt.Sprintf("%d", len(rsp.Data.Items))
+		json.NewEncoder(w).Encode(rsp)
+	}))
+	defer ts.Close()
+
+	ca := newCmdbApi("", EurekaApp)
+	ca.setInstances(&fargo.Application{
+		Instances: []*fargo.Instance{{HomePageUrl: ts.URL + "/", Status: fargo.UP}},
+	})
+	results, err := ca.getTerms([]string{"309c23340000", "309c23340002"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NotNil(t, results["309c23340002"])
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// BreakerFailures consecutive failures trip the breaker of a CMDB instance
+	BreakerFailures = 3
+	// BreakerCooldown the first open duration of a tripped breaker, it doubles every
+	// failed trial until BreakerMaxCooldown
+	BreakerCooldown    = 10 * time.Second
+	BreakerMaxCooldown = 5 * time.Minute
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// breaker is a circuit breaker of a CMDB instance. It opens after BreakerFailures
+// consecutive failures, and lets one trial request through once the cooldown expires.
+type breaker struct {
+	sync.Mutex
+
+	state    breakerState
+	failures int
+	cooldown time.Duration
+	openedAt time.Time
+	now      func() time.Time
+}
+
+func newBreaker() *breaker {
+	return &breaker{
+		cooldown: BreakerCooldown,
+		now:      time.Now,
+	}
+}
+
+// allow returns true if a request could be sent to the instance
+func (b *breaker) allow() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	switch b.state {
+	case breakerClosed:
+		return true
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	default:
+		// a trial is in flight
+		return false
+	}
+}
+
+func (b *breaker) succeed() {
+	b.Lock()
+	b.state = breakerClosed
+	b.failures = 0
+	b.cooldown = BreakerCooldown
+	b.Unlock()
+}
+
+// fail returns true if the breaker is opened by this failure
+func (b *breaker) fail() (opened bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures++
+	switch b.state {
+	case breakerHalfOpen:
+		b.cooldown *= 2
+		if b.cooldown > BreakerMaxCooldown {
+			b.cooldown = BreakerMaxCooldown
+		}
+	case breakerClosed:
+		if b.failures < BreakerFailures {
+			return
+		}
+	default:
+		return
+	}
+	b.state = breakerOpen
+	b.openedAt = b.now()
+	opened = true
+	return
+}
+
+func (b *breaker) getState() breakerState {
+	b.Lock()
+	defer b.Unlock()
+	return b.state
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultCmdbCacheTTL ttl of found terminals
+	DefaultCmdbCacheTTL = time.Hour
+	// DefaultCmdbNegativeTTL ttl of not found terminals
+	DefaultCmdbNegativeTTL = 5 * time.Minute
+
+	// cmdbSweepInterval is the min interval of evicting the unused entries
+	cmdbSweepInterval = time.Minute
+)
+
+var (
+	cmdbCacheCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "cmdb_cache",
+			Help:      "CMDB terminal cache lookups by result: hit, negative_hit, stale, miss",
+		}, []string{"result"})
+	cmdbRefreshCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "cmdb_refresh",
+			Help:      "CMDB terminal requests by result: succ, failed",
+		}, []string{"result"})
+	cmdbMetricOnce sync.Once
+)
+
+func initMetricsForCmdb() {
+	prometheus.MustRegister(cmdbCacheCountVec)
+	prometheus.MustRegister(cmdbRefreshCountVec)
+}
+
+type termEntry struct {
+	terms     *TermsRespBody
+	found     bool
+	expiredAt time.Time
+}
+
+// termCall is a lookup in flight, concurrent lookups of the same mac share it
+type termCall struct {
+	done  chan struct{}
+	gen   uint64
+	entry *termEntry
+	err   error
+}
+
+// termCache caches TermsRespBody per mac. An expired entry is still served while
+// it is refreshed in background, and lookups of the same mac are single-flight.
+// An expired not found entry, or a found one nobody looked up for a ttl after it
+// expired, is evicted. The result of a lookup started before an invalidation is
+// returned to its waiters but not cached.
+type termCache struct {
+	sync.Mutex
+
+	ttl, negativeTTL time.Duration
+	entries          map[string]*termEntry
+	calls            map[string]*termCall
+	gen              uint64
+	lastSweep        time.Time
+	fetch            func(mac string) (*TermsRespBody, bool, error)
+	now              func() time.Time
+}
+
+func newTermCache(ttl, negativeTTL time.Duration, fetch func(mac string) (*TermsRespBody, bool, error)) *termCache {
+	cmdbMetricOnce.Do(initMetricsForCmdb)
+	return &termCache{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     make(map[string]*termEntry),
+		calls:       make(map[string]*termCall),
+		fetch:       fetch,
+		now:         time.Now,
+	}
+}
+
+func (tc *termCache) get(mac string) (terms *TermsRespBody, found bool, err error) {
+	tc.Lock()
+	entry, ok := tc.entries[mac]
+	if ok {
+		if tc.now().Before(entry.expiredAt) {
+			tc.Unlock()
+			if entry.found {
+				cmdbCacheCountVec.WithLabelValues("hit").Inc()
+			} else {
+				cmdbCacheCountVec.WithLabelValues("negative_hit").Inc()
+			}
+			return entry.terms, entry.found, nil
+		}
+
+		// serve the stale one, and refresh in background
+		tc.startCallLocked(mac)
+		tc.Unlock()
+		cmdbCacheCountVec.WithLabelValues("stale").Inc()
+		return entry.terms, entry.found, nil
+	}
+
+	call := tc.startCallLocked(mac)
+	tc.Unlock()
+	cmdbCacheCountVec.WithLabelValues("miss").Inc()
+
+	<-call.done
+	if call.err != nil {
+		return nil, false, call.err
+	}
+	return call.entry.terms, call.entry.found, nil
+}
+
+func (tc *termCache) startCallLocked(mac string) *termCall {
+	if call, ok := tc.calls[mac]; ok {
+		return call
+	}
+
+	call := &termCall{done: make(chan struct{}), gen: tc.gen}
+	tc.calls[mac] = call
+	go tc.doCall(mac, call)
+	return call
+}
+
+func (tc *termCache) doCall(mac string, call *termCall) {
+	tc.Lock()
+	fetch := tc.fetch
+	tc.Unlock()
+	terms, found, err := fetch(mac)
+
+	tc.Lock()
+	if tc.calls[mac] == call {
+		delete(tc.calls, mac)
+	}
+	if err != nil {
+		// keep the stale entry if there is one
+		cmdbRefreshCountVec.WithLabelValues("failed").Inc()
+		log.Warnf("cmdb: refresh %s failed, errors:%+v", mac, err)
+		call.err = err
+	} else {
+		cmdbRefreshCountVec.WithLabelValues("succ").Inc()
+		ttl := tc.ttl
+		if !found {
+			ttl = tc.negativeTTL
+		}
+		now := tc.now()
+		call.entry = &termEntry{
+			terms:     terms,
+			found:     found,
+			expiredAt: now.Add(ttl),
+		}
+		if call.gen == tc.gen {
+			tc.entries[mac] = call.entry
+		}
+		if now.Sub(tc.lastSweep) >= cmdbSweepInterval {
+			tc.sweepLocked(now)
+		}
+	}
+	tc.Unlock()
+
+	close(call.done)
+}
+
+// sweepLocked evicts the expired not found entries, and the found ones expired for longer than ttl
+func (tc *termCache) sweepLocked(now time.Time) {
+	tc.lastSweep = now
+	for mac, entry := range tc.entries {
+		if (!entry.found && now.After(entry.expiredAt)) || now.Sub(entry.expiredAt) > tc.ttl {
+			delete(tc.entries, mac)
+		}
+	}
+}
+
+func (tc *termCache) setFetch(fetch func(mac string) (*TermsRespBody, bool, error)) {
+	tc.Lock()
+	tc.fetch = fetch
+	tc.Unlock()
+}
+
+func (tc *termCache) invalidate(mac string) {
+	tc.Lock()
+	delete(tc.entries, mac)
+	// the lookups in flight may return what's invalidated
+	delete(tc.calls, mac)
+	tc.gen++
+	tc.Unlock()
+	log.Infof("cmdb: %s invalidated", mac)
+}
+
+// SetCacheTTL changes the ttl of found and not found terminals
+func (ca *CmdbApi) SetCacheTTL(ttl, negativeTTL time.Duration) {
+	ca.cache.Lock()
+	ca.cache.ttl = ttl
+	ca.cache.negativeTTL = negativeTTL
+	ca.cache.Unlock()
+}
+
+// Invalidate drops the cached terminal, the next lookup goes to CMDB
+func (ca *CmdbApi) Invalidate(mac string) {
+	ca.cache.invalidate(mac)
+}
+
+// CmdbInvalidateHandler returns a http handler that drops a cached terminal.
+// Usage: POST /cmdb/invalidate?mac=309c233431b2
+func (fs *FileServer) CmdbInvalidateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		mac := r.URL.Query().Get("mac")
+		if mac == "" {
+			http.Error(w, "mac is required", http.StatusBadRequest)
+			return
+		}
+		inv, ok := fs.resolver.(invalidator)
+		if !ok {
+			http.Error(w, "the position resolver has no cache", http.StatusNotFound)
+			return
+		}
+		inv.Invalidate(mac)
+		w.Write([]byte("ok\n"))
+	})
+}
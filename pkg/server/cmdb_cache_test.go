@@ -0,0 +1,132 @@
+package server
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTermCache(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	tc := newTermCache(time.Hour, time.Minute, func(mac string) (*TermsRespBody, bool, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		if mac == "000000000000" {
+			return &TermsRespBody{}, false, nil
+		}
+		return &TermsRespBody{Data: Data{Items: []Item{{DeviceId: mac, AreaId: "8"}}}}, true, nil
+	})
+	now := time.Now()
+	tc.now = func() time.Time { return now }
+
+	// concurrent misses share one request
+	var wg sync.WaitGroup
+	results := make([]*TermsRespBody, 10)
+	errs := make([]error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, errs[i] = tc.get(MyMac)
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for i := range results {
+		require.NoError(t, errs[i])
+		require.Equal(t, "8", results[i].Data.Items[0].AreaId)
+	}
+
+	// negative cache
+	_, found, err := tc.get("000000000000")
+	require.NoError(t, err)
+	require.False(t, found)
+	_, found, _ = tc.get("000000000000")
+	require.False(t, found)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// the stale one is served while refreshing
+	now = now.Add(2 * time.Hour)
+	_, found, err = tc.get(MyMac)
+	require.NoError(t, err)
+	require.True(t, found)
+	for i := 0; i < 100 && atomic.LoadInt32(&calls) != 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	tc.invalidate(MyMac)
+	_, found, _ = tc.get(MyMac)
+	require.True(t, found)
+	require.Equal(t, int32(4), atomic.LoadInt32(&calls))
+}
+
+func TestTermCacheEvict(t *testing.T) {
+	tc := newTermCache(time.Hour, time.Minute, func(mac string) (*TermsRespBody, bool, error) {
+		if mac == MyMac {
+			return &TermsRespBody{Data: Data{Items: []Item{{DeviceId: mac, AreaId: "8"}}}}, true, nil
+		}
+		return &TermsRespBody{}, false, nil
+	})
+	now := time.Now()
+	tc.now = func() time.Time { return now }
+
+	for _, mac := range []string{MyMac, "000000000001", "000000000002"} {
+		_, _, err := tc.get(mac)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, len(tc.entries))
+
+	// the not found ones are evicted once expired
+	now = now.Add(2 * time.Minute)
+	_, _, err := tc.get("000000000003")
+	require.NoError(t, err)
+	tc.Lock()
+	require.Equal(t, 2, len(tc.entries))
+	require.NotNil(t, tc.entries[MyMac])
+	tc.Unlock()
+
+	// the found one is evicted if nobody looks it up for a ttl after it expired
+	now = now.Add(3 * time.Hour)
+	_, _, err = tc.get("000000000004")
+	require.NoError(t, err)
+	tc.Lock()
+	require.Equal(t, 1, len(tc.entries))
+	require.Nil(t, tc.entries[MyMac])
+	tc.Unlock()
+}
+
+func TestTermCacheInvalidateInFlight(t *testing.T) {
+	var calls int32
+	release := make(chan struct{}, 2)
+	tc := newTermCache(time.Hour, time.Minute, func(mac string) (*TermsRespBody, bool, error) {
+		n := atomic.AddInt32(&calls, 1)
+		<-release
+		return &TermsRespBody{Data: Data{Items: []Item{{DeviceId: mac, AreaId: strconv.Itoa(int(n))}}}}, true, nil
+	})
+
+	done := make(chan *TermsRespBody)
+	go func() {
+		terms, _, _ := tc.get(MyMac)
+		done <- terms
+	}()
+	for atomic.LoadInt32(&calls) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	// invalidated while the first lookup is in flight, its result is stale
+	tc.invalidate(MyMac)
+	release <- struct{}{}
+	require.Equal(t, "1", (<-done).Data.Items[0].AreaId)
+
+	release <- struct{}{}
+	terms, _, err := tc.get(MyMac)
+	require.NoError(t, err)
+	require.Equal(t, "2", terms.Data.Items[0].AreaId)
+}
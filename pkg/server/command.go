@@ -0,0 +1,231 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/infinivision/filesyncer/pkg/pb"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AdminTokenHeader is the header of the token required by the admin handlers
+	AdminTokenHeader = "X-Admin-Token"
+)
+
+var (
+	// ErrTermNotConnected the terminal has no bound session on this server
+	ErrTermNotConnected = errors.New("terminal not connected")
+	// ErrCommandNotAcked the terminal doesn't acknowledge the command in time
+	ErrCommandNotAcked = errors.New("command not acknowledged")
+	// ErrCommandTimeout the terminal doesn't return the command result in time
+	ErrCommandTimeout = errors.New("command timeout")
+)
+
+// termManager binds terminals (by mac) to their sessions, and tracks the commands in flight
+type termManager struct {
+	sync.RWMutex
+
+	seq      uint64
+	terms    map[string]*session
+	pendings map[uint64]*pendingCmd
+}
+
+type pendingCmd struct {
+	mac  string
+	ackC chan struct{}
+	rspC chan *pb.CommandRsp
+}
+
+func newTermManager() *termManager {
+	return &termManager{
+		terms:    make(map[string]*session),
+		pendings: make(map[uint64]*pendingCmd),
+	}
+}
+
+func (mgr *termManager) bind(mac string, s *session) {
+	mgr.Lock()
+	if old, ok := mgr.terms[mac]; !ok || old != s {
+		log.Infof("term-%s: bound to session %s", mac, s.addr)
+	}
+	mgr.terms[mac] = s
+	mgr.Unlock()
+}
+
+func (mgr *termManager) unbind(s *session) {
+	mgr.Lock()
+	if old, ok := mgr.terms[s.mac]; ok && old == s {
+		delete(mgr.terms, s.mac)
+		log.Infof("term-%s: unbound from session %s", s.mac, s.addr)
+	}
+	mgr.Unlock()
+}
+
+func (mgr *termManager) macs() []string {
+	mgr.RLock()
+	defer mgr.RUnlock()
+
+	macs := make([]string, 0, len(mgr.terms))
+	for mac := range mgr.terms {
+		macs = append(macs, mac)
+	}
+	return macs
+}
+
+func (mgr *termManager) sendCommand(mac string, cmdType pb.CommandType, value string, timeout time.Duration) (*pb.CommandRsp, error) {
+	mgr.Lock()
+	s, ok := mgr.terms[mac]
+	if !ok {
+		mgr.Unlock()
+		return nil, errors.Wrapf(ErrTermNotConnected, "mac %s", mac)
+	}
+	mgr.seq++
+	id := mgr.seq
+	pending := &pendingCmd{
+		mac:  mac,
+		ackC: make(chan struct{}, 1),
+		rspC: make(chan *pb.CommandRsp, 1),
+	}
+	mgr.pendings[id] = pending
+	mgr.Unlock()
+
+	defer func() {
+		mgr.Lock()
+		delete(mgr.pendings, id)
+		mgr.Unlock()
+	}()
+
+	log.Infof("command-%d: send %s %q to %s", id, cmdType, value, mac)
+	s.doRsp(&pb.Command{
+		ID:    id,
+		Mac:   mac,
+		Type:  cmdType,
+		Value: value,
+	})
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-pending.ackC:
+	case rsp := <-pending.rspC:
+		return rsp, nil
+	case <-timer.C:
+		return nil, errors.Wrapf(ErrCommandNotAcked, "command-%d to %s", id, mac)
+	}
+
+	select {
+	case rsp := <-pending.rspC:
+		return rsp, nil
+	case <-timer.C:
+		return nil, errors.Wrapf(ErrCommandTimeout, "command-%d to %s", id, mac)
+	}
+}
+
+func (mgr *termManager) onAck(ack *pb.CommandAck) {
+	mgr.RLock()
+	pending, ok := mgr.pendings[ack.ID]
+	mgr.RUnlock()
+
+	if !ok || pending.mac != ack.Mac {
+		log.Warnf("command-%d: ack from %s is ignored", ack.ID, ack.Mac)
+		return
+	}
+
+	log.Debugf("command-%d: acked by %s", ack.ID, ack.Mac)
+	select {
+	case pending.ackC <- struct{}{}:
+	default:
+	}
+}
+
+func (mgr *termManager) onRsp(rsp *pb.CommandRsp) {
+	mgr.RLock()
+	pending, ok := mgr.pendings[rsp.ID]
+	mgr.RUnlock()
+
+	if !ok || pending.mac != rsp.Mac {
+		log.Warnf("command-%d: result from %s is ignored", rsp.ID, rsp.Mac)
+		return
+	}
+
+	log.Infof("command-%d: %s returned %s %s", rsp.ID, rsp.Mac, rsp.Code, rsp.Result)
+	select {
+	case pending.rspC <- rsp:
+	default:
+	}
+}
+
+// SendCommand sends a command to the terminal identified by mac, and waits for its result.
+// Only terminals connected to this server can be targeted.
+func (fs *FileServer) SendCommand(mac string, cmdType pb.CommandType, value string, timeout time.Duration) (*pb.CommandRsp, error) {
+	return termMgr.sendCommand(mac, cmdType, value, timeout)
+}
+
+// Terminals returns macs of the terminals connected to this server
+func (fs *FileServer) Terminals() []string {
+	return termMgr.macs()
+}
+
+// CommandHandler returns a http handler that sends commands to terminals, the token
+// is required in the X-Admin-Token header, the handler is disabled if it's empty.
+// Usage: POST /command?mac=309c233431b2&type=CommandSetLogLevel&value=debug&timeout=30s
+// The log file content is returned as the body for CommandUploadLog.
+func (fs *FileServer) CommandHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if token == "" {
+			http.Error(w, "terminal commands are disabled without --admin-token", http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(AdminTokenHeader)), []byte(token)) != 1 {
+			http.Error(w, "invalid "+AdminTokenHeader, http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+		mac := q.Get("mac")
+		cmdType, ok := pb.CommandType_value[q.Get("type")]
+		if mac == "" || !ok {
+			http.Error(w, "mac and a valid type are required", http.StatusBadRequest)
+			return
+		}
+		timeout := 30 * time.Second
+		if v := q.Get("timeout"); v != "" {
+			var err error
+			if timeout, err = time.ParseDuration(v); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		rsp, err := fs.SendCommand(mac, pb.CommandType(cmdType), q.Get("value"), timeout)
+		if err != nil {
+			code := http.StatusGatewayTimeout
+			if errors.Cause(err) == ErrTermNotConnected {
+				code = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		w.Header().Set("X-Command-Code", rsp.Code.String())
+		w.Header().Set("X-Command-Result", strconv.Quote(rsp.Result))
+		if rsp.Code != pb.CodeSucc {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		if len(rsp.Data) > 0 {
+			w.Write(rsp.Data)
+		} else {
+			w.Write([]byte(rsp.Result + "\n"))
+		}
+	})
+}
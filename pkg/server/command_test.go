@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandHandlerAuth(t *testing.T) {
+	termMgr = newTermManager()
+	fs := &FileServer{}
+	send := func(h http.Handler, method, token string) int {
+		req := httptest.NewRequest(method, "/command?mac=309c233431b2&type=CommandRescan", nil)
+		if token != "" {
+			req.Header.Set(AdminTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// disabled without a token
+	require.Equal(t, http.StatusForbidden, send(fs.CommandHandler(""), "POST", "secret"))
+
+	h := fs.CommandHandler("secret")
+	require.Equal(t, http.StatusMethodNotAllowed, send(h, "GET", "secret"))
+	require.Equal(t, http.StatusUnauthorized, send(h, "POST", ""))
+	require.Equal(t, http.StatusUnauthorized, send(h, "POST", "wrong"))
+	// the terminal isn't connected
+	require.Equal(t, http.StatusNotFound, send(h, "POST", "secret"))
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CorrectionMerge the visits of From are re-labeled as Uid
+	CorrectionMerge = "merge"
+	// CorrectionSplit the visits of Xids are moved from From to the new Uid
+	CorrectionSplit = "split"
+
+	// CorrectionKey is the Redis list of correction events
+	CorrectionKey = "identity_correction_queue"
+)
+
+// CorrectionEvent is an identity correction for the downstream of visits.
+// PictureIds and VisitTimes are the visits moved by a split.
+type CorrectionEvent struct {
+	Kind       string   `json:"kind"`
+	Uid        int64    `json:"uid"`
+	From       int64    `json:"from"`
+	Xids       []int64  `json:"xids"`
+	PictureIds []string `json:"pictureIds,omitempty"`
+	VisitTimes []uint64 `json:"visitTimes,omitempty"`
+	At         int64    `json:"at"`
+}
+
+// NewCorrectionEvent returns a correction event happened now
+func NewCorrectionEvent(kind string, uid, from int64, xids []int64) *CorrectionEvent {
+	return &CorrectionEvent{
+		Kind: kind,
+		Uid:  uid,
+		From: from,
+		Xids: xids,
+		At:   time.Now().Unix(),
+	}
+}
+
+// PublishCorrection appends the event to the Redis list key
+func PublishCorrection(rcli *redis.Client, key string, ev *CorrectionEvent) (err error) {
+	var data []byte
+	if data, err = json.Marshal(ev); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = rcli.RPush(key, data).Err(); err != nil {
+		err = errors.Wrapf(err, "publish correction to %s", key)
+	}
+	return
+}
+
+// ScanVisits calls fn with each visit and its encoding in the Redis list que, batch visits at a time
+func ScanVisits(rcli *redis.Client, que string, batch int64, fn func(visit *Visit, raw string)) (err error) {
+	for idx := int64(0); ; idx += batch {
+		var recs []string
+		if recs, err = rcli.LRange(que, idx, idx+batch-1).Result(); err != nil {
+			err = errors.Wrapf(err, "scan %s", que)
+			return
+		}
+		for _, rec := range recs {
+			var visit *Visit
+			if visit, err = DecodeVisit([]byte(rec)); err != nil {
+				err = errors.Wrapf(err, "scan %s", que)
+				return
+			}
+			fn(visit, rec)
+		}
+		if int64(len(recs)) < batch {
+			return
+		}
+	}
+}
@@ -0,0 +1,524 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// StagePredict the face inference failed
+	StagePredict = "predict"
+	// StageIdentify the vector search or the uid allocation failed
+	StageIdentify = "identify"
+	// StageRecord writing the visit to PostgreSQL failed
+	StageRecord = "record"
+
+	// DeadLetterKey is the Redis list of dead letters
+	DeadLetterKey = "dead_letter_queue"
+)
+
+var (
+	deadLetterCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "dead_letter",
+			Help:      "Images put into the dead letter queue by failure stage.",
+		}, []string{"stage"})
+	redriveCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "dead_letter_redrive",
+			Help:      "Re-driven dead letters by stage and result: succ, failed.",
+		}, []string{"stage", "result"})
+	deadLetterOnce sync.Once
+)
+
+func initMetricsForDeadLetter() {
+	prometheus.MustRegister(deadLetterCountVec)
+	prometheus.MustRegister(redriveCountVec)
+}
+
+// DeadLetter is an image failed in identification or recording. The image itself
+// is in OSS with ObjID. Visit is set if only the recording failed.
+type DeadLetter struct {
+	ObjID    string     `json:"objId"`
+	Shop     uint64     `json:"shop"`
+	Position uint32     `json:"position"`
+	ModTime  int64      `json:"modTime"`
+	Camera   CameraInfo `json:"camera"`
+	Mac      string     `json:"mac,omitempty"`
+	CameraIp string     `json:"cameraIp,omitempty"`
+	Stage    string     `json:"stage"`
+	Error    string     `json:"error"`
+	FailedAt int64      `json:"failedAt"`
+	Attempts int        `json:"attempts"`
+	Visit    []byte     `json:"visit,omitempty"`
+	// Sinks are the sinks failed to record Visit, empty means all
+	Sinks []string `json:"sinks,omitempty"`
+
+	// raw is the encoded letter popped from the queue
+	raw []byte
+}
+
+// NewDeadLetter returns a dead letter of the image failed at the stage
+func NewDeadLetter(img ImgMsg, stage string, err error) *DeadLetter {
+	return &DeadLetter{
+		ObjID:    img.ObjID,
+		Shop:     img.Shop,
+		Position: img.Position,
+		ModTime:  img.ModTime,
+		Camera:   img.Camera,
+		Mac:      img.Mac,
+		CameraIp: img.CameraIp,
+		Stage:    stage,
+		Error:    err.Error(),
+		FailedAt: time.Now().Unix(),
+		Attempts: 1,
+	}
+}
+
+// ImgMsg returns the ImgMsg to re-drive, the image is read from OSS by the caller
+func (dl *DeadLetter) ImgMsg(img []byte) ImgMsg {
+	return ImgMsg{
+		Shop:     dl.Shop,
+		Position: dl.Position,
+		ModTime:  dl.ModTime,
+		ObjID:    dl.ObjID,
+		Img:      img,
+		Camera:   dl.Camera,
+		Mac:      dl.Mac,
+		CameraIp: dl.CameraIp,
+	}
+}
+
+// Fail updates the dead letter which failed again in re-driving
+func (dl *DeadLetter) Fail(stage string, err error) {
+	dl.Stage = stage
+	dl.Error = err.Error()
+	dl.FailedAt = time.Now().Unix()
+	dl.Attempts++
+}
+
+// DeadLetterQueue is a durable queue of dead letters
+type DeadLetterQueue interface {
+	Push(letters ...*DeadLetter) error
+	// Pop moves at most n letters from the head to the processing ones and returns them.
+	// Malformed letters are logged and set aside instead.
+	Pop(n int) ([]*DeadLetter, error)
+	// Ack removes the popped letters from the processing ones once they are handled
+	Ack(letters ...*DeadLetter) error
+	// Recover queues back the letters popped but never acked, e.g. by a crashed re-drive
+	Recover() (int64, error)
+	// Remove removes the queued and processing letters matched, it only counts them in dry run
+	Remove(match func(dl *DeadLetter) bool, dryRun bool) (removed int64, err error)
+	Len() (int64, error)
+}
+
+// ObserveDeadLetters updates the dead letter metrics
+func ObserveDeadLetters(letters ...*DeadLetter) {
+	deadLetterOnce.Do(initMetricsForDeadLetter)
+	for _, dl := range letters {
+		deadLetterCountVec.WithLabelValues(dl.Stage).Inc()
+	}
+}
+
+// ObserveRedrive updates the re-drive metrics
+func ObserveRedrive(stage string, succ bool) {
+	deadLetterOnce.Do(initMetricsForDeadLetter)
+	result := "succ"
+	if !succ {
+		result = "failed"
+	}
+	redriveCountVec.WithLabelValues(stage, result).Inc()
+}
+
+var (
+	// popDeadLetterScript moves the head of KEYS[1] to the tail of KEYS[2]
+	popDeadLetterScript = redis.NewScript(`
+local v = redis.call('LPOP', KEYS[1])
+if v then
+	redis.call('RPUSH', KEYS[2], v)
+end
+return v
+`)
+)
+
+// redisDeadLetterQueue keeps dead letters in a Redis list. Popped letters are
+// kept in <key>:processing until acked, malformed ones are moved to <key>:malformed.
+type redisDeadLetterQueue struct {
+	rcli          *redis.Client
+	key           string
+	processingKey string
+	malformedKey  string
+}
+
+// NewRedisDeadLetterQueue returns a dead letter queue of a Redis list
+func NewRedisDeadLetterQueue(rcli *redis.Client, key string) DeadLetterQueue {
+	return &redisDeadLetterQueue{
+		rcli:          rcli,
+		key:           key,
+		processingKey: key + ":processing",
+		malformedKey:  key + ":malformed",
+	}
+}
+
+func (q *redisDeadLetterQueue) Push(letters ...*DeadLetter) (err error) {
+	if len(letters) == 0 {
+		return
+	}
+	values := make([]interface{}, 0, len(letters))
+	for _, dl := range letters {
+		var data []byte
+		if data, err = json.Marshal(dl); err != nil {
+			err = errors.Wrap(err, "")
+			return
+		}
+		values = append(values, data)
+	}
+	if err = q.rcli.RPush(q.key, values...).Err(); err != nil {
+		err = errors.Wrapf(err, "push to %s", q.key)
+	}
+	return
+}
+
+func (q *redisDeadLetterQueue) Pop(n int) (letters []*DeadLetter, err error) {
+	for len(letters) < n {
+		var data string
+		if data, err = popDeadLetterScript.Run(q.rcli, []string{q.key, q.processingKey}).String(); err != nil {
+			if err == redis.Nil {
+				err = nil
+				return
+			}
+			err = errors.Wrapf(err, "pop from %s", q.key)
+			return
+		}
+		dl := &DeadLetter{}
+		if decodeErr := json.Unmarshal([]byte(data), dl); decodeErr != nil {
+			log.Errorf("move malformed dead letter %s to %s, errors:%+v", data, q.malformedKey, decodeErr)
+			if err = q.rcli.RPush(q.malformedKey, data).Err(); err == nil {
+				err = q.rcli.LRem(q.processingKey, 1, data).Err()
+			}
+			if err != nil {
+				err = errors.Wrapf(err, "move to %s", q.malformedKey)
+				return
+			}
+			continue
+		}
+		dl.raw = []byte(data)
+		letters = append(letters, dl)
+	}
+	return
+}
+
+func (q *redisDeadLetterQueue) Ack(letters ...*DeadLetter) (err error) {
+	if len(letters) == 0 {
+		return
+	}
+	pipe := q.rcli.TxPipeline()
+	for _, dl := range letters {
+		pipe.LRem(q.processingKey, 1, dl.raw)
+	}
+	if _, err = pipe.Exec(); err != nil {
+		err = errors.Wrapf(err, "ack %s", q.processingKey)
+	}
+	return
+}
+
+func (q *redisDeadLetterQueue) Recover() (n int64, err error) {
+	for {
+		if err = q.rcli.RPopLPush(q.processingKey, q.key).Err(); err != nil {
+			if err == redis.Nil {
+				err = nil
+				return
+			}
+			err = errors.Wrapf(err, "recover %s", q.processingKey)
+			return
+		}
+		n++
+	}
+}
+
+func (q *redisDeadLetterQueue) Remove(match func(dl *DeadLetter) bool, dryRun bool) (removed int64, err error) {
+	for _, key := range []string{q.key, q.processingKey} {
+		var values []string
+		if values, err = q.rcli.LRange(key, 0, -1).Result(); err != nil {
+			err = errors.Wrapf(err, "scan %s", key)
+			return
+		}
+		pipe := q.rcli.Pipeline()
+		var cmds []*redis.IntCmd
+		for _, value := range values {
+			dl := &DeadLetter{}
+			if json.Unmarshal([]byte(value), dl) != nil || !match(dl) {
+				continue
+			}
+			if dryRun {
+				removed++
+				continue
+			}
+			cmds = append(cmds, pipe.LRem(key, 1, value))
+		}
+		if len(cmds) == 0 {
+			continue
+		}
+		if _, err = pipe.Exec(); err != nil {
+			err = errors.Wrapf(err, "remove from %s", key)
+			return
+		}
+		for _, cmd := range cmds {
+			removed += cmd.Val()
+		}
+	}
+	return
+}
+
+func (q *redisDeadLetterQueue) Len() (n int64, err error) {
+	if n, err = q.rcli.LLen(q.key).Result(); err != nil {
+		err = errors.Wrapf(err, "")
+	}
+	return
+}
+
+// fileDeadLetterQueue keeps dead letters in a JSON lines file. Letters are
+// appended on push, and the file is rewritten on pop. Popped letters are kept
+// in <path>.processing until acked, malformed lines are moved to <path>.malformed.
+type fileDeadLetterQueue struct {
+	sync.Mutex
+	path           string
+	processingPath string
+	malformedPath  string
+}
+
+// NewFileDeadLetterQueue returns a dead letter queue of a local file
+func NewFileDeadLetterQueue(path string) DeadLetterQueue {
+	return &fileDeadLetterQueue{
+		path:           path,
+		processingPath: path + ".processing",
+		malformedPath:  path + ".malformed",
+	}
+}
+
+func (q *fileDeadLetterQueue) Push(letters ...*DeadLetter) (err error) {
+	q.Lock()
+	defer q.Unlock()
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	for _, dl := range letters {
+		if err = enc.Encode(dl); err != nil {
+			err = errors.Wrap(err, "")
+			return
+		}
+	}
+	err = appendFile(q.path, buf.Bytes())
+	return
+}
+
+// appendFile appends the data to the file and syncs it
+func appendFile(path string, data []byte) (err error) {
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = f.Close(); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+func (q *fileDeadLetterQueue) Pop(n int) (letters []*DeadLetter, err error) {
+	q.Lock()
+	defer q.Unlock()
+
+	var lines [][]byte
+	if lines, err = readLines(q.path); err != nil || len(lines) == 0 {
+		return
+	}
+	var popped, malformed [][]byte
+	taken := 0
+	for _, line := range lines {
+		if len(letters) == n {
+			break
+		}
+		taken++
+		dl := &DeadLetter{}
+		if decodeErr := json.Unmarshal(line, dl); decodeErr != nil {
+			log.Errorf("move malformed dead letter %s to %s, errors:%+v", string(line), q.malformedPath, decodeErr)
+			malformed = append(malformed, line)
+			continue
+		}
+		dl.raw = line
+		letters = append(letters, dl)
+		popped = append(popped, line)
+	}
+
+	// a crash before the rewrite leaves the letters in both, they are re-driven twice but never lost
+	if len(malformed) != 0 {
+		if err = appendFile(q.malformedPath, joinLines(malformed)); err != nil {
+			return
+		}
+	}
+	if len(popped) != 0 {
+		if err = appendFile(q.processingPath, joinLines(popped)); err != nil {
+			return
+		}
+	}
+	err = rewriteFile(q.path, lines[taken:])
+	return
+}
+
+func (q *fileDeadLetterQueue) Ack(letters ...*DeadLetter) (err error) {
+	if len(letters) == 0 {
+		return
+	}
+	q.Lock()
+	defer q.Unlock()
+
+	acked := make(map[string]int, len(letters))
+	for _, dl := range letters {
+		acked[string(dl.raw)]++
+	}
+	var lines, remaining [][]byte
+	if lines, err = readLines(q.processingPath); err != nil {
+		return
+	}
+	for _, line := range lines {
+		if acked[string(line)] > 0 {
+			acked[string(line)]--
+			continue
+		}
+		remaining = append(remaining, line)
+	}
+	err = rewriteFile(q.processingPath, remaining)
+	return
+}
+
+func (q *fileDeadLetterQueue) Recover() (n int64, err error) {
+	q.Lock()
+	defer q.Unlock()
+
+	var lines [][]byte
+	if lines, err = readLines(q.processingPath); err != nil || len(lines) == 0 {
+		return
+	}
+	if err = appendFile(q.path, joinLines(lines)); err != nil {
+		return
+	}
+	if err = os.Remove(q.processingPath); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	n = int64(len(lines))
+	return
+}
+
+func (q *fileDeadLetterQueue) Remove(match func(dl *DeadLetter) bool, dryRun bool) (removed int64, err error) {
+	q.Lock()
+	defer q.Unlock()
+
+	for _, path := range []string{q.path, q.processingPath} {
+		var lines, remaining [][]byte
+		if lines, err = readLines(path); err != nil || len(lines) == 0 {
+			if err != nil {
+				return
+			}
+			continue
+		}
+		for _, line := range lines {
+			dl := &DeadLetter{}
+			if json.Unmarshal(line, dl) == nil && match(dl) {
+				removed++
+				continue
+			}
+			remaining = append(remaining, line)
+		}
+		if dryRun || len(remaining) == len(lines) {
+			continue
+		}
+		if err = rewriteFile(path, remaining); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (q *fileDeadLetterQueue) Len() (n int64, err error) {
+	q.Lock()
+	defer q.Unlock()
+
+	var lines [][]byte
+	lines, err = readLines(q.path)
+	n = int64(len(lines))
+	return
+}
+
+func joinLines(lines [][]byte) []byte {
+	return append(bytes.Join(lines, []byte("\n")), '\n')
+}
+
+// rewriteFile replaces the file with the lines atomically
+func rewriteFile(path string, lines [][]byte) (err error) {
+	var data []byte
+	if len(lines) != 0 {
+		data = joinLines(lines)
+	}
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+func readLines(path string) (lines [][]byte, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+			return
+		}
+		err = errors.Wrap(err, "")
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		lines = append(lines, append([]byte(nil), line...))
+	}
+	if err = scanner.Err(); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
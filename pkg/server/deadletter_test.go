@@ -0,0 +1,131 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func testDeadLetterQueue(t *testing.T, q DeadLetterQueue) {
+	n, err := q.Len()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+
+	img := ImgMsg{Shop: 8, Position: 1, ModTime: 1551369600, ObjID: "obj1", Camera: CameraInfo{Zone: "A", Enabled: true}}
+	require.NoError(t, q.Push(NewDeadLetter(img, StagePredict, errors.New("timeout"))))
+	img.ObjID = "obj2"
+	require.NoError(t, q.Push(NewDeadLetter(img, StageIdentify, errors.New("hyena")), NewDeadLetter(img, StageRecord, errors.New("pg"))))
+	n, err = q.Len()
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+
+	letters, err := q.Pop(2)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(letters))
+	require.Equal(t, "obj1", letters[0].ObjID)
+	require.Equal(t, StagePredict, letters[0].Stage)
+	require.Equal(t, "timeout", letters[0].Error)
+	require.Equal(t, "A", letters[0].ImgMsg(nil).Camera.Zone)
+	require.Equal(t, StageIdentify, letters[1].Stage)
+
+	letters[1].Fail(StageRecord, errors.New("pg"))
+	require.Equal(t, 2, letters[1].Attempts)
+	require.NoError(t, q.Push(letters[1]))
+	require.NoError(t, q.Ack(letters...))
+
+	// the popped but unacked letters are recovered
+	letters, err = q.Pop(10)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(letters))
+	require.Equal(t, StageRecord, letters[1].Stage)
+	require.Equal(t, 2, letters[1].Attempts)
+	n, err = q.Len()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+	require.NoError(t, q.Ack(letters[0]))
+	recovered, err := q.Recover()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), recovered)
+	letters, err = q.Pop(10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(letters))
+	require.Equal(t, 2, letters[0].Attempts)
+	require.NoError(t, q.Ack(letters...))
+	recovered, err = q.Recover()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), recovered)
+
+	// remove the queued and processing ones matched
+	require.NoError(t, q.Push(NewDeadLetter(ImgMsg{ObjID: "obj3"}, StagePredict, errors.New("timeout")),
+		NewDeadLetter(ImgMsg{ObjID: "obj4"}, StagePredict, errors.New("timeout")),
+		NewDeadLetter(ImgMsg{ObjID: "obj3"}, StagePredict, errors.New("timeout"))))
+	letters, err = q.Pop(1)
+	require.NoError(t, err)
+	require.Equal(t, "obj3", letters[0].ObjID)
+	match := func(dl *DeadLetter) bool { return dl.ObjID == "obj3" }
+	removed, err := q.Remove(match, true)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), removed)
+	removed, err = q.Remove(match, false)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), removed)
+	n, err = q.Len()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	recovered, err = q.Recover()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), recovered)
+}
+
+func TestFileDeadLetterQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testDeadLetterQueue(t, NewFileDeadLetterQueue(filepath.Join(dir, "dlq.jsonl")))
+}
+
+func TestFileDeadLetterQueueMalformed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dlq.jsonl")
+	q := NewFileDeadLetterQueue(path)
+	require.NoError(t, q.Push(NewDeadLetter(ImgMsg{ObjID: "obj1"}, StagePredict, errors.New("timeout"))))
+	require.NoError(t, appendFile(path, []byte("{bad\n")))
+	require.NoError(t, q.Push(NewDeadLetter(ImgMsg{ObjID: "obj2"}, StagePredict, errors.New("timeout"))))
+
+	// the malformed line is set aside, the others are popped
+	letters, err := q.Pop(10)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(letters))
+	require.Equal(t, "obj2", letters[1].ObjID)
+	lines, err := readLines(path + ".malformed")
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("{bad")}, lines)
+}
+
+func TestRedisDeadLetterQueue(t *testing.T) {
+	if RedisAddr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	rcli := redis.NewClient(&redis.Options{Addr: RedisAddr, DB: 15})
+	defer rcli.Close()
+	require.NoError(t, rcli.FlushDB().Err())
+
+	testDeadLetterQueue(t, NewRedisDeadLetterQueue(rcli, DeadLetterKey))
+
+	q := NewRedisDeadLetterQueue(rcli, DeadLetterKey)
+	require.NoError(t, rcli.RPush(DeadLetterKey, "{bad").Err())
+	require.NoError(t, q.Push(NewDeadLetter(ImgMsg{ObjID: "obj1"}, StagePredict, errors.New("timeout"))))
+	letters, err := q.Pop(10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(letters))
+	require.Equal(t, []string{"{bad"}, rcli.LRange(DeadLetterKey+":malformed", 0, -1).Val())
+}
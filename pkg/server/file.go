@@ -9,7 +9,20 @@ import (
 	"github.com/fagongzi/log"
 	"github.com/fagongzi/util/uuid"
 	"github.com/infinivision/filesyncer/pkg/pb"
+	"github.com/infinivision/filesyncer/pkg/queue"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	imgPublishCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "image_publish",
+			Help:      "Images published to the image queue by result: succ, failed, malformed (the camera meta)",
+		}, []string{"result"})
+	imgPublishOnce sync.Once
 )
 
 type fileManager struct {
@@ -19,16 +32,19 @@ type fileManager struct {
 	allc  uint64
 	files map[uint64]*file
 
-	cmdb  *CmdbApi
-	imgCh chan<- ImgMsg
+	resolver PositionResolver
+	imgQ     queue.Producer
 }
 
-func newFileManager(cfg RetryCfg, cmdb *CmdbApi, imgCh chan<- ImgMsg) *fileManager {
+func newFileManager(cfg RetryCfg, resolver PositionResolver, imgQ queue.Producer) *fileManager {
+	imgPublishOnce.Do(func() {
+		prometheus.MustRegister(imgPublishCountVec)
+	})
 	return &fileManager{
-		files: make(map[uint64]*file, 1024),
-		cfg:   cfg,
-		cmdb:  cmdb,
-		imgCh: imgCh,
+		files:    make(map[uint64]*file, 1024),
+		cfg:      cfg,
+		resolver: resolver,
+		imgQ:     imgQ,
 	}
 }
 
@@ -84,76 +100,96 @@ func (mgr *fileManager) completeFile(req *pb.UploadCompleteReq) pb.Code {
 	fid := req.ID
 
 	log.Debugf("file-%d: complete file", req.ID)
-	mgr.RLock()
-	if f, ok := mgr.files[fid]; ok {
-		times := 0
-		duration := mgr.cfg.RetryInterval
-
-		for {
-			if times > 0 {
-				log.Infof("file-%d: retry the %d times",
-					fid,
-					times)
-			}
+	// the file is taken out of the manager, so pushing to oss and publishing
+	// the image don't block the other uploads
+	mgr.Lock()
+	f, ok := mgr.files[fid]
+	if ok {
+		mgr.remove(fid)
+	}
+	mgr.Unlock()
+	if !ok {
+		log.Debugf("file-%d: complete file with missing", req.ID)
+		return pb.CodeMissing
+	}
 
-			log.Debugf("file-%d: complete file start push to oss", req.ID)
-			objID, code := f.complete(req)
-			log.Debugf("file-%d: complete file end push to oss", req.ID)
-			if code != pb.CodeOSSError {
-				if mgr.imgCh != nil {
-					var shop uint64
-					var position uint32
-					var found bool
-					var err error
-					log.Debugf("file-%d: complete file start call position", req.ID)
-					if shop, position, found, err = mgr.cmdb.GetPosition(f.meta.Mac, f.meta.Camera); err != nil {
-						log.Warnf("GetPosition(%s, %s) failed with error %+v", f.meta.Mac, f.meta.Camera, err)
-					} else if !found {
-						log.Warnf("GetPosition(%s, %s) didn't find", f.meta.Mac, f.meta.Camera)
-					} else {
-						var img []byte
-						var err error
-						f.readed = 0
-						if img, err = ioutil.ReadAll(f); err != nil {
-							err = errors.Wrap(err, "")
-							log.Errorf("%+v", err)
-						} else {
-							log.Debugf("got an image from shop %v, mac %v, camera %v", shop, f.meta.Mac, f.meta.Camera)
-							mgr.imgCh <- ImgMsg{Shop: shop, Position: position, ModTime: f.meta.ModTime, ObjID: objID, Img: img}
-							log.Debugf("got an image from shop %v, mac %v, camera %v complete", shop, f.meta.Mac, f.meta.Camera)
-						}
-						log.Debugf("file-%d: complete file end call position", req.ID)
-					}
-					log.Debugf("file-%d: complete file end call position", req.ID)
-				}
-				mgr.remove(fid)
-				mgr.RUnlock()
-				log.Debugf("file-%d: complete file end", req.ID)
-				return code
-			}
+	times := 0
+	duration := mgr.cfg.RetryInterval
+	for {
+		if times > 0 {
+			log.Infof("file-%d: retry the %d times",
+				fid,
+				times)
+		}
 
-			if times > 0 {
-				duration = time.Duration(mgr.cfg.RetryFactor) * duration
+		log.Debugf("file-%d: complete file start push to oss", req.ID)
+		objID, code := f.complete(req)
+		log.Debugf("file-%d: complete file end push to oss", req.ID)
+		if code != pb.CodeOSSError {
+			camTracker.observe(f.meta.Mac, f.meta.Camera)
+			if mgr.imgQ != nil {
+				mgr.publish(f, objID)
 			}
+			log.Debugf("file-%d: complete file end", req.ID)
+			return code
+		}
 
-			times++
-			if times >= mgr.cfg.MaxTimes {
-				log.Warnf("file-%d: retry failed in %d times",
-					fid,
-					times)
-				mgr.remove(fid)
-				mgr.RUnlock()
-				log.Debugf("file-%d: complete file end with over max times", req.ID)
-				return pb.CodeMaxRetries
-			}
+		if times > 0 {
+			duration = time.Duration(mgr.cfg.RetryFactor) * duration
+		}
 
-			time.Sleep(duration)
+		times++
+		if times >= mgr.cfg.MaxTimes {
+			log.Warnf("file-%d: retry failed in %d times",
+				fid,
+				times)
+			log.Debugf("file-%d: complete file end with over max times", req.ID)
+			return pb.CodeMaxRetries
 		}
+
+		time.Sleep(duration)
+	}
+}
+
+// publish resolves the position of the image, and publishes it to the image queue
+func (mgr *fileManager) publish(f *file, objID string) {
+	log.Debugf("file-%d: complete file start call position", f.id)
+	shop, cam, found, err := mgr.resolver.GetCamera(f.meta.Mac, f.meta.Camera)
+	if errors.Cause(err) == ErrMalformedCameraMeta {
+		// the position may be wrong, it's fixed in CMDB rather than guessed
+		imgPublishCountVec.WithLabelValues("malformed").Inc()
+		log.Errorf("reject the image %s, errors:%+v", objID, err)
+		return
+	} else if err != nil {
+		log.Warnf("GetCamera(%s, %s) failed with error %+v", f.meta.Mac, f.meta.Camera, err)
+		return
+	} else if !found {
+		log.Warnf("GetCamera(%s, %s) didn't find", f.meta.Mac, f.meta.Camera)
+		return
+	} else if !cam.Enabled {
+		log.Debugf("camera %s of %s is disabled, skip the image", f.meta.Camera, f.meta.Mac)
+		return
 	}
+	log.Debugf("file-%d: complete file end call position", f.id)
 
-	mgr.RUnlock()
-	log.Debugf("file-%d: complete file with missing", req.ID)
-	return pb.CodeMissing
+	var img, data []byte
+	f.readed = 0
+	if img, err = ioutil.ReadAll(f); err != nil {
+		log.Errorf("%+v", errors.Wrap(err, ""))
+		return
+	}
+	msg := ImgMsg{Shop: shop, Position: cam.Position, ModTime: f.meta.ModTime, ObjID: objID, Img: img, Camera: cam, Mac: f.meta.Mac, CameraIp: f.meta.Camera}
+	if data, err = msg.Marshal(); err != nil {
+		log.Errorf("%+v", err)
+		return
+	}
+	if err = mgr.imgQ.Publish(data); err != nil {
+		imgPublishCountVec.WithLabelValues("failed").Inc()
+		log.Errorf("publish %s from shop %v, mac %v, camera %v failed, errors:%+v", objID, shop, f.meta.Mac, f.meta.Camera, err)
+		return
+	}
+	imgPublishCountVec.WithLabelValues("succ").Inc()
+	log.Debugf("published an image from shop %v, mac %v, camera %v", shop, f.meta.Mac, f.meta.Camera)
 }
 
 func (mgr *fileManager) remove(id uint64) {
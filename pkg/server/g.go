@@ -3,22 +3,55 @@ package server
 import (
 	"github.com/fagongzi/log"
 	"github.com/infinivision/filesyncer/pkg/oss"
+	"github.com/infinivision/filesyncer/pkg/queue"
 )
 
 var (
 	fileMgr     *fileManager
+	termMgr     *termManager
+	camTracker  *cameraTracker
 	objectStore oss.ObjectStorage
 	bucketName  string
 )
 
-func initG(cfg *Cfg, cmdb *CmdbApi, imgCh chan<- ImgMsg) {
+func initG(cfg *Cfg, resolver PositionResolver, imgQ queue.Producer) {
 	bucketName = cfg.Oss.BucketName
-	initFileManager(cfg.Retry, cmdb, imgCh)
+	initFileManager(cfg.Retry, resolver, imgQ)
+	termMgr = newTermManager()
+	camTracker = newCameraTracker(cfg.Liveness, newNotifier(cfg.Liveness))
 	initObjectStore(cfg.Oss)
 }
 
-func initFileManager(cfg RetryCfg, cmdb *CmdbApi, imgCh chan<- ImgMsg) {
-	fileMgr = newFileManager(cfg, cmdb, imgCh)
+func initFileManager(cfg RetryCfg, resolver PositionResolver, imgQ queue.Producer) {
+	fileMgr = newFileManager(cfg, resolver, imgQ)
+}
+
+// newPositionResolver returns nil if the image processing is disabled
+func newPositionResolver(cfg *Cfg, imgQ queue.Producer) PositionResolver {
+	if imgQ == nil {
+		log.Infof("image processing is disabled, start without position resolver")
+		return nil
+	}
+
+	if cfg.PositionFile != "" {
+		resolver, err := NewFileResolver(cfg.PositionFile, cfg.PositionReload)
+		if err != nil {
+			log.Fatalf("init position file resolver failed, errors: %+v", err)
+		}
+		log.Infof("positions are resolved with file %s", cfg.PositionFile)
+		return resolver
+	}
+
+	cmdb, err := NewCmdbApi(cfg.EurekaAddr, cfg.EurekaApp)
+	if err != nil {
+		log.Fatalf("init CMDB position resolver failed, errors: %+v", err)
+	}
+	if cfg.CmdbCacheTTL > 0 {
+		cmdb.SetCacheTTL(cfg.CmdbCacheTTL, cfg.CmdbNegativeTTL)
+	}
+	cmdb.SetBatch(cfg.CmdbBatchWindow, cfg.CmdbBatchSize)
+	log.Infof("positions are resolved with CMDB %s of %s", cfg.EurekaApp, cfg.EurekaAddr)
+	return cmdb
 }
 
 func initObjectStore(cfg OssCfg) {
@@ -0,0 +1,655 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	// HistoryKeyPrefix + uid is the Redis hash of the history summary of the uid
+	HistoryKeyPrefix = "history_"
+	// HistoryVisitsKeyPrefix + uid is the Redis sorted set of the latest visits of the uid scored by VisitTime
+	HistoryVisitsKeyPrefix = "history_visits_"
+
+	historyShopPrefix = "shop_"
+)
+
+// History is the summary of the visits of a uid
+type History struct {
+	Uid       int64 `json:"uid"`
+	FirstSeen int64 `json:"firstSeen"`
+	LastSeen  int64 `json:"lastSeen"`
+	Visits    int64 `json:"visits"`
+	// PrevVisit is the last seen of the visit before the latest one, 0 if there is none
+	PrevVisit int64 `json:"prevVisit"`
+	// Shops is the last seen at each shop
+	Shops map[uint64]int64 `json:"shops"`
+}
+
+// prevVisitAt returns the last seen of the visit before the one a sighting at ts belongs to,
+// it's always before ts. A late sighting of a past visit gets the latest one known before it.
+func (h *History) prevVisitAt(ts, gap int64) int64 {
+	switch {
+	case h.Visits == 0:
+		return 0
+	case ts > h.LastSeen+gap:
+		return h.LastSeen
+	case ts >= h.LastSeen-gap:
+		return h.PrevVisit
+	case h.PrevVisit != 0 && h.PrevVisit < ts-gap:
+		return h.PrevVisit
+	case h.FirstSeen < ts-gap:
+		return h.FirstSeen
+	}
+	return 0
+}
+
+// touch adds a sighting at ts to the history, it's the same as touchScript. A late
+// sighting of a past visit keeps the latest visit, it's counted as a visit only if
+// it's before the first one, since the other past visits are unknown.
+func (h *History) touch(ts int64, shop uint64, gap int64) {
+	if h.Visits == 0 {
+		h.FirstSeen, h.LastSeen, h.Visits = ts, ts, 1
+	} else if ts > h.LastSeen+gap {
+		h.PrevVisit, h.LastSeen = h.LastSeen, ts
+		h.Visits++
+	} else if ts >= h.LastSeen-gap {
+		if ts > h.LastSeen {
+			h.LastSeen = ts
+		}
+	} else if ts < h.FirstSeen-gap {
+		h.Visits++
+	}
+	if ts < h.FirstSeen {
+		h.FirstSeen = ts
+	}
+	if h.Shops == nil {
+		h.Shops = make(map[uint64]int64)
+	}
+	if at, ok := h.Shops[shop]; !ok || ts > at {
+		h.Shops[shop] = ts
+	}
+}
+
+// replayHistory returns the history of uid built from the visits in time order
+func replayHistory(uid int64, visits []*Visit, gap int64) *History {
+	h := &History{Uid: uid, Shops: make(map[uint64]int64)}
+	for _, visit := range visits {
+		h.touch(int64(visit.VisitTime), visit.Shop, gap)
+	}
+	return h
+}
+
+// splitHistory splits the visits of h in time order by the xids, and rebuilds the
+// history left and the one moved from them. The visits trimmed from h are older
+// than all, they're kept in the history left.
+func splitHistory(h *History, all []*Visit, xids []int64, gap int64) (left, moved *History, kept, movedVisits []*Visit) {
+	split := make(map[int64]bool, len(xids))
+	for _, xid := range xids {
+		split[xid] = true
+	}
+	for _, visit := range all {
+		if split[visit.Xid] {
+			movedVisits = append(movedVisits, visit)
+		} else {
+			kept = append(kept, visit)
+		}
+	}
+	whole := replayHistory(h.Uid, all, gap)
+	left = replayHistory(h.Uid, kept, gap)
+	moved = replayHistory(0, movedVisits, gap)
+
+	trimmed := h.Visits - whole.Visits
+	if trimmed <= 0 {
+		return
+	}
+	// the latest trimmed sighting is the last seen of a shop before the retained visits
+	var latest int64
+	for shop, at := range h.Shops {
+		if whole.Visits != 0 && at >= whole.FirstSeen {
+			continue
+		}
+		if _, ok := left.Shops[shop]; !ok {
+			left.Shops[shop] = at
+		}
+		if at > latest {
+			latest = at
+		}
+	}
+	if left.Visits == 0 {
+		left.LastSeen = latest
+	} else if left.Visits == 1 {
+		left.PrevVisit = latest
+	}
+	left.FirstSeen = h.FirstSeen
+	left.Visits += trimmed
+	return
+}
+
+// HistoryStore keeps the visit history of uids alongside the identity store, so that
+// a visit tells whether its uid is returning. Sightings of a uid within the gap of its
+// last sighting are one visit.
+type HistoryStore interface {
+	// Record sets IsReturning and PrevVisitTime of the visits, then adds them to the histories of
+	// their uids in order. A low confidence visit is set but not added, a visit of uid 0 is neither.
+	Record(visits ...*Visit) error
+	// Get returns the history of uid, Visits is 0 if the uid has never visited
+	Get(uid int64) (h *History, err error)
+	// ListVisits returns at most limit latest visits of uid in [tsStart, tsEnd), the latest first
+	ListVisits(uid int64, tsStart, tsEnd int64, limit int64) (visits []*Visit, err error)
+	// Merge moves the history of src to dst, Visits of dst is the sum of both
+	Merge(dst, src int64) error
+	// Split moves the visits of the xids from the history of src to dst, the history of src
+	// is rebuilt from the visits left, and the one of the visits moved is merged into dst
+	Split(src, dst int64, xids []int64) error
+	// Forget removes the history of uid, and returns the number of visits removed, or to remove in dry run
+	Forget(uid int64, dryRun bool) (removed int64, err error)
+}
+
+// HistoryKey returns the Redis key of the history summary of uid
+func HistoryKey(uid int64) string {
+	return HistoryKeyPrefix + strconv.FormatInt(uid, 10)
+}
+
+// HistoryVisitsKey returns the Redis key of the visits of uid
+func HistoryVisitsKey(uid int64) string {
+	return HistoryVisitsKeyPrefix + strconv.FormatInt(uid, 10)
+}
+
+var (
+	// KEYS: history key. ARGV: visit time, shop, gap, 1 to return the previous visit only.
+	// Returns the previous visit, it's the same as History.prevVisitAt and History.touch.
+	touchScript = redis.NewScript(`
+local ts, gap = tonumber(ARGV[1]), tonumber(ARGV[3])
+local h = redis.call('HMGET', KEYS[1], 'first', 'last', 'prev')
+local first, last, prev = tonumber(h[1]), tonumber(h[2]), tonumber(h[3]) or 0
+local ret, visits = 0, 0
+if not first then
+	first, last, visits = ts, ts, 1
+elseif ts > last + gap then
+	ret, prev, last, visits = last, last, ts, 1
+elseif ts >= last - gap then
+	ret = prev
+	if ts > last then
+		last = ts
+	end
+else
+	if prev ~= 0 and prev < ts - gap then
+		ret = prev
+	elseif first < ts - gap then
+		ret = first
+	end
+	if ts < first - gap then
+		visits = 1
+	end
+end
+if ARGV[4] == '1' then
+	return ret
+end
+if ts < first then
+	first = ts
+end
+redis.call('HMSET', KEYS[1], 'first', first, 'last', last, 'prev', prev)
+if visits ~= 0 then
+	redis.call('HINCRBY', KEYS[1], 'visits', visits)
+end
+local field = '` + historyShopPrefix + `' .. ARGV[2]
+local at = tonumber(redis.call('HGET', KEYS[1], field))
+if not at or ts > at then
+	redis.call('HSET', KEYS[1], field, ts)
+end
+return ret
+`)
+	// KEYS: dst history key, src history key, dst visits key, src visits key. ARGV: max visits
+	mergeHistoryScript = redis.NewScript(`
+local src = redis.call('HGETALL', KEYS[2])
+for i = 1, #src, 2 do
+	local field, v = src[i], tonumber(src[i + 1])
+	if field == 'visits' then
+		redis.call('HINCRBY', KEYS[1], field, v)
+	else
+		local d = tonumber(redis.call('HGET', KEYS[1], field))
+		if not d or (field == 'first' and v < d) or (field ~= 'first' and v > d) then
+			redis.call('HSET', KEYS[1], field, v)
+		end
+	end
+end
+redis.call('ZUNIONSTORE', KEYS[3], 2, KEYS[3], KEYS[4], 'AGGREGATE', 'MAX')
+redis.call('ZREMRANGEBYRANK', KEYS[3], 0, -tonumber(ARGV[1]) - 1)
+redis.call('DEL', KEYS[2], KEYS[4])
+return 1
+`)
+)
+
+type redisHistoryStore struct {
+	rcli      *redis.Client
+	gap       int64
+	maxVisits int64
+}
+
+// NewRedisHistoryStore returns a history store in Redis keeping at most maxVisits latest visits per uid
+func NewRedisHistoryStore(rcli *redis.Client, gap time.Duration, maxVisits int64) HistoryStore {
+	return &redisHistoryStore{rcli: rcli, gap: int64(gap / time.Second), maxVisits: maxVisits}
+}
+
+func (s *redisHistoryStore) Record(visits ...*Visit) (err error) {
+	var touched []*Visit
+	var cmds []*redis.Cmd
+	if cmds, err = s.touch(visits); err != nil || len(cmds) == 0 {
+		return
+	}
+	for i, cmd := range cmds {
+		visit := visits[i]
+		if cmd == nil {
+			continue
+		}
+		var prev int64
+		if prev, err = cmd.Int64(); err != nil {
+			err = errors.Wrapf(err, "record history of uid %d", visit.Uid)
+			return
+		}
+		setReturning(visit, prev)
+		if !visit.LowConfidence {
+			touched = append(touched, visit)
+		}
+	}
+	if len(touched) == 0 {
+		return
+	}
+
+	if _, err = s.rcli.TxPipelined(func(pipe redis.Pipeliner) (err error) {
+		for _, visit := range touched {
+			key := HistoryVisitsKey(int64(visit.Uid))
+			if err = zaddVisits(pipe, key, []*Visit{visit}); err != nil {
+				return
+			}
+			pipe.ZRemRangeByRank(key, 0, -s.maxVisits-1)
+		}
+		return
+	}); err != nil {
+		err = errors.Wrap(err, "record history")
+	}
+	return
+}
+
+// touch runs touchScript of the visits in a pipeline, the command of a visit of uid 0 is nil
+func (s *redisHistoryStore) touch(visits []*Visit) (cmds []*redis.Cmd, err error) {
+	run := func() error {
+		pipe := s.rcli.Pipeline()
+		defer pipe.Close()
+		cmds = make([]*redis.Cmd, len(visits))
+		n := 0
+		for i, visit := range visits {
+			if visit.Uid == 0 {
+				continue
+			}
+			only := 0
+			if visit.LowConfidence {
+				only = 1
+			}
+			cmds[i] = touchScript.EvalSha(pipe, []string{HistoryKey(int64(visit.Uid))}, visit.VisitTime, visit.Shop, s.gap, only)
+			n++
+		}
+		if n == 0 {
+			cmds = nil
+			return nil
+		}
+		_, err := pipe.Exec()
+		return err
+	}
+	if err = run(); err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		if err = touchScript.Load(s.rcli).Err(); err == nil {
+			err = run()
+		}
+	}
+	if err != nil {
+		err = errors.Wrap(err, "record history")
+	}
+	return
+}
+
+func setReturning(visit *Visit, prev int64) {
+	visit.IsReturning = prev != 0
+	visit.PrevVisitTime = uint64(prev)
+}
+
+func (s *redisHistoryStore) Get(uid int64) (h *History, err error) {
+	return s.get(s.rcli, uid)
+}
+
+func (s *redisHistoryStore) get(c redis.Cmdable, uid int64) (h *History, err error) {
+	key := HistoryKey(uid)
+	var fields map[string]string
+	if fields, err = c.HGetAll(key).Result(); err != nil {
+		err = errors.Wrapf(err, "keyHistory %v", key)
+		return
+	}
+	h = &History{Uid: uid, Shops: make(map[uint64]int64)}
+	for field, value := range fields {
+		var v int64
+		if v, err = strconv.ParseInt(value, 10, 64); err != nil {
+			err = errors.Wrapf(err, "keyHistory %v field %s", key, field)
+			return
+		}
+		switch field {
+		case "first":
+			h.FirstSeen = v
+		case "last":
+			h.LastSeen = v
+		case "visits":
+			h.Visits = v
+		case "prev":
+			h.PrevVisit = v
+		default:
+			if !strings.HasPrefix(field, historyShopPrefix) {
+				continue
+			}
+			var shop uint64
+			if shop, err = strconv.ParseUint(strings.TrimPrefix(field, historyShopPrefix), 10, 64); err != nil {
+				err = errors.Wrapf(err, "keyHistory %v field %s", key, field)
+				return
+			}
+			h.Shops[shop] = v
+		}
+	}
+	return
+}
+
+func (s *redisHistoryStore) ListVisits(uid int64, tsStart, tsEnd int64, limit int64) (visits []*Visit, err error) {
+	key := HistoryVisitsKey(uid)
+	var members []string
+	if members, err = s.rcli.ZRevRangeByScore(key, redis.ZRangeBy{
+		Min:   strconv.FormatInt(tsStart, 10),
+		Max:   "(" + strconv.FormatInt(tsEnd, 10),
+		Count: limit,
+	}).Result(); err != nil {
+		err = errors.Wrapf(err, "keyHistoryVisits %v", key)
+		return
+	}
+	for _, member := range members {
+		var visit *Visit
+		if visit, err = DecodeVisit([]byte(member)); err != nil {
+			return
+		}
+		visits = append(visits, visit)
+	}
+	return
+}
+
+func (s *redisHistoryStore) Merge(dst, src int64) (err error) {
+	if dst == src {
+		err = errors.Errorf("merge history of uid %d into itself", dst)
+		return
+	}
+	if err = mergeHistoryScript.Run(s.rcli, []string{HistoryKey(dst), HistoryKey(src), HistoryVisitsKey(dst), HistoryVisitsKey(src)}, s.maxVisits).Err(); err != nil {
+		err = errors.Wrapf(err, "merge history of uid %d into %d", src, dst)
+	}
+	return
+}
+
+func (s *redisHistoryStore) Split(src, dst int64, xids []int64) (err error) {
+	if dst == src {
+		err = errors.Errorf("split history of uid %d into itself", src)
+		return
+	}
+	key, visitsKey := HistoryKey(src), HistoryVisitsKey(src)
+	// the moved history is merged into dst via the temporary keys
+	tmpKey, tmpVisitsKey := HistoryKey(dst)+"_split", HistoryVisitsKey(dst)+"_split"
+	if err = s.rcli.Watch(func(tx *redis.Tx) (err error) {
+		var h *History
+		if h, err = s.get(tx, src); err != nil {
+			return
+		}
+		var members []string
+		if members, err = tx.ZRange(visitsKey, 0, -1).Result(); err != nil {
+			return
+		}
+		all := make([]*Visit, 0, len(members))
+		for _, member := range members {
+			var visit *Visit
+			if visit, err = DecodeVisit([]byte(member)); err != nil {
+				return
+			}
+			all = append(all, visit)
+		}
+		left, moved, kept, movedVisits := splitHistory(h, all, xids, s.gap)
+		if len(movedVisits) == 0 {
+			return
+		}
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) (err error) {
+			pipe.Del(key, visitsKey)
+			if left.Visits != 0 {
+				pipe.HMSet(key, historyFields(left))
+				if err = zaddVisits(pipe, visitsKey, kept); err != nil {
+					return
+				}
+			}
+			pipe.HMSet(tmpKey, historyFields(moved))
+			if err = zaddVisits(pipe, tmpVisitsKey, movedVisits); err != nil {
+				return
+			}
+			mergeHistoryScript.Eval(pipe, []string{HistoryKey(dst), tmpKey, HistoryVisitsKey(dst), tmpVisitsKey}, s.maxVisits)
+			return
+		})
+		return
+	}, key, visitsKey); err != nil {
+		err = errors.Wrapf(err, "split history of uid %d into %d", src, dst)
+	}
+	return
+}
+
+func historyFields(h *History) map[string]interface{} {
+	fields := map[string]interface{}{
+		"first":  h.FirstSeen,
+		"last":   h.LastSeen,
+		"visits": h.Visits,
+		"prev":   h.PrevVisit,
+	}
+	for shop, at := range h.Shops {
+		fields[historyShopPrefix+strconv.FormatUint(shop, 10)] = at
+	}
+	return fields
+}
+
+func zaddVisits(pipe redis.Pipeliner, key string, visits []*Visit) (err error) {
+	for _, visit := range visits {
+		var data []byte
+		if data, err = visit.Marshal(); err != nil {
+			return errors.Wrap(err, "")
+		}
+		pipe.ZAdd(key, redis.Z{Score: float64(visit.VisitTime), Member: data})
+	}
+	return
+}
+
+func (s *redisHistoryStore) Forget(uid int64, dryRun bool) (removed int64, err error) {
+	key := HistoryVisitsKey(uid)
+	if dryRun {
+		if removed, err = s.rcli.ZCard(key).Result(); err != nil {
+			err = errors.Wrapf(err, "keyHistoryVisits %v", key)
+		}
+		return
+	}
+	var card *redis.IntCmd
+	if _, err = s.rcli.TxPipelined(func(pipe redis.Pipeliner) error {
+		card = pipe.ZCard(key)
+		pipe.Del(HistoryKey(uid), key)
+		return nil
+	}); err != nil {
+		err = errors.Wrapf(err, "forget history of uid %d", uid)
+		return
+	}
+	removed = card.Val()
+	return
+}
+
+// MemoryHistoryStore is a history store in process for tests
+type MemoryHistoryStore struct {
+	sync.Mutex
+
+	gap       int64
+	maxVisits int
+	histories map[int64]*History
+	// visits of a uid in time order
+	visits map[int64][]*Visit
+}
+
+// NewMemoryHistoryStore returns an empty MemoryHistoryStore
+func NewMemoryHistoryStore(gap time.Duration, maxVisits int) *MemoryHistoryStore {
+	return &MemoryHistoryStore{
+		gap:       int64(gap / time.Second),
+		maxVisits: maxVisits,
+		histories: make(map[int64]*History),
+		visits:    make(map[int64][]*Visit),
+	}
+}
+
+// Record implements HistoryStore
+func (s *MemoryHistoryStore) Record(visits ...*Visit) error {
+	s.Lock()
+	defer s.Unlock()
+	for _, visit := range visits {
+		if visit.Uid == 0 {
+			continue
+		}
+		uid := int64(visit.Uid)
+		h, ok := s.histories[uid]
+		if !ok {
+			h = &History{Uid: uid}
+		}
+		ts := int64(visit.VisitTime)
+		setReturning(visit, h.prevVisitAt(ts, s.gap))
+		if visit.LowConfidence {
+			continue
+		}
+		h.touch(ts, visit.Shop, s.gap)
+		s.histories[uid] = h
+		v := *visit
+		s.addVisits(uid, &v)
+	}
+	return nil
+}
+
+func (s *MemoryHistoryStore) addVisits(uid int64, visits ...*Visit) {
+	all := append(s.visits[uid], visits...)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].VisitTime < all[j].VisitTime })
+	if len(all) > s.maxVisits {
+		all = all[len(all)-s.maxVisits:]
+	}
+	s.visits[uid] = all
+}
+
+// Get implements HistoryStore
+func (s *MemoryHistoryStore) Get(uid int64) (h *History, err error) {
+	s.Lock()
+	defer s.Unlock()
+	h = &History{Uid: uid, Shops: make(map[uint64]int64)}
+	if old, ok := s.histories[uid]; ok {
+		*h = *old
+		h.Shops = make(map[uint64]int64, len(old.Shops))
+		for shop, at := range old.Shops {
+			h.Shops[shop] = at
+		}
+	}
+	return
+}
+
+// ListVisits implements HistoryStore
+func (s *MemoryHistoryStore) ListVisits(uid int64, tsStart, tsEnd int64, limit int64) (visits []*Visit, err error) {
+	s.Lock()
+	defer s.Unlock()
+	all := s.visits[uid]
+	for i := len(all) - 1; i >= 0 && int64(len(visits)) < limit; i-- {
+		if ts := int64(all[i].VisitTime); ts >= tsStart && ts < tsEnd {
+			visits = append(visits, all[i])
+		}
+	}
+	return
+}
+
+// Merge implements HistoryStore
+func (s *MemoryHistoryStore) Merge(dst, src int64) (err error) {
+	if dst == src {
+		err = errors.Errorf("merge history of uid %d into itself", dst)
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	sh, ok := s.histories[src]
+	if !ok {
+		return
+	}
+	s.merge(dst, sh, s.visits[src])
+	delete(s.histories, src)
+	delete(s.visits, src)
+	return
+}
+
+// merge merges the history sh and its visits into dst
+func (s *MemoryHistoryStore) merge(dst int64, sh *History, visits []*Visit) {
+	dh, ok := s.histories[dst]
+	if !ok {
+		dh = &History{Uid: dst, FirstSeen: sh.FirstSeen, Shops: make(map[uint64]int64)}
+		s.histories[dst] = dh
+	}
+	if sh.FirstSeen < dh.FirstSeen {
+		dh.FirstSeen = sh.FirstSeen
+	}
+	if sh.LastSeen > dh.LastSeen {
+		dh.LastSeen = sh.LastSeen
+	}
+	if sh.PrevVisit > dh.PrevVisit {
+		dh.PrevVisit = sh.PrevVisit
+	}
+	dh.Visits += sh.Visits
+	for shop, at := range sh.Shops {
+		if at > dh.Shops[shop] {
+			dh.Shops[shop] = at
+		}
+	}
+	s.addVisits(dst, visits...)
+}
+
+// Split implements HistoryStore
+func (s *MemoryHistoryStore) Split(src, dst int64, xids []int64) (err error) {
+	if dst == src {
+		err = errors.Errorf("split history of uid %d into itself", src)
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	h, ok := s.histories[src]
+	if !ok {
+		return
+	}
+	left, moved, kept, movedVisits := splitHistory(h, s.visits[src], xids, s.gap)
+	if len(movedVisits) == 0 {
+		return
+	}
+	if left.Visits == 0 {
+		delete(s.histories, src)
+		delete(s.visits, src)
+	} else {
+		s.histories[src], s.visits[src] = left, kept
+	}
+	s.merge(dst, moved, movedVisits)
+	return
+}
+
+// Forget implements HistoryStore
+func (s *MemoryHistoryStore) Forget(uid int64, dryRun bool) (removed int64, err error) {
+	s.Lock()
+	defer s.Unlock()
+	removed = int64(len(s.visits[uid]))
+	if !dryRun {
+		delete(s.histories, uid)
+		delete(s.visits, uid)
+	}
+	return
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func testHistoryStore(t *testing.T, s HistoryStore) {
+	record := func(uid, ts, shop uint64, lowConfidence bool) *Visit {
+		visit := &Visit{PictureId: "obj", Uid: uid, VisitTime: ts, Shop: shop, LowConfidence: lowConfidence}
+		require.NoError(t, s.Record(visit))
+		return visit
+	}
+
+	h, err := s.Get(1)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), h.Visits)
+
+	// the first visit of uid 1, two sightings within the gap
+	visit := record(1, 1000, 8, false)
+	require.False(t, visit.IsReturning)
+	require.Equal(t, uint64(0), visit.PrevVisitTime)
+	visit = record(1, 1030, 9, false)
+	require.False(t, visit.IsReturning)
+
+	// a low confidence one is marked only
+	visit = record(1, 2000, 8, true)
+	require.True(t, visit.IsReturning)
+	require.Equal(t, uint64(1030), visit.PrevVisitTime)
+
+	// the second visit
+	visit = record(1, 3000, 8, false)
+	require.True(t, visit.IsReturning)
+	require.Equal(t, uint64(1030), visit.PrevVisitTime)
+	visit = record(1, 3050, 8, false)
+	require.True(t, visit.IsReturning)
+	require.Equal(t, uint64(1030), visit.PrevVisitTime)
+
+	// uid 0 is nobody
+	visit = record(0, 3000, 8, false)
+	require.False(t, visit.IsReturning)
+
+	h, err = s.Get(1)
+	require.NoError(t, err)
+	require.Equal(t, &History{Uid: 1, FirstSeen: 1000, LastSeen: 3050, Visits: 2, PrevVisit: 1030,
+		Shops: map[uint64]int64{8: 3050, 9: 1030}}, h)
+
+	visits, err := s.ListVisits(1, 0, 4000, 10)
+	require.NoError(t, err)
+	require.Len(t, visits, 3)
+	require.Equal(t, uint64(3050), visits[0].VisitTime)
+	require.True(t, visits[0].IsReturning)
+	visits, err = s.ListVisits(1, 1000, 3050, 10)
+	require.NoError(t, err)
+	require.Len(t, visits, 2)
+	require.Equal(t, uint64(3000), visits[0].VisitTime)
+	// at most maxVisits of 3 are kept
+	record(1, 5000, 8, false)
+	visits, err = s.ListVisits(1, 0, 6000, 10)
+	require.NoError(t, err)
+	require.Len(t, visits, 3)
+	require.Equal(t, uint64(3000), visits[2].VisitTime)
+
+	// merge uid 2 into 1
+	record(2, 500, 7, false)
+	require.NoError(t, s.Merge(1, 2))
+	require.Error(t, s.Merge(1, 1))
+	h, err = s.Get(1)
+	require.NoError(t, err)
+	require.Equal(t, int64(500), h.FirstSeen)
+	require.Equal(t, int64(5000), h.LastSeen)
+	require.Equal(t, int64(4), h.Visits)
+	require.Equal(t, int64(500), h.Shops[7])
+	h, err = s.Get(2)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), h.Visits)
+
+	// late sightings of uid 3
+	record(3, 5000, 8, false)
+	record(3, 9000, 8, false)
+	visit = record(3, 7000, 9, false)
+	require.True(t, visit.IsReturning)
+	require.Equal(t, uint64(5000), visit.PrevVisitTime)
+	visit = record(3, 6000, 9, true)
+	require.Equal(t, uint64(5000), visit.PrevVisitTime)
+	visit = record(3, 1000, 8, false)
+	require.False(t, visit.IsReturning)
+	visit = record(3, 1030, 8, false)
+	require.False(t, visit.IsReturning)
+	visit = record(3, 8990, 8, false)
+	require.Equal(t, uint64(5000), visit.PrevVisitTime)
+	h, err = s.Get(3)
+	require.NoError(t, err)
+	require.Equal(t, &History{Uid: 3, FirstSeen: 1000, LastSeen: 9000, Visits: 3, PrevVisit: 5000,
+		Shops: map[uint64]int64{8: 9000, 9: 7000}}, h)
+
+	removed, err := s.Forget(1, true)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), removed)
+	removed, err = s.Forget(1, false)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), removed)
+	h, err = s.Get(1)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), h.Visits)
+	visits, err = s.ListVisits(1, 0, 6000, 10)
+	require.NoError(t, err)
+	require.Len(t, visits, 0)
+}
+
+func testHistorySplit(t *testing.T, s HistoryStore) {
+	for _, visit := range []*Visit{
+		{PictureId: "obj1", Uid: 1, Xid: 10, VisitTime: 1000, Shop: 8},
+		{PictureId: "obj2", Uid: 1, Xid: 20, VisitTime: 2000, Shop: 9},
+		{PictureId: "obj3", Uid: 1, Xid: 10, VisitTime: 3000, Shop: 8},
+	} {
+		require.NoError(t, s.Record(visit))
+	}
+	require.Error(t, s.Split(1, 1, []int64{20}))
+	require.NoError(t, s.Split(1, 2, []int64{20}))
+
+	h, err := s.Get(1)
+	require.NoError(t, err)
+	require.Equal(t, &History{Uid: 1, FirstSeen: 1000, LastSeen: 3000, Visits: 2, PrevVisit: 1000,
+		Shops: map[uint64]int64{8: 3000}}, h)
+	visits, err := s.ListVisits(1, 0, 5000, 10)
+	require.NoError(t, err)
+	require.Len(t, visits, 2)
+	require.Equal(t, "obj3", visits[0].PictureId)
+
+	h, err = s.Get(2)
+	require.NoError(t, err)
+	require.Equal(t, &History{Uid: 2, FirstSeen: 2000, LastSeen: 2000, Visits: 1,
+		Shops: map[uint64]int64{9: 2000}}, h)
+	visits, err = s.ListVisits(2, 0, 5000, 10)
+	require.NoError(t, err)
+	require.Len(t, visits, 1)
+	require.Equal(t, "obj2", visits[0].PictureId)
+
+	// the visits trimmed are kept by the uid split
+	require.NoError(t, s.Record(&Visit{PictureId: "obj5", Uid: 3, Xid: 30, VisitTime: 1000, Shop: 7}))
+	for i, ts := range []uint64{2000, 3000, 4000} {
+		require.NoError(t, s.Record(&Visit{PictureId: "obj", Uid: 3, Xid: int64(40 + i), VisitTime: ts, Shop: 8}))
+	}
+	require.NoError(t, s.Split(3, 4, []int64{40, 41, 42}))
+	h, err = s.Get(3)
+	require.NoError(t, err)
+	require.Equal(t, &History{Uid: 3, FirstSeen: 1000, LastSeen: 1000, Visits: 1,
+		Shops: map[uint64]int64{7: 1000}}, h)
+	h, err = s.Get(4)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), h.Visits)
+}
+
+func TestMemoryHistoryStore(t *testing.T) {
+	testHistoryStore(t, NewMemoryHistoryStore(time.Minute, 3))
+	testHistorySplit(t, NewMemoryHistoryStore(time.Minute, 3))
+}
+
+func TestRedisHistoryStore(t *testing.T) {
+	if RedisAddr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	rcli := redis.NewClient(&redis.Options{Addr: RedisAddr, DB: 15})
+	defer rcli.Close()
+	require.NoError(t, rcli.FlushDB().Err())
+	testHistoryStore(t, NewRedisHistoryStore(rcli, time.Minute, 3))
+	require.NoError(t, rcli.FlushDB().Err())
+	testHistorySplit(t, NewRedisHistoryStore(rcli, time.Minute, 3))
+}
@@ -0,0 +1,354 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	// NextUidKey is the Redis counter of uids
+	NextUidKey = "faceserver_next_uid"
+	// XidKeyPrefix + "%016x" of xid is the Redis key of the uid of the xid
+	XidKeyPrefix = "xid_"
+	// UidKeyPrefix + uid is the Redis list of the xids of the uid, the latest first
+	UidKeyPrefix = "uid_"
+)
+
+var (
+	// ErrIdentityNotFound the xid or uid is unknown
+	ErrIdentityNotFound = errors.New("identity not found")
+)
+
+// IdentityStore maps xids, the ids of vectors in the vector index, to uids.
+// A uid has one or more xids, the latest first.
+type IdentityStore interface {
+	AllocateUid() (uid int64, err error)
+	// Associate adds xid to uid, the xid is removed from its previous uid if any
+	Associate(uid, xid int64) error
+	// GetUid returns ErrIdentityNotFound if the xid is unknown
+	GetUid(xid int64) (uid int64, err error)
+	ListXids(uid int64) (xids []int64, err error)
+	// Merge moves the xids of src to dst and removes src
+	Merge(dst, src int64) (moved []int64, err error)
+	// Split moves the xids of uid to a new uid, they must be of uid
+	Split(uid int64, xids []int64) (newUid int64, err error)
+	// Forget removes uid and its xids
+	Forget(uid int64) (xids []int64, err error)
+}
+
+// XidKey returns the Redis key of xid
+func XidKey(xid int64) string {
+	return XidKeyPrefix + FormatXid(xid)
+}
+
+// UidKey returns the Redis key of uid
+func UidKey(uid int64) string {
+	return UidKeyPrefix + strconv.FormatInt(uid, 10)
+}
+
+// FormatXid returns xid in the 16 hex digits form of the Redis keys
+func FormatXid(xid int64) string {
+	return fmt.Sprintf("%016x", uint64(xid))
+}
+
+// ParseXid parses the 16 hex digits form of xid
+func ParseXid(s string) (xid int64, err error) {
+	var v uint64
+	if v, err = strconv.ParseUint(s, 16, 64); err != nil {
+		err = errors.Wrapf(err, "xid %s", s)
+		return
+	}
+	xid = int64(v)
+	return
+}
+
+var (
+	// KEYS: xid key, uid key. ARGV: uid, xid
+	associateScript = redis.NewScript(`
+local old = redis.call('GET', KEYS[1])
+if old then
+	redis.call('LREM', '` + UidKeyPrefix + `' .. old, 0, ARGV[2])
+end
+redis.call('SET', KEYS[1], ARGV[1])
+redis.call('LPUSH', KEYS[2], ARGV[2])
+return 1
+`)
+	// KEYS: dst uid key, src uid key. ARGV: dst uid
+	mergeScript = redis.NewScript(`
+local xids = redis.call('LRANGE', KEYS[2], 0, -1)
+for i = #xids, 1, -1 do
+	redis.call('SET', '` + XidKeyPrefix + `' .. xids[i], ARGV[1])
+	redis.call('LPUSH', KEYS[1], xids[i])
+end
+redis.call('DEL', KEYS[2])
+return xids
+`)
+	// KEYS: uid key. ARGV: uid, xids...
+	splitScript = redis.NewScript(`
+for i = 2, #ARGV do
+	if redis.call('GET', '` + XidKeyPrefix + `' .. ARGV[i]) ~= ARGV[1] then
+		return redis.error_reply('xid ' .. ARGV[i] .. ' is not of uid ' .. ARGV[1])
+	end
+end
+local uid = redis.call('INCR', '` + NextUidKey + `')
+for i = #ARGV, 2, -1 do
+	redis.call('LREM', KEYS[1], 0, ARGV[i])
+	redis.call('SET', '` + XidKeyPrefix + `' .. ARGV[i], uid)
+	redis.call('LPUSH', '` + UidKeyPrefix + `' .. uid, ARGV[i])
+end
+return uid
+`)
+	// KEYS: uid key. ARGV: uid
+	forgetScript = redis.NewScript(`
+local xids = redis.call('LRANGE', KEYS[1], 0, -1)
+for _, xid in ipairs(xids) do
+	local key = '` + XidKeyPrefix + `' .. xid
+	if redis.call('GET', key) == ARGV[1] then
+		redis.call('DEL', key)
+	end
+end
+redis.call('DEL', KEYS[1])
+return xids
+`)
+)
+
+type redisIdentityStore struct {
+	rcli *redis.Client
+}
+
+// NewRedisIdentityStore returns an identity store in Redis. Every change is
+// a Lua script, so the xid keys and the uid lists are always consistent.
+func NewRedisIdentityStore(rcli *redis.Client) IdentityStore {
+	return &redisIdentityStore{rcli: rcli}
+}
+
+func (s *redisIdentityStore) AllocateUid() (uid int64, err error) {
+	if uid, err = s.rcli.Incr(NextUidKey).Result(); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+func (s *redisIdentityStore) Associate(uid, xid int64) (err error) {
+	if err = associateScript.Run(s.rcli, []string{XidKey(xid), UidKey(uid)}, uid, FormatXid(xid)).Err(); err != nil {
+		err = errors.Wrapf(err, "associate xid %016x with uid %d", uint64(xid), uid)
+	}
+	return
+}
+
+func (s *redisIdentityStore) GetUid(xid int64) (uid int64, err error) {
+	key := XidKey(xid)
+	var value string
+	if value, err = s.rcli.Get(key).Result(); err != nil {
+		if err == redis.Nil {
+			err = ErrIdentityNotFound
+		}
+		err = errors.Wrapf(err, "keyXid %v", key)
+		return
+	}
+	if uid, err = strconv.ParseInt(value, 10, 64); err != nil {
+		err = errors.Wrapf(err, "keyXid %v", key)
+	}
+	return
+}
+
+func (s *redisIdentityStore) ListXids(uid int64) (xids []int64, err error) {
+	key := UidKey(uid)
+	var values []string
+	if values, err = s.rcli.LRange(key, 0, -1).Result(); err != nil {
+		err = errors.Wrapf(err, "keyUid %v", key)
+		return
+	}
+	return parseXids(values)
+}
+
+func (s *redisIdentityStore) Merge(dst, src int64) (moved []int64, err error) {
+	if dst == src {
+		err = errors.Errorf("merge uid %d into itself", dst)
+		return
+	}
+	var values []string
+	if values, err = scriptStrings(mergeScript.Run(s.rcli, []string{UidKey(dst), UidKey(src)}, dst)); err != nil {
+		err = errors.Wrapf(err, "merge uid %d into %d", src, dst)
+		return
+	}
+	return parseXids(values)
+}
+
+func (s *redisIdentityStore) Split(uid int64, xids []int64) (newUid int64, err error) {
+	if len(xids) == 0 {
+		err = errors.Errorf("split uid %d without xids", uid)
+		return
+	}
+	args := []interface{}{uid}
+	for _, xid := range xids {
+		args = append(args, FormatXid(xid))
+	}
+	if newUid, err = splitScript.Run(s.rcli, []string{UidKey(uid)}, args...).Int64(); err != nil {
+		err = errors.Wrapf(err, "split uid %d", uid)
+	}
+	return
+}
+
+func (s *redisIdentityStore) Forget(uid int64) (xids []int64, err error) {
+	var values []string
+	if values, err = scriptStrings(forgetScript.Run(s.rcli, []string{UidKey(uid)}, uid)); err != nil {
+		err = errors.Wrapf(err, "forget uid %d", uid)
+		return
+	}
+	return parseXids(values)
+}
+
+func scriptStrings(cmd *redis.Cmd) (values []string, err error) {
+	var rsp interface{}
+	if rsp, err = cmd.Result(); err != nil {
+		return
+	}
+	items, _ := rsp.([]interface{})
+	for _, item := range items {
+		s, _ := item.(string)
+		values = append(values, s)
+	}
+	return
+}
+
+func parseXids(values []string) (xids []int64, err error) {
+	for _, value := range values {
+		var xid int64
+		if xid, err = ParseXid(value); err != nil {
+			return
+		}
+		xids = append(xids, xid)
+	}
+	return
+}
+
+// MemoryIdentityStore is an identity store in process for tests
+type MemoryIdentityStore struct {
+	sync.Mutex
+
+	nextUid int64
+	uids    map[int64]int64
+	xids    map[int64][]int64
+}
+
+// NewMemoryIdentityStore returns an empty MemoryIdentityStore
+func NewMemoryIdentityStore() *MemoryIdentityStore {
+	return &MemoryIdentityStore{
+		uids: make(map[int64]int64),
+		xids: make(map[int64][]int64),
+	}
+}
+
+// AllocateUid implements IdentityStore
+func (s *MemoryIdentityStore) AllocateUid() (uid int64, err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.nextUid++
+	return s.nextUid, nil
+}
+
+// Associate implements IdentityStore
+func (s *MemoryIdentityStore) Associate(uid, xid int64) error {
+	s.Lock()
+	defer s.Unlock()
+	if old, ok := s.uids[xid]; ok {
+		s.xids[old] = removeXid(s.xids[old], xid)
+		if len(s.xids[old]) == 0 {
+			delete(s.xids, old)
+		}
+	}
+	s.uids[xid] = uid
+	s.xids[uid] = append([]int64{xid}, s.xids[uid]...)
+	return nil
+}
+
+func removeXid(xids []int64, xid int64) (left []int64) {
+	for _, x := range xids {
+		if x != xid {
+			left = append(left, x)
+		}
+	}
+	return
+}
+
+// GetUid implements IdentityStore
+func (s *MemoryIdentityStore) GetUid(xid int64) (uid int64, err error) {
+	s.Lock()
+	defer s.Unlock()
+	var ok bool
+	if uid, ok = s.uids[xid]; !ok {
+		err = errors.Wrapf(ErrIdentityNotFound, "xid %016x", uint64(xid))
+	}
+	return
+}
+
+// ListXids implements IdentityStore
+func (s *MemoryIdentityStore) ListXids(uid int64) (xids []int64, err error) {
+	s.Lock()
+	defer s.Unlock()
+	xids = append(xids, s.xids[uid]...)
+	return
+}
+
+// Merge implements IdentityStore
+func (s *MemoryIdentityStore) Merge(dst, src int64) (moved []int64, err error) {
+	if dst == src {
+		err = errors.Errorf("merge uid %d into itself", dst)
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	moved = s.xids[src]
+	for _, xid := range moved {
+		s.uids[xid] = dst
+	}
+	s.xids[dst] = append(append([]int64(nil), moved...), s.xids[dst]...)
+	delete(s.xids, src)
+	return
+}
+
+// Split implements IdentityStore
+func (s *MemoryIdentityStore) Split(uid int64, xids []int64) (newUid int64, err error) {
+	if len(xids) == 0 {
+		err = errors.Errorf("split uid %d without xids", uid)
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	for _, xid := range xids {
+		if old, ok := s.uids[xid]; !ok || old != uid {
+			err = errors.Errorf("xid %016x is not of uid %d", uint64(xid), uid)
+			return
+		}
+	}
+	s.nextUid++
+	newUid = s.nextUid
+	for _, xid := range xids {
+		s.xids[uid] = removeXid(s.xids[uid], xid)
+		s.uids[xid] = newUid
+	}
+	if len(s.xids[uid]) == 0 {
+		delete(s.xids, uid)
+	}
+	s.xids[newUid] = append([]int64(nil), xids...)
+	return
+}
+
+// Forget implements IdentityStore
+func (s *MemoryIdentityStore) Forget(uid int64) (xids []int64, err error) {
+	s.Lock()
+	defer s.Unlock()
+	xids = s.xids[uid]
+	for _, xid := range xids {
+		if s.uids[xid] == uid {
+			delete(s.uids, xid)
+		}
+	}
+	delete(s.xids, uid)
+	return
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// RedisAddr is a scratch Redis, its DB 15 is flushed by the tests
+	RedisAddr = os.Getenv("REDIS_ADDR")
+)
+
+func testIdentityStore(t *testing.T, s IdentityStore) {
+	uid1, err := s.AllocateUid()
+	require.NoError(t, err)
+	uid2, err := s.AllocateUid()
+	require.NoError(t, err)
+	require.NotEqual(t, uid1, uid2)
+
+	_, err = s.GetUid(1)
+	require.Equal(t, ErrIdentityNotFound, errors.Cause(err))
+
+	require.NoError(t, s.Associate(uid1, 1))
+	require.NoError(t, s.Associate(uid1, 2))
+	require.NoError(t, s.Associate(uid2, 3))
+	// xids are 64 bits hashes
+	require.NoError(t, s.Associate(uid2, -4))
+	uid, err := s.GetUid(-4)
+	require.NoError(t, err)
+	require.Equal(t, uid2, uid)
+	xids, err := s.ListXids(uid1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2, 1}, xids)
+
+	// re-associating moves the xid
+	require.NoError(t, s.Associate(uid2, 1))
+	xids, err = s.ListXids(uid1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2}, xids)
+
+	moved, err := s.Merge(uid1, uid2)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, -4, 3}, moved)
+	xids, err = s.ListXids(uid1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, -4, 3, 2}, xids)
+	xids, err = s.ListXids(uid2)
+	require.NoError(t, err)
+	require.Empty(t, xids)
+	uid, err = s.GetUid(3)
+	require.NoError(t, err)
+	require.Equal(t, uid1, uid)
+	_, err = s.Merge(uid1, uid1)
+	require.Error(t, err)
+
+	_, err = s.Split(uid1, []int64{3, 5})
+	require.Error(t, err)
+	uid3, err := s.Split(uid1, []int64{-4, 3})
+	require.NoError(t, err)
+	require.NotEqual(t, uid2, uid3)
+	xids, err = s.ListXids(uid3)
+	require.NoError(t, err)
+	require.Equal(t, []int64{-4, 3}, xids)
+	uid, err = s.GetUid(3)
+	require.NoError(t, err)
+	require.Equal(t, uid3, uid)
+	xids, err = s.ListXids(uid1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, xids)
+
+	xids, err = s.Forget(uid1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, xids)
+	for _, xid := range xids {
+		_, err = s.GetUid(xid)
+		require.Equal(t, ErrIdentityNotFound, errors.Cause(err))
+	}
+	xids, err = s.ListXids(uid1)
+	require.NoError(t, err)
+	require.Empty(t, xids)
+}
+
+func TestMemoryIdentityStore(t *testing.T) {
+	testIdentityStore(t, NewMemoryIdentityStore())
+}
+
+func TestRedisIdentityStore(t *testing.T) {
+	if RedisAddr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	rcli := redis.NewClient(&redis.Options{Addr: RedisAddr, DB: 15})
+	defer rcli.Close()
+	require.NoError(t, rcli.FlushDB().Err())
+	testIdentityStore(t, NewRedisIdentityStore(rcli))
+}
@@ -0,0 +1,352 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// alertBuffer is the max alerts waiting for delivery by the webhook
+	alertBuffer = 256
+
+	// AlertCameraSilent a camera has no upload during opening hours
+	AlertCameraSilent = "camera_silent"
+	// AlertCameraRecovered a silent camera uploads again
+	AlertCameraRecovered = "camera_recovered"
+)
+
+var (
+	//metrics per camera
+	cameraUploadCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "camera_upload",
+			Help:      "camera upload count",
+		}, []string{"mac", "camera"})
+	cameraLastUploadGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "camera_last_upload_timestamp_seconds",
+			Help:      "camera last upload unix time",
+		}, []string{"mac", "camera"})
+	cameraUploadRateGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "camera_upload_per_minute",
+			Help:      "camera uploads per minute in the last check interval",
+		}, []string{"mac", "camera"})
+	cameraSilentGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "camera_silent",
+			Help:      "1 if the camera is detected silent during opening hours",
+		}, []string{"mac", "camera"})
+	cameraMetricOnce sync.Once
+)
+
+func initMetricsForCameras() {
+	prometheus.MustRegister(cameraUploadCountVec)
+	prometheus.MustRegister(cameraLastUploadGaugeVec)
+	prometheus.MustRegister(cameraUploadRateGaugeVec)
+	prometheus.MustRegister(cameraSilentGaugeVec)
+}
+
+// LivenessCfg camera liveness detector cfg
+type LivenessCfg struct {
+	// SilentTimeout a camera without upload for longer than it is silent, 0 disables the detector
+	SilentTimeout time.Duration
+	CheckInterval time.Duration
+	// OpenHour and CloseHour are the opening hours in local time, [OpenHour, CloseHour).
+	// The shop is always open if they are equal, and CloseHour < OpenHour means closing after midnight.
+	OpenHour  int
+	CloseHour int
+	// Webhook receives alerts as json if set, otherwise alerts are logged
+	Webhook        string
+	WebhookTimeout time.Duration
+}
+
+// ParseOpeningHours parses opening hours in format "8-22", nothing else is allowed
+func ParseOpeningHours(s string) (open, close int, err error) {
+	fields := strings.Split(s, "-")
+	if len(fields) != 2 {
+		err = errors.Errorf("invalid opening hours %q", s)
+		return
+	}
+	if open, err = strconv.Atoi(fields[0]); err != nil {
+		err = errors.Wrapf(err, "invalid opening hours %q", s)
+		return
+	}
+	if close, err = strconv.Atoi(fields[1]); err != nil {
+		err = errors.Wrapf(err, "invalid opening hours %q", s)
+		return
+	}
+	if open < 0 || open > 23 || close < 0 || close > 24 {
+		err = errors.Errorf("invalid opening hours %q", s)
+		return
+	}
+	return
+}
+
+func (cfg *LivenessCfg) isOpen(t time.Time) bool {
+	h := t.Hour()
+	switch {
+	case cfg.OpenHour == cfg.CloseHour:
+		return true
+	case cfg.OpenHour < cfg.CloseHour:
+		return h >= cfg.OpenHour && h < cfg.CloseHour
+	default:
+		return h >= cfg.OpenHour || h < cfg.CloseHour
+	}
+}
+
+// Alert is emitted by the camera liveness detector
+type Alert struct {
+	Kind     string    `json:"kind"`
+	Mac      string    `json:"mac"`
+	Camera   string    `json:"camera"`
+	LastSeen time.Time `json:"lastSeen"`
+	At       time.Time `json:"at"`
+	// TerminalActive is true if other cameras of the same terminal are still uploading
+	TerminalActive bool `json:"terminalActive"`
+}
+
+// Notifier delivers alerts
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+type logNotifier struct{}
+
+// NewLogNotifier returns a notifier which writes alerts to the log
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) Notify(alert Alert) error {
+	log.Warnf("alert %s: mac %s, camera %s, last seen %s, terminal active %v",
+		alert.Kind,
+		alert.Mac,
+		alert.Camera,
+		alert.LastSeen.Format(time.RFC3339),
+		alert.TerminalActive)
+	return nil
+}
+
+type webhookNotifier struct {
+	url string
+	hc  *http.Client
+}
+
+// NewWebhookNotifier returns a notifier which posts alerts as json to the url
+func NewWebhookNotifier(url string, timeout time.Duration) Notifier {
+	return &webhookNotifier{
+		url: url,
+		hc:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (n *webhookNotifier) Notify(alert Alert) (err error) {
+	var body []byte
+	if body, err = json.Marshal(alert); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	var resp *http.Response
+	if resp, err = n.hc.Post(n.url, "application/json", bytes.NewReader(body)); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		err = errors.Errorf("webhook %s returned %s", n.url, resp.Status)
+		return
+	}
+	return
+}
+
+type asyncNotifier struct {
+	notifier Notifier
+	c        chan Alert
+}
+
+// NewAsyncNotifier returns a notifier which delivers alerts by notifier in a goroutine, so that
+// a slow notifier never blocks the uploads. An alert is dropped if buffer alerts are waiting.
+func NewAsyncNotifier(notifier Notifier, buffer int) Notifier {
+	n := &asyncNotifier{
+		notifier: notifier,
+		c:        make(chan Alert, buffer),
+	}
+	go n.run()
+	return n
+}
+
+func (n *asyncNotifier) Notify(alert Alert) error {
+	select {
+	case n.c <- alert:
+		return nil
+	default:
+		return errors.Errorf("%d alerts are waiting for delivery, dropped", cap(n.c))
+	}
+}
+
+func (n *asyncNotifier) run() {
+	for alert := range n.c {
+		if err := n.notifier.Notify(alert); err != nil {
+			log.Errorf("notify alert %+v failed, errors:%+v", alert, err)
+		}
+	}
+}
+
+type cameraKey struct {
+	mac    string
+	camera string
+}
+
+type cameraStat struct {
+	lastSeen  time.Time
+	count     uint64
+	lastCount uint64
+	silent    bool
+}
+
+// cameraTracker tracks uploads per (mac, camera), and detects silent cameras
+type cameraTracker struct {
+	sync.Mutex
+
+	cfg      LivenessCfg
+	notifier Notifier
+	now      func() time.Time
+	cameras  map[cameraKey]*cameraStat
+}
+
+func newCameraTracker(cfg LivenessCfg, notifier Notifier) *cameraTracker {
+	cameraMetricOnce.Do(initMetricsForCameras)
+	return &cameraTracker{
+		cfg:      cfg,
+		notifier: notifier,
+		now:      time.Now,
+		cameras:  make(map[cameraKey]*cameraStat),
+	}
+}
+
+func (ct *cameraTracker) observe(mac, camera string) {
+	now := ct.now()
+	key := cameraKey{mac: mac, camera: camera}
+
+	ct.Lock()
+	stat, ok := ct.cameras[key]
+	if !ok {
+		stat = &cameraStat{}
+		ct.cameras[key] = stat
+	}
+	stat.lastSeen = now
+	stat.count++
+	recovered := stat.silent
+	stat.silent = false
+	ct.Unlock()
+
+	cameraUploadCountVec.WithLabelValues(mac, camera).Inc()
+	cameraLastUploadGaugeVec.WithLabelValues(mac, camera).Set(float64(now.Unix()))
+	if recovered {
+		cameraSilentGaugeVec.WithLabelValues(mac, camera).Set(0)
+		ct.notify(Alert{
+			Kind:           AlertCameraRecovered,
+			Mac:            mac,
+			Camera:         camera,
+			LastSeen:       now,
+			At:             now,
+			TerminalActive: true,
+		})
+	}
+}
+
+// check updates the upload rates, and emits alerts for the cameras who became silent
+func (ct *cameraTracker) check(interval time.Duration) {
+	now := ct.now()
+	open := ct.cfg.isOpen(now)
+
+	var alerts []Alert
+	ct.Lock()
+	active := make(map[string]bool)
+	for key, stat := range ct.cameras {
+		if now.Sub(stat.lastSeen) < ct.cfg.SilentTimeout {
+			active[key.mac] = true
+		}
+	}
+	for key, stat := range ct.cameras {
+		if interval > 0 {
+			perMinute := float64(stat.count-stat.lastCount) / interval.Minutes()
+			cameraUploadRateGaugeVec.WithLabelValues(key.mac, key.camera).Set(perMinute)
+		}
+		stat.lastCount = stat.count
+
+		if !open || stat.silent || now.Sub(stat.lastSeen) < ct.cfg.SilentTimeout {
+			continue
+		}
+		stat.silent = true
+		cameraSilentGaugeVec.WithLabelValues(key.mac, key.camera).Set(1)
+		alerts = append(alerts, Alert{
+			Kind:           AlertCameraSilent,
+			Mac:            key.mac,
+			Camera:         key.camera,
+			LastSeen:       stat.lastSeen,
+			At:             now,
+			TerminalActive: active[key.mac],
+		})
+	}
+	ct.Unlock()
+
+	for _, alert := range alerts {
+		ct.notify(alert)
+	}
+}
+
+func (ct *cameraTracker) notify(alert Alert) {
+	if err := ct.notifier.Notify(alert); err != nil {
+		log.Errorf("notify alert %+v failed, errors:%+v", alert, err)
+	}
+}
+
+func (ct *cameraTracker) start(ctx context.Context) {
+	if ct.cfg.SilentTimeout <= 0 || ct.cfg.CheckInterval <= 0 {
+		log.Infof("camera liveness detector is disabled")
+		return
+	}
+
+	go func() {
+		log.Infof("camera liveness detector started")
+		ticker := time.NewTicker(ct.cfg.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Infof("camera liveness detector stopped")
+				return
+			case <-ticker.C:
+				ct.check(ct.cfg.CheckInterval)
+			}
+		}
+	}()
+}
+
+func newNotifier(cfg LivenessCfg) Notifier {
+	if cfg.Webhook != "" {
+		return NewAsyncNotifier(NewWebhookNotifier(cfg.Webhook, cfg.WebhookTimeout), alertBuffer)
+	}
+	return NewLogNotifier()
+}
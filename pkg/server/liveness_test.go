@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type alertRecorder struct {
+	alerts []Alert
+}
+
+func (r *alertRecorder) Notify(alert Alert) error {
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func TestOpeningHours(t *testing.T) {
+	open, close, err := ParseOpeningHours("8-22")
+	require.NoError(t, err)
+	cfg := LivenessCfg{OpenHour: open, CloseHour: close}
+	require.True(t, cfg.isOpen(time.Date(2019, 4, 18, 8, 0, 0, 0, time.Local)))
+	require.False(t, cfg.isOpen(time.Date(2019, 4, 18, 22, 0, 0, 0, time.Local)))
+
+	cfg = LivenessCfg{OpenHour: 20, CloseHour: 2}
+	require.True(t, cfg.isOpen(time.Date(2019, 4, 18, 1, 0, 0, 0, time.Local)))
+	require.False(t, cfg.isOpen(time.Date(2019, 4, 18, 12, 0, 0, 0, time.Local)))
+
+	for _, s := range []string{"8", "8-22xyz", "08:00-22:00xyz", "8-22-23", " 8-22", "8-25", "-1-22"} {
+		_, _, err = ParseOpeningHours(s)
+		require.Error(t, err, s)
+	}
+	open, close, err = ParseOpeningHours("08-22")
+	require.NoError(t, err)
+	require.Equal(t, 8, open)
+	require.Equal(t, 22, close)
+}
+
+type blockingNotifier struct {
+	release chan struct{}
+	alerts  chan Alert
+}
+
+func (n *blockingNotifier) Notify(alert Alert) error {
+	<-n.release
+	n.alerts <- alert
+	return nil
+}
+
+func TestAsyncNotifier(t *testing.T) {
+	slow := &blockingNotifier{release: make(chan struct{}), alerts: make(chan Alert, 4)}
+	n := NewAsyncNotifier(slow, 1)
+
+	// the first one is in delivery, the second one waits, the third one is dropped
+	require.NoError(t, n.Notify(Alert{Camera: "1"}))
+	for len(n.(*asyncNotifier).c) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, n.Notify(Alert{Camera: "2"}))
+	require.Error(t, n.Notify(Alert{Camera: "3"}))
+
+	close(slow.release)
+	require.Equal(t, "1", (<-slow.alerts).Camera)
+	require.Equal(t, "2", (<-slow.alerts).Camera)
+}
+
+func TestCameraSilentDetect(t *testing.T) {
+	now := time.Date(2019, 4, 18, 10, 0, 0, 0, time.Local)
+	rec := &alertRecorder{}
+	ct := newCameraTracker(LivenessCfg{SilentTimeout: 10 * time.Minute, OpenHour: 8, CloseHour: 22}, rec)
+	ct.now = func() time.Time { return now }
+
+	ct.observe("309c233431b2", "192.168.150.243")
+	ct.observe("309c233431b2", "192.168.150.244")
+
+	now = now.Add(11 * time.Minute)
+	ct.observe("309c233431b2", "192.168.150.244")
+	ct.check(time.Minute)
+	require.Equal(t, 1, len(rec.alerts))
+	require.Equal(t, AlertCameraSilent, rec.alerts[0].Kind)
+	require.Equal(t, "192.168.150.243", rec.alerts[0].Camera)
+	require.True(t, rec.alerts[0].TerminalActive)
+
+	// alert only once
+	ct.check(time.Minute)
+	require.Equal(t, 1, len(rec.alerts))
+
+	ct.observe("309c233431b2", "192.168.150.243")
+	require.Equal(t, 2, len(rec.alerts))
+	require.Equal(t, AlertCameraRecovered, rec.alerts[1].Kind)
+}
+
+func TestCameraSilentOutOfOpeningHours(t *testing.T) {
+	now := time.Date(2019, 4, 18, 21, 55, 0, 0, time.Local)
+	rec := &alertRecorder{}
+	ct := newCameraTracker(LivenessCfg{SilentTimeout: 10 * time.Minute, OpenHour: 8, CloseHour: 22}, rec)
+	ct.now = func() time.Time { return now }
+
+	ct.observe("309c233431b2", "192.168.150.243")
+	now = now.Add(time.Hour)
+	ct.check(time.Minute)
+	require.Equal(t, 0, len(rec.alerts))
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	alertC := make(chan Alert, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		alertC <- alert
+	}))
+	defer ts.Close()
+
+	n := NewWebhookNotifier(ts.URL, time.Second)
+	require.NoError(t, n.Notify(Alert{Kind: AlertCameraSilent, Mac: "309c233431b2", Camera: "192.168.150.243"}))
+	alert := <-alertC
+	require.Equal(t, AlertCameraSilent, alert.Kind)
+	require.Equal(t, "192.168.150.243", alert.Camera)
+
+	failed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failed.Close()
+	require.Error(t, NewWebhookNotifier(failed.URL, time.Second).Notify(Alert{}))
+}
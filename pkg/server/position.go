@@ -0,0 +1,208 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fagongzi/log"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// PositionResolver resolves the shop of a terminal and the position of its cameras
+type PositionResolver interface {
+	GetShop(mac string) (shop uint64, found bool, err error)
+	GetPosition(mac, cameraIp string) (shop uint64, pos uint32, found bool, err error)
+	GetCamera(mac, cameraIp string) (shop uint64, cam CameraInfo, found bool, err error)
+}
+
+// invalidator is implemented by resolvers who cache terminals
+type invalidator interface {
+	Invalidate(mac string)
+}
+
+// PositionEntry is a camera in the position mapping file
+type PositionEntry struct {
+	Mac      string `json:"mac"`
+	Camera   string `json:"camera"`
+	Shop     uint64 `json:"shop"`
+	Position uint32 `json:"position"`
+
+	Direction string            `json:"direction,omitempty"`
+	Zone      string            `json:"zone,omitempty"`
+	Entrance  bool              `json:"entrance,omitempty"`
+	Exit      bool              `json:"exit,omitempty"`
+	Enabled   *bool             `json:"enabled,omitempty"`
+	Extra     map[string]string `json:"extra,omitempty"`
+}
+
+type termPositions struct {
+	shop    uint64
+	cameras map[string]CameraInfo
+}
+
+// FileResolver resolves positions with a YAML or JSON mapping file, for example:
+//
+//   - mac: 309c233431b2
+//     camera: 192.168.150.243
+//     shop: 8
+//     position: 1
+//
+// The file is reloaded when its modify time changes.
+type FileResolver struct {
+	sync.RWMutex
+
+	path    string
+	modTime time.Time
+	terms   map[string]*termPositions
+	stopC   chan struct{}
+}
+
+// NewFileResolver loads the mapping file, and checks it for reload every interval.
+// The reload is disabled if interval is 0.
+func NewFileResolver(path string, interval time.Duration) (fr *FileResolver, err error) {
+	fr = &FileResolver{
+		path:  path,
+		stopC: make(chan struct{}),
+	}
+	if _, err = fr.reload(); err != nil {
+		return
+	}
+
+	if interval > 0 {
+		go fr.startReload(interval)
+	}
+	return
+}
+
+// Close stops reloading the mapping file
+func (fr *FileResolver) Close() {
+	close(fr.stopC)
+}
+
+// GetShop implements PositionResolver
+func (fr *FileResolver) GetShop(mac string) (shop uint64, found bool, err error) {
+	fr.RLock()
+	defer fr.RUnlock()
+
+	if term, ok := fr.terms[mac]; ok {
+		shop, found = term.shop, true
+	}
+	return
+}
+
+// GetPosition implements PositionResolver
+func (fr *FileResolver) GetPosition(mac, cameraIp string) (shop uint64, pos uint32, found bool, err error) {
+	var cam CameraInfo
+	shop, cam, found, err = fr.GetCamera(mac, cameraIp)
+	pos = cam.Position
+	return
+}
+
+// GetCamera implements PositionResolver
+func (fr *FileResolver) GetCamera(mac, cameraIp string) (shop uint64, cam CameraInfo, found bool, err error) {
+	fr.RLock()
+	defer fr.RUnlock()
+
+	if term, ok := fr.terms[mac]; ok {
+		shop = term.shop
+		cam, found = term.cameras[cameraIp]
+	}
+	return
+}
+
+func (fr *FileResolver) startReload(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fr.stopC:
+			return
+		case <-ticker.C:
+			if reloaded, err := fr.reload(); err != nil {
+				log.Errorf("position file %s reload failed, keep the old one, errors:%+v", fr.path, err)
+			} else if reloaded {
+				log.Infof("position file %s reloaded", fr.path)
+			}
+		}
+	}
+}
+
+// reload loads the mapping file if it's modified since the last load
+func (fr *FileResolver) reload() (reloaded bool, err error) {
+	var info os.FileInfo
+	if info, err = os.Stat(fr.path); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	fr.RLock()
+	modTime := fr.modTime
+	fr.RUnlock()
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadFile(fr.path); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	var terms map[string]*termPositions
+	if terms, err = parsePositions(data); err != nil {
+		err = errors.Wrapf(err, "parse %s", fr.path)
+		return
+	}
+
+	fr.Lock()
+	fr.terms = terms
+	fr.modTime = info.ModTime()
+	fr.Unlock()
+	reloaded = true
+	return
+}
+
+func parsePositions(data []byte) (terms map[string]*termPositions, err error) {
+	var entries []PositionEntry
+	// YAML is a superset of JSON
+	if err = yaml.Unmarshal(data, &entries); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+
+	terms = make(map[string]*termPositions)
+	for i, entry := range entries {
+		if entry.Mac == "" || entry.Camera == "" {
+			err = errors.Errorf("entry %d: mac and camera are required", i)
+			return
+		}
+		term, ok := terms[entry.Mac]
+		if !ok {
+			term = &termPositions{
+				shop:    entry.Shop,
+				cameras: make(map[string]CameraInfo),
+			}
+			terms[entry.Mac] = term
+		} else if term.shop != entry.Shop {
+			err = errors.Errorf("entry %d: mac %s is in both shop %d and %d", i, entry.Mac, term.shop, entry.Shop)
+			return
+		}
+		if _, ok := term.cameras[entry.Camera]; ok {
+			err = errors.Errorf("entry %d: duplicated camera %s of mac %s", i, entry.Camera, entry.Mac)
+			return
+		}
+		cam := CameraInfo{
+			Position:  entry.Position,
+			Direction: entry.Direction,
+			Zone:      entry.Zone,
+			Entrance:  entry.Entrance,
+			Exit:      entry.Exit,
+			Enabled:   entry.Enabled == nil || *entry.Enabled,
+			Extra:     entry.Extra,
+		}
+		term.cameras[entry.Camera] = cam
+	}
+	return
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "positions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "positions.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+- mac: 309c233431b2
+  camera: 192.168.150.243
+  shop: 8
+  position: 1
+- mac: 309c233431b2
+  camera: 192.168.150.244
+  shop: 8
+  position: 2
+`), 0644))
+
+	fr, err := NewFileResolver(path, 0)
+	require.NoError(t, err)
+	defer fr.Close()
+
+	shop, found, err := fr.GetShop(MyMac)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, uint64(8), shop)
+
+	shop, pos, found, err := fr.GetPosition(MyMac, "192.168.150.244")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, uint64(8), shop)
+	require.Equal(t, uint32(2), pos)
+
+	_, _, found, _ = fr.GetPosition("000000000000", MyCamera)
+	require.False(t, found)
+
+	// JSON works too, and an invalid file keeps the old mapping
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{"mac":"309c233431b2","camera":"192.168.150.243","shop":9,"position":3}]`), 0644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	reloaded, err := fr.reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	shop, pos, found, _ = fr.GetPosition(MyMac, MyCamera)
+	require.True(t, found)
+	require.Equal(t, uint64(9), shop)
+	require.Equal(t, uint32(3), pos)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`- mac: 309c233431b2`), 0644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second)))
+	_, err = fr.reload()
+	require.Error(t, err)
+	_, pos, found, _ = fr.GetPosition(MyMac, MyCamera)
+	require.True(t, found)
+	require.Equal(t, uint32(3), pos)
+}
+
+func TestParsePositionsConflict(t *testing.T) {
+	_, err := parsePositions([]byte(`
+- {mac: 309c233431b2, camera: 192.168.150.243, shop: 8, position: 1}
+- {mac: 309c233431b2, camera: 192.168.150.244, shop: 9, position: 2}
+`))
+	require.Error(t, err)
+
+	_, err = parsePositions([]byte(`
+- {mac: 309c233431b2, camera: 192.168.150.243, shop: 8, position: 1}
+- {mac: 309c233431b2, camera: 192.168.150.243, shop: 8, position: 2}
+`))
+	require.Error(t, err)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"math/rand"
 	"sync"
@@ -10,14 +11,37 @@ import (
 	"github.com/fagongzi/goetty"
 	"github.com/fagongzi/log"
 	"github.com/infinivision/filesyncer/pkg/codec"
+	"github.com/infinivision/filesyncer/pkg/queue"
+	"github.com/pkg/errors"
 )
 
+// ImgMsg is a completed image published to the image queue
 type ImgMsg struct {
 	Shop     uint64
 	Position uint32
 	ModTime  int64
 	ObjID    string
 	Img      []byte
+	Camera   CameraInfo
+	// Mac and CameraIp are the terminal and camera the image is uploaded by
+	Mac      string
+	CameraIp string
+}
+
+// Marshal encodes the ImgMsg as a queue message
+func (msg *ImgMsg) Marshal() (data []byte, err error) {
+	if data, err = json.Marshal(msg); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+// Unmarshal decodes a queue message
+func (msg *ImgMsg) Unmarshal(data []byte) (err error) {
+	if err = json.Unmarshal(data, msg); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
 }
 
 // FileServer file server
@@ -28,22 +52,18 @@ type FileServer struct {
 	sessions  map[int64]*session
 	tcpServer *goetty.Server
 
-	ctx    context.Context
-	cancel context.CancelFunc
-	cmdb   *CmdbApi
-	imgCh  chan<- ImgMsg
+	ctx      context.Context
+	cancel   context.CancelFunc
+	resolver PositionResolver
+	imgQ     queue.Producer
 }
 
 // NewFileServer create a file server
 // The file server will received files via tcp protocol,
 // and support resume data from break point.
-func NewFileServer(cfg *Cfg, imgCh chan<- ImgMsg) *FileServer {
-	cmdb, err := NewCmdbApi(cfg.EurekaAddr, cfg.EurekaApp)
-	if err != nil {
-		log.Fatalf("%+v", err)
-	}
-
-	initG(cfg, cmdb, imgCh)
+func NewFileServer(cfg *Cfg, imgQ queue.Producer) *FileServer {
+	resolver := newPositionResolver(cfg, imgQ)
+	initG(cfg, resolver, imgQ)
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &FileServer{
@@ -55,15 +75,21 @@ func NewFileServer(cfg *Cfg, imgCh chan<- ImgMsg) *FileServer {
 			goetty.WithServerMiddleware(goetty.NewSyncProtocolServerMiddleware(codec.FileDecoder, codec.FileEncoder, func(conn goetty.IOSession, msg interface{}) error {
 				return conn.WriteAndFlush(msg)
 			}))),
-		ctx:    ctx,
-		cancel: cancel,
-		cmdb:   cmdb,
-		imgCh:  imgCh,
+		ctx:      ctx,
+		cancel:   cancel,
+		resolver: resolver,
+		imgQ:     imgQ,
 	}
 }
 
+// SetNotifier replaces the notifier of camera liveness alerts, it must be called before Start
+func (fs *FileServer) SetNotifier(notifier Notifier) {
+	camTracker.notifier = notifier
+}
+
 // Start start the file server
 func (fs *FileServer) Start() error {
+	camTracker.start(fs.ctx)
 	return fs.tcpServer.Start(fs.doConnection)
 }
 
@@ -86,6 +112,7 @@ func (fs *FileServer) doConnection(conn goetty.IOSession) error {
 
 	defer func() {
 		fs.removeSession(s)
+		termMgr.unbind(s)
 		s.close()
 		log.Debugf("net: %s is closed", addr)
 	}()
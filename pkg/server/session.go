@@ -55,6 +55,64 @@ var (
 			Name:      "term_load_average_1",
 			Help:      "terminal load average 1 minute",
 		}, []string{"mac"})
+	termQueueDepthGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "term_queue_depth",
+			Help:      "terminal files waiting for upload",
+		}, []string{"mac"})
+	termBacklogBytesGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "term_backlog_bytes",
+			Help:      "terminal bytes waiting for upload",
+		}, []string{"mac"})
+	termOldestPendingGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "term_oldest_pending_seconds",
+			Help:      "terminal age of the oldest file waiting for upload",
+		}, []string{"mac"})
+	termUploadsCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "term_uploads_total",
+			Help:      "terminal uploads, advanced by the increase of the totals reported by terminal",
+		}, []string{"mac", "result"})
+	termNetGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "term_net",
+			Help:      "terminal network interface counters since boot",
+		}, []string{"mac", "iface", "counter"})
+	termCpuTemperatureGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "term_cpu_temperature_celsius",
+			Help:      "terminal CPU temperature",
+		}, []string{"mac"})
+	termUptimeGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "term_uptime_seconds",
+			Help:      "terminal uptime",
+		}, []string{"mac"})
+	termVersionGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "term_version",
+			Help:      "terminal faceclient version, the value is always 1",
+		}, []string{"mac", "version"})
+	termVersions   sync.Map
+	termUploads    sync.Map
 	termMetricOnce sync.Once
 )
 
@@ -65,10 +123,76 @@ func initMetricsForTerms() {
 	prometheus.MustRegister(termMemPercentGaugeVec)
 	prometheus.MustRegister(termDiskPercentGaugeVec)
 	prometheus.MustRegister(termLoadAverage1GaugeVec)
+	prometheus.MustRegister(termQueueDepthGaugeVec)
+	prometheus.MustRegister(termBacklogBytesGaugeVec)
+	prometheus.MustRegister(termOldestPendingGaugeVec)
+	prometheus.MustRegister(termUploadsCountVec)
+	prometheus.MustRegister(termNetGaugeVec)
+	prometheus.MustRegister(termCpuTemperatureGaugeVec)
+	prometheus.MustRegister(termUptimeGaugeVec)
+	prometheus.MustRegister(termVersionGaugeVec)
+}
+
+func observeSysUsage(req *pb.SysUsage) {
+	termCpuPercentGaugeVec.WithLabelValues(req.Mac).Set(float64(req.CpuUsedPercent))
+	termMemPercentGaugeVec.WithLabelValues(req.Mac).Set(float64(req.MemUsedPercent))
+	termDiskPercentGaugeVec.WithLabelValues(req.Mac).Set(float64(req.DiskUsedPercent))
+	termLoadAverage1GaugeVec.WithLabelValues(req.Mac).Set(req.LoadAverage1)
+	termQueueDepthGaugeVec.WithLabelValues(req.Mac).Set(float64(req.QueueDepth))
+	termBacklogBytesGaugeVec.WithLabelValues(req.Mac).Set(float64(req.BacklogBytes))
+	termOldestPendingGaugeVec.WithLabelValues(req.Mac).Set(float64(req.OldestPendingAge))
+	observeUploads(req)
+	for _, c := range req.Nets {
+		termNetGaugeVec.WithLabelValues(req.Mac, c.Name, "bytes_sent").Set(float64(c.BytesSent))
+		termNetGaugeVec.WithLabelValues(req.Mac, c.Name, "bytes_recv").Set(float64(c.BytesRecv))
+		termNetGaugeVec.WithLabelValues(req.Mac, c.Name, "packets_sent").Set(float64(c.PacketsSent))
+		termNetGaugeVec.WithLabelValues(req.Mac, c.Name, "packets_recv").Set(float64(c.PacketsRecv))
+		termNetGaugeVec.WithLabelValues(req.Mac, c.Name, "errin").Set(float64(c.Errin))
+		termNetGaugeVec.WithLabelValues(req.Mac, c.Name, "errout").Set(float64(c.Errout))
+	}
+	termCpuTemperatureGaugeVec.WithLabelValues(req.Mac).Set(req.CpuTemperature)
+	termUptimeGaugeVec.WithLabelValues(req.Mac).Set(float64(req.Uptime))
+
+	// old terminals don't report version
+	if req.Version != "" {
+		if old, loaded := termVersions.Load(req.Mac); loaded && old.(string) != req.Version {
+			termVersionGaugeVec.DeleteLabelValues(req.Mac, old.(string))
+		}
+		termVersions.Store(req.Mac, req.Version)
+		termVersionGaugeVec.WithLabelValues(req.Mac, req.Version).Set(1)
+	}
+}
+
+// uploadTotals is the upload totals reported by a terminal last time
+type uploadTotals struct {
+	succ, failed uint64
+}
+
+// observeUploads advances the upload counters by the increase of the totals since the
+// last report. A report of the same totals adds nothing, a total less than the last
+// one means the terminal restarted, it's the increase itself.
+func observeUploads(req *pb.SysUsage) {
+	var last uploadTotals
+	if v, ok := termUploads.Load(req.Mac); ok {
+		last = v.(uploadTotals)
+	}
+	termUploads.Store(req.Mac, uploadTotals{succ: req.UploadSuccTotal, failed: req.UploadFailedTotal})
+	termUploadsCountVec.WithLabelValues(req.Mac, "succ").Add(float64(increase(last.succ, req.UploadSuccTotal)))
+	termUploadsCountVec.WithLabelValues(req.Mac, "failed").Add(float64(increase(last.failed, req.UploadFailedTotal)))
+}
+
+func increase(last, total uint64) uint64 {
+	if total < last {
+		return total
+	}
+	return total - last
 }
 
 type session struct {
+	sync.Mutex
+
 	addr string
+	mac  string
 	id   int64
 	fid  int32
 	conn goetty.IOSession
@@ -89,8 +213,18 @@ func (s *session) close() {
 	}
 }
 
+func (s *session) bind(mac string) {
+	if mac == "" || mac == s.mac {
+		return
+	}
+
+	s.mac = mac
+	termMgr.bind(mac, s)
+}
+
 func (s *session) onReq(msg interface{}) error {
 	if req, ok := msg.(*pb.InitUploadReq); ok {
+		s.bind(req.Mac)
 		termFilesizeHistogramVec.WithLabelValues(req.Mac).Observe(float64(req.ContentLength))
 		s.initUpload(req)
 	} else if req, ok := msg.(*pb.UploadReq); ok {
@@ -100,13 +234,16 @@ func (s *session) onReq(msg interface{}) error {
 	} else if req, ok := msg.(*pb.UploadCompleteReq); ok {
 		s.uploadComplete(req)
 	} else if req, ok := msg.(*pb.Heartbeat); ok {
+		s.bind(req.Mac)
 		termHeartbeatCountVec.WithLabelValues(req.Mac).Inc()
 		s.doRsp(msg)
 	} else if req, ok := msg.(*pb.SysUsage); ok {
-		termCpuPercentGaugeVec.WithLabelValues(req.Mac).Set(float64(req.CpuUsedPercent))
-		termMemPercentGaugeVec.WithLabelValues(req.Mac).Set(float64(req.MemUsedPercent))
-		termDiskPercentGaugeVec.WithLabelValues(req.Mac).Set(float64(req.DiskUsedPercent))
-		termLoadAverage1GaugeVec.WithLabelValues(req.Mac).Set(req.LoadAverage1)
+		s.bind(req.Mac)
+		observeSysUsage(req)
+	} else if req, ok := msg.(*pb.CommandAck); ok {
+		termMgr.onAck(req)
+	} else if req, ok := msg.(*pb.CommandRsp); ok {
+		termMgr.onRsp(req)
 	}
 	return nil
 }
@@ -159,5 +296,8 @@ func (s *session) doRsp(rsp interface{}) {
 		rsp,
 		rsp)
 
+	// commands are sent out of the read loop, so writes need to be serialized
+	s.Lock()
 	s.conn.WriteAndFlush(rsp)
+	s.Unlock()
 }
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/infinivision/filesyncer/pkg/pb"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveSysUsageUploads(t *testing.T) {
+	termMetricOnce.Do(initMetricsForTerms)
+	usage := &pb.SysUsage{Mac: "309c233431b2", UploadSuccTotal: 5, UploadFailedTotal: 1}
+	// the same totals reported twice are not added up
+	observeSysUsage(usage)
+	observeSysUsage(usage)
+	require.Equal(t, float64(5), testutil.ToFloat64(termUploadsCountVec.WithLabelValues(usage.Mac, "succ")))
+	require.Equal(t, float64(1), testutil.ToFloat64(termUploadsCountVec.WithLabelValues(usage.Mac, "failed")))
+
+	usage.UploadSuccTotal = 7
+	observeSysUsage(usage)
+	require.Equal(t, float64(7), testutil.ToFloat64(termUploadsCountVec.WithLabelValues(usage.Mac, "succ")))
+
+	// the terminal restarted, its totals start over
+	usage.UploadSuccTotal, usage.UploadFailedTotal = 2, 0
+	observeSysUsage(usage)
+	require.Equal(t, float64(9), testutil.ToFloat64(termUploadsCountVec.WithLabelValues(usage.Mac, "succ")))
+	require.Equal(t, float64(1), testutil.ToFloat64(termUploadsCountVec.WithLabelValues(usage.Mac, "failed")))
+}
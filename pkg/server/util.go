@@ -1,70 +1,36 @@
 package server
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	io "io"
-	"io/ioutil"
-	"mime/multipart"
-	"net/http"
-	"net/textproto"
-	"sort"
+	"strconv"
 	"time"
 
-	"github.com/fagongzi/log"
 	"github.com/go-redis/redis"
 	"github.com/pkg/errors"
 )
 
-func PostFiles(hc *http.Client, servURL string, filenames []string, imgs [][]byte, respObj interface{}) (duration time.Duration, err error) {
-	var resp *http.Response
-	var part io.Writer
-	t0 := time.Now()
-	reqBody := &bytes.Buffer{}
-	writer := multipart.NewWriter(reqBody)
-	for i, img := range imgs {
-		//part, err := writer.CreateFormFile("data", "image.jpg") //generates "Content-Type: application/octet-stream"
-		partHeader := textproto.MIMEHeader{}
-		disposition := fmt.Sprintf("form-data; name=\"data\"; filename=\"%s\"", filenames[i])
-		log.Debugf("disposition: %s", disposition)
-		partHeader.Add("Content-Disposition", disposition)
-		partHeader.Add("Content-Type", "image/jpeg")
-		if part, err = writer.CreatePart(partHeader); err != nil {
-			err = errors.Wrap(err, "")
-			return
-		}
-		if _, err = part.Write(img); err != nil {
-			err = errors.Wrap(err, "")
-			return
-		}
-	}
-	writer.Close()
-	req, err := http.NewRequest("POST", servURL, reqBody)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+const (
+	// VisitQueueKey is the Redis list of the visits in the order they're identified
+	VisitQueueKey = "visit_queue"
+	// VisitIndexKey is the Redis sorted set of the visits scored by VisitTime
+	VisitIndexKey = "visit_index"
+)
 
-	if resp, err = hc.Do(req); err != nil {
-		err = errors.Wrap(err, "")
-		return
-	}
-	duration = time.Since(t0)
-	var respBody []byte
-	respBody, err = ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
-	if err != nil {
-		err = errors.Wrap(err, "")
-		return
-	}
-	if err = json.Unmarshal(respBody, respObj); err != nil {
-		err = errors.Wrapf(err, "failed to decode respBody: %+v", string(respBody))
-		return
+// AppendVisit appends the encoded visit to the list que and adds it to the sorted set index in a transaction
+func AppendVisit(rcli *redis.Client, que, index string, visit *Visit, data []byte) (err error) {
+	if _, err = rcli.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.RPush(que, data)
+		pipe.ZAdd(index, redis.Z{Score: float64(visit.VisitTime), Member: data})
+		return nil
+	}); err != nil {
+		err = errors.Wrapf(err, "append visit to %s and %s", que, index)
 	}
 	return
 }
 
-func GetVisitIdxRange(rcli *redis.Client, que, dateStart, dateEnd string) (idxStart, idxEnd int64, err error) {
-	var tsStart int64
-	tsEnd := time.Now().Unix()
+// ParseTimeRange parses the RFC3339 dates to the unix time range [tsStart, tsEnd).
+// An empty dateStart means the beginning, an empty dateEnd means now.
+func ParseTimeRange(dateStart, dateEnd string) (tsStart, tsEnd int64, err error) {
+	tsEnd = time.Now().Unix()
 	if dateStart != "" {
 		var tmpT time.Time
 		if tmpT, err = time.Parse(time.RFC3339, dateStart); err != nil {
@@ -83,47 +49,107 @@ func GetVisitIdxRange(rcli *redis.Client, que, dateStart, dateEnd string) (idxSt
 	}
 	if tsStart >= tsEnd {
 		err = errors.Errorf("invalid time range: %s - %s", dateStart, dateEnd)
-		return
 	}
-	var qLen int64
-	if qLen, err = rcli.LLen(que).Result(); err != nil {
-		err = errors.Wrapf(err, "")
-		return
-	}
-	idxEnd = qLen
+	return
+}
 
-	// determine index range with binary search
-	if dateStart != "" {
-		idxTmp := sort.Search(int(qLen), func(i int) bool {
-			var recs []string
-			if recs, err = rcli.LRange(que, int64(i), int64(i)).Result(); err != nil {
-				err = errors.Wrapf(err, "")
-				log.Fatal(err)
-			}
-			var visit Visit
-			if err = visit.Unmarshal([]byte(recs[0])); err != nil {
-				err = errors.Wrapf(err, "")
-				log.Fatal(err)
+// VisitCursor is a position in the sorted set of visits: Skip visits at Time are returned already
+type VisitCursor struct {
+	Time int64 `json:"time"`
+	Skip int64 `json:"skip"`
+}
+
+// RangeVisits calls fn with the visits of the sorted set index with VisitTime in [tsStart, tsEnd)
+// in time order, batch visits per round trip. It stops at the first error of fn.
+func RangeVisits(rcli *redis.Client, index string, tsStart, tsEnd int64, batch int64, fn func(visits []*Visit) error) (err error) {
+	return RangeVisitsFrom(rcli, index, VisitCursor{Time: tsStart}, tsEnd, batch, func(visits []*Visit, next VisitCursor) error {
+		return fn(visits)
+	})
+}
+
+// RangeVisitsFrom is RangeVisits from the cursor, fn is called with the cursor after the visits as well.
+// The pages continue from the last visit time, so visits added meanwhile don't shift them.
+func RangeVisitsFrom(rcli *redis.Client, index string, cur VisitCursor, tsEnd int64, batch int64, fn func(visits []*Visit, next VisitCursor) error) (err error) {
+	return rangeVisits(rcli, index, cur, tsEnd, batch, func(visits []*Visit, raws [][]byte, next VisitCursor) error {
+		return fn(visits, next)
+	})
+}
+
+// RangeRawVisits is RangeVisits, fn is called with the encoded visits as well
+func RangeRawVisits(rcli *redis.Client, index string, tsStart, tsEnd int64, batch int64, fn func(visits []*Visit, raws [][]byte) error) (err error) {
+	return rangeVisits(rcli, index, VisitCursor{Time: tsStart}, tsEnd, batch, func(visits []*Visit, raws [][]byte, next VisitCursor) error {
+		return fn(visits, raws)
+	})
+}
+
+func rangeVisits(rcli *redis.Client, index string, cur VisitCursor, tsEnd int64, batch int64, fn func(visits []*Visit, raws [][]byte, next VisitCursor) error) (err error) {
+	for {
+		var recs []redis.Z
+		if recs, err = rcli.ZRangeByScoreWithScores(index, redis.ZRangeBy{
+			Min:    strconv.FormatInt(cur.Time, 10),
+			Max:    "(" + strconv.FormatInt(tsEnd, 10),
+			Offset: cur.Skip,
+			Count:  batch,
+		}).Result(); err != nil {
+			err = errors.Wrapf(err, "range %s", index)
+			return
+		}
+		visits := make([]*Visit, 0, len(recs))
+		raws := make([][]byte, 0, len(recs))
+		for _, rec := range recs {
+			var visit *Visit
+			raw := []byte(rec.Member.(string))
+			if visit, err = DecodeVisit(raw); err != nil {
+				err = errors.Wrapf(err, "range %s", index)
+				return
 			}
-			return int64(visit.VisitTime) >= tsStart
-		})
-		idxStart = int64(idxTmp)
-	}
-	if dateEnd != "" {
-		idxTmp := sort.Search(int(qLen), func(i int) bool {
-			var recs []string
-			if recs, err = rcli.LRange(que, int64(i), int64(i)).Result(); err != nil {
-				err = errors.Wrapf(err, "")
-				log.Fatal(err)
+			visits = append(visits, visit)
+			raws = append(raws, raw)
+			if ts := int64(rec.Score); ts != cur.Time {
+				cur = VisitCursor{Time: ts}
 			}
-			var visit Visit
-			if err = visit.Unmarshal([]byte(recs[0])); err != nil {
-				err = errors.Wrapf(err, "")
-				log.Fatal(err)
+			cur.Skip++
+		}
+		if len(visits) != 0 {
+			if err = fn(visits, raws, cur); err != nil {
+				return
 			}
-			return int64(visit.VisitTime) >= tsEnd
-		})
-		idxEnd = int64(idxTmp)
+		}
+		if int64(len(recs)) < batch {
+			return
+		}
+	}
+}
+
+// IndexVisits adds the visits of the list que to the sorted set index, batch visits per round trip.
+// It's idempotent, a visit already in the index is not added again.
+func IndexVisits(rcli *redis.Client, que, index string, batch int64) (indexed int64, err error) {
+	var zs []redis.Z
+	flush := func() (err error) {
+		if len(zs) == 0 {
+			return
+		}
+		var added int64
+		if added, err = rcli.ZAdd(index, zs...).Result(); err != nil {
+			err = errors.Wrapf(err, "index %s", index)
+			return
+		}
+		indexed += added
+		zs = zs[:0]
+		return
+	}
+	var flushErr error
+	if err = ScanVisits(rcli, que, batch, func(visit *Visit, raw string) {
+		zs = append(zs, redis.Z{Score: float64(visit.VisitTime), Member: raw})
+		if int64(len(zs)) >= batch && flushErr == nil {
+			flushErr = flush()
+		}
+	}); err != nil {
+		return
+	}
+	if err = flushErr; err != nil {
+		return
 	}
+	err = flush()
 	return
 }
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	tsStart, tsEnd, err := ParseTimeRange("2019-03-01T00:00:00+08:00", "2019-03-02T00:00:00+08:00")
+	require.NoError(t, err)
+	require.Equal(t, int64(1551369600), tsStart)
+	require.Equal(t, int64(1551369600+86400), tsEnd)
+
+	tsStart, tsEnd, err = ParseTimeRange("", "")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), tsStart)
+	require.True(t, tsEnd > 0)
+
+	_, _, err = ParseTimeRange("2019-03-02T00:00:00+08:00", "2019-03-01T00:00:00+08:00")
+	require.Error(t, err)
+	_, _, err = ParseTimeRange("yesterday", "")
+	require.Error(t, err)
+}
+
+func TestRedisVisitIndex(t *testing.T) {
+	if RedisAddr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	rcli := redis.NewClient(&redis.Options{Addr: RedisAddr, DB: 15})
+	defer rcli.Close()
+	require.NoError(t, rcli.FlushDB().Err())
+
+	// written out of time order, several of them at the same time
+	times := []uint64{100, 300, 200, 200, 200, 100, 400}
+	for i, ts := range times {
+		visit := &Visit{PictureId: string(rune('a' + i)), VisitTime: ts}
+		data, err := visit.Marshal()
+		require.NoError(t, err)
+		if i < 4 {
+			// written before the index
+			require.NoError(t, rcli.RPush(VisitQueueKey, data).Err())
+		} else {
+			require.NoError(t, AppendVisit(rcli, VisitQueueKey, VisitIndexKey, visit, data))
+		}
+	}
+	indexed, err := IndexVisits(rcli, VisitQueueKey, VisitIndexKey, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), indexed)
+	indexed, err = IndexVisits(rcli, VisitQueueKey, VisitIndexKey, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), indexed)
+
+	var got []uint64
+	require.NoError(t, RangeVisits(rcli, VisitIndexKey, 100, 400, 2, func(visits []*Visit) error {
+		require.True(t, len(visits) <= 2)
+		for _, visit := range visits {
+			got = append(got, visit.VisitTime)
+		}
+		return nil
+	}))
+	require.Equal(t, []uint64{100, 100, 200, 200, 200, 300}, got)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// VisitVersion is the version of the Visit records written now.
+	// Version 0 records carry only PictureId, Quality, VisitTime, Shop, Position, Uid, Age and Gender.
+	// Version 1 records don't carry IsReturning and PrevVisitTime.
+	VisitVersion uint32 = 2
+)
+
+// DecodeVisit decodes a Visit record of any version. The fields missing in an old record
+// are filled with what they would have been: a sighting seen once at its position.
+func DecodeVisit(data []byte) (visit *Visit, err error) {
+	visit = &Visit{}
+	if err = visit.Unmarshal(data); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	if visit.Version == 0 {
+		upgradeVisit(visit)
+	}
+	return
+}
+
+func upgradeVisit(visit *Visit) {
+	if visit.LastSeen == 0 {
+		visit.LastSeen = visit.VisitTime
+	}
+	if visit.Sightings == 0 {
+		visit.Sightings = 1
+	}
+	if len(visit.Positions) == 0 {
+		visit.Positions = []uint32{visit.Position}
+	}
+}
+
+// Camera returns the camera the visit is sighted by
+func (visit *Visit) Camera() CameraInfo {
+	return CameraInfo{
+		Position:  visit.Position,
+		Direction: visit.Direction,
+		Zone:      visit.Zone,
+		Entrance:  visit.Entrance,
+		Exit:      visit.Exit,
+		Enabled:   true,
+	}
+}
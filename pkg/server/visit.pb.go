@@ -24,15 +24,88 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// Decision is how the identifier dealt with the face
+type Decision int32
+
+const (
+	// DecisionUnknown the record is written before version 1
+	DecisionUnknown Decision = 0
+	// DecisionNew no match, a new uid is allocated
+	DecisionNew Decision = 1
+	// DecisionMerged a near match, the vector is added to the uid
+	DecisionMerged Decision = 2
+	// DecisionIgnored a match, the index is not changed
+	DecisionIgnored Decision = 3
+	// DecisionUpdated a close match, the vector replaces the matched one
+	DecisionUpdated Decision = 4
+	// DecisionTakenOver the matched vector of a forgotten uid is taken over by a new uid
+	DecisionTakenOver Decision = 5
+	// DecisionLowConfidence the face is rejected by the quality gate
+	DecisionLowConfidence Decision = 6
+)
+
+var Decision_name = map[int32]string{
+	0: "DecisionUnknown",
+	1: "DecisionNew",
+	2: "DecisionMerged",
+	3: "DecisionIgnored",
+	4: "DecisionUpdated",
+	5: "DecisionTakenOver",
+	6: "DecisionLowConfidence",
+}
+
+var Decision_value = map[string]int32{
+	"DecisionUnknown":       0,
+	"DecisionNew":           1,
+	"DecisionMerged":        2,
+	"DecisionIgnored":       3,
+	"DecisionUpdated":       4,
+	"DecisionTakenOver":     5,
+	"DecisionLowConfidence": 6,
+}
+
+func (x Decision) String() string {
+	return proto.EnumName(Decision_name, int32(x))
+}
+
+func (Decision) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_a498f0e5194d943b, []int{0}
+}
+
+// Visit is a sighting, or a session of sightings, of a uid at a shop.
+// Version 0 records carry only the fields 1 - 8, version 1 ones the fields 1 - 25,
+// version 2 ones the fields 1 - 27 with IsReturning and PrevVisitTime, readers use DecodeVisit.
 type Visit struct {
-	PictureId            string   `protobuf:"bytes,1,opt,name=PictureId,proto3" json:"PictureId,omitempty"`
-	Quality              float32  `protobuf:"fixed32,2,opt,name=Quality,proto3" json:"Quality,omitempty"`
-	VisitTime            uint64   `protobuf:"varint,3,opt,name=VisitTime,proto3" json:"VisitTime,omitempty"`
-	Shop                 uint64   `protobuf:"varint,4,opt,name=Shop,proto3" json:"Shop,omitempty"`
-	Position             uint32   `protobuf:"varint,5,opt,name=Position,proto3" json:"Position,omitempty"`
-	Uid                  uint64   `protobuf:"varint,6,opt,name=Uid,proto3" json:"Uid,omitempty"`
-	Age                  uint32   `protobuf:"varint,7,opt,name=Age,proto3" json:"Age,omitempty"`
-	Gender               uint32   `protobuf:"varint,8,opt,name=Gender,proto3" json:"Gender,omitempty"`
+	PictureId     string   `protobuf:"bytes,1,opt,name=PictureId,proto3" json:"PictureId,omitempty"`
+	Quality       float32  `protobuf:"fixed32,2,opt,name=Quality,proto3" json:"Quality,omitempty"`
+	VisitTime     uint64   `protobuf:"varint,3,opt,name=VisitTime,proto3" json:"VisitTime,omitempty"`
+	Shop          uint64   `protobuf:"varint,4,opt,name=Shop,proto3" json:"Shop,omitempty"`
+	Position      uint32   `protobuf:"varint,5,opt,name=Position,proto3" json:"Position,omitempty"`
+	Uid           uint64   `protobuf:"varint,6,opt,name=Uid,proto3" json:"Uid,omitempty"`
+	Age           uint32   `protobuf:"varint,7,opt,name=Age,proto3" json:"Age,omitempty"`
+	Gender        uint32   `protobuf:"varint,8,opt,name=Gender,proto3" json:"Gender,omitempty"`
+	Zone          string   `protobuf:"bytes,9,opt,name=Zone,proto3" json:"Zone,omitempty"`
+	Entrance      bool     `protobuf:"varint,10,opt,name=Entrance,proto3" json:"Entrance,omitempty"`
+	Exit          bool     `protobuf:"varint,11,opt,name=Exit,proto3" json:"Exit,omitempty"`
+	Xid           int64    `protobuf:"varint,12,opt,name=Xid,proto3" json:"Xid,omitempty"`
+	LowConfidence bool     `protobuf:"varint,13,opt,name=LowConfidence,proto3" json:"LowConfidence,omitempty"`
+	LastSeen      uint64   `protobuf:"varint,14,opt,name=LastSeen,proto3" json:"LastSeen,omitempty"`
+	Positions     []uint32 `protobuf:"varint,15,rep,packed,name=Positions,proto3" json:"Positions,omitempty"`
+	Sightings     uint32   `protobuf:"varint,16,opt,name=Sightings,proto3" json:"Sightings,omitempty"`
+	Version       uint32   `protobuf:"varint,17,opt,name=Version,proto3" json:"Version,omitempty"`
+	// Distance is the inner product with MatchedXid, the best match of the vector index
+	Distance   float32  `protobuf:"fixed32,18,opt,name=Distance,proto3" json:"Distance,omitempty"`
+	MatchedXid int64    `protobuf:"varint,19,opt,name=MatchedXid,proto3" json:"MatchedXid,omitempty"`
+	Decision   Decision `protobuf:"varint,20,opt,name=Decision,proto3,enum=server.Decision" json:"Decision,omitempty"`
+	Mac        string   `protobuf:"bytes,21,opt,name=Mac,proto3" json:"Mac,omitempty"`
+	CameraIp   string   `protobuf:"bytes,22,opt,name=CameraIp,proto3" json:"CameraIp,omitempty"`
+	Direction  string   `protobuf:"bytes,23,opt,name=Direction,proto3" json:"Direction,omitempty"`
+	// DistThr2 and DistThr3 are the thresholds of the identifier
+	DistThr2 float32 `protobuf:"fixed32,24,opt,name=DistThr2,proto3" json:"DistThr2,omitempty"`
+	DistThr3 float32 `protobuf:"fixed32,25,opt,name=DistThr3,proto3" json:"DistThr3,omitempty"`
+	// IsReturning the uid has visited before, PrevVisitTime is the last seen of its previous visit
+	IsReturning          bool     `protobuf:"varint,26,opt,name=IsReturning,proto3" json:"IsReturning,omitempty"`
+	PrevVisitTime        uint64   `protobuf:"varint,27,opt,name=PrevVisitTime,proto3" json:"PrevVisitTime,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -72,29 +145,51 @@ func (m *Visit) XXX_DiscardUnknown() {
 var xxx_messageInfo_Visit proto.InternalMessageInfo
 
 func init() {
-	proto.RegisterType((*Visit)(nil), "main.Visit")
+	proto.RegisterEnum("server.Decision", Decision_name, Decision_value)
+	proto.RegisterType((*Visit)(nil), "server.Visit")
 }
 
 func init() { proto.RegisterFile("visit.proto", fileDescriptor_a498f0e5194d943b) }
 
 var fileDescriptor_a498f0e5194d943b = []byte{
-	// 254 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x44, 0x90, 0x4f, 0x4b, 0xc3, 0x40,
-	0x10, 0xc5, 0xbb, 0x6d, 0x9a, 0xb6, 0x53, 0x04, 0x59, 0x44, 0x86, 0x22, 0x21, 0x78, 0xca, 0x29,
-	0x1e, 0x3c, 0x7a, 0xb2, 0x17, 0xf1, 0x16, 0xe3, 0x9f, 0x7b, 0x6c, 0xd6, 0x38, 0x90, 0xee, 0x84,
-	0x64, 0x2b, 0xf8, 0x4d, 0xfc, 0x48, 0xf5, 0xe6, 0x47, 0xd0, 0xf8, 0x45, 0x64, 0x47, 0x6d, 0x6f,
-	0xef, 0xfd, 0xde, 0xdb, 0x65, 0x78, 0x30, 0x7f, 0xa1, 0x8e, 0x5c, 0xda, 0xb4, 0xec, 0x58, 0x07,
-	0xeb, 0x82, 0xec, 0xe2, 0xa8, 0xe2, 0x8a, 0x05, 0x9c, 0x79, 0xf5, 0x9b, 0x9d, 0xbe, 0x2b, 0x18,
-	0x3f, 0xf8, 0xae, 0x3e, 0x81, 0x59, 0x46, 0x2b, 0xb7, 0x69, 0xcd, 0x75, 0x89, 0x2a, 0x56, 0xc9,
-	0x2c, 0xdf, 0x03, 0x8d, 0x30, 0xb9, 0xd9, 0x14, 0x35, 0xb9, 0x57, 0x1c, 0xc6, 0x2a, 0x19, 0xe6,
-	0xff, 0xd6, 0xbf, 0x93, 0x0f, 0xee, 0x68, 0x6d, 0x70, 0x14, 0xab, 0x24, 0xc8, 0xf7, 0x40, 0x6b,
-	0x08, 0x6e, 0x9f, 0xb9, 0xc1, 0x40, 0x02, 0xd1, 0x7a, 0x01, 0xd3, 0x8c, 0x3b, 0x72, 0xc4, 0x16,
-	0xc7, 0xb1, 0x4a, 0x0e, 0xf2, 0x9d, 0xd7, 0x87, 0x30, 0xba, 0xa7, 0x12, 0x43, 0xa9, 0x7b, 0xe9,
-	0xc9, 0x65, 0x65, 0x70, 0x22, 0x45, 0x2f, 0xf5, 0x31, 0x84, 0x57, 0xc6, 0x96, 0xa6, 0xc5, 0xa9,
-	0xc0, 0x3f, 0xb7, 0xbc, 0xd8, 0x7e, 0x45, 0x83, 0x6d, 0x1f, 0xa9, 0x8f, 0x3e, 0x52, 0x9f, 0x7d,
-	0xa4, 0xde, 0xbe, 0xa3, 0x01, 0xe0, 0xca, 0xa6, 0x64, 0x9f, 0xc8, 0x92, 0xdf, 0x84, 0x6d, 0xca,
-	0x75, 0xd1, 0xa4, 0xc6, 0xd5, 0xcb, 0xb9, 0x9c, 0x99, 0xf9, 0x1d, 0xba, 0xc7, 0x50, 0xf6, 0x38,
-	0xff, 0x09, 0x00, 0x00, 0xff, 0xff, 0x09, 0x48, 0x68, 0x3b, 0x3a, 0x01, 0x00, 0x00,
+	// 586 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x93, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0xc7, 0xb3, 0xcd, 0x47, 0xd3, 0x0d, 0x69, 0xdd, 0xed, 0x07, 0xdb, 0x80, 0x2c, 0x0b, 0x71,
+	0xb0, 0x10, 0x32, 0x52, 0x7b, 0xe4, 0x44, 0x3f, 0x84, 0x22, 0xb5, 0x10, 0xdc, 0x0f, 0x21, 0x6e,
+	0xc6, 0x9e, 0x3a, 0xab, 0xb6, 0xbb, 0xd1, 0x7a, 0x9b, 0xc2, 0x1b, 0xf0, 0x08, 0xdc, 0x91, 0x78,
+	0x96, 0x1e, 0x79, 0x04, 0x28, 0x67, 0xde, 0x01, 0xcd, 0xb4, 0xb6, 0x93, 0xdb, 0xff, 0xff, 0x9b,
+	0xf1, 0xce, 0xce, 0xcc, 0x9a, 0xf7, 0xa6, 0xaa, 0x50, 0x2e, 0x9a, 0x58, 0xe3, 0x8c, 0xe8, 0x14,
+	0x60, 0xa7, 0x60, 0x07, 0xeb, 0xb9, 0xc9, 0x0d, 0xa1, 0x57, 0xa8, 0xee, 0xa3, 0xcf, 0xfe, 0xb5,
+	0x79, 0xfb, 0x0c, 0xb3, 0xc5, 0x53, 0xbe, 0x34, 0x52, 0xa9, 0xbb, 0xb6, 0x30, 0xcc, 0x24, 0x0b,
+	0x58, 0xb8, 0x14, 0xd7, 0x40, 0x48, 0xbe, 0xf8, 0xe1, 0x3a, 0xb9, 0x54, 0xee, 0xab, 0x5c, 0x08,
+	0x58, 0xb8, 0x10, 0x97, 0x16, 0xbf, 0xa3, 0x03, 0x4e, 0xd4, 0x15, 0xc8, 0x66, 0xc0, 0xc2, 0x56,
+	0x5c, 0x03, 0x21, 0x78, 0xeb, 0x78, 0x6c, 0x26, 0xb2, 0x45, 0x01, 0xd2, 0x62, 0xc0, 0xbb, 0x23,
+	0x53, 0x28, 0xa7, 0x8c, 0x96, 0xed, 0x80, 0x85, 0xfd, 0xb8, 0xf2, 0xc2, 0xe3, 0xcd, 0x53, 0x95,
+	0xc9, 0x0e, 0xa5, 0xa3, 0x44, 0xf2, 0x26, 0x07, 0xb9, 0x48, 0x89, 0x28, 0xc5, 0x26, 0xef, 0xbc,
+	0x05, 0x9d, 0x81, 0x95, 0x5d, 0x82, 0x0f, 0x0e, 0x6b, 0x7d, 0x32, 0x1a, 0xe4, 0x12, 0x5d, 0x9e,
+	0x34, 0xd6, 0x3a, 0xd0, 0xce, 0x26, 0x3a, 0x05, 0xc9, 0x03, 0x16, 0x76, 0xe3, 0xca, 0x63, 0xfe,
+	0xc1, 0x17, 0xe5, 0x64, 0x8f, 0x38, 0x69, 0xac, 0xf6, 0x51, 0x65, 0xf2, 0x51, 0xc0, 0xc2, 0x66,
+	0x8c, 0x52, 0x3c, 0xe7, 0xfd, 0x43, 0x73, 0xb3, 0x67, 0xf4, 0xb9, 0xca, 0x00, 0x8f, 0xe9, 0x53,
+	0xfa, 0x3c, 0xc4, 0x3a, 0x87, 0x49, 0xe1, 0x8e, 0x01, 0xb4, 0x5c, 0xa6, 0xcb, 0x57, 0x9e, 0x26,
+	0xfb, 0xd0, 0x5f, 0x21, 0x57, 0x82, 0x66, 0xd8, 0x8f, 0x6b, 0x80, 0xd1, 0x63, 0x95, 0x8f, 0x9d,
+	0xd2, 0x79, 0x21, 0x3d, 0x6a, 0xa8, 0x06, 0x38, 0xf7, 0x33, 0xb0, 0x05, 0x8e, 0x6a, 0x95, 0x62,
+	0xa5, 0xc5, 0x8a, 0xfb, 0xaa, 0x70, 0xd4, 0x99, 0xa0, 0x95, 0x54, 0x5e, 0xf8, 0x9c, 0x1f, 0x25,
+	0x2e, 0x1d, 0x43, 0x86, 0xcd, 0xac, 0x51, 0x33, 0x33, 0x44, 0xbc, 0xe4, 0xdd, 0x7d, 0x48, 0x15,
+	0x1d, 0xbb, 0x1e, 0xb0, 0x70, 0x79, 0xdb, 0x8b, 0xee, 0x9f, 0x49, 0x54, 0xf2, 0xb8, 0xca, 0xc0,
+	0x99, 0x1c, 0x25, 0xa9, 0xdc, 0xa0, 0xb1, 0xa2, 0xc4, 0xda, 0x7b, 0xc9, 0x15, 0xd8, 0x64, 0x38,
+	0x91, 0x9b, 0x84, 0x2b, 0x8f, 0xfd, 0xec, 0x2b, 0x0b, 0x29, 0xad, 0xf7, 0xf1, 0xfd, 0x3b, 0xaa,
+	0x40, 0x79, 0xeb, 0x93, 0xb1, 0xdd, 0x96, 0xb2, 0xbe, 0x35, 0xfa, 0x99, 0xd8, 0x8e, 0xdc, 0x9a,
+	0x8b, 0xed, 0x88, 0x80, 0xf7, 0x86, 0x45, 0x0c, 0xee, 0xda, 0x6a, 0xa5, 0x73, 0x39, 0xa0, 0x1d,
+	0xcc, 0x22, 0xdc, 0xd3, 0xc8, 0xc2, 0xb4, 0x7e, 0x8b, 0x4f, 0x68, 0x0d, 0xf3, 0xf0, 0xc5, 0x4f,
+	0x56, 0xb7, 0x2e, 0xd6, 0xf8, 0x4a, 0xa9, 0x4f, 0xf5, 0x85, 0x36, 0x37, 0xda, 0x6b, 0x88, 0x15,
+	0xde, 0x2b, 0xe1, 0x3b, 0xb8, 0xf1, 0x98, 0x10, 0x7c, 0xb9, 0x04, 0x47, 0x60, 0x73, 0xc8, 0xbc,
+	0x85, 0xd9, 0x2f, 0x87, 0xb9, 0x36, 0x16, 0x32, 0xaf, 0x39, 0x77, 0xdc, 0x24, 0x4b, 0x1c, 0x64,
+	0x5e, 0x4b, 0x6c, 0xf0, 0xd5, 0x12, 0x9e, 0x24, 0x17, 0xa0, 0xdf, 0x4f, 0xc1, 0x7a, 0x6d, 0xb1,
+	0xc5, 0x37, 0x4a, 0x3c, 0xf7, 0x90, 0xbc, 0xce, 0xa0, 0xf5, 0xed, 0x87, 0xdf, 0xd8, 0x7d, 0x7d,
+	0xfb, 0xc7, 0x6f, 0xdc, 0xde, 0xf9, 0xec, 0xd7, 0x9d, 0xcf, 0x7e, 0xdf, 0xf9, 0xec, 0xfb, 0x5f,
+	0xbf, 0xc1, 0x65, 0xaa, 0x23, 0xa5, 0xcf, 0x95, 0x56, 0x53, 0xfa, 0x36, 0x32, 0x97, 0xc9, 0x24,
+	0x02, 0x77, 0xb9, 0xdb, 0xa3, 0x1e, 0x47, 0xf8, 0x53, 0x17, 0x9f, 0x3b, 0xf4, 0x73, 0xef, 0xfc,
+	0x1f, 0x00, 0x3a, 0xb3, 0xed, 0x50, 0x09, 0x04, 0x00, 0x00,
 }
 
 func (m *Visit) Marshal() (dAtA []byte, err error) {
@@ -154,6 +249,164 @@ func (m *Visit) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintVisit(dAtA, i, uint64(m.Gender))
 	}
+	if len(m.Zone) > 0 {
+		dAtA[i] = 0x4a
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(len(m.Zone)))
+		i += copy(dAtA[i:], m.Zone)
+	}
+	if m.Entrance {
+		dAtA[i] = 0x50
+		i++
+		if m.Entrance {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Exit {
+		dAtA[i] = 0x58
+		i++
+		if m.Exit {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Xid != 0 {
+		dAtA[i] = 0x60
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(m.Xid))
+	}
+	if m.LowConfidence {
+		dAtA[i] = 0x68
+		i++
+		if m.LowConfidence {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.LastSeen != 0 {
+		dAtA[i] = 0x70
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(m.LastSeen))
+	}
+	if len(m.Positions) > 0 {
+		dAtA2 := make([]byte, len(m.Positions)*10)
+		var j1 int
+		for _, num := range m.Positions {
+			for num >= 1<<7 {
+				dAtA2[j1] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j1++
+			}
+			dAtA2[j1] = uint8(num)
+			j1++
+		}
+		dAtA[i] = 0x7a
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(j1))
+		i += copy(dAtA[i:], dAtA2[:j1])
+	}
+	if m.Sightings != 0 {
+		dAtA[i] = 0x80
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(m.Sightings))
+	}
+	if m.Version != 0 {
+		dAtA[i] = 0x88
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(m.Version))
+	}
+	if m.Distance != 0 {
+		dAtA[i] = 0x95
+		i++
+		dAtA[i] = 0x1
+		i++
+		encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(math.Float32bits(float32(m.Distance))))
+		i += 4
+	}
+	if m.MatchedXid != 0 {
+		dAtA[i] = 0x98
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(m.MatchedXid))
+	}
+	if m.Decision != 0 {
+		dAtA[i] = 0xa0
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(m.Decision))
+	}
+	if len(m.Mac) > 0 {
+		dAtA[i] = 0xaa
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(len(m.Mac)))
+		i += copy(dAtA[i:], m.Mac)
+	}
+	if len(m.CameraIp) > 0 {
+		dAtA[i] = 0xb2
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(len(m.CameraIp)))
+		i += copy(dAtA[i:], m.CameraIp)
+	}
+	if len(m.Direction) > 0 {
+		dAtA[i] = 0xba
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(len(m.Direction)))
+		i += copy(dAtA[i:], m.Direction)
+	}
+	if m.DistThr2 != 0 {
+		dAtA[i] = 0xc5
+		i++
+		dAtA[i] = 0x1
+		i++
+		encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(math.Float32bits(float32(m.DistThr2))))
+		i += 4
+	}
+	if m.DistThr3 != 0 {
+		dAtA[i] = 0xcd
+		i++
+		dAtA[i] = 0x1
+		i++
+		encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(math.Float32bits(float32(m.DistThr3))))
+		i += 4
+	}
+	if m.IsReturning {
+		dAtA[i] = 0xd0
+		i++
+		dAtA[i] = 0x1
+		i++
+		if m.IsReturning {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.PrevVisitTime != 0 {
+		dAtA[i] = 0xd8
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintVisit(dAtA, i, uint64(m.PrevVisitTime))
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
@@ -200,6 +453,71 @@ func (m *Visit) Size() (n int) {
 	if m.Gender != 0 {
 		n += 1 + sovVisit(uint64(m.Gender))
 	}
+	l = len(m.Zone)
+	if l > 0 {
+		n += 1 + l + sovVisit(uint64(l))
+	}
+	if m.Entrance {
+		n += 2
+	}
+	if m.Exit {
+		n += 2
+	}
+	if m.Xid != 0 {
+		n += 1 + sovVisit(uint64(m.Xid))
+	}
+	if m.LowConfidence {
+		n += 2
+	}
+	if m.LastSeen != 0 {
+		n += 1 + sovVisit(uint64(m.LastSeen))
+	}
+	if len(m.Positions) > 0 {
+		l = 0
+		for _, e := range m.Positions {
+			l += sovVisit(uint64(e))
+		}
+		n += 1 + sovVisit(uint64(l)) + l
+	}
+	if m.Sightings != 0 {
+		n += 2 + sovVisit(uint64(m.Sightings))
+	}
+	if m.Version != 0 {
+		n += 2 + sovVisit(uint64(m.Version))
+	}
+	if m.Distance != 0 {
+		n += 6
+	}
+	if m.MatchedXid != 0 {
+		n += 2 + sovVisit(uint64(m.MatchedXid))
+	}
+	if m.Decision != 0 {
+		n += 2 + sovVisit(uint64(m.Decision))
+	}
+	l = len(m.Mac)
+	if l > 0 {
+		n += 2 + l + sovVisit(uint64(l))
+	}
+	l = len(m.CameraIp)
+	if l > 0 {
+		n += 2 + l + sovVisit(uint64(l))
+	}
+	l = len(m.Direction)
+	if l > 0 {
+		n += 2 + l + sovVisit(uint64(l))
+	}
+	if m.DistThr2 != 0 {
+		n += 6
+	}
+	if m.DistThr3 != 0 {
+		n += 6
+	}
+	if m.IsReturning {
+		n += 3
+	}
+	if m.PrevVisitTime != 0 {
+		n += 2 + sovVisit(uint64(m.PrevVisitTime))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -234,7 +552,7 @@ func (m *Visit) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -262,7 +580,7 @@ func (m *Visit) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -272,6 +590,9 @@ func (m *Visit) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthVisit
 			}
 			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthVisit
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -302,7 +623,7 @@ func (m *Visit) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.VisitTime |= (uint64(b) & 0x7F) << shift
+				m.VisitTime |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -321,7 +642,7 @@ func (m *Visit) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Shop |= (uint64(b) & 0x7F) << shift
+				m.Shop |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -340,7 +661,7 @@ func (m *Visit) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Position |= (uint32(b) & 0x7F) << shift
+				m.Position |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -359,7 +680,7 @@ func (m *Visit) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Uid |= (uint64(b) & 0x7F) << shift
+				m.Uid |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -378,7 +699,7 @@ func (m *Visit) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Age |= (uint32(b) & 0x7F) << shift
+				m.Age |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -397,7 +718,457 @@ func (m *Visit) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Gender |= (uint32(b) & 0x7F) << shift
+				m.Gender |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Zone", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthVisit
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthVisit
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Zone = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Entrance", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Entrance = bool(v != 0)
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Exit", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Exit = bool(v != 0)
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Xid", wireType)
+			}
+			m.Xid = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Xid |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LowConfidence", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.LowConfidence = bool(v != 0)
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastSeen", wireType)
+			}
+			m.LastSeen = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LastSeen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 15:
+			if wireType == 0 {
+				var v uint32
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowVisit
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= uint32(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Positions = append(m.Positions, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowVisit
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthVisit
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthVisit
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.Positions) == 0 {
+					m.Positions = make([]uint32, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint32
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowVisit
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= uint32(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Positions = append(m.Positions, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Positions", wireType)
+			}
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sightings", wireType)
+			}
+			m.Sightings = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Sightings |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 17:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			m.Version = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Version |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 18:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Distance", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint32(encoding_binary.LittleEndian.Uint32(dAtA[iNdEx:]))
+			iNdEx += 4
+			m.Distance = float32(math.Float32frombits(v))
+		case 19:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MatchedXid", wireType)
+			}
+			m.MatchedXid = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MatchedXid |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 20:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Decision", wireType)
+			}
+			m.Decision = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Decision |= Decision(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 21:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthVisit
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthVisit
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Mac = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 22:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CameraIp", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthVisit
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthVisit
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.CameraIp = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 23:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Direction", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthVisit
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthVisit
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Direction = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 24:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DistThr2", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint32(encoding_binary.LittleEndian.Uint32(dAtA[iNdEx:]))
+			iNdEx += 4
+			m.DistThr2 = float32(math.Float32frombits(v))
+		case 25:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DistThr3", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint32(encoding_binary.LittleEndian.Uint32(dAtA[iNdEx:]))
+			iNdEx += 4
+			m.DistThr3 = float32(math.Float32frombits(v))
+		case 26:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IsReturning", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IsReturning = bool(v != 0)
+		case 27:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrevVisitTime", wireType)
+			}
+			m.PrevVisitTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowVisit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PrevVisitTime |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -411,6 +1182,9 @@ func (m *Visit) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthVisit
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthVisit
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -478,10 +1252,13 @@ func skipVisit(dAtA []byte) (n int, err error) {
 					break
 				}
 			}
-			iNdEx += length
 			if length < 0 {
 				return 0, ErrInvalidLengthVisit
 			}
+			iNdEx += length
+			if iNdEx < 0 {
+				return 0, ErrInvalidLengthVisit
+			}
 			return iNdEx, nil
 		case 3:
 			for {
@@ -510,6 +1287,9 @@ func skipVisit(dAtA []byte) (n int, err error) {
 					return 0, err
 				}
 				iNdEx = start + next
+				if iNdEx < 0 {
+					return 0, ErrInvalidLengthVisit
+				}
 			}
 			return iNdEx, nil
 		case 4:
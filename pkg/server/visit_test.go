@@ -0,0 +1,36 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeVisit(t *testing.T) {
+	// a version 0 record
+	old := &Visit{PictureId: "obj1", Quality: 0.9, VisitTime: 1551369600, Shop: 8, Position: 2, Uid: 12, Age: 30, Gender: 1}
+	data, err := old.Marshal()
+	require.NoError(t, err)
+	visit, err := DecodeVisit(data)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), visit.Version)
+	require.Equal(t, DecisionUnknown, visit.Decision)
+	require.Equal(t, "obj1", visit.PictureId)
+	require.Equal(t, uint64(12), visit.Uid)
+	require.Equal(t, uint64(1551369600), visit.LastSeen)
+	require.Equal(t, uint32(1), visit.Sightings)
+	require.Equal(t, []uint32{2}, visit.Positions)
+
+	cur := &Visit{PictureId: "obj2", VisitTime: 1551369600, LastSeen: 1551369660, Shop: 8, Position: 1, Positions: []uint32{1, 2}, Sightings: 3, Uid: 12,
+		Version: VisitVersion, Distance: 0.83, MatchedXid: 7, Xid: 7, Decision: DecisionIgnored, Mac: "309c233431b2", CameraIp: "192.168.150.244",
+		Direction: "north", DistThr2: 0.7, DistThr3: 0.9, IsReturning: true, PrevVisitTime: 1551283200}
+	data, err = cur.Marshal()
+	require.NoError(t, err)
+	visit, err = DecodeVisit(data)
+	require.NoError(t, err)
+	require.Equal(t, cur, visit)
+	require.Equal(t, "north", visit.Camera().Direction)
+
+	_, err = DecodeVisit([]byte{0xff})
+	require.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	// XidDbKey is the Redis hash of the vector index dbs, by the 16 hex digits form of xid
+	XidDbKey = "xid_db"
+)
+
+// XidDbStore keeps the db of each xid seen in search results of a vector index
+// that can't remove vectors, so that the vector of a forgotten xid can be
+// overwritten in its db.
+type XidDbStore interface {
+	SetDb(xid int64, db uint64) error
+	// GetDbs returns the known dbs of xids, the unknown ones are absent
+	GetDbs(xids []int64) (dbs map[int64]uint64, err error)
+	Remove(xids []int64) error
+}
+
+type redisXidDbStore struct {
+	rcli *redis.Client
+}
+
+// NewRedisXidDbStore returns a XidDbStore of the Redis hash xid_db
+func NewRedisXidDbStore(rcli *redis.Client) XidDbStore {
+	return &redisXidDbStore{rcli: rcli}
+}
+
+func (s *redisXidDbStore) SetDb(xid int64, db uint64) (err error) {
+	if err = s.rcli.HSet(XidDbKey, FormatXid(xid), db).Err(); err != nil {
+		err = errors.Wrapf(err, "set db of xid %016x", uint64(xid))
+	}
+	return
+}
+
+func (s *redisXidDbStore) GetDbs(xids []int64) (dbs map[int64]uint64, err error) {
+	dbs = make(map[int64]uint64, len(xids))
+	if len(xids) == 0 {
+		return
+	}
+	fields := make([]string, 0, len(xids))
+	for _, xid := range xids {
+		fields = append(fields, FormatXid(xid))
+	}
+	var values []interface{}
+	if values, err = s.rcli.HMGet(XidDbKey, fields...).Result(); err != nil {
+		err = errors.Wrapf(err, "get dbs of %s", XidDbKey)
+		return
+	}
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var db uint64
+		if db, err = strconv.ParseUint(str, 10, 64); err != nil {
+			err = errors.Wrapf(err, "db of xid %s", fields[i])
+			return
+		}
+		dbs[xids[i]] = db
+	}
+	return
+}
+
+func (s *redisXidDbStore) Remove(xids []int64) (err error) {
+	if len(xids) == 0 {
+		return
+	}
+	fields := make([]string, 0, len(xids))
+	for _, xid := range xids {
+		fields = append(fields, FormatXid(xid))
+	}
+	if err = s.rcli.HDel(XidDbKey, fields...).Err(); err != nil {
+		err = errors.Wrapf(err, "remove from %s", XidDbKey)
+	}
+	return
+}
+
+// MemoryXidDbStore is a XidDbStore in process for tests
+type MemoryXidDbStore struct {
+	sync.Mutex
+	dbs map[int64]uint64
+}
+
+// NewMemoryXidDbStore returns an empty MemoryXidDbStore
+func NewMemoryXidDbStore() *MemoryXidDbStore {
+	return &MemoryXidDbStore{dbs: make(map[int64]uint64)}
+}
+
+// SetDb implements XidDbStore
+func (s *MemoryXidDbStore) SetDb(xid int64, db uint64) error {
+	s.Lock()
+	defer s.Unlock()
+	s.dbs[xid] = db
+	return nil
+}
+
+// GetDbs implements XidDbStore
+func (s *MemoryXidDbStore) GetDbs(xids []int64) (dbs map[int64]uint64, err error) {
+	s.Lock()
+	defer s.Unlock()
+	dbs = make(map[int64]uint64, len(xids))
+	for _, xid := range xids {
+		if db, ok := s.dbs[xid]; ok {
+			dbs[xid] = db
+		}
+	}
+	return
+}
+
+// Remove implements XidDbStore
+func (s *MemoryXidDbStore) Remove(xids []int64) error {
+	s.Lock()
+	defer s.Unlock()
+	for _, xid := range xids {
+		delete(s.dbs, xid)
+	}
+	return nil
+}
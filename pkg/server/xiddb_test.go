@@ -0,0 +1,40 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func testXidDbStore(t *testing.T, s XidDbStore) {
+	require.NoError(t, s.SetDb(-2, 3))
+	require.NoError(t, s.SetDb(10, 0))
+	require.NoError(t, s.SetDb(10, 4))
+
+	dbs, err := s.GetDbs([]int64{-2, 10, 11})
+	require.NoError(t, err)
+	require.Equal(t, map[int64]uint64{-2: 3, 10: 4}, dbs)
+
+	require.NoError(t, s.Remove([]int64{10, 11}))
+	dbs, err = s.GetDbs([]int64{-2, 10})
+	require.NoError(t, err)
+	require.Equal(t, map[int64]uint64{-2: 3}, dbs)
+	dbs, err = s.GetDbs(nil)
+	require.NoError(t, err)
+	require.Empty(t, dbs)
+}
+
+func TestMemoryXidDbStore(t *testing.T) {
+	testXidDbStore(t, NewMemoryXidDbStore())
+}
+
+func TestRedisXidDbStore(t *testing.T) {
+	if RedisAddr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	rcli := redis.NewClient(&redis.Options{Addr: RedisAddr, DB: 15})
+	defer rcli.Close()
+	require.NoError(t, rcli.FlushDB().Err())
+	testXidDbStore(t, NewRedisXidDbStore(rcli))
+}
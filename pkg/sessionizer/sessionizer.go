@@ -0,0 +1,233 @@
+package sessionizer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+)
+
+type key struct {
+	shop uint64
+	uid  uint64
+}
+
+// session is an open visit, with the sources of its sightings
+type session struct {
+	visit   *server.Visit
+	sources []interface{}
+	// touched is the wall clock the last sighting is added
+	touched time.Time
+}
+
+// Sessionizer merges the sightings of a uid at a shop into one visit if each
+// is within gap of the session. The merged visit is seen first at VisitTime
+// and last at LastSeen, with the best picture and all positions covered.
+// It's not safe for concurrent use, the visits of a shop should be added by
+// one goroutine in order.
+type Sessionizer struct {
+	gap        uint64
+	sessions   map[key]*session
+	watermark  uint64
+	watermarks map[uint64]uint64
+	// done are the sources of the visits closed since the last Done
+	done []interface{}
+	now  func() time.Time
+}
+
+// New returns a Sessionizer, gap 0 disables merging
+func New(gap time.Duration) *Sessionizer {
+	return &Sessionizer{
+		gap:        uint64(gap / time.Second),
+		sessions:   make(map[key]*session),
+		watermarks: make(map[uint64]uint64),
+		now:        time.Now,
+	}
+}
+
+// Add adds the sightings and returns the visits closed by them. A sighting of
+// no uid, or earlier than its open session by more than gap, is returned as is.
+func (s *Sessionizer) Add(visits ...*server.Visit) (closed []*server.Visit) {
+	return s.AddSources(visits, nil)
+}
+
+// AddSources adds the sightings like Add, sources[i] is the source of visits[i],
+// such as its queue message. The sources of a visit are returned by Done once
+// the visit is closed.
+func (s *Sessionizer) AddSources(visits []*server.Visit, sources []interface{}) (closed []*server.Visit) {
+	now := s.now()
+	for i, v := range visits {
+		var src interface{}
+		if i < len(sources) {
+			src = sources[i]
+		}
+		if v.VisitTime > s.watermark {
+			s.watermark = v.VisitTime
+		}
+		if v.VisitTime > s.watermarks[v.Shop] {
+			s.watermarks[v.Shop] = v.VisitTime
+		}
+		if s.gap == 0 || v.Uid == 0 {
+			closed = append(closed, v)
+			s.finish(src)
+			continue
+		}
+		k := key{shop: v.Shop, uid: v.Uid}
+		sess, ok := s.sessions[k]
+		switch {
+		case !ok:
+			s.sessions[k] = &session{visit: open(v)}
+		case v.VisitTime > sess.visit.LastSeen+s.gap:
+			closed = append(closed, s.close(k))
+			s.sessions[k] = &session{visit: open(v)}
+		case v.VisitTime+s.gap < sess.visit.VisitTime:
+			closed = append(closed, open(v))
+			s.finish(src)
+			continue
+		default:
+			merge(sess.visit, v)
+		}
+		sess = s.sessions[k]
+		sess.touched = now
+		if src != nil {
+			sess.sources = append(sess.sources, src)
+		}
+	}
+	return
+}
+
+// Expire closes the sessions last seen more than gap before now
+func (s *Sessionizer) Expire(now uint64) (closed []*server.Visit) {
+	for k, sess := range s.sessions {
+		if sess.visit.LastSeen+s.gap < now {
+			closed = append(closed, s.close(k))
+		}
+	}
+	sortVisits(closed)
+	return
+}
+
+// ExpireShops closes the sessions last seen more than gap before the latest
+// sighting of their shop, the clocks of the cameras are not compared with ours.
+// A session no sighting is added to for maxIdle by the wall clock is closed
+// too, in case its shop sends nothing any more.
+func (s *Sessionizer) ExpireShops(maxIdle time.Duration) (closed []*server.Visit) {
+	idleSince := s.now().Add(-maxIdle)
+	for k, sess := range s.sessions {
+		if sess.visit.LastSeen+s.gap < s.watermarks[k.shop] || sess.touched.Before(idleSince) {
+			closed = append(closed, s.close(k))
+		}
+	}
+	sortVisits(closed)
+	return
+}
+
+// Flush closes all sessions
+func (s *Sessionizer) Flush() (closed []*server.Visit) {
+	for k := range s.sessions {
+		closed = append(closed, s.close(k))
+	}
+	sortVisits(closed)
+	return
+}
+
+// Done returns the sources of the visits closed since the last call
+func (s *Sessionizer) Done() (sources []interface{}) {
+	sources, s.done = s.done, nil
+	return
+}
+
+func (s *Sessionizer) close(k key) *server.Visit {
+	sess := s.sessions[k]
+	delete(s.sessions, k)
+	s.done = append(s.done, sess.sources...)
+	return sess.visit
+}
+
+func (s *Sessionizer) finish(src interface{}) {
+	if src != nil {
+		s.done = append(s.done, src)
+	}
+}
+
+// Watermark returns the latest visit time added
+func (s *Sessionizer) Watermark() uint64 {
+	return s.watermark
+}
+
+// Len returns the number of open sessions
+func (s *Sessionizer) Len() int {
+	return len(s.sessions)
+}
+
+func sortVisits(visits []*server.Visit) {
+	sort.Slice(visits, func(i, j int) bool {
+		if visits[i].VisitTime != visits[j].VisitTime {
+			return visits[i].VisitTime < visits[j].VisitTime
+		}
+		return visits[i].Uid < visits[j].Uid
+	})
+}
+
+func open(v *server.Visit) *server.Visit {
+	sess := *v
+	if sess.LastSeen < sess.VisitTime {
+		sess.LastSeen = sess.VisitTime
+	}
+	if len(sess.Positions) == 0 {
+		sess.Positions = []uint32{sess.Position}
+	} else {
+		sess.Positions = append([]uint32(nil), sess.Positions...)
+	}
+	if sess.Sightings == 0 {
+		sess.Sightings = 1
+	}
+	return &sess
+}
+
+// merge merges the sighting v into the session
+func merge(sess, v *server.Visit) {
+	sighting := open(v)
+	sess.Sightings += sighting.Sightings
+	for _, pos := range sighting.Positions {
+		if !hasPosition(sess.Positions, pos) {
+			sess.Positions = append(sess.Positions, pos)
+		}
+	}
+	sess.Entrance = sess.Entrance || v.Entrance
+	sess.Exit = sess.Exit || v.Exit
+	if sighting.LastSeen > sess.LastSeen {
+		sess.LastSeen = sighting.LastSeen
+	}
+	if v.VisitTime < sess.VisitTime {
+		sess.VisitTime = v.VisitTime
+		sess.Position = v.Position
+		sess.Zone = v.Zone
+		sess.Direction = v.Direction
+		sess.Mac = v.Mac
+		sess.CameraIp = v.CameraIp
+		sess.IsReturning = v.IsReturning
+		sess.PrevVisitTime = v.PrevVisitTime
+	}
+	// the best picture, a confident one is better than any low confidence one
+	if (sess.LowConfidence && !v.LowConfidence) || (sess.LowConfidence == v.LowConfidence && v.Quality > sess.Quality) {
+		sess.PictureId = v.PictureId
+		sess.Quality = v.Quality
+		sess.Age = v.Age
+		sess.Gender = v.Gender
+		sess.Xid = v.Xid
+		sess.LowConfidence = v.LowConfidence
+		sess.MatchedXid = v.MatchedXid
+		sess.Distance = v.Distance
+		sess.Decision = v.Decision
+	}
+}
+
+func hasPosition(positions []uint32, pos uint32) bool {
+	for _, p := range positions {
+		if p == pos {
+			return true
+		}
+	}
+	return false
+}
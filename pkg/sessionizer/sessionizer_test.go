@@ -0,0 +1,158 @@
+package sessionizer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/stretchr/testify/require"
+)
+
+func sighting(shop, uid, ts uint64, pos uint32, quality float32) *server.Visit {
+	return &server.Visit{
+		PictureId: fmt.Sprintf("%d-%d-%d", shop, uid, ts),
+		Shop:      shop,
+		Uid:       uid,
+		VisitTime: ts,
+		Position:  pos,
+		Quality:   quality,
+	}
+}
+
+func TestSessionizer(t *testing.T) {
+	s := New(30 * time.Second)
+
+	// a person stands in front of camera 0 then walks to camera 1
+	var closed []*server.Visit
+	for ts := uint64(1000); ts < 1060; ts += 2 {
+		pos := uint32(0)
+		if ts >= 1040 {
+			pos = 1
+		}
+		quality := float32(0.5)
+		if ts == 1010 {
+			quality = 0.9
+		}
+		closed = append(closed, s.Add(sighting(1, 7, ts, pos, quality))...)
+	}
+	require.Empty(t, closed)
+	require.Equal(t, 1, s.Len())
+
+	require.Empty(t, s.Expire(1088))
+	closed = s.Expire(1089)
+	require.Equal(t, 1, len(closed))
+	v := closed[0]
+	require.Equal(t, uint64(1000), v.VisitTime)
+	require.Equal(t, uint64(1058), v.LastSeen)
+	require.Equal(t, uint32(0), v.Position)
+	require.Equal(t, []uint32{0, 1}, v.Positions)
+	require.Equal(t, uint32(30), v.Sightings)
+	require.Equal(t, "1-7-1010", v.PictureId)
+	require.Equal(t, float32(0.9), v.Quality)
+	require.Equal(t, 0, s.Len())
+}
+
+func TestSessionizerGap(t *testing.T) {
+	s := New(30 * time.Second)
+	require.Empty(t, s.Add(
+		sighting(1, 7, 1000, 0, 0.5),
+		sighting(1, 8, 1000, 0, 0.5), // another person
+		sighting(2, 7, 1010, 0, 0.5), // another shop
+		sighting(1, 7, 1030, 0, 0.5),
+	))
+	require.Equal(t, 3, s.Len())
+
+	// more than gap after the last sighting
+	closed := s.Add(sighting(1, 7, 1061, 0, 0.5))
+	require.Equal(t, 1, len(closed))
+	require.Equal(t, uint64(1000), closed[0].VisitTime)
+	require.Equal(t, uint64(1030), closed[0].LastSeen)
+	require.Equal(t, uint32(2), closed[0].Sightings)
+
+	// a late sighting of the closed session
+	closed = s.Add(sighting(1, 7, 1020, 0, 0.5))
+	require.Equal(t, 1, len(closed))
+	require.Equal(t, uint32(1), closed[0].Sightings)
+
+	// an earlier sighting within the gap of the open session
+	require.Empty(t, s.Add(sighting(1, 7, 1040, 2, 0.5)))
+	require.Equal(t, uint64(1061), s.Watermark())
+
+	// no uid
+	closed = s.Add(sighting(1, 0, 1062, 0, 0.5))
+	require.Equal(t, 1, len(closed))
+	require.Equal(t, uint64(0), closed[0].Uid)
+
+	closed = s.Flush()
+	require.Equal(t, 3, len(closed))
+	require.Equal(t, uint64(8), closed[0].Uid)
+	require.Equal(t, uint64(2), closed[1].Shop)
+	require.Equal(t, uint64(1040), closed[2].VisitTime)
+	require.Equal(t, uint64(1061), closed[2].LastSeen)
+	require.Equal(t, uint32(2), closed[2].Position)
+	require.Equal(t, []uint32{0, 2}, closed[2].Positions)
+}
+
+func TestSessionizerBestPicture(t *testing.T) {
+	s := New(30 * time.Second)
+	low := sighting(1, 7, 1000, 0, 0.95)
+	low.LowConfidence = true
+	s.Add(low, sighting(1, 7, 1001, 0, 0.6), sighting(1, 7, 1002, 0, 0.7))
+	closed := s.Flush()
+	require.Equal(t, 1, len(closed))
+	require.Equal(t, "1-7-1002", closed[0].PictureId)
+	require.False(t, closed[0].LowConfidence)
+}
+
+func TestSessionizerDisabled(t *testing.T) {
+	s := New(0)
+	visits := []*server.Visit{sighting(1, 7, 1000, 0, 0.5), sighting(1, 7, 1001, 0, 0.5)}
+	require.Equal(t, visits, s.Add(visits...))
+	require.Equal(t, 0, s.Len())
+}
+
+func TestSessionizerSources(t *testing.T) {
+	s := New(30 * time.Second)
+	require.Empty(t, s.AddSources([]*server.Visit{
+		sighting(1, 7, 1000, 0, 0.5),
+		sighting(1, 7, 1010, 0, 0.5),
+		sighting(1, 8, 1010, 0, 0.5),
+	}, []interface{}{"a", "b", "c"}))
+	require.Empty(t, s.Done())
+
+	// no uid, and a late sighting, are closed at once
+	closed := s.AddSources([]*server.Visit{sighting(1, 0, 1020, 0, 0.5), sighting(1, 8, 900, 0, 0.5)}, []interface{}{"d", "e"})
+	require.Equal(t, 2, len(closed))
+	require.Equal(t, []interface{}{"d", "e"}, s.Done())
+
+	closed = s.AddSources([]*server.Visit{sighting(1, 7, 1050, 0, 0.5)}, []interface{}{"f"})
+	require.Equal(t, 1, len(closed))
+	require.Equal(t, []interface{}{"a", "b"}, s.Done())
+	require.Equal(t, 2, len(s.Flush()))
+	require.ElementsMatch(t, []interface{}{"c", "f"}, s.Done())
+}
+
+func TestSessionizerExpireShops(t *testing.T) {
+	now := time.Unix(5000, 0)
+	s := New(30 * time.Second)
+	s.now = func() time.Time { return now }
+	// the cameras of shop 2 are 1000s behind
+	s.Add(sighting(1, 7, 1000, 0, 0.5), sighting(2, 7, 10, 0, 0.5))
+	require.Empty(t, s.ExpireShops(time.Minute))
+
+	// by the latest sighting of each shop
+	s.Add(sighting(1, 8, 1030, 0, 0.5), sighting(2, 8, 40, 0, 0.5))
+	require.Empty(t, s.ExpireShops(time.Minute))
+	s.Add(sighting(2, 8, 41, 0, 0.5))
+	closed := s.ExpireShops(time.Minute)
+	require.Equal(t, 1, len(closed))
+	require.Equal(t, uint64(2), closed[0].Shop)
+	require.Equal(t, uint64(7), closed[0].Uid)
+
+	// nothing is sent for maxIdle
+	now = now.Add(time.Minute + time.Second)
+	closed = s.ExpireShops(time.Minute)
+	require.Equal(t, 3, len(closed))
+	require.Equal(t, 0, s.Len())
+}
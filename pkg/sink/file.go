@@ -0,0 +1,65 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+)
+
+// FileSink appends the visits as JSON lines to a file
+type FileSink struct {
+	sync.Mutex
+	f *os.File
+	w *bufio.Writer
+}
+
+// NewFileSink opens the file for appending, it's created if not exists
+func NewFileSink(path string) (s *FileSink, err error) {
+	if path == "" {
+		err = errors.New("file sink requires a path")
+		return
+	}
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	s = &FileSink{f: f, w: bufio.NewWriter(f)}
+	return
+}
+
+// Write appends a line per visit, the lines are flushed before it returns
+func (s *FileSink) Write(visits []*server.Visit) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	enc := json.NewEncoder(s.w)
+	for _, visit := range visits {
+		if err = enc.Encode(visit); err != nil {
+			err = errors.Wrap(err, "")
+			return
+		}
+	}
+	if err = s.w.Flush(); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+// Close flushes and closes the file
+func (s *FileSink) Close() (err error) {
+	s.Lock()
+	defer s.Unlock()
+	if err = s.w.Flush(); err != nil {
+		s.f.Close()
+		err = errors.Wrap(err, "")
+		return
+	}
+	if err = s.f.Close(); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
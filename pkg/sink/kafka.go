@@ -0,0 +1,58 @@
+package sink
+
+import (
+	"strconv"
+
+	"github.com/Shopify/sarama"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+)
+
+// KafkaSink produces the protobuf encoded visits to a topic, keyed by shop
+type KafkaSink struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+// NewKafkaSink returns a sink of the topic
+func NewKafkaSink(addrs []string, topic string) (s *KafkaSink, err error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 10
+	config.Producer.Return.Successes = true
+	s = &KafkaSink{topic: topic}
+	if s.producer, err = sarama.NewSyncProducer(addrs, config); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+// Write produces the visits in a batch
+func (s *KafkaSink) Write(visits []*server.Visit) (err error) {
+	var raws [][]byte
+	if raws, err = marshal(visits); err != nil {
+		return
+	}
+	return s.WriteRaw(visits, raws)
+}
+
+// WriteRaw produces the encoded visits in a batch
+func (s *KafkaSink) WriteRaw(visits []*server.Visit, raws [][]byte) (err error) {
+	msgs := make([]*sarama.ProducerMessage, 0, len(visits))
+	for i, visit := range visits {
+		msgs = append(msgs, &sarama.ProducerMessage{
+			Topic: s.topic,
+			Key:   sarama.StringEncoder(strconv.FormatUint(visit.Shop, 10)),
+			Value: sarama.ByteEncoder(raws[i]),
+		})
+	}
+	if err = s.producer.SendMessages(msgs); err != nil {
+		err = errors.Wrapf(err, "produce to %s", s.topic)
+	}
+	return
+}
+
+// Close closes the producer
+func (s *KafkaSink) Close() error {
+	return s.producer.Close()
+}
@@ -0,0 +1,72 @@
+package sink
+
+import (
+	"time"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// PostgresSink calls the stored procedures insert_user and insert_visit_event
+type PostgresSink struct {
+	db *sqlx.DB
+}
+
+// NewPostgresSink returns a sink of the database, it's not closed with the sink
+func NewPostgresSink(db *sqlx.DB) *PostgresSink {
+	return &PostgresSink{db: db}
+}
+
+// Write records the visits in a transaction, none of them is recorded if it fails
+func (s *PostgresSink) Write(visits []*server.Visit) (err error) {
+	var tx *sqlx.Tx
+	if tx, err = s.db.Beginx(); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	for _, visit := range visits {
+		if err = recordVisit(tx, visit); err != nil {
+			tx.Rollback()
+			return
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		err = errors.Wrap(err, "")
+	}
+	return
+}
+
+func recordVisit(tx *sqlx.Tx, visit *server.Visit) (err error) {
+	if visit.Uid == 0 {
+		// a low confidence face of nobody known
+		return
+	}
+	vt := time.Unix(int64(visit.VisitTime), 0).Format(time.RFC3339)
+	// Note: If db.Query is used, then the connection will not be released to pool since the cursor is not closed.
+	// A low confidence picture isn't kept as a picture of the user.
+	if !visit.LowConfidence {
+		if _, err = tx.Exec("SELECT insert_user($1, $2, $3, $4, $5, $6)", visit.Uid, visit.PictureId, visit.Quality, visit.Gender, visit.Age, vt); err != nil {
+			err = errors.Wrapf(err, "")
+			return
+		}
+	}
+	if _, err = tx.Exec("SELECT insert_visit_event($1, $2, $3, $4, $5, $6)", visit.Shop, visit.Uid, visit.Position, visit.Gender, visit.Age, vt); err != nil {
+		err = errors.Wrapf(err, "")
+		return
+	}
+	// a session is seen till LastSeen, which extends the duration as a sighting at position 1 does
+	if visit.LastSeen > visit.VisitTime && visit.Position <= 1 {
+		lt := time.Unix(int64(visit.LastSeen), 0).Format(time.RFC3339)
+		if _, err = tx.Exec("SELECT insert_visit_event($1, $2, $3, $4, $5, $6)", visit.Shop, visit.Uid, 1, visit.Gender, visit.Age, lt); err != nil {
+			err = errors.Wrapf(err, "")
+			return
+		}
+	}
+	return
+}
+
+// Close does nothing, the database is owned by the caller
+func (s *PostgresSink) Close() error {
+	return nil
+}
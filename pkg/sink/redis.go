@@ -0,0 +1,57 @@
+package sink
+
+import (
+	"github.com/go-redis/redis"
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+)
+
+// RedisSink appends the protobuf encoded visits to a list, the same format as visit_queue
+type RedisSink struct {
+	rcli *redis.Client
+	key  string
+}
+
+// NewRedisSink returns a sink of the list key
+func NewRedisSink(addr, key string) *RedisSink {
+	return &RedisSink{
+		rcli: redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: "", // no password set
+			DB:       0,  // use default DB
+		}),
+		key: key,
+	}
+}
+
+// Write appends the visits with one RPUSH
+func (s *RedisSink) Write(visits []*server.Visit) (err error) {
+	if len(visits) == 0 {
+		return
+	}
+	var raws [][]byte
+	if raws, err = marshal(visits); err != nil {
+		return
+	}
+	return s.WriteRaw(visits, raws)
+}
+
+// WriteRaw appends the encoded visits with one RPUSH
+func (s *RedisSink) WriteRaw(visits []*server.Visit, raws [][]byte) (err error) {
+	if len(raws) == 0 {
+		return
+	}
+	vals := make([]interface{}, 0, len(raws))
+	for _, raw := range raws {
+		vals = append(vals, raw)
+	}
+	if err = s.rcli.RPush(s.key, vals...).Err(); err != nil {
+		err = errors.Wrapf(err, "append to %s", s.key)
+	}
+	return
+}
+
+// Close closes the client
+func (s *RedisSink) Close() error {
+	return s.rcli.Close()
+}
@@ -0,0 +1,292 @@
+package sink
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// KindPostgres calls insert_user and insert_visit_event, a batch in a transaction
+	KindPostgres = "postgres"
+	// KindKafka produces the encoded visits to a Kafka topic
+	KindKafka = "kafka"
+	// KindRedis appends the encoded visits to a Redis list
+	KindRedis = "redis"
+	// KindFile appends the visits as JSON lines to a file
+	KindFile = "file"
+)
+
+var (
+	sinkWriteCountVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mcd",
+			Subsystem: "faceserver",
+			Name:      "visit_sink_write",
+			Help:      "Visits written by sink and result: succ, failed.",
+		}, []string{"sink", "result"})
+	sinkOnce sync.Once
+)
+
+// VisitSink is where the identified visits go. A sink is safe for concurrent use.
+type VisitSink interface {
+	// Write writes the visits, a failed Write may have written some of them
+	Write(visits []*server.Visit) error
+	Close() error
+}
+
+// RawWriter is implemented by the sinks writing the encoded visits. They write
+// the bytes the visits are decoded from as is, rather than encode them again.
+type RawWriter interface {
+	WriteRaw(visits []*server.Visit, raws [][]byte) error
+}
+
+// WriteRaw writes the visits decoded from raws to the sink
+func WriteRaw(s VisitSink, visits []*server.Visit, raws [][]byte) error {
+	if w, ok := s.(RawWriter); ok {
+		return w.WriteRaw(visits, raws)
+	}
+	return s.Write(visits)
+}
+
+// Cfg is the config of the sinks
+type Cfg struct {
+	Kinds []string
+	// DB is the PostgreSQL of KindPostgres
+	DB *sqlx.DB
+	// KafkaAddrs and KafkaTopic are the brokers and topic of KindKafka
+	KafkaAddrs []string
+	KafkaTopic string
+	// RedisAddr and RedisKey are the server and list of KindRedis
+	RedisAddr string
+	RedisKey  string
+	// File is the path of KindFile
+	File string
+}
+
+// ParseKinds parses the comma separated sink kinds
+func ParseKinds(kinds string) (parsed []string) {
+	for _, kind := range strings.Split(kinds, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			parsed = append(parsed, kind)
+		}
+	}
+	return
+}
+
+// New returns the sink of cfg.Kinds, a fan-out of them if there are more than one
+func New(cfg Cfg) (sink VisitSink, err error) {
+	sinkOnce.Do(func() {
+		prometheus.MustRegister(sinkWriteCountVec)
+	})
+	if len(cfg.Kinds) == 0 {
+		err = errors.New("no visit sink")
+		return
+	}
+	var sinks []VisitSink
+	for _, kind := range cfg.Kinds {
+		var s VisitSink
+		switch kind {
+		case KindPostgres:
+			if cfg.DB == nil {
+				err = errors.New("postgres sink requires a database")
+			} else {
+				s = NewPostgresSink(cfg.DB)
+			}
+		case KindKafka:
+			s, err = NewKafkaSink(cfg.KafkaAddrs, cfg.KafkaTopic)
+		case KindRedis:
+			s = NewRedisSink(cfg.RedisAddr, cfg.RedisKey)
+		case KindFile:
+			s, err = NewFileSink(cfg.File)
+		default:
+			err = errors.Errorf("unsupported visit sink %q", kind)
+		}
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return
+		}
+		sinks = append(sinks, observed{kind: kind, sink: s})
+	}
+	if len(sinks) == 1 {
+		sink = sinks[0]
+	} else {
+		sink = NewFanOut(sinks...)
+	}
+	return
+}
+
+// observed counts the visits written by the sink
+type observed struct {
+	kind string
+	sink VisitSink
+}
+
+func (o observed) Write(visits []*server.Visit) (err error) {
+	return o.write(visits, o.sink.Write)
+}
+
+func (o observed) WriteRaw(visits []*server.Visit, raws [][]byte) (err error) {
+	return o.write(visits, func(visits []*server.Visit) error {
+		return WriteRaw(o.sink, visits, raws)
+	})
+}
+
+func (o observed) write(visits []*server.Visit, write func(visits []*server.Visit) error) (err error) {
+	if len(visits) == 0 {
+		return
+	}
+	if err = write(visits); err != nil {
+		sinkWriteCountVec.WithLabelValues(o.kind, "failed").Add(float64(len(visits)))
+		err = errors.Wrapf(err, "%s sink", o.kind)
+		return
+	}
+	sinkWriteCountVec.WithLabelValues(o.kind, "succ").Add(float64(len(visits)))
+	return
+}
+
+func (o observed) Close() error {
+	return o.sink.Close()
+}
+
+// FanOut writes the visits to every sink
+type FanOut struct {
+	sinks []VisitSink
+}
+
+// NewFanOut returns a sink writing to all the sinks
+func NewFanOut(sinks ...VisitSink) *FanOut {
+	return &FanOut{sinks: sinks}
+}
+
+// FanOutError is the error of a FanOut write, Failed are the sinks failed
+type FanOutError struct {
+	Failed []VisitSink
+	msgs   []string
+	total  int
+}
+
+func (e *FanOutError) Error() string {
+	return fmt.Sprintf("%d of %d sinks failed: %s", len(e.Failed), e.total, strings.Join(e.msgs, "; "))
+}
+
+// Write writes the visits to every sink even if some of them fail, err is a
+// *FanOutError of the failed ones. Use Retry to write the visits again to them only.
+func (f *FanOut) Write(visits []*server.Visit) (err error) {
+	return f.write(func(s VisitSink) error {
+		return s.Write(visits)
+	})
+}
+
+// WriteRaw is Write of the encoded visits
+func (f *FanOut) WriteRaw(visits []*server.Visit, raws [][]byte) (err error) {
+	return f.write(func(s VisitSink) error {
+		return WriteRaw(s, visits, raws)
+	})
+}
+
+func (f *FanOut) write(write func(s VisitSink) error) (err error) {
+	var fe *FanOutError
+	for _, s := range f.sinks {
+		if e := write(s); e != nil {
+			if fe == nil {
+				fe = &FanOutError{total: len(f.sinks)}
+			}
+			fe.Failed = append(fe.Failed, s)
+			fe.msgs = append(fe.msgs, e.Error())
+		}
+	}
+	if fe != nil {
+		err = fe
+	}
+	return
+}
+
+// Retry returns the sink to write the visits again after the Write to s failed
+// with err, the failed sinks of a FanOut, or else s itself
+func Retry(s VisitSink, err error) VisitSink {
+	if fe, ok := errors.Cause(err).(*FanOutError); ok && len(fe.Failed) != 0 {
+		if len(fe.Failed) == 1 {
+			return fe.Failed[0]
+		}
+		return NewFanOut(fe.Failed...)
+	}
+	return s
+}
+
+// Kinds returns the kinds of the sinks of s
+func Kinds(s VisitSink) (kinds []string) {
+	switch s := s.(type) {
+	case observed:
+		kinds = append(kinds, s.kind)
+	case *FanOut:
+		for _, sub := range s.sinks {
+			kinds = append(kinds, Kinds(sub)...)
+		}
+	}
+	return
+}
+
+// Select returns the sinks of s of the kinds, s itself if kinds is empty or none matches
+func Select(s VisitSink, kinds []string) VisitSink {
+	f, ok := s.(*FanOut)
+	if !ok || len(kinds) == 0 {
+		return s
+	}
+	var selected []VisitSink
+	for _, sub := range f.sinks {
+		for _, kind := range Kinds(sub) {
+			if hasKind(kinds, kind) {
+				selected = append(selected, sub)
+				break
+			}
+		}
+	}
+	switch len(selected) {
+	case 0:
+		return s
+	case 1:
+		return selected[0]
+	}
+	return NewFanOut(selected...)
+}
+
+func hasKind(kinds []string, kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Close closes all the sinks
+func (f *FanOut) Close() (err error) {
+	for _, s := range f.sinks {
+		if e := s.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// marshal encodes the visits
+func marshal(visits []*server.Visit) (raws [][]byte, err error) {
+	raws = make([][]byte, 0, len(visits))
+	for _, visit := range visits {
+		var data []byte
+		if data, err = visit.Marshal(); err != nil {
+			err = errors.Wrap(err, "")
+			return
+		}
+		raws = append(raws, data)
+	}
+	return
+}
@@ -0,0 +1,144 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/infinivision/filesyncer/pkg/server"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySink struct {
+	visits []*server.Visit
+	err    error
+	closed bool
+}
+
+func (s *memorySink) Write(visits []*server.Visit) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.visits = append(s.visits, visits...)
+	return nil
+}
+
+// rawSink is a memorySink writing the encoded visits
+type rawSink struct {
+	memorySink
+	raws [][]byte
+}
+
+func (s *rawSink) WriteRaw(visits []*server.Visit, raws [][]byte) error {
+	s.raws = append(s.raws, raws...)
+	return s.Write(visits)
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "visits.jsonl")
+
+	s, err := New(Cfg{Kinds: ParseKinds(" file "), File: path})
+	require.NoError(t, err)
+	require.NoError(t, s.Write([]*server.Visit{{PictureId: "obj1", Uid: 1}, {PictureId: "obj2", Uid: 2}}))
+	require.NoError(t, s.Write(nil))
+	require.NoError(t, s.Close())
+
+	// appends to the existing file
+	s, err = New(Cfg{Kinds: []string{KindFile}, File: path})
+	require.NoError(t, err)
+	require.NoError(t, s.Write([]*server.Visit{{PictureId: "obj3", Uid: 1, Decision: server.DecisionNew}}))
+	require.NoError(t, s.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var visit server.Visit
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &visit))
+		ids = append(ids, visit.PictureId)
+	}
+	require.Equal(t, []string{"obj1", "obj2", "obj3"}, ids)
+}
+
+func TestFanOut(t *testing.T) {
+	a, b, c := &memorySink{}, &memorySink{err: errors.New("down")}, &memorySink{}
+	f := NewFanOut(a, b, c)
+	visits := []*server.Visit{{PictureId: "obj1", Uid: 1}}
+	err := f.Write(visits)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1 of 3 sinks failed")
+	// the others are written anyway
+	require.Equal(t, visits, a.visits)
+	require.Equal(t, visits, c.visits)
+
+	// written again to the failed one only
+	b.err = nil
+	require.NoError(t, Retry(f, errors.Wrap(err, "record")).Write(visits))
+	require.Equal(t, 1, len(a.visits))
+	require.Equal(t, 1, len(b.visits))
+	require.Equal(t, 1, len(c.visits))
+	require.Equal(t, f, Retry(f, errors.New("not of fan-out")))
+
+	require.NoError(t, f.Write(visits))
+	require.Equal(t, 2, len(a.visits))
+	require.Equal(t, 2, len(b.visits))
+
+	require.NoError(t, f.Close())
+	require.True(t, a.closed && b.closed && c.closed)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Cfg{})
+	require.Error(t, err)
+	_, err = New(Cfg{Kinds: []string{"mysql"}})
+	require.Error(t, err)
+	_, err = New(Cfg{Kinds: []string{KindPostgres}})
+	require.Error(t, err)
+	_, err = New(Cfg{Kinds: []string{KindFile}})
+	require.Error(t, err)
+
+	s, err := New(Cfg{Kinds: []string{KindRedis, KindRedis}, RedisAddr: "127.0.0.1:6379", RedisKey: "visit_sink"})
+	require.NoError(t, err)
+	_, ok := s.(*FanOut)
+	require.True(t, ok)
+	require.NoError(t, s.Close())
+}
+
+func TestSelect(t *testing.T) {
+	pg, kafka := observed{kind: KindPostgres, sink: &memorySink{}}, observed{kind: KindKafka, sink: &memorySink{}}
+	f := NewFanOut(pg, kafka)
+	require.Equal(t, []string{KindPostgres, KindKafka}, Kinds(f))
+	require.Equal(t, kafka, Select(f, []string{KindKafka}))
+	require.Equal(t, f, Select(f, nil))
+	require.Equal(t, f, Select(f, []string{KindRedis}))
+	require.Equal(t, []string{KindPostgres, KindKafka}, Kinds(Select(f, []string{KindKafka, KindPostgres})))
+
+	err := NewFanOut(pg, observed{kind: KindKafka, sink: &memorySink{err: errors.New("down")}}).Write([]*server.Visit{{PictureId: "obj1"}})
+	require.Error(t, err)
+	require.Equal(t, []string{KindKafka}, Kinds(Retry(f, err)))
+}
+
+func TestWriteRaw(t *testing.T) {
+	raw, plain := &rawSink{}, &memorySink{}
+	visits := []*server.Visit{{PictureId: "obj1", Uid: 1}}
+	// a record of an older version is written as is
+	raws := [][]byte{[]byte("v0")}
+	require.NoError(t, WriteRaw(NewFanOut(observed{kind: KindKafka, sink: raw}, plain), visits, raws))
+	require.Equal(t, raws, raw.raws)
+	require.Equal(t, visits, raw.visits)
+	require.Equal(t, visits, plain.visits)
+}
@@ -0,0 +1,294 @@
+package vecindex
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/fagongzi/log"
+	"github.com/pkg/errors"
+)
+
+// MemoryIndex is an exact inner-product index in a single db 0. It's for tests
+// and small single-store deployments, a search costs O(n*dim).
+// If it's opened with a file, every add, update and remove is appended to the
+// file and replayed on the next open. A removal is a record of zero vector.
+type MemoryIndex struct {
+	sync.RWMutex
+
+	dim     int
+	distThr float32
+	xids    []int64
+	vecs    []float32
+	slots   map[int64]int
+
+	path string
+	f    *os.File
+}
+
+// NewMemoryIndex returns an empty index of dim-dimension vectors. Search
+// returns xid -1 if the best distance is less than distThr.
+func NewMemoryIndex(dim int, distThr float32) *MemoryIndex {
+	return &MemoryIndex{
+		dim:     dim,
+		distThr: distThr,
+		slots:   make(map[int64]int),
+	}
+}
+
+// OpenMemoryIndex returns an index backed by the file at path. The file is
+// compacted on open, an incomplete record at the end (crash while writing) is dropped.
+func OpenMemoryIndex(path string, dim int, distThr float32) (idx *MemoryIndex, err error) {
+	idx = NewMemoryIndex(dim, distThr)
+	idx.path = path
+	if err = idx.load(); err != nil {
+		return
+	}
+	if err = idx.compact(); err != nil {
+		return
+	}
+	if idx.f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		err = errors.Wrapf(err, "open %s", path)
+	}
+	return
+}
+
+func (idx *MemoryIndex) recordSize() int {
+	return 8 + idx.dim*4
+}
+
+func (idx *MemoryIndex) load() (err error) {
+	var f *os.File
+	if f, err = os.Open(idx.path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+			return
+		}
+		err = errors.Wrapf(err, "open %s", idx.path)
+		return
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	rec := make([]byte, idx.recordSize())
+	vec := make([]float32, idx.dim)
+	for {
+		var n int
+		if n, err = io.ReadFull(r, rec); err != nil {
+			if err == io.EOF {
+				err = nil
+			} else if err == io.ErrUnexpectedEOF {
+				log.Warnf("vecindex: drop the incomplete record (%d bytes) at the end of %s", n, idx.path)
+				err = nil
+			} else {
+				err = errors.Wrapf(err, "read %s", idx.path)
+			}
+			return
+		}
+		xid := int64(binary.LittleEndian.Uint64(rec))
+		for i := range vec {
+			vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(rec[8+i*4:]))
+		}
+		if isZero(vec) {
+			idx.remove(xid)
+		} else {
+			idx.put(xid, vec)
+		}
+	}
+}
+
+func isZero(vec []float32) bool {
+	for _, v := range vec {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (idx *MemoryIndex) compact() (err error) {
+	tmp := idx.path + ".tmp"
+	var f *os.File
+	if f, err = os.Create(tmp); err != nil {
+		err = errors.Wrapf(err, "create %s", tmp)
+		return
+	}
+	w := bufio.NewWriter(f)
+	for i, xid := range idx.xids {
+		if _, err = w.Write(idx.encode(xid, idx.vecs[i*idx.dim:(i+1)*idx.dim])); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		err = errors.Wrapf(err, "write %s", tmp)
+		return
+	}
+	if err = os.Rename(tmp, idx.path); err != nil {
+		err = errors.Wrapf(err, "rename %s", tmp)
+	}
+	return
+}
+
+func (idx *MemoryIndex) encode(xid int64, vec []float32) []byte {
+	rec := make([]byte, idx.recordSize())
+	binary.LittleEndian.PutUint64(rec, uint64(xid))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(rec[8+i*4:], math.Float32bits(v))
+	}
+	return rec
+}
+
+// put adds or replaces the vector of xid
+func (idx *MemoryIndex) put(xid int64, vec []float32) {
+	if slot, ok := idx.slots[xid]; ok {
+		copy(idx.vecs[slot*idx.dim:], vec)
+		return
+	}
+	idx.slots[xid] = len(idx.xids)
+	idx.xids = append(idx.xids, xid)
+	idx.vecs = append(idx.vecs, vec...)
+}
+
+// remove moves the last vector to the slot of xid
+func (idx *MemoryIndex) remove(xid int64) {
+	slot, ok := idx.slots[xid]
+	if !ok {
+		return
+	}
+	last := len(idx.xids) - 1
+	if slot != last {
+		idx.xids[slot] = idx.xids[last]
+		copy(idx.vecs[slot*idx.dim:(slot+1)*idx.dim], idx.vecs[last*idx.dim:])
+		idx.slots[idx.xids[slot]] = slot
+	}
+	idx.xids = idx.xids[:last]
+	idx.vecs = idx.vecs[:last*idx.dim]
+	delete(idx.slots, xid)
+}
+
+func (idx *MemoryIndex) append(xb []float32, xids []int64) (err error) {
+	if idx.f == nil {
+		return
+	}
+	data := make([]byte, 0, len(xids)*idx.recordSize())
+	for i, xid := range xids {
+		data = append(data, idx.encode(xid, xb[i*idx.dim:(i+1)*idx.dim])...)
+	}
+	if _, err = idx.f.Write(data); err != nil {
+		err = errors.Wrapf(err, "write %s", idx.path)
+	}
+	return
+}
+
+// Search implements VectorIndex
+func (idx *MemoryIndex) Search(xq []float32) (dbs []uint64, distances []float32, xids []int64, err error) {
+	if len(xq) == 0 || len(xq)%idx.dim != 0 {
+		err = errors.Errorf("query length %d is not a multiple of dim %d", len(xq), idx.dim)
+		return
+	}
+	n := len(xq) / idx.dim
+	dbs = make([]uint64, n)
+	distances = make([]float32, n)
+	xids = make([]int64, n)
+
+	idx.RLock()
+	defer idx.RUnlock()
+	for q := 0; q < n; q++ {
+		query := xq[q*idx.dim : (q+1)*idx.dim]
+		xids[q] = -1
+		best := float32(math.Inf(-1))
+		for i, xid := range idx.xids {
+			vec := idx.vecs[i*idx.dim : (i+1)*idx.dim]
+			var prod float32
+			for j, v := range query {
+				prod += v * vec[j]
+			}
+			if prod > best {
+				best, xids[q] = prod, xid
+			}
+		}
+		if xids[q] != -1 && best >= idx.distThr {
+			distances[q] = best
+		} else {
+			xids[q] = -1
+		}
+	}
+	return
+}
+
+// AddWithIds implements VectorIndex, an existing xid is replaced
+func (idx *MemoryIndex) AddWithIds(xb []float32, xids []int64) (err error) {
+	if len(xb) != len(xids)*idx.dim {
+		err = errors.Errorf("%d floats are not %d vectors of dim %d", len(xb), len(xids), idx.dim)
+		return
+	}
+	idx.Lock()
+	defer idx.Unlock()
+	if err = idx.append(xb, xids); err != nil {
+		return
+	}
+	for i, xid := range xids {
+		idx.put(xid, xb[i*idx.dim:(i+1)*idx.dim])
+	}
+	return
+}
+
+// UpdateWithIds implements VectorIndex
+func (idx *MemoryIndex) UpdateWithIds(db uint64, xid int64, xb []float32) (err error) {
+	if len(xb) != idx.dim {
+		err = errors.Errorf("vector length %d is not dim %d", len(xb), idx.dim)
+		return
+	}
+	idx.Lock()
+	defer idx.Unlock()
+	if _, ok := idx.slots[xid]; db != 0 || !ok {
+		err = errors.Errorf("xid %016x not found in db %d", uint64(xid), db)
+		return
+	}
+	if err = idx.append(xb, []int64{xid}); err != nil {
+		return
+	}
+	idx.put(xid, xb)
+	return
+}
+
+// RemoveIds implements Remover, unknown xids are ignored
+func (idx *MemoryIndex) RemoveIds(xids []int64) (err error) {
+	idx.Lock()
+	defer idx.Unlock()
+	if err = idx.append(make([]float32, len(xids)*idx.dim), xids); err != nil {
+		return
+	}
+	for _, xid := range xids {
+		idx.remove(xid)
+	}
+	return
+}
+
+// Len returns the number of vectors
+func (idx *MemoryIndex) Len() int {
+	idx.RLock()
+	defer idx.RUnlock()
+	return len(idx.xids)
+}
+
+// Close closes the file of the index
+func (idx *MemoryIndex) Close() (err error) {
+	idx.Lock()
+	defer idx.Unlock()
+	if idx.f != nil {
+		err = idx.f.Close()
+		idx.f = nil
+	}
+	return
+}
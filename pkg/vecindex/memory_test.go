@@ -0,0 +1,90 @@
+package vecindex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var _ VectorIndex = &MemoryIndex{}
+var _ Remover = &MemoryIndex{}
+
+func TestMemoryIndex(t *testing.T) {
+	idx := NewMemoryIndex(2, 0.5)
+	dbs, ds, xids, err := idx.Search([]float32{1, 0})
+	require.NoError(t, err)
+	require.Equal(t, []int64{-1}, xids)
+	require.Equal(t, []uint64{0}, dbs)
+	require.Equal(t, []float32{0}, ds)
+
+	require.NoError(t, idx.AddWithIds([]float32{1, 0, 0, 1}, []int64{10, 20}))
+	require.Error(t, idx.AddWithIds([]float32{1, 0, 0}, []int64{30, 40}))
+	require.Equal(t, 2, idx.Len())
+
+	// two queries in one search
+	_, ds, xids, err = idx.Search([]float32{0.8, 0.6, 0.6, 0.8})
+	require.NoError(t, err)
+	require.Equal(t, []int64{10, 20}, xids)
+	require.InDelta(t, 0.8, ds[0], 1e-6)
+	require.InDelta(t, 0.8, ds[1], 1e-6)
+
+	// below the threshold
+	_, _, xids, err = idx.Search([]float32{-1, 0})
+	require.NoError(t, err)
+	require.Equal(t, []int64{-1}, xids)
+
+	_, _, _, err = idx.Search([]float32{1, 0, 0})
+	require.Error(t, err)
+
+	require.NoError(t, idx.UpdateWithIds(0, 10, []float32{-1, 0}))
+	_, ds, xids, err = idx.Search([]float32{-1, 0})
+	require.NoError(t, err)
+	require.Equal(t, []int64{10}, xids)
+	require.InDelta(t, 1, ds[0], 1e-6)
+	require.Error(t, idx.UpdateWithIds(0, 30, []float32{1, 0}))
+	require.Error(t, idx.UpdateWithIds(1, 10, []float32{1, 0}))
+
+	require.NoError(t, idx.RemoveIds([]int64{10, 30}))
+	require.Equal(t, 1, idx.Len())
+	_, _, xids, err = idx.Search([]float32{-1, 0, 0, 1})
+	require.NoError(t, err)
+	require.Equal(t, []int64{-1, 20}, xids)
+}
+
+func TestMemoryIndexFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vecindex")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index")
+
+	idx, err := OpenMemoryIndex(path, 2, 0.5)
+	require.NoError(t, err)
+	require.NoError(t, idx.AddWithIds([]float32{1, 0, 0, 1}, []int64{10, 20}))
+	require.NoError(t, idx.UpdateWithIds(0, 10, []float32{-1, 0}))
+	require.NoError(t, idx.AddWithIds([]float32{0.6, 0.8}, []int64{30}))
+	require.NoError(t, idx.RemoveIds([]int64{30}))
+	require.NoError(t, idx.Close())
+
+	// an incomplete record is dropped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	idx, err = OpenMemoryIndex(path, 2, 0.5)
+	require.NoError(t, err)
+	defer idx.Close()
+	require.Equal(t, 2, idx.Len())
+	_, _, xids, err := idx.Search([]float32{-1, 0, 0, 1})
+	require.NoError(t, err)
+	require.Equal(t, []int64{10, 20}, xids)
+
+	// compacted to the latest vectors
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, int64(2*idx.recordSize()), info.Size())
+}
@@ -0,0 +1,44 @@
+package vecindex
+
+import (
+	"time"
+
+	"github.com/infinivision/hyena/pkg/proxy"
+	"github.com/pkg/errors"
+)
+
+const (
+	// KindHyena is the Hyena cluster accessed via MQ and PD
+	KindHyena = "hyena"
+	// KindMemory is the in-process exact inner-product index
+	KindMemory = "memory"
+)
+
+// VectorIndex searches normalized vectors by inner product. The methods have
+// the same semantics as the Hyena proxy, so proxy.Proxy is a VectorIndex.
+type VectorIndex interface {
+	// Search returns the best db, distance and xid of each vector in xq,
+	// the xid is -1 if nothing is better than the distance threshold of the index.
+	Search(xq []float32) (dbs []uint64, distances []float32, xids []int64, err error)
+	// AddWithIds adds len(xids) vectors
+	AddWithIds(xb []float32, xids []int64) error
+	// UpdateWithIds replaces the vector of xid in db
+	UpdateWithIds(db uint64, xid int64, xb []float32) error
+}
+
+// Remover is implemented by the indexes that can remove vectors. Hyena can't,
+// its vectors can only be overwritten.
+type Remover interface {
+	RemoveIds(xids []int64) error
+}
+
+// NewHyenaIndex returns the Hyena proxy as a VectorIndex
+func NewHyenaIndex(mqs, pds []string, searchTimeout time.Duration) (idx VectorIndex, err error) {
+	var p proxy.Proxy
+	if p, err = proxy.NewMQBasedProxy("hyena", mqs, pds, proxy.WithSearchTimeout(searchTimeout)); err != nil {
+		err = errors.Wrap(err, "")
+		return
+	}
+	idx = p
+	return
+}